@@ -0,0 +1,253 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BatchMode says what happens to the rest of a batch of pick-up schedules when
+// one of them cannot be saved
+type BatchMode string
+
+const (
+	// BatchModeAllOrNothing keeps the schedules of a batch only when every one
+	// of them could be saved
+	BatchModeAllOrNothing BatchMode = "all_or_nothing"
+	// BatchModeBestEffort keeps every schedule of a batch that could be saved
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+// BatchModes are the modes a batch can be submitted in
+var BatchModes = []BatchMode{BatchModeAllOrNothing, BatchModeBestEffort}
+
+// maxBatchSize is the most pick-up schedules one batch can hold
+const maxBatchSize = 100
+
+// ScheduleBatch is a list of pick-up schedules submitted at once. Mode is
+// all_or_nothing when left out.
+type ScheduleBatch struct {
+	Mode      BatchMode        `json:"mode"`
+	Schedules []PickUpSchedule `json:"schedules"`
+}
+
+// BatchItemResult is the outcome of one schedule of a batch, which is the
+// index-th of the request
+type BatchItemResult struct {
+	Index     int             `json:"index"`
+	Status    string          `json:"status"`
+	IsSuccess bool            `json:"is_success"`
+	Message   string          `json:"message"`
+	Data      *PickUpSchedule `json:"data,omitempty"`
+	Errors    []FieldError    `json:"errors,omitempty"`
+	// ExistingScheduleID and NextOpenDate are set like in PostResponse
+	ExistingScheduleID string `json:"existing_schedule_id,omitempty"`
+	NextOpenDate       string `json:"next_open_date,omitempty"`
+}
+
+type BatchResponse struct {
+	Status    string    `json:"status"`
+	IsSuccess bool      `json:"is_success"`
+	Message   string    `json:"message"`
+	Mode      BatchMode `json:"mode"`
+	TotalData int       `json:"total_data"`
+	// Saved and Failed count the schedules of the batch that were kept and
+	// the ones that were not
+	Saved  int               `json:"saved"`
+	Failed int               `json:"failed"`
+	Data   []BatchItemResult `json:"data"`
+	Errors []FieldError      `json:"errors,omitempty"`
+}
+
+// SubmitPickUpSchedulesService saves a batch of pick-up schedules, each
+// checked like in SubmitPickUpScheduleService, and returns the outcome of
+// every one of them in the order of the batch.
+//
+// A best_effort batch keeps the schedules that could be saved and succeeds
+// even when some could not. An all_or_nothing batch is checked as a whole,
+// each schedule along with the ones before it, and saved by the repository at
+// once, so nothing of it is stored when one schedule cannot be saved, and the
+// error of that schedule is returned.
+func (s *bookService) SubmitPickUpSchedulesService(ctx context.Context, batch ScheduleBatch) (BatchResponse, error) {
+	if batch.Mode == "" {
+		batch.Mode = BatchModeAllOrNothing
+	}
+
+	var fields []FieldError
+	if batch.Mode != BatchModeAllOrNothing && batch.Mode != BatchModeBestEffort {
+		fields = append(fields, FieldError{Field: "mode", Message: fmt.Sprintf("must be one of %v", BatchModes)})
+	}
+	if len(batch.Schedules) == 0 || len(batch.Schedules) > maxBatchSize {
+		fields = append(fields, FieldError{Field: "schedules", Message: fmt.Sprintf("must hold 1 to %d pick-up schedules", maxBatchSize)})
+	}
+	if len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return BatchResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   err.Error(),
+			Mode:      batch.Mode,
+			Data:      []BatchItemResult{},
+			Errors:    fields,
+		}, err
+	}
+
+	if batch.Mode == BatchModeBestEffort {
+		return s.submitBestEffort(ctx, batch), nil
+	}
+	return s.submitAllOrNothing(ctx, batch)
+}
+
+// submitBestEffort saves every schedule of the batch that can be saved
+func (s *bookService) submitBestEffort(ctx context.Context, batch ScheduleBatch) BatchResponse {
+	results := make([]BatchItemResult, len(batch.Schedules))
+	for i, schedule := range batch.Schedules {
+		response, _ := s.SubmitPickUpScheduleService(ctx, schedule)
+		results[i] = batchItemResultOf(i, response)
+	}
+
+	return batchResponse(batch.Mode, results, nil)
+}
+
+// submitAllOrNothing checks every schedule of the batch, each against the ones
+// before it, and saves them all at once
+func (s *bookService) submitAllOrNothing(ctx context.Context, batch ScheduleBatch) (BatchResponse, error) {
+	results := make([]BatchItemResult, len(batch.Schedules))
+	schedules := make([]PickUpSchedule, len(batch.Schedules))
+	branches := make([]Branch, len(batch.Schedules))
+
+	var invalid error
+	for i, schedule := range batch.Schedules {
+		// Availability is computed, never taken from the request
+		schedule.BookInfo.AvailableCopies = nil
+		branch, err := s.validatePickUpSchedule(ctx, &schedule)
+		if err != nil {
+			results[i] = batchItemResultOf(i, invalidPostResponse(err))
+			if invalid == nil {
+				invalid = fmt.Errorf("pick-up schedule %d of the batch: %w", i, err)
+			}
+		}
+		schedules[i], branches[i] = schedule, branch
+	}
+	if invalid != nil {
+		for i := range results {
+			if results[i].Status == "" {
+				results[i] = notSavedItemResult(i, "not saved, another pick-up schedule of the batch is invalid")
+			}
+		}
+		return batchResponse(batch.Mode, results, invalid), invalid
+	}
+
+	// The schedules before one are not saved yet, so they are checked along
+	// with the stored ones as the requests they will be saved as
+	reservations := make([][]Reservation, len(schedules))
+	earlier := make([]PickUpSchedule, 0, len(schedules))
+	for i, schedule := range schedules {
+		var err error
+		reservations[i], err = s.checkNewPickUpSchedule(ctx, schedule, branches[i], earlier...)
+		if err != nil {
+			return failedBatch(batch.Mode, results, i, limitOf(err, schedule))
+		}
+		schedule.Status = ScheduleStatusRequested
+		earlier = append(earlier, schedule)
+	}
+
+	saved, err := s.repository.SavePickUpSchedules(ctx, schedules, reservations)
+	var batchErr *BatchSaveError
+	if errors.As(err, &batchErr) {
+		failed := schedules[batchErr.Index]
+		return failedBatch(batch.Mode, results, batchErr.Index, s.conflictOf(ctx, limitOf(batchErr.Err, failed), failed, branches[batchErr.Index]))
+	}
+	if err != nil {
+		for i := range results {
+			results[i] = batchItemResultOf(i, unsavedPostResponse(err))
+		}
+		return batchResponse(batch.Mode, results, err), err
+	}
+
+	// Borrowers and webhooks are only told once the batch is kept
+	for i, schedule := range saved {
+		results[i] = batchItemResultOf(i, PostResponse{
+			Status:    "201 CREATED",
+			IsSuccess: true,
+			Message:   "save new data books successfully!",
+			TotalData: 1,
+			Data:      schedule,
+		})
+		s.publish(ctx, WebhookScheduleCreated, schedule)
+		s.notifyOf(ctx, NotificationConfirmation, schedule)
+	}
+	return batchResponse(batch.Mode, results, nil), nil
+}
+
+// failedBatch answers an all_or_nothing batch of which nothing is saved because
+// the failed-th schedule could not be
+func failedBatch(mode BatchMode, results []BatchItemResult, failed int, err error) (BatchResponse, error) {
+	for i := range results {
+		results[i] = notSavedItemResult(i, fmt.Sprintf("not saved, pick-up schedule %d of the batch could not be saved", failed))
+	}
+	results[failed] = batchItemResultOf(failed, unsavedPostResponse(err))
+	err = fmt.Errorf("pick-up schedule %d of the batch: %w", failed, err)
+	return batchResponse(mode, results, err), err
+}
+
+// batchResponse sums up the results of a batch, err is the error of a batch
+// that failed as a whole
+func batchResponse(mode BatchMode, results []BatchItemResult, err error) BatchResponse {
+	response := BatchResponse{
+		Mode:      mode,
+		TotalData: len(results),
+		Data:      results,
+	}
+	for _, result := range results {
+		if result.IsSuccess {
+			response.Saved++
+		} else {
+			response.Failed++
+		}
+	}
+
+	switch {
+	case err != nil:
+		response.Status = statusOf(err)
+		response.Message = fmt.Sprintf("failed to save the batch: %v", err)
+	case response.Failed > 0:
+		response.Status = "207 Multi-Status"
+		response.Message = fmt.Sprintf("saved %d of %d pick-up schedules", response.Saved, len(results))
+	default:
+		response.Status = "201 CREATED"
+		response.IsSuccess = true
+		response.Message = "save new data books successfully!"
+	}
+
+	return response
+}
+
+// batchItemResultOf turns the answer to one schedule of a batch into its result
+func batchItemResultOf(index int, response PostResponse) BatchItemResult {
+	result := BatchItemResult{
+		Index:              index,
+		Status:             response.Status,
+		IsSuccess:          response.IsSuccess,
+		Message:            response.Message,
+		Errors:             response.Errors,
+		ExistingScheduleID: response.ExistingScheduleID,
+		NextOpenDate:       response.NextOpenDate,
+	}
+	if response.IsSuccess {
+		schedule := response.Data
+		result.Data = &schedule
+	}
+	return result
+}
+
+// notSavedItemResult is the result of a schedule of an all_or_nothing batch
+// that is not kept because of another one
+func notSavedItemResult(index int, message string) BatchItemResult {
+	return BatchItemResult{
+		Index:     index,
+		Status:    "424 Failed Dependency",
+		IsSuccess: false,
+		Message:   message,
+	}
+}
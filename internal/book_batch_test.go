@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBookService_SubmitPickUpSchedulesService(t *testing.T) {
+	ctx := context.Background()
+	book := Book{Title: "TestBook"}
+	other := Book{Title: "OtherBook"}
+	newService := func() (BookService, BookRepository) {
+		repository := NewInMemoryRepository(ctx)
+		return NewService(repository, newMockBranches(testBranch).stock(5, "fiction", book, other), newMockBorrowers(testBorrower), WithClock(serviceClock)), repository
+	}
+	scheduleOf := func(book Book, date string) PickUpSchedule {
+		return PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate(date)}
+	}
+	statuses := func(response BatchResponse) []string {
+		var statuses []string
+		for _, result := range response.Data {
+			statuses = append(statuses, result.Status)
+		}
+		return statuses
+	}
+	count := func(repository BookRepository, status ScheduleStatus) int {
+		page, err := repository.ListPickUpSchedules(ctx, ScheduleQuery{Status: status, Sort: "created_at", Limit: maxScheduleLimit})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return len(page.Schedules)
+	}
+
+	t.Run("PositiveCase_AllSaved", func(t *testing.T) {
+		service, repository := newService()
+
+		response, err := service.SubmitPickUpSchedulesService(ctx, ScheduleBatch{Schedules: []PickUpSchedule{
+			scheduleOf(book, "2023-11-21T10:00:00Z"),
+			scheduleOf(other, "2023-11-22T10:00:00Z"),
+		}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !response.IsSuccess || response.Mode != BatchModeAllOrNothing || response.Saved != 2 || response.Failed != 0 {
+			t.Errorf("Expected both schedules saved, got %+v", response)
+		}
+		if response.Data[1].Index != 1 || response.Data[1].Data == nil || response.Data[1].Data.BookInfo.Title != "OtherBook" {
+			t.Errorf("Expected the results in the order of the batch, got %+v", response.Data)
+		}
+		if got := count(repository, ScheduleStatusRequested); got != 2 {
+			t.Errorf("Expected 2 stored schedules, got %d", got)
+		}
+	})
+
+	t.Run("PositiveCase_BestEffort", func(t *testing.T) {
+		service, repository := newService()
+
+		response, err := service.SubmitPickUpSchedulesService(ctx, ScheduleBatch{Mode: BatchModeBestEffort, Schedules: []PickUpSchedule{
+			scheduleOf(book, "2023-11-21T10:00:00Z"),
+			// The same book on the same day as the first one
+			scheduleOf(book, "2023-11-21T14:00:00Z"),
+			scheduleOf(Book{}, "2023-11-22T10:00:00Z"),
+			scheduleOf(other, "2023-11-22T10:00:00Z"),
+		}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := []string{"201 CREATED", "409 Conflict", "400 Bad Request", "201 CREATED"}
+		if got := statuses(response); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+			t.Errorf("Expected item statuses %v, got %v", want, got)
+		}
+		if response.Status != "207 Multi-Status" || response.IsSuccess || response.Saved != 2 || response.Failed != 2 {
+			t.Errorf("Expected a partly saved batch, got %+v", response)
+		}
+		if response.Data[1].ExistingScheduleID != response.Data[0].Data.ID {
+			t.Errorf("Expected the duplicate to point to the first schedule, got %+v", response.Data[1])
+		}
+		if len(response.Data[2].Errors) != 1 || response.Data[2].Errors[0].Field != "book_info.title" {
+			t.Errorf("Expected the invalid field, got %+v", response.Data[2])
+		}
+		if got := count(repository, ScheduleStatusRequested); got != 2 {
+			t.Errorf("Expected 2 stored schedules, got %d", got)
+		}
+	})
+
+	t.Run("NegativeCase_AllOrNothingInvalid", func(t *testing.T) {
+		service, repository := newService()
+
+		response, err := service.SubmitPickUpSchedulesService(ctx, ScheduleBatch{Mode: BatchModeAllOrNothing, Schedules: []PickUpSchedule{
+			scheduleOf(book, "2023-11-21T10:00:00Z"),
+			scheduleOf(Book{}, "2023-11-22T10:00:00Z"),
+		}})
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected *ValidationError, got %v", err)
+		}
+
+		if response.Status != "400 Bad Request" || response.Saved != 0 {
+			t.Errorf("Expected a rejected batch, got %+v", response)
+		}
+		if got := statuses(response); got[0] != "424 Failed Dependency" || got[1] != "400 Bad Request" {
+			t.Errorf("Expected the invalid schedule to fail the other, got %v", got)
+		}
+		if got := count(repository, ""); got != 0 {
+			t.Errorf("Expected nothing stored, got %d schedules", got)
+		}
+	})
+
+	t.Run("NegativeCase_AllOrNothingNothingSaved", func(t *testing.T) {
+		service, repository := newService()
+		batch := ScheduleBatch{Schedules: []PickUpSchedule{
+			scheduleOf(book, "2023-11-21T10:00:00Z"),
+			scheduleOf(other, "2023-11-21T12:00:00Z"),
+			// The same book on the same day as the first one
+			scheduleOf(book, "2023-11-21T14:00:00Z"),
+			scheduleOf(other, "2023-11-23T10:00:00Z"),
+		}}
+
+		response, err := service.SubmitPickUpSchedulesService(ctx, batch)
+		if !errors.Is(err, ErrDuplicateSchedule) {
+			t.Fatalf("Expected ErrDuplicateSchedule, got %v", err)
+		}
+
+		want := []string{"424 Failed Dependency", "424 Failed Dependency", "409 Conflict", "424 Failed Dependency"}
+		if got := statuses(response); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+			t.Errorf("Expected item statuses %v, got %v", want, got)
+		}
+		if response.Status != "409 Conflict" || response.Saved != 0 || response.Failed != 4 {
+			t.Errorf("Expected a failed batch, got %+v", response)
+		}
+		if response.Data[0].Data != nil {
+			t.Errorf("Expected no data for the first schedule, got %+v", response.Data[0].Data)
+		}
+		if got := count(repository, ""); got != 0 {
+			t.Errorf("Expected nothing stored, got %d schedules", got)
+		}
+
+		// The failed batch holds nothing
+		batch.Schedules = batch.Schedules[:2]
+		if _, err := service.SubmitPickUpSchedulesService(ctx, batch); err != nil {
+			t.Errorf("Expected the batch to be saved, got %v", err)
+		}
+	})
+
+	t.Run("NegativeCase_AllOrNothingWeeklyLimit", func(t *testing.T) {
+		service, repository := newService()
+
+		// One more pick-up in the week than the borrower is allowed
+		response, err := service.SubmitPickUpSchedulesService(ctx, ScheduleBatch{Schedules: []PickUpSchedule{
+			scheduleOf(book, "2023-11-21T10:00:00Z"),
+			scheduleOf(other, "2023-11-22T10:00:00Z"),
+			scheduleOf(book, "2023-11-23T10:00:00Z"),
+			scheduleOf(other, "2023-11-24T10:00:00Z"),
+		}})
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("Expected *LimitError, got %v", err)
+		}
+
+		if got := statuses(response); got[2] != "424 Failed Dependency" || got[3] == "424 Failed Dependency" {
+			t.Errorf("Expected the last schedule to fail the others, got %v", got)
+		}
+		if got := count(repository, ""); got != 0 {
+			t.Errorf("Expected nothing stored, got %d schedules", got)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidBatch", func(t *testing.T) {
+		service, _ := newService()
+
+		for name, batch := range map[string]ScheduleBatch{
+			"schedules": {},
+			"mode":      {Mode: "some", Schedules: []PickUpSchedule{scheduleOf(book, "2023-11-21T10:00:00Z")}},
+		} {
+			response, err := service.SubmitPickUpSchedulesService(ctx, batch)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected *ValidationError, got %v", err)
+			}
+			if response.Status != "400 Bad Request" || len(response.Errors) != 1 || response.Errors[0].Field != name {
+				t.Errorf("Expected a 400 on %s, got %+v", name, response)
+			}
+		}
+	})
+}
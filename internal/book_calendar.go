@@ -0,0 +1,228 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CalendarResponse carries an iCalendar feed, which is served as text/calendar
+// when the request succeeded and as JSON otherwise
+type CalendarResponse struct {
+	Status    string       `json:"status"`
+	IsSuccess bool         `json:"is_success"`
+	Message   string       `json:"message"`
+	TotalData int          `json:"total_data"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Calendar is the rendered feed with one event per pick-up
+	Calendar []byte `json:"-"`
+}
+
+func (s *bookService) GetPickUpScheduleCalendarService(ctx context.Context, id string) (CalendarResponse, error) {
+	schedule, err := s.repository.GetPickUpScheduleByID(ctx, id)
+	if err != nil {
+		return failedCalendarResponse("failed to fetch pick-up schedule calendar", err), err
+	}
+
+	return s.calendarResponse(ctx, fmt.Sprintf("Pick-up of %s", schedule.BookInfo.Title), []PickUpSchedule{schedule})
+}
+
+// GetBorrowerCalendarService returns every pick-up of the borrower, cancelled
+// ones included so subscribed calendars drop them
+func (s *bookService) GetBorrowerCalendarService(ctx context.Context, borrowerID string) (CalendarResponse, error) {
+	borrower, err := s.borrowers.GetBorrower(ctx, borrowerID)
+	if err != nil {
+		return failedCalendarResponse("failed to fetch borrower calendar", err), err
+	}
+
+	schedules, err := s.allSchedules(ctx, ScheduleQuery{BorrowerID: borrower.ID, Sort: "pick_up_date"})
+	if err != nil {
+		return failedCalendarResponse("failed to fetch borrower calendar", err), err
+	}
+
+	return s.calendarResponse(ctx, fmt.Sprintf("Pick-ups of %s", borrower.Name), schedules)
+}
+
+// GetBranchCalendarService returns the pick-ups at the branch on a day as
+// 2006-01-02 in its time zone, today when day is empty
+func (s *bookService) GetBranchCalendarService(ctx context.Context, branchID, day string) (CalendarResponse, error) {
+	branch, err := s.branches.GetBranch(ctx, branchID)
+	if err != nil {
+		return failedCalendarResponse("failed to fetch branch calendar", err), err
+	}
+	loc, err := branch.Location()
+	if err != nil {
+		return failedCalendarResponse("failed to fetch branch calendar", err), err
+	}
+
+	start, err := s.branchDay(day, loc)
+	if err != nil {
+		return failedCalendarResponse("invalid branch calendar", err), err
+	}
+	onDay, err := s.schedulesOnDay(ctx, branch.ID, start)
+	if err != nil {
+		return failedCalendarResponse("failed to fetch branch calendar", err), err
+	}
+
+	return s.calendarResponse(ctx, fmt.Sprintf("Pick-ups at %s on %s", branch.Name, start.Format(pickUpDayLayout)), onDay)
+}
+
+// branchDay returns the midnight starting the day as 2006-01-02 in the time
+// zone loc of a branch, today when day is empty
+func (s *bookService) branchDay(day string, loc *time.Location) (time.Time, error) {
+	if day == "" {
+		return midnight(s.now().In(loc)), nil
+	}
+	date, err := time.ParseInLocation(pickUpDayLayout, day, loc)
+	if err != nil {
+		return time.Time{}, &ValidationError{Fields: []FieldError{{Field: "date", Message: "must be a date like 2023-12-01"}}}
+	}
+	return date, nil
+}
+
+// schedulesOnDay returns the schedules at the branch picked up on the local
+// day starting at start, cancelled ones included, by pick-up date
+func (s *bookService) schedulesOnDay(ctx context.Context, branchID string, start time.Time) ([]PickUpSchedule, error) {
+	day := start.Format(pickUpDayLayout)
+	return s.allSchedules(ctx, ScheduleQuery{
+		BranchID:   branchID,
+		PickUpFrom: day,
+		PickUpTo:   day,
+		Sort:       "pick_up_date",
+	})
+}
+
+// calendarResponse renders the schedules as a calendar named name
+func (s *bookService) calendarResponse(ctx context.Context, name string, schedules []PickUpSchedule) (CalendarResponse, error) {
+	calendar := Calendar{Name: name}
+	branches := map[string]*Branch{}
+	for _, schedule := range schedules {
+		// Dates stored before they were checked cannot be placed in a calendar
+		if schedule.PickUpDate.IsZero() || schedule.PickUpDate.Err() != nil {
+			continue
+		}
+
+		branch, cached := branches[schedule.BranchID]
+		if !cached && schedule.BranchID != "" {
+			found, err := s.branches.GetBranch(ctx, schedule.BranchID)
+			switch {
+			case errors.Is(err, ErrBranchNotFound):
+			case err != nil:
+				return failedCalendarResponse("failed to fetch branch of pick-up schedule", err), err
+			default:
+				branch = &found
+			}
+			branches[schedule.BranchID] = branch
+		}
+
+		history, err := s.repository.GetPickUpScheduleHistory(ctx, schedule.ID)
+		if err != nil {
+			return failedCalendarResponse("failed to fetch history of pick-up schedule", err), err
+		}
+
+		calendar.Events = append(calendar.Events, s.calendarEventOf(schedule, len(history), branch))
+	}
+
+	response := CalendarResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch pick-up calendar successfully!",
+		TotalData: len(calendar.Events),
+		Calendar:  calendar.ICS(s.now()),
+	}
+
+	return response, nil
+}
+
+// calendarEventOf returns the event of a pick-up at branch, which is nil when
+// the schedule has no branch, changed as many times as its history has changes
+func (s *bookService) calendarEventOf(schedule PickUpSchedule, changes int, branch *Branch) CalendarEvent {
+	// The sequence of a changed event has to grow. A cancellation is not in the
+	// history but is the last change a schedule can get.
+	sequence := changes
+	if schedule.Cancellation != nil {
+		sequence++
+	}
+
+	at := schedule.PickUpDate.Time()
+	event := CalendarEvent{
+		UID:          schedule.ID + "@" + calendarUIDDomain,
+		Sequence:     sequence,
+		Summary:      fmt.Sprintf("Pick up %s", schedule.BookInfo.Title),
+		Start:        at,
+		End:          at.Add(s.slots.Length),
+		AllDay:       !schedule.PickUpDate.HasTime(),
+		Timezone:     schedule.Timezone,
+		Created:      schedule.CreatedAt,
+		LastModified: schedule.UpdatedAt,
+	}
+
+	switch schedule.Status {
+	case ScheduleStatusRequested, scheduleStatusActive:
+		event.Status = CalendarEventTentative
+	case ScheduleStatusCancelled, ScheduleStatusNoShow:
+		event.Status = CalendarEventCancelled
+	default:
+		event.Status = CalendarEventConfirmed
+	}
+
+	description := []string{
+		fmt.Sprintf("Genre: %s", schedule.Genre),
+		fmt.Sprintf("Status: %s", schedule.Status),
+		fmt.Sprintf("Pick-up schedule: %s", schedule.ID),
+	}
+	if schedule.Cancellation != nil && schedule.Cancellation.Reason != "" {
+		description = append(description, fmt.Sprintf("Cancelled: %s", schedule.Cancellation.Reason))
+	}
+	event.Description = strings.Join(description, "\n")
+
+	if branch != nil {
+		event.Location = branch.Name
+		if branch.Address != "" {
+			event.Location += ", " + branch.Address
+		}
+		// Schedules booked before time zones were attached take the branch's
+		if event.Timezone == "" {
+			event.Timezone = branch.Timezone
+		}
+	}
+
+	return event
+}
+
+// allSchedules returns every schedule matching the query, going through all
+// of its pages
+func (s *bookService) allSchedules(ctx context.Context, query ScheduleQuery) ([]PickUpSchedule, error) {
+	query.Limit = maxScheduleLimit
+	var schedules []PickUpSchedule
+	for {
+		page, err := s.repository.ListPickUpSchedules(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, page.Schedules...)
+		if page.NextCursor == "" {
+			return schedules, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+// failedCalendarResponse describes an error of a calendar request
+func failedCalendarResponse(message string, err error) CalendarResponse {
+	response := CalendarResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   fmt.Sprintf("%s: %v", message, err),
+		TotalData: 0,
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Errors = validationErr.Fields
+	}
+
+	return response
+}
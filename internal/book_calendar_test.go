@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBookService_CalendarServices(t *testing.T) {
+	ctx := context.Background()
+	jakarta := Branch{ID: "jakarta", Name: "Jakarta", Address: "Jalan Sudirman 1", Timezone: "Asia/Jakarta", OpeningHours: everyDay("00:00", "24:00")}
+	book := Book{Title: "TestBook"}
+	service := NewService(NewInMemoryRepository(ctx), newMockBranches(jakarta).stock(5, "fiction", book),
+		newMockBorrowers(testBorrower), WithClock(serviceClock))
+	submit := func(date string) PickUpSchedule {
+		t.Helper()
+		response, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BranchID: "jakarta", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate(date)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return response.Data
+	}
+
+	// 06:00 in Jakarta is still the day before in UTC
+	early := submit("2023-11-22T06:00")
+	late := submit("2023-11-23T16:00")
+	cancelled := submit("2023-11-24T10:00")
+	if _, err := service.CancelPickUpScheduleService(ctx, cancelled.ID, Cancellation{CancelledBy: "reader", Reason: "away, sorry"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("PositiveCase_Schedule", func(t *testing.T) {
+		response, err := service.GetPickUpScheduleCalendarService(ctx, early.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		feed := string(response.Calendar)
+		for _, want := range []string{
+			"X-WR-CALNAME:Pick-up of TestBook\r\n",
+			"TZID:Asia/Jakarta\r\n",
+			"UID:" + early.ID + "@" + calendarUIDDomain + "\r\n",
+			"DTSTART;TZID=Asia/Jakarta:20231122T060000\r\n",
+			"DTEND;TZID=Asia/Jakarta:20231122T063000\r\n",
+			"STATUS:TENTATIVE\r\n",
+			"SEQUENCE:0\r\n",
+			"SUMMARY:Pick up TestBook\r\n",
+			`LOCATION:Jakarta\, Jalan Sudirman 1` + "\r\n",
+		} {
+			if !strings.Contains(feed, want) {
+				t.Errorf("Expected %q in %q", want, feed)
+			}
+		}
+		if response.TotalData != 1 || response.Status != "200 OK" {
+			t.Errorf("Expected one event, got %+v", response)
+		}
+	})
+
+	t.Run("PositiveCase_Borrower", func(t *testing.T) {
+		response, err := service.GetBorrowerCalendarService(ctx, "reader")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		feed := string(response.Calendar)
+		if response.TotalData != 3 || strings.Count(feed, "BEGIN:VEVENT") != 3 {
+			t.Errorf("Expected every pick-up of the borrower, got %d in %q", response.TotalData, feed)
+		}
+		// The cancelled pick-up stays in the feed so calendars drop it
+		for _, want := range []string{
+			"X-WR-CALNAME:Pick-ups of " + testBorrower.Name + "\r\n",
+			"UID:" + cancelled.ID + "@" + calendarUIDDomain + "\r\n",
+			"STATUS:CANCELLED\r\n",
+			`Cancelled: away\, sorry`,
+		} {
+			if !strings.Contains(feed, want) {
+				t.Errorf("Expected %q in %q", want, feed)
+			}
+		}
+	})
+
+	t.Run("PositiveCase_SequenceGrowsWithEveryChange", func(t *testing.T) {
+		sequenceOf := func(id string) string {
+			t.Helper()
+			response, err := service.GetPickUpScheduleCalendarService(ctx, id)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, line := range strings.Split(string(response.Calendar), "\r\n") {
+				if strings.HasPrefix(line, "SEQUENCE:") {
+					return line
+				}
+			}
+			return ""
+		}
+
+		// Changes within the same second still count
+		moved := submit("2023-11-25T10:00")
+		if _, err := service.ReschedulePickUpScheduleService(ctx, moved.ID, Reschedule{PickUpDate: mustParsePickUpDate("2023-11-25T11:00"), ChangedBy: "reader"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := sequenceOf(moved.ID); got != "SEQUENCE:1" {
+			t.Errorf("Expected SEQUENCE:1 after a reschedule, got %q", got)
+		}
+		if _, err := service.CancelPickUpScheduleService(ctx, moved.ID, Cancellation{CancelledBy: "reader"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := sequenceOf(moved.ID); got != "SEQUENCE:2" {
+			t.Errorf("Expected SEQUENCE:2 after a cancellation, got %q", got)
+		}
+	})
+
+	t.Run("PositiveCase_BranchDay", func(t *testing.T) {
+		for day, want := range map[string]string{
+			"2023-11-22": early.ID,
+			"2023-11-23": late.ID,
+		} {
+			response, err := service.GetBranchCalendarService(ctx, "jakarta", day)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			feed := string(response.Calendar)
+			if response.TotalData != 1 || !strings.Contains(feed, "UID:"+want+"@") {
+				t.Errorf("Expected only %s on %s, got %q", want, day, feed)
+			}
+		}
+	})
+
+	t.Run("PositiveCase_BranchToday", func(t *testing.T) {
+		response, err := service.GetBranchCalendarService(ctx, "jakarta", "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.TotalData != 0 || !strings.Contains(string(response.Calendar), "Pick-ups at Jakarta on 2023-11-20") {
+			t.Errorf("Expected an empty calendar of today, got %q", response.Calendar)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		_, err := service.GetPickUpScheduleCalendarService(ctx, "missing")
+		if !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+		_, err = service.GetBorrowerCalendarService(ctx, "missing")
+		if !errors.Is(err, ErrBorrowerNotFound) {
+			t.Errorf("Expected ErrBorrowerNotFound, got %v", err)
+		}
+		response, err := service.GetBranchCalendarService(ctx, "missing", "")
+		if !errors.Is(err, ErrBranchNotFound) || response.Status != "404 Not Found" {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidDate", func(t *testing.T) {
+		response, err := service.GetBranchCalendarService(ctx, "jakarta", "22-11-2023")
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected *ValidationError, got %v", err)
+		}
+		if response.Status != "400 Bad Request" || len(response.Errors) != 1 || response.Errors[0].Field != "date" {
+			t.Errorf("Expected a 400 on date, got %+v", response)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDuplicateSchedule is matched by a *ScheduleConflictError for a
+	// borrower booking the same book on the same day twice
+	ErrDuplicateSchedule = errors.New("duplicate pick-up schedule")
+	// ErrScheduleConflict is matched by a *ScheduleConflictError for a
+	// borrower booking overlapping slots at different branches
+	ErrScheduleConflict = errors.New("conflicting pick-up schedule")
+)
+
+const (
+	// pickUpDayKeyPrefix starts the reservation keys of a book a borrower
+	// picks up on a day
+	pickUpDayKeyPrefix = "pick-up-day:"
+	// pickUpTimeKeyPrefix starts the reservation keys of the time a borrower
+	// spends at pick-ups
+	pickUpTimeKeyPrefix = "pick-up-time:"
+)
+
+// pickUpDayKey is the reservation key of the book of a borrower picked up on
+// the local day, which only one schedule can hold
+func pickUpDayKey(borrowerID, genre string, book Book, day string) string {
+	return pickUpDayKeyPrefix + borrowerID + ":" + bookKey(genre, book) + ":" + day
+}
+
+// pickUpTimeReservations are what a pick-up of the borrower at the branch in
+// the slot from start claims, so that no pick-up of the borrower at another
+// branch overlaps it. Time is cut into steps of the slots, which overlap
+// exactly when they share a step. A step is held by the pick-ups in it and at
+// the branch by the ones at the branch, which are not in the way.
+func (c SlotConfig) pickUpTimeReservations(borrowerID, branchID string, start time.Time) []Reservation {
+	step := c.step()
+	end := start.Add(c.Length)
+	var reservations []Reservation
+	for at := start.UTC().Truncate(step); at.Before(end); at = at.Add(step) {
+		key := pickUpTimeKeyPrefix + borrowerID + ":" + at.Format(time.RFC3339)
+		atBranch := key + ":" + branchID
+		reservations = append(reservations, Reservation{Key: key, Limit: 1, Excluded: []string{atBranch}}, Reservation{Key: atBranch})
+	}
+	return reservations
+}
+
+// ScheduleConflictError is returned when a borrower already has a pick-up
+// schedule that a new or moved one would duplicate or overlap
+type ScheduleConflictError struct {
+	// Existing is the schedule of the borrower that is in the way
+	Existing PickUpSchedule
+	// Duplicate is set when Existing is for the same book on the same day,
+	// otherwise its slot overlaps at another branch
+	Duplicate bool
+}
+
+func (e *ScheduleConflictError) Error() string {
+	// A schedule of the same batch is not saved yet and has no ID
+	existing := "pick-up schedule " + e.Existing.ID
+	if e.Existing.ID == "" {
+		existing = "another pick-up schedule"
+	}
+	if e.Duplicate {
+		return fmt.Sprintf("%v: borrower %s already has %s for %q on %s", ErrDuplicateSchedule, e.Existing.BorrowerID, existing, e.Existing.BookInfo.Title, e.Existing.PickUpDate)
+	}
+	branch := "branch " + e.Existing.BranchID
+	if e.Existing.BranchID == "" {
+		branch = "another branch"
+	}
+	return fmt.Sprintf("%v: borrower %s already has %s at %s at %s", ErrScheduleConflict, e.Existing.BorrowerID, existing, branch, e.Existing.PickUpDate)
+}
+
+func (e *ScheduleConflictError) Is(target error) bool {
+	if e.Duplicate {
+		return target == ErrDuplicateSchedule
+	}
+	return target == ErrScheduleConflict
+}
+
+// checkScheduleConflicts fails with a *ScheduleConflictError when the
+// borrower of the schedule already has another one for the same book on the
+// same local day, or one at another branch whose slot overlaps its slot.
+// Schedules that gave their reservations back are not in the way, and a
+// duplicate is reported before a conflict. batch are the schedules saved
+// along with a new one.
+func (s *bookService) checkScheduleConflicts(ctx context.Context, schedule PickUpSchedule, branch Branch, batch ...PickUpSchedule) error {
+	if schedule.BorrowerID == "" {
+		return nil
+	}
+
+	loc, err := branch.Location()
+	if err != nil {
+		return err
+	}
+	at := schedule.PickUpDate.Time()
+	dayStart := midnight(at.In(loc))
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	// Slots overlapping this one start less than a slot length away
+	start, end := dayStart, dayEnd
+	if earliest := at.Add(-s.slots.Length); earliest.Before(start) {
+		start = earliest
+	}
+	if latest := at.Add(s.slots.Length); latest.After(end) {
+		end = latest
+	}
+
+	schedules, err := s.borrowerSchedules(ctx, schedule.BorrowerID, start, end, batch...)
+	if err != nil {
+		return fmt.Errorf("failed to check pick-up conflicts: %v", err)
+	}
+
+	var conflict error
+	key := bookKey(schedule.Genre, schedule.BookInfo)
+	for _, other := range schedules {
+		if other.ID != "" && other.ID == schedule.ID || !holdsReservations(other.Status) {
+			continue
+		}
+
+		otherAt := other.PickUpDate.Time()
+		if other.Genre == schedule.Genre && bookKey(other.Genre, other.BookInfo) == key && !otherAt.Before(dayStart) && otherAt.Before(dayEnd) {
+			return &ScheduleConflictError{Existing: other, Duplicate: true}
+		}
+		// Pick-ups saved without a time have no slot to overlap
+		overlaps := other.PickUpDate.HasTime() && otherAt.Before(at.Add(s.slots.Length)) && at.Before(otherAt.Add(s.slots.Length))
+		if conflict == nil && other.BranchID != schedule.BranchID && overlaps {
+			conflict = &ScheduleConflictError{Existing: other}
+		}
+	}
+
+	return conflict
+}
+
+// conflictOf returns the *ScheduleConflictError a full pick-up day or time
+// reservation of the borrower of the schedule at the branch stands for, and
+// any other error as it is. The schedule in the way was saved at the same
+// moment and is looked up again, or left unnamed when it cannot be found yet.
+func (s *bookService) conflictOf(ctx context.Context, err error, schedule PickUpSchedule, branch Branch) error {
+	var capacityErr *CapacityError
+	if schedule.BorrowerID == "" || !errors.As(err, &capacityErr) {
+		return err
+	}
+	duplicate := strings.HasPrefix(capacityErr.Key, pickUpDayKeyPrefix)
+	if !duplicate && !strings.HasPrefix(capacityErr.Key, pickUpTimeKeyPrefix) {
+		return err
+	}
+
+	var conflictErr *ScheduleConflictError
+	if errors.As(s.checkScheduleConflicts(ctx, schedule, branch), &conflictErr) {
+		return conflictErr
+	}
+	existing := PickUpSchedule{BorrowerID: schedule.BorrowerID, Genre: schedule.Genre, BookInfo: schedule.BookInfo, PickUpDate: schedule.PickUpDate}
+	return &ScheduleConflictError{Existing: existing, Duplicate: duplicate}
+}
@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestBookService_ScheduleConflicts(t *testing.T) {
+	ctx := context.Background()
+	emma := Book{Title: "Emma", Author: []string{"Jane Austen"}}
+	persuasion := Book{Title: "Persuasion", Author: []string{"Jane Austen"}}
+	sanditon := Book{Title: "Sanditon", Author: []string{"Jane Austen"}}
+	north := Branch{ID: "north", Name: "North", Timezone: "UTC", OpeningHours: everyDay("00:00", "24:00")}
+	kathmandu := Branch{ID: "kathmandu", Name: "Kathmandu", Timezone: "Asia/Kathmandu", OpeningHours: everyDay("00:00", "24:00")}
+	newConflictService := func() BookService {
+		return NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch, north, kathmandu).stock(2, "fiction", emma, persuasion, sanditon),
+			newMockBorrowers(testBorrower, Borrower{ID: "other", Name: "Other"}), WithClock(serviceClock))
+	}
+	submit := func(service BookService, branchID, borrowerID string, book Book, date string) (PostResponse, error) {
+		return service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BookInfo: book, BranchID: branchID, BorrowerID: borrowerID, PickUpDate: mustParsePickUpDate(date)})
+	}
+
+	t.Run("Duplicates", func(t *testing.T) {
+		service := newConflictService()
+		first, err := submit(service, "central", "reader", emma, "2023-11-21T10:00:00Z")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, tc := range []struct {
+			name, branchID, date string
+		}{
+			{"SameRequest", "central", "2023-11-21T10:00:00Z"},
+			{"LaterTheSameDay", "central", "2023-11-21T15:00:00Z"},
+			{"AnotherBranch", "north", "2023-11-21T15:00:00Z"},
+		} {
+			response, err := submit(service, tc.branchID, "reader", emma, tc.date)
+			if !errors.Is(err, ErrDuplicateSchedule) {
+				t.Errorf("%s: expected ErrDuplicateSchedule, got %v", tc.name, err)
+			}
+			if response.Status != "409 Conflict" || response.ExistingScheduleID != first.Data.ID || len(response.Errors) != 1 || response.Errors[0].Field != "book_info" {
+				t.Errorf("%s: expected 409 pointing to %s, got %+v", tc.name, first.Data.ID, response)
+			}
+		}
+
+		for _, tc := range []struct {
+			name, borrowerID string
+			book             Book
+			date             string
+		}{
+			{"AnotherBook", "reader", persuasion, "2023-11-21T10:00:00Z"},
+			{"AnotherDay", "reader", emma, "2023-11-22T10:00:00Z"},
+			{"AnotherBorrower", "other", emma, "2023-11-21T10:00:00Z"},
+		} {
+			if _, err := submit(service, "central", tc.borrowerID, tc.book, tc.date); err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+		}
+
+		if _, err := service.CancelPickUpScheduleService(ctx, first.Data.ID, Cancellation{CancelledBy: "reader"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := submit(service, "central", "reader", emma, "2023-11-21T10:00:00Z"); err != nil {
+			t.Errorf("Expected a cancelled schedule not to be a duplicate, got %v", err)
+		}
+	})
+
+	t.Run("OverlappingSlots", func(t *testing.T) {
+		service := newConflictService()
+		first, err := submit(service, "central", "reader", emma, "2023-11-21T04:30:00Z")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, tc := range []struct {
+			branchID, date string
+		}{
+			{"north", "2023-11-21T04:30:00Z"},
+			// 04:45 UTC, the slots of Kathmandu are offset by a quarter of an hour
+			{"kathmandu", "2023-11-21T10:30:00+05:45"},
+		} {
+			response, err := submit(service, tc.branchID, "reader", persuasion, tc.date)
+			if !errors.Is(err, ErrScheduleConflict) {
+				t.Errorf("Expected ErrScheduleConflict at %s, got %v", tc.branchID, err)
+			}
+			if response.Status != "409 Conflict" || response.ExistingScheduleID != first.Data.ID || len(response.Errors) != 1 || response.Errors[0].Field != "pick_up_date" {
+				t.Errorf("Expected 409 pointing to %s at %s, got %+v", first.Data.ID, tc.branchID, response)
+			}
+		}
+
+		for _, tc := range []struct {
+			branchID string
+			book     Book
+			date     string
+		}{
+			{"north", persuasion, "2023-11-21T05:00:00Z"},
+			{"central", sanditon, "2023-11-21T04:30:00Z"},
+		} {
+			if _, err := submit(service, tc.branchID, "reader", tc.book, tc.date); err != nil {
+				t.Errorf("Unexpected error at %s %s: %v", tc.branchID, tc.date, err)
+			}
+		}
+	})
+
+	t.Run("Reschedule", func(t *testing.T) {
+		service := newConflictService()
+		first, err := submit(service, "central", "reader", emma, "2023-11-21T10:00:00Z")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		second, err := submit(service, "central", "reader", emma, "2023-11-22T10:00:00Z")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		response, err := service.ReschedulePickUpScheduleService(ctx, second.Data.ID, Reschedule{PickUpDate: mustParsePickUpDate("2023-11-21T12:00:00Z"), ChangedBy: "reader"})
+		if !errors.Is(err, ErrDuplicateSchedule) || response.ExistingScheduleID != first.Data.ID {
+			t.Errorf("Expected ErrDuplicateSchedule pointing to %s, got %+v, %v", first.Data.ID, response, err)
+		}
+
+		// Moving within the same day is not a duplicate of itself
+		if _, err := service.ReschedulePickUpScheduleService(ctx, first.Data.ID, Reschedule{PickUpDate: mustParsePickUpDate("2023-11-21T12:00:00Z"), ChangedBy: "reader"}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	// Pick-ups saved at the same moment must not all pass the check before
+	// any of them is stored
+	for _, store := range []struct {
+		name string
+		open func(t *testing.T) BookRepository
+	}{
+		{"InMemory", func(t *testing.T) BookRepository { return NewInMemoryRepository(ctx) }},
+		{"SQL", func(t *testing.T) BookRepository {
+			repo, err := NewSQLRepository(ctx, openTestDB(t))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			return repo
+		}},
+	} {
+		t.Run("Concurrent_"+store.name, func(t *testing.T) {
+			for _, tc := range []struct {
+				name     string
+				branchOf func(i int) string
+				bookOf   func(i int) Book
+				date     string
+			}{
+				{"Duplicates", func(int) string { return "central" }, func(int) Book { return emma }, "2023-11-21T10:00:00Z"},
+				{"Overlaps", func(i int) string {
+					if i%2 == 0 {
+						return "central"
+					}
+					return "north"
+				}, func(i int) Book {
+					if i%2 == 0 {
+						return emma
+					}
+					return persuasion
+				}, "2023-11-21T04:30:00Z"},
+			} {
+				service := NewService(store.open(t), newMockBranches(testBranch, north, kathmandu).stock(2, "fiction", emma, persuasion, sanditon),
+					newMockBorrowers(testBorrower), WithClock(serviceClock))
+
+				const workers = 20
+				var wg sync.WaitGroup
+				errs := make(chan error, workers)
+				for i := 0; i < workers; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						_, err := submit(service, tc.branchOf(i), "reader", tc.bookOf(i), tc.date)
+						errs <- err
+					}(i)
+				}
+				wg.Wait()
+				close(errs)
+
+				saved := 0
+				for err := range errs {
+					switch {
+					case err == nil:
+						saved++
+					case !errors.Is(err, ErrDuplicateSchedule) && !errors.Is(err, ErrScheduleConflict):
+						t.Errorf("%s: expected a conflict, got %v", tc.name, err)
+					}
+				}
+				if saved != 1 {
+					t.Errorf("%s: expected exactly one schedule to be saved, got %d", tc.name, saved)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	fileLogName      = "schedules.log"
+	fileSnapshotName = "schedules.snapshot"
+
+	// fileSnapshotInterval is how often the log is folded into a new snapshot
+	fileSnapshotInterval = 5 * time.Minute
+)
+
+// errTornRecord marks a record that was not completely written, which is what
+// a crash in the middle of an append leaves behind at the end of the log.
+var errTornRecord = errors.New("torn record")
+
+// FileRepository is a BookRepository that keeps every pick-up schedule in
+// memory and makes it durable in a local directory. Every new or changed
+// schedule is appended to a log and fsynced before it becomes visible, and the log is
+// periodically folded into a snapshot. On startup the snapshot and the log are
+// replayed, dropping a partially written record left behind by a crash.
+type FileRepository struct {
+	*InMemoryRepository
+
+	dir string
+
+	// mu guards the log file and the sequence counter
+	mu      sync.Mutex
+	log     *os.File
+	seq     uint64
+	pending int
+
+	snapshotMu sync.Mutex
+	stopOnce   sync.Once
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// logRecord is a single entry of the append-only log, holding the latest
+// version of everything stored about one schedule, or with Batch of every
+// schedule of a batch saved at once
+type logRecord struct {
+	Seq uint64 `json:"seq"`
+	scheduleRecord
+	Batch []scheduleRecord `json:"batch,omitempty"`
+}
+
+// snapshotRecord holds every schedule written up to and including Seq, and
+// the change histories and reservation keys by schedule ID
+type snapshotRecord struct {
+	Seq          uint64                      `json:"seq"`
+	Schedules    []PickUpSchedule            `json:"schedules"`
+	Histories    map[string][]ScheduleChange `json:"histories,omitempty"`
+	Reservations map[string][]string         `json:"reservations,omitempty"`
+}
+
+func NewFileRepository(ctx context.Context, dir string) (*FileRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	r := &FileRepository{
+		InMemoryRepository: NewInMemoryRepository(ctx),
+		dir:                dir,
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+
+	if err := r.recover(); err != nil {
+		return nil, err
+	}
+	r.persist = r.appendRecord
+
+	go r.snapshotLoop(ctx)
+
+	return r, nil
+}
+
+// Snapshot writes every stored schedule to a new snapshot and empties the log.
+// Writers are blocked while the snapshot is written.
+func (r *FileRepository) Snapshot() error {
+	r.snapshotMu.Lock()
+	defer r.snapshotMu.Unlock()
+
+	records, unfreeze := r.freeze()
+	defer unfreeze()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.log == nil {
+		return fmt.Errorf("file repository is closed")
+	}
+
+	snapshot := snapshotRecord{
+		Seq:          r.seq,
+		Schedules:    []PickUpSchedule{},
+		Histories:    make(map[string][]ScheduleChange),
+		Reservations: make(map[string][]string),
+	}
+	for _, record := range records {
+		snapshot.Schedules = append(snapshot.Schedules, record.Schedule)
+		if len(record.History) > 0 {
+			snapshot.Histories[record.Schedule.ID] = record.History
+		}
+		if len(record.Reservations) > 0 {
+			snapshot.Reservations[record.Schedule.ID] = record.Reservations
+		}
+	}
+	if err := r.writeSnapshot(snapshot); err != nil {
+		return err
+	}
+
+	// A crash before the truncate is harmless, replay skips records the
+	// snapshot already contains
+	if err := r.log.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate log: %v", err)
+	}
+	if _, err := r.log.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to truncate log: %v", err)
+	}
+	r.pending = 0
+
+	return r.log.Sync()
+}
+
+// Close stops the periodic snapshots, writes a final snapshot and closes the log
+func (r *FileRepository) Close() error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+
+	err := r.Snapshot()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.log != nil {
+		if closeErr := r.log.Close(); err == nil {
+			err = closeErr
+		}
+		r.log = nil
+	}
+
+	return err
+}
+
+func (r *FileRepository) snapshotLoop(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(fileSnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			pending := r.pending
+			r.mu.Unlock()
+
+			if pending > 0 {
+				// A failed snapshot only means a longer replay, the log is intact
+				_ = r.Snapshot()
+			}
+		}
+	}
+}
+
+// appendRecord is the persist hook of the embedded InMemoryRepository. The
+// records of a batch go into a single log record, so a crash never leaves
+// part of a batch behind.
+func (r *FileRepository) appendRecord(records ...scheduleRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.log == nil {
+		return fmt.Errorf("file repository is closed")
+	}
+
+	entry := logRecord{Seq: r.seq + 1}
+	if len(records) == 1 {
+		entry.scheduleRecord = records[0]
+	} else {
+		entry.Batch = records
+	}
+	line, err := encodeRecord(entry)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.log.Write(line); err != nil {
+		return fmt.Errorf("failed to append to log: %v", err)
+	}
+	if err := r.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log: %v", err)
+	}
+
+	r.seq++
+	r.pending++
+
+	return nil
+}
+
+// recover loads the snapshot and replays the log on top of it
+func (r *FileRepository) recover() error {
+	snapshot, err := r.readSnapshot()
+	if err != nil {
+		return err
+	}
+	for _, schedule := range snapshot.Schedules {
+		r.restore(scheduleRecord{
+			Schedule:     schedule,
+			History:      snapshot.Histories[schedule.ID],
+			Reservations: snapshot.Reservations[schedule.ID],
+		})
+	}
+	r.seq = snapshot.Seq
+
+	file, err := os.OpenFile(filepath.Join(r.dir, fileLogName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log: %v", err)
+	}
+
+	valid, err := r.replay(file, snapshot.Seq)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	// Drop whatever a crash left behind after the last complete record
+	if err := file.Truncate(valid); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to truncate torn log: %v", err)
+	}
+	if _, err := file.Seek(valid, io.SeekStart); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to seek log: %v", err)
+	}
+
+	r.log = file
+
+	return nil
+}
+
+// replay restores every log record newer than after and returns the offset
+// right behind the last valid record
+func (r *FileRepository) replay(file *os.File, after uint64) (int64, error) {
+	reader := bufio.NewReader(file)
+
+	var valid int64
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) == 0 && readErr == io.EOF {
+			return valid, nil
+		}
+		if readErr != nil && readErr != io.EOF {
+			return 0, fmt.Errorf("failed to read log: %v", readErr)
+		}
+
+		var record logRecord
+		err := decodeRecord(line, &record)
+		if err != nil {
+			// Only the last record can be torn, anything else is corruption
+			if rest, _ := io.ReadAll(reader); bytes.IndexByte(rest, '\n') >= 0 {
+				return 0, fmt.Errorf("corrupt log record at offset %d: %v", valid, err)
+			}
+			return valid, nil
+		}
+
+		if record.Seq > after {
+			if len(record.Batch) == 0 {
+				r.restore(record.scheduleRecord)
+			}
+			for _, batched := range record.Batch {
+				r.restore(batched)
+			}
+			r.seq = record.Seq
+			r.pending++
+		}
+		valid += int64(len(line))
+	}
+}
+
+func (r *FileRepository) readSnapshot() (snapshotRecord, error) {
+	var snapshot snapshotRecord
+
+	data, err := os.ReadFile(filepath.Join(r.dir, fileSnapshotName))
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+
+	if err := decodeRecord(data, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("corrupt snapshot: %v", err)
+	}
+
+	return snapshot, nil
+}
+
+// writeSnapshot replaces the snapshot atomically
+func (r *FileRepository) writeSnapshot(snapshot snapshotRecord) error {
+	data, err := encodeRecord(snapshot)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(r.dir, fileSnapshotName, "snapshot", data)
+}
+
+// writeFileAtomic replaces the named file in dir by renaming a synced temp
+// file, what describes the file in error messages
+func writeFileAtomic(dir, name, what string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", what, err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", what, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync %s: %v", what, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", what, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", what, err)
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir makes a rename inside dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open data directory: %v", err)
+	}
+	defer func(d *os.File) {
+		_ = d.Close()
+	}(d)
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync data directory: %v", err)
+	}
+
+	return nil
+}
+
+// encodeRecord frames v as one line: the CRC-32 of the JSON payload in hex,
+// a space, the payload and a newline
+func encodeRecord(v interface{}) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record: %v", err)
+	}
+
+	line := make([]byte, 0, len(payload)+10)
+	line = append(line, fmt.Sprintf("%08x ", crc32.ChecksumIEEE(payload))...)
+	line = append(line, payload...)
+
+	return append(line, '\n'), nil
+}
+
+// decodeRecord verifies and decodes a line written by encodeRecord
+func decodeRecord(line []byte, v interface{}) error {
+	if len(line) < 10 || line[len(line)-1] != '\n' || line[8] != ' ' {
+		return errTornRecord
+	}
+
+	checksum, err := strconv.ParseUint(string(line[:8]), 16, 32)
+	if err != nil {
+		return errTornRecord
+	}
+
+	payload := line[9 : len(line)-1]
+	if crc32.ChecksumIEEE(payload) != uint32(checksum) {
+		return errTornRecord
+	}
+
+	return json.Unmarshal(payload, v)
+}
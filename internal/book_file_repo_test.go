@@ -0,0 +1,392 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileRepository_Suite(t *testing.T) {
+	testBookRepository(t, func(t *testing.T) BookRepository {
+		repo, err := NewFileRepository(context.Background(), t.TempDir())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = repo.Close()
+		})
+		return repo
+	})
+}
+
+func TestFileRepository_SavePickUpSchedule(t *testing.T) {
+	// Initialize the repository in a fresh directory
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repo, err := NewFileRepository(ctx, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("PositiveCase_SurvivesRestart", func(t *testing.T) {
+		for _, title := range []string{"Book1", "Book2"} {
+			if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: title}}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		// Simulate a crash: drop the repository without closing it
+		reopened, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer func() {
+			_ = reopened.Close()
+		}()
+
+		pickUpSchedules := storedSchedules(reopened.InMemoryRepository, "fiction")
+		if len(pickUpSchedules) != 2 {
+			t.Fatalf("Expected 2 pick-up schedules, got %d", len(pickUpSchedules))
+		}
+		if pickUpSchedules[0].BookInfo.Title != "Book1" || pickUpSchedules[1].BookInfo.Title != "Book2" {
+			t.Errorf("Expected schedules in save order, got %v", pickUpSchedules)
+		}
+	})
+
+	t.Run("PositiveCase_CancellationSurvivesRestart", func(t *testing.T) {
+		dir := t.TempDir()
+		repo, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		saved, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Cancelled"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.CancelPickUpSchedule(ctx, saved[0].ID, Cancellation{CancelledBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		reopened, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer func() {
+			_ = reopened.Close()
+		}()
+
+		// Both log records describe the same schedule
+		pickUpSchedules := storedSchedules(reopened.InMemoryRepository, "fiction")
+		if len(pickUpSchedules) != 1 || pickUpSchedules[0].Status != ScheduleStatusCancelled {
+			t.Errorf("Expected one cancelled schedule, got %v", pickUpSchedules)
+		}
+	})
+
+	t.Run("PositiveCase_HistorySurvivesRestartAndSnapshot", func(t *testing.T) {
+		dir := t.TempDir()
+		repo, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		saved, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Moved"}, PickUpDate: mustParsePickUpDate("2023-12-01")})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		id := saved[0].ID
+		if _, err := repo.ReschedulePickUpSchedule(ctx, id, Reschedule{PickUpDate: mustParsePickUpDate("2023-12-02"), ChangedBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.Snapshot(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// The second change only lives in the log
+		if _, err := repo.ReschedulePickUpSchedule(ctx, id, Reschedule{PickUpDate: mustParsePickUpDate("2023-12-03"), ChangedBy: "borrower"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		reopened, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer func() {
+			_ = reopened.Close()
+		}()
+
+		history, err := reopened.GetPickUpScheduleHistory(ctx, id)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(history) != 2 || history[0].ToPickUpDate.String() != "2023-12-02" || history[1].ToPickUpDate.String() != "2023-12-03" {
+			t.Errorf("Expected both changes to be restored, got %v", history)
+		}
+	})
+
+	t.Run("PositiveCase_ReservationsSurviveRestart", func(t *testing.T) {
+		dir := t.TempDir()
+		repo, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		slot := Reservation{Key: "slot:2023-12-01T10:00:00Z", Limit: 2}
+
+		saved, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Cancelled"}}, slot)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "love", BookInfo: Book{Title: "Kept"}}, slot); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.Snapshot(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// The cancellation only lives in the log
+		if _, err := repo.CancelPickUpSchedule(ctx, saved[0].ID, Cancellation{CancelledBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		reopened, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer func() {
+			_ = reopened.Close()
+		}()
+
+		counts, err := reopened.CountReservations(ctx, []string{slot.Key})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if counts[slot.Key] != 1 {
+			t.Errorf("Expected 1 holder after restart, got %d", counts[slot.Key])
+		}
+		if _, err := reopened.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Second"}}, slot); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if _, err := reopened.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Third"}}, slot); !errors.Is(err, ErrCapacityExceeded) {
+			t.Errorf("Expected ErrCapacityExceeded, got %v", err)
+		}
+	})
+
+	t.Run("PositiveCase_BatchSurvivesRestart", func(t *testing.T) {
+		dir := t.TempDir()
+		repo, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		slot := []Reservation{{Key: "slot:2023-12-01T10:00:00Z", Limit: 2}}
+		batch := []PickUpSchedule{{Genre: "fiction", BookInfo: Book{Title: "Book1"}}, {Genre: "love", BookInfo: Book{Title: "Book2"}}}
+		if _, err := repo.SavePickUpSchedules(ctx, batch, [][]Reservation{slot, slot}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		reopened, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer func() {
+			_ = reopened.Close()
+		}()
+
+		if len(storedSchedules(reopened.InMemoryRepository, "fiction")) != 1 || len(storedSchedules(reopened.InMemoryRepository, "love")) != 1 {
+			t.Errorf("Expected both schedules of the batch after restart")
+		}
+		counts, err := reopened.CountReservations(ctx, []string{slot[0].Key})
+		if err != nil || counts[slot[0].Key] != 2 {
+			t.Errorf("Expected 2 holders after restart, got %v (%v)", counts, err)
+		}
+	})
+
+	t.Run("NegativeCase_Closed", func(t *testing.T) {
+		repo, err := NewFileRepository(ctx, t.TempDir())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction"}); err == nil {
+			t.Error("Expected error, but got nil")
+		}
+		if len(storedSchedules(repo.InMemoryRepository, "fiction")) != 0 {
+			t.Error("Expected failed save not to be stored")
+		}
+	})
+}
+
+func TestFileRepository_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repo, err := NewFileRepository(ctx, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Before"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := repo.Snapshot(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("PositiveCase_LogEmptied", func(t *testing.T) {
+		info, err := os.Stat(filepath.Join(dir, fileLogName))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Size() != 0 {
+			t.Errorf("Expected empty log after snapshot, got %d bytes", info.Size())
+		}
+	})
+
+	t.Run("PositiveCase_SnapshotAndLogReplayed", func(t *testing.T) {
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "love", BookInfo: Book{Title: "After"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		reopened, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer func() {
+			_ = reopened.Close()
+		}()
+
+		if len(storedSchedules(reopened.InMemoryRepository, "fiction")) != 1 {
+			t.Error("Expected snapshot schedule to be restored")
+		}
+		if len(storedSchedules(reopened.InMemoryRepository, "love")) != 1 {
+			t.Error("Expected log schedule to be restored")
+		}
+	})
+}
+
+func TestFileRepository_Recovery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("PositiveCase_TornWrite", func(t *testing.T) {
+		dir := t.TempDir()
+		repo, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Complete"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// Append half a record, as left behind by a crash during a write
+		logPath := filepath.Join(dir, fileLogName)
+		before, err := os.Stat(logPath)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		appendToFile(t, logPath, `0badc0de {"seq":2,"schedule":{"book_info":{"ti`)
+
+		reopened, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer func() {
+			_ = reopened.Close()
+		}()
+
+		if len(storedSchedules(reopened.InMemoryRepository, "fiction")) != 1 {
+			t.Error("Expected only the complete schedule to be restored")
+		}
+
+		after, err := os.Stat(logPath)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if after.Size() != before.Size() {
+			t.Errorf("Expected torn record to be truncated to %d bytes, got %d", before.Size(), after.Size())
+		}
+
+		// New writes continue behind the last complete record
+		if _, err := reopened.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Next"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("PositiveCase_CrashBeforeLogTruncate", func(t *testing.T) {
+		dir := t.TempDir()
+		repo, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// Write a snapshot but keep the log, as if the truncate never happened
+		logData, err := os.ReadFile(filepath.Join(dir, fileLogName))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.Snapshot(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		appendToFile(t, filepath.Join(dir, fileLogName), string(logData))
+
+		reopened, err := NewFileRepository(ctx, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer func() {
+			_ = reopened.Close()
+		}()
+
+		if got := len(storedSchedules(reopened.InMemoryRepository, "fiction")); got != 1 {
+			t.Errorf("Expected 1 pick-up schedule, got %d", got)
+		}
+	})
+
+	t.Run("NegativeCase_CorruptRecord", func(t *testing.T) {
+		dir := t.TempDir()
+		record, err := encodeRecord(logRecord{Seq: 2, scheduleRecord: scheduleRecord{Schedule: PickUpSchedule{Genre: "fiction"}}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		appendToFile(t, filepath.Join(dir, fileLogName), "0badc0de {}\n"+string(record))
+
+		// A damaged record followed by complete ones is not a torn write
+		if _, err := NewFileRepository(ctx, dir); err == nil {
+			t.Error("Expected error, but got nil")
+		}
+	})
+}
+
+// storedSchedules returns the schedules of a genre without calling the external API
+func storedSchedules(repo *InMemoryRepository, genre string) []PickUpSchedule {
+	data := repo.shard(genre, false)
+	if data == nil {
+		return nil
+	}
+
+	data.mu.RLock()
+	defer data.mu.RUnlock()
+
+	return append([]PickUpSchedule(nil), data.PickUpSchedules...)
+}
+
+func appendToFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
@@ -1,9 +1,16 @@
 package internal
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -11,16 +18,56 @@ import (
 type BookHandler interface {
 	GetBooksByGenreHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
 	SubmitPickUpScheduleHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	SubmitPickUpSchedulesHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetPickUpScheduleHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ListPickUpSchedulesHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CancelPickUpScheduleHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ReschedulePickUpScheduleHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	TransitionPickUpScheduleHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetPickUpScheduleHistoryHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetPickUpScheduleCalendarHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetBorrowerCalendarHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetBranchCalendarHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetPickUpManifestHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ListSlotsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ListOverdueLoansHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	PlaceHoldHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetHoldHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ConfirmHoldHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CancelHoldHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
 }
 
 type bookHandler struct {
 	service BookService
+
+	// idempotency is nil unless WithIdempotencyKeys is given
+	idempotency       IdempotencyRepository
+	idempotencyWindow time.Duration
+	now               func() time.Time
 }
 
-func NewHandler(service BookService) BookHandler {
-	return &bookHandler{
+// HandlerOption configures the BookHandler returned by NewHandler
+type HandlerOption func(*bookHandler)
+
+// WithIdempotencyKeys makes POST /books/schedule and POST /schedules/batch
+// answer a request carrying the Idempotency-Key header of an earlier one with
+// the answer to that request, for config.Window after it was first made
+func WithIdempotencyKeys(repository IdempotencyRepository, config IdempotencyConfig) HandlerOption {
+	return func(h *bookHandler) {
+		h.idempotency = repository
+		h.idempotencyWindow = config.Window
+	}
+}
+
+func NewHandler(service BookService, options ...HandlerOption) BookHandler {
+	h := &bookHandler{
 		service: service,
+		now:     time.Now,
+	}
+	for _, option := range options {
+		option(h)
 	}
+	return h
 }
 
 func (h *bookHandler) GetBooksByGenreHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
@@ -30,21 +77,230 @@ func (h *bookHandler) GetBooksByGenreHandler(w http.ResponseWriter, r *http.Requ
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	response, err := json.Marshal(books)
+	writeJSON(w, http.StatusOK, books)
+}
+
+func (h *bookHandler) SubmitPickUpScheduleHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_, err = w.Write(response)
+
+	var schedule PickUpSchedule
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&schedule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.serveIdempotent(w, r, body, func() (int, interface{}) {
+		pickUpSchedule, err := h.service.SubmitPickUpScheduleService(r.Context(), schedule)
+		if err != nil {
+			return statusCodeOf(err), pickUpSchedule
+		}
+		return http.StatusCreated, pickUpSchedule
+	})
+}
+
+// SubmitPickUpSchedulesHandler serves POST /schedules/batch with a mode and a
+// list of pick-up schedules. A batch that was saved in full is HTTP 201, a
+// best_effort batch that was saved in part HTTP 207 and an all_or_nothing batch
+// that was not saved gets the status of the schedule that failed it.
+func (h *bookHandler) SubmitPickUpSchedulesHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var batch ScheduleBatch
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+
+	h.serveIdempotent(w, r, body, func() (int, interface{}) {
+		response, err := h.service.SubmitPickUpSchedulesService(r.Context(), batch)
+		switch {
+		case err != nil:
+			return statusCodeOf(err), response
+		case response.Failed > 0:
+			return http.StatusMultiStatus, response
+		default:
+			return http.StatusCreated, response
+		}
+	})
 }
 
-func (h *bookHandler) SubmitPickUpScheduleHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	var schedule PickUpSchedule
-	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+// serveIdempotent answers the request with the status and response of serve.
+// A request with an Idempotency-Key header is only served once: a retry with
+// the same key and body gets the stored answer again, flagged by the
+// Idempotent-Replayed header, and one with another body is rejected. Answers
+// with a 5xx status are not stored, so the request can be retried.
+func (h *bookHandler) serveIdempotent(w http.ResponseWriter, r *http.Request, body []byte, serve func() (int, interface{})) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" || h.idempotency == nil {
+		status, response := serve()
+		writeJSON(w, status, response)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidIdempotencyKey, IdempotencyKeyHeader, maxIdempotencyKeyLength)
+		writeJSON(w, statusCodeOf(err), failedPostResponse(err))
+		return
+	}
+
+	now := h.now().UTC()
+	fingerprint := requestFingerprint(r.Method, r.URL.Path, body)
+	record, reserved, err := h.idempotency.ReserveIdempotencyKey(r.Context(), IdempotencyRecord{Key: key, Fingerprint: fingerprint, CreatedAt: now}, now.Add(-h.idempotencyWindow))
+	switch {
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, failedPostResponse(fmt.Errorf("failed to check idempotency key: %v", err)))
+		return
+	case reserved:
+	case record.Fingerprint != fingerprint:
+		writeJSON(w, statusCodeOf(ErrIdempotencyKeyReused), failedPostResponse(ErrIdempotencyKeyReused))
+		return
+	case !record.completed():
+		writeJSON(w, statusCodeOf(ErrIdempotencyKeyInFlight), failedPostResponse(ErrIdempotencyKeyInFlight))
+		return
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(record.StatusCode)
+		_, _ = w.Write(record.Response)
+		return
+	}
+
+	// The answer is stored even when the client is gone, it is the one to
+	// retry. Without a stored answer, also when serve panics, the key is
+	// released so the request can be retried.
+	ctx := context.WithoutCancel(r.Context())
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		if err := h.idempotency.ReleaseIdempotencyKey(ctx, key); err != nil {
+			log.Printf("failed to release idempotency key %q: %v", key, err)
+		}
+	}()
+
+	status, response := serve()
+	data, err := json.Marshal(response)
+	if err == nil && status < http.StatusInternalServerError {
+		err = h.idempotency.CompleteIdempotencyKey(ctx, key, status, data)
+		completed = err == nil
+	}
+	if err != nil {
+		log.Printf("failed to store the answer to idempotency key %q: %v", key, err)
+	}
+	writeJSON(w, status, response)
+}
+
+// failedPostResponse describes an error of a request that never reached the
+// service
+func failedPostResponse(err error) PostResponse {
+	return PostResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   err.Error(),
+		Data:      PickUpSchedule{},
+		TotalData: 0,
+	}
+}
+
+// GetPickUpScheduleHandler serves GET /books/schedule/:id. httprouter cannot
+// register that path next to /books/:genre, so it is routed as
+// /books/:genre/:id and anything but "schedule" in place of the genre is 404.
+func (h *bookHandler) GetPickUpScheduleHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if params.ByName("genre") != "schedule" {
+		http.NotFound(w, r)
+		return
+	}
+
+	pickUpSchedule, err := h.service.GetPickUpScheduleService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), pickUpSchedule)
+		return
+	}
+	writeJSON(w, http.StatusOK, pickUpSchedule)
+}
+
+// ListPickUpSchedulesHandler serves GET /schedules. Every query parameter is
+// optional: genre, branch_id, borrower_id, status, title, pick_up_from,
+// pick_up_to, sort, cursor and limit.
+func (h *bookHandler) ListPickUpSchedulesHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	values := r.URL.Query()
+	query := ScheduleQuery{
+		Genre:      values.Get("genre"),
+		BranchID:   values.Get("branch_id"),
+		BorrowerID: values.Get("borrower_id"),
+		Status:     ScheduleStatus(values.Get("status")),
+		Title:      values.Get("title"),
+		PickUpFrom: values.Get("pick_up_from"),
+		PickUpTo:   values.Get("pick_up_to"),
+		Sort:       values.Get("sort"),
+		Cursor:     values.Get("cursor"),
+	}
+	if limit := values.Get("limit"); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, "limit must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	pickUpSchedules, err := h.service.ListPickUpSchedulesService(r.Context(), query)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), pickUpSchedules)
+		return
+	}
+	writeJSON(w, http.StatusOK, pickUpSchedules)
+}
+
+// ListOverdueLoansHandler serves GET /loans/overdue, oldest due date first,
+// optionally filtered by branch_id and genre and paged like GET /schedules
+func (h *bookHandler) ListOverdueLoansHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	values := r.URL.Query()
+	query := ScheduleQuery{
+		Genre:    values.Get("genre"),
+		BranchID: values.Get("branch_id"),
+		Cursor:   values.Get("cursor"),
+	}
+	if limit := values.Get("limit"); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, "limit must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	loans, err := h.service.ListOverdueLoansService(r.Context(), query)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), loans)
+		return
+	}
+	writeJSON(w, http.StatusOK, loans)
+}
+
+// CancelPickUpScheduleHandler serves DELETE /books/schedule/:id. The body says
+// who cancelled and why: {"cancelled_by": "...", "reason": "..."}.
+func (h *bookHandler) CancelPickUpScheduleHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var cancellation Cancellation
+	if err := json.NewDecoder(r.Body).Decode(&cancellation); err != nil && err != io.EOF {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -55,13 +311,242 @@ func (h *bookHandler) SubmitPickUpScheduleHandler(w http.ResponseWriter, r *http
 		}
 	}(r.Body)
 
-	pickUpSchedule, err := h.service.SubmitPickUpScheduleService(schedule)
-	response, err := json.Marshal(pickUpSchedule)
+	pickUpSchedule, err := h.service.CancelPickUpScheduleService(r.Context(), params.ByName("id"), cancellation)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), pickUpSchedule)
+		return
+	}
+	writeJSON(w, http.StatusOK, pickUpSchedule)
+}
+
+// ReschedulePickUpScheduleHandler serves PATCH /books/schedule/:id with a
+// Reschedule body, e.g. {"pick_up_date": "...", "changed_by": "..."}.
+func (h *bookHandler) ReschedulePickUpScheduleHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var reschedule Reschedule
+	if err := json.NewDecoder(r.Body).Decode(&reschedule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	pickUpSchedule, err := h.service.ReschedulePickUpScheduleService(r.Context(), params.ByName("id"), reschedule)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), pickUpSchedule)
+		return
+	}
+	writeJSON(w, http.StatusOK, pickUpSchedule)
+}
+
+// TransitionPickUpScheduleHandler serves POST /books/schedule/:id/status with
+// a StatusTransition body, e.g. {"status": "confirmed", "changed_by": "..."}.
+func (h *bookHandler) TransitionPickUpScheduleHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var transition StatusTransition
+	if err := json.NewDecoder(r.Body).Decode(&transition); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	pickUpSchedule, err := h.service.TransitionPickUpScheduleService(r.Context(), params.ByName("id"), transition)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), pickUpSchedule)
+		return
+	}
+	writeJSON(w, http.StatusOK, pickUpSchedule)
+}
+
+// GetPickUpScheduleHistoryHandler serves GET /books/schedule/:id/history,
+// routed as /books/:genre/:id/history for the same reason as
+// GetPickUpScheduleHandler.
+func (h *bookHandler) GetPickUpScheduleHistoryHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if params.ByName("genre") != "schedule" {
+		http.NotFound(w, r)
+		return
+	}
+
+	history, err := h.service.GetPickUpScheduleHistoryService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), history)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// GetPickUpScheduleCalendarHandler serves GET /books/schedule/:id/calendar.ics,
+// routed as /books/:genre/:id/calendar.ics for the same reason as
+// GetPickUpScheduleHandler.
+func (h *bookHandler) GetPickUpScheduleCalendarHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if params.ByName("genre") != "schedule" {
+		http.NotFound(w, r)
+		return
+	}
+
+	calendar, err := h.service.GetPickUpScheduleCalendarService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), calendar)
+		return
+	}
+	writeCalendar(w, calendar)
+}
+
+// GetBorrowerCalendarHandler serves GET /borrowers/:id/calendar.ics with every
+// pick-up of the borrower, meant to be subscribed to from a calendar app
+func (h *bookHandler) GetBorrowerCalendarHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	calendar, err := h.service.GetBorrowerCalendarService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), calendar)
+		return
+	}
+	writeCalendar(w, calendar)
+}
+
+// GetBranchCalendarHandler serves GET /branches/:id/calendar.ics?date=2023-12-01
+// with the pick-ups at the branch that day, today when date is left out
+func (h *bookHandler) GetBranchCalendarHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	calendar, err := h.service.GetBranchCalendarService(r.Context(), params.ByName("id"), r.URL.Query().Get("date"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), calendar)
+		return
+	}
+	writeCalendar(w, calendar)
+}
+
+// GetPickUpManifestHandler serves GET /branches/:id/manifest?date=2023-12-01
+// with the pick-ups at the branch that day by slot, today when date is left
+// out. format=csv, or an Accept header of text/csv, returns it as CSV to print
+// or open in a spreadsheet instead of JSON.
+func (h *bookHandler) GetPickUpManifestHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	values := r.URL.Query()
+	format := values.Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		format = "csv"
+	}
+	if format != "" && format != "json" && format != "csv" {
+		err := &ValidationError{Fields: []FieldError{{Field: "format", Message: "must be json or csv"}}}
+		writeJSON(w, statusCodeOf(err), failedManifestResponse("invalid pick-up manifest", err))
+		return
+	}
+
+	manifest, err := h.service.GetPickUpManifestService(r.Context(), params.ByName("id"), values.Get("date"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), manifest)
+		return
+	}
+	if format != "csv" {
+		writeJSON(w, http.StatusOK, manifest)
+		return
+	}
+
+	rendered, err := manifest.Data.CSV()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, failedManifestResponse("failed to render pick-up manifest", err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "pick-up-manifest-"+manifest.Data.Date+".csv"))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(rendered)
+	if err != nil {
+		return
+	}
+}
+
+// ListSlotsHandler serves GET /slots?branch_id=...&date=2023-12-01 with the
+// remaining capacity of every pick-up slot the branch is open for that day
+func (h *bookHandler) ListSlotsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	values := r.URL.Query()
+	slots, err := h.service.ListSlotsService(r.Context(), values.Get("branch_id"), values.Get("date"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), slots)
+		return
+	}
+	writeJSON(w, http.StatusOK, slots)
+}
+
+// PlaceHoldHandler serves POST /holds, queueing for a book at a branch with
+// the genre, book_info and branch_id of a pick-up schedule
+func (h *bookHandler) PlaceHoldHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var hold Hold
+	if err := json.NewDecoder(r.Body).Decode(&hold); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	placed, err := h.service.PlaceHoldService(r.Context(), hold)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), placed)
+		return
+	}
+	writeJSON(w, http.StatusCreated, placed)
+}
+
+// GetHoldHandler serves GET /holds/:id, including the position of a waiting
+// hold in its queue
+func (h *bookHandler) GetHoldHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	hold, err := h.service.GetHoldService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), hold)
+		return
+	}
+	writeJSON(w, http.StatusOK, hold)
+}
+
+// ConfirmHoldHandler serves POST /holds/:id/confirm, accepting the pick-up
+// schedule offered to the hold
+func (h *bookHandler) ConfirmHoldHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	hold, err := h.service.ConfirmHoldService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), hold)
+		return
+	}
+	writeJSON(w, http.StatusOK, hold)
+}
+
+// CancelHoldHandler serves DELETE /holds/:id, leaving the queue or turning
+// down the offered pick-up schedule
+func (h *bookHandler) CancelHoldHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	hold, err := h.service.CancelHoldService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), hold)
+		return
+	}
+	writeJSON(w, http.StatusOK, hold)
+}
+
+// writeCalendar writes the feed of a successful calendar request
+func writeCalendar(w http.ResponseWriter, calendar CalendarResponse) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(calendar.Calendar)
+	if err != nil {
+		return
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	response, err := json.Marshal(v)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	_, err = w.Write(response)
 	if err != nil {
 		return
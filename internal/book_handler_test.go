@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -17,16 +19,168 @@ type mockService struct {
 	getBooksByGenreError         error
 	submitPickUpScheduleResponse PostResponse
 	submitPickUpScheduleError    error
+	submitPickUpScheduleCalls    int
+	submitPickUpSchedulePanic    bool
+	submitBatchResponse          BatchResponse
+	submitBatchError             error
+	submitBatchInput             ScheduleBatch
+	getPickUpScheduleResponse    PostResponse
+	getPickUpScheduleError       error
+	listPickUpSchedulesResponse  ScheduleListResponse
+	listPickUpSchedulesError     error
+	listPickUpSchedulesQuery     ScheduleQuery
+	cancelPickUpScheduleResponse PostResponse
+	cancelPickUpScheduleError    error
+	cancelPickUpScheduleInput    Cancellation
+	rescheduleResponse           PostResponse
+	rescheduleError              error
+	rescheduleInput              Reschedule
+	transitionResponse           PostResponse
+	transitionError              error
+	transitionInput              StatusTransition
+	markOverdueResponse          ScheduleListResponse
+	markOverdueError             error
+	listOverdueResponse          ScheduleListResponse
+	listOverdueError             error
+	listOverdueQuery             ScheduleQuery
+	historyResponse              ScheduleHistoryResponse
+	historyError                 error
+	calendarResponse             CalendarResponse
+	calendarError                error
+	calendarOf                   string
+	calendarDay                  string
+	manifestResponse             ManifestResponse
+	manifestError                error
+	manifestBranchID             string
+	manifestDay                  string
+	listSlotsResponse            SlotListResponse
+	listSlotsError               error
+	listSlotsBranchID            string
+	listSlotsDay                 string
+	placeHoldResponse            HoldResponse
+	placeHoldError               error
+	placeHoldInput               Hold
+	getHoldResponse              HoldResponse
+	getHoldError                 error
+	confirmHoldResponse          HoldResponse
+	confirmHoldError             error
+	cancelHoldResponse           HoldResponse
+	cancelHoldError              error
+	processHoldsResponse         HoldListResponse
+	processHoldsError            error
+	holdID                       string
 }
 
 func (m *mockService) GetBooksByGenreService(ctx context.Context, genre string) (Response, error) {
 	return m.getBooksByGenreResponse, m.getBooksByGenreError
 }
 
-func (m *mockService) SubmitPickUpScheduleService(schedule PickUpSchedule) (PostResponse, error) {
+func (m *mockService) SubmitPickUpScheduleService(ctx context.Context, schedule PickUpSchedule) (PostResponse, error) {
+	m.submitPickUpScheduleCalls++
+	if m.submitPickUpSchedulePanic {
+		panic("submit failed")
+	}
 	return m.submitPickUpScheduleResponse, m.submitPickUpScheduleError
 }
 
+func (m *mockService) SubmitPickUpSchedulesService(ctx context.Context, batch ScheduleBatch) (BatchResponse, error) {
+	m.submitBatchInput = batch
+	return m.submitBatchResponse, m.submitBatchError
+}
+
+func (m *mockService) GetPickUpScheduleService(ctx context.Context, id string) (PostResponse, error) {
+	return m.getPickUpScheduleResponse, m.getPickUpScheduleError
+}
+
+func (m *mockService) CancelPickUpScheduleService(ctx context.Context, id string, cancellation Cancellation) (PostResponse, error) {
+	m.cancelPickUpScheduleInput = cancellation
+	return m.cancelPickUpScheduleResponse, m.cancelPickUpScheduleError
+}
+
+func (m *mockService) ReschedulePickUpScheduleService(ctx context.Context, id string, reschedule Reschedule) (PostResponse, error) {
+	m.rescheduleInput = reschedule
+	return m.rescheduleResponse, m.rescheduleError
+}
+
+func (m *mockService) TransitionPickUpScheduleService(ctx context.Context, id string, transition StatusTransition) (PostResponse, error) {
+	m.transitionInput = transition
+	return m.transitionResponse, m.transitionError
+}
+
+func (m *mockService) MarkOverdueLoansService(ctx context.Context) (ScheduleListResponse, error) {
+	return m.markOverdueResponse, m.markOverdueError
+}
+
+func (m *mockService) ListOverdueLoansService(ctx context.Context, query ScheduleQuery) (ScheduleListResponse, error) {
+	m.listOverdueQuery = query
+	return m.listOverdueResponse, m.listOverdueError
+}
+
+func (m *mockService) GetPickUpScheduleHistoryService(ctx context.Context, id string) (ScheduleHistoryResponse, error) {
+	return m.historyResponse, m.historyError
+}
+
+func (m *mockService) GetPickUpScheduleCalendarService(ctx context.Context, id string) (CalendarResponse, error) {
+	m.calendarOf = "schedule " + id
+	return m.calendarResponse, m.calendarError
+}
+
+func (m *mockService) GetBorrowerCalendarService(ctx context.Context, borrowerID string) (CalendarResponse, error) {
+	m.calendarOf = "borrower " + borrowerID
+	return m.calendarResponse, m.calendarError
+}
+
+func (m *mockService) GetBranchCalendarService(ctx context.Context, branchID, day string) (CalendarResponse, error) {
+	m.calendarOf = "branch " + branchID
+	m.calendarDay = day
+	return m.calendarResponse, m.calendarError
+}
+
+func (m *mockService) GetPickUpManifestService(ctx context.Context, branchID, day string) (ManifestResponse, error) {
+	m.manifestBranchID = branchID
+	m.manifestDay = day
+	return m.manifestResponse, m.manifestError
+}
+
+func (m *mockService) ListSlotsService(ctx context.Context, branchID, day string) (SlotListResponse, error) {
+	m.listSlotsBranchID = branchID
+	m.listSlotsDay = day
+	return m.listSlotsResponse, m.listSlotsError
+}
+
+func (m *mockService) PlaceHoldService(ctx context.Context, hold Hold) (HoldResponse, error) {
+	m.placeHoldInput = hold
+	return m.placeHoldResponse, m.placeHoldError
+}
+
+func (m *mockService) GetHoldService(ctx context.Context, id string) (HoldResponse, error) {
+	m.holdID = id
+	return m.getHoldResponse, m.getHoldError
+}
+
+func (m *mockService) ConfirmHoldService(ctx context.Context, id string) (HoldResponse, error) {
+	m.holdID = id
+	return m.confirmHoldResponse, m.confirmHoldError
+}
+
+func (m *mockService) CancelHoldService(ctx context.Context, id string) (HoldResponse, error) {
+	m.holdID = id
+	return m.cancelHoldResponse, m.cancelHoldError
+}
+
+func (m *mockService) ProcessHoldsService(ctx context.Context) (HoldListResponse, error) {
+	return m.processHoldsResponse, m.processHoldsError
+}
+
+func (m *mockService) SendRemindersService(ctx context.Context) (ScheduleListResponse, error) {
+	return ScheduleListResponse{}, nil
+}
+
+func (m *mockService) ListPickUpSchedulesService(ctx context.Context, query ScheduleQuery) (ScheduleListResponse, error) {
+	m.listPickUpSchedulesQuery = query
+	return m.listPickUpSchedulesResponse, m.listPickUpSchedulesError
+}
+
 func TestBookHandler_GetBooksByGenreHandler(t *testing.T) {
 	mockService := &mockService{
 		getBooksByGenreResponse: Response{
@@ -119,7 +273,7 @@ func TestBookHandler_SubmitPickUpScheduleHandler(t *testing.T) {
 
 		router.ServeHTTP(rec, req)
 
-		if rec.Code != http.StatusOK {
+		if rec.Code != http.StatusCreated {
 			t.Errorf("Expected status code 201, got %d", rec.Code)
 		}
 
@@ -152,4 +306,1025 @@ func TestBookHandler_SubmitPickUpScheduleHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("NegativeCase_ServiceFailure", func(t *testing.T) {
+		mockService.submitPickUpScheduleResponse = PostResponse{Status: "500 Internal Server Error"}
+		mockService.submitPickUpScheduleError = fmt.Errorf("Failed to save schedule")
+
+		req := httptest.NewRequest("POST", "/books/schedule", bytes.NewReader([]byte(`{"genre": "fiction"}`)))
+		rec := httptest.NewRecorder()
+
+		router := httprouter.New()
+		router.POST("/books/schedule", handler.SubmitPickUpScheduleHandler)
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status code 500, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_Duplicate", func(t *testing.T) {
+		conflictErr := &ScheduleConflictError{Existing: PickUpSchedule{ID: "abc", BorrowerID: "reader"}, Duplicate: true}
+		mockService.submitPickUpScheduleResponse = conflictPostResponse(conflictErr)
+		mockService.submitPickUpScheduleError = conflictErr
+
+		req := httptest.NewRequest("POST", "/books/schedule", bytes.NewReader([]byte(`{"genre": "fiction"}`)))
+		rec := httptest.NewRecorder()
+
+		router := httprouter.New()
+		router.POST("/books/schedule", handler.SubmitPickUpScheduleHandler)
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected status code 409, got %d", rec.Code)
+		}
+
+		var response PostResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.ExistingScheduleID != "abc" {
+			t.Errorf("Expected the existing schedule in the body, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestBookHandler_SubmitPickUpScheduleHandler_IdempotencyKeys(t *testing.T) {
+	now := time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)
+	newHandler := func(service BookService) *bookHandler {
+		handler := NewHandler(service, WithIdempotencyKeys(NewInMemoryIdempotencyRepository(), IdempotencyConfig{Window: time.Hour})).(*bookHandler)
+		handler.now = func() time.Time { return now }
+		return handler
+	}
+	submit := func(handler BookHandler, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/books/schedule", bytes.NewReader([]byte(body)))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.SubmitPickUpScheduleHandler(rec, req, nil)
+		return rec
+	}
+	body := `{"genre": "fiction", "book_info": {"title": "NewBook"}}`
+	created := PostResponse{Status: "201 CREATED", IsSuccess: true, Message: "save new data books successfully!", TotalData: 1, Data: PickUpSchedule{ID: "first"}}
+
+	t.Run("PositiveCase_RetryReplaysAnswer", func(t *testing.T) {
+		service := &mockService{submitPickUpScheduleResponse: created}
+		handler := newHandler(service)
+
+		first := submit(handler, "retry-1", body)
+		// Whitespace does not make it another request
+		retry := submit(handler, "retry-1", `{"genre":"fiction","book_info":{"title":"NewBook"}}`)
+
+		if service.submitPickUpScheduleCalls != 1 {
+			t.Errorf("Expected 1 schedule to be submitted, got %d", service.submitPickUpScheduleCalls)
+		}
+		if retry.Code != first.Code || retry.Body.String() != first.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("Expected the retry to get %d %s, got %d %s", first.Code, first.Body, retry.Code, retry.Body)
+		}
+		if first.Header().Get("Idempotent-Replayed") != "" {
+			t.Error("Expected the first answer not to be flagged as replayed")
+		}
+	})
+
+	t.Run("PositiveCase_RejectionReplayed", func(t *testing.T) {
+		service := &mockService{submitPickUpScheduleResponse: PostResponse{Status: "409 Conflict"}, submitPickUpScheduleError: ErrCapacityExceeded}
+		handler := newHandler(service)
+
+		submit(handler, "retry-1", body)
+		retry := submit(handler, "retry-1", body)
+		if service.submitPickUpScheduleCalls != 1 || retry.Code != http.StatusConflict {
+			t.Errorf("Expected the 409 to be replayed without submitting again, got %d after %d calls", retry.Code, service.submitPickUpScheduleCalls)
+		}
+	})
+
+	t.Run("PositiveCase_ServerErrorNotStored", func(t *testing.T) {
+		service := &mockService{submitPickUpScheduleResponse: PostResponse{Status: "500 Internal Server Error"}, submitPickUpScheduleError: fmt.Errorf("database is down")}
+		handler := newHandler(service)
+
+		submit(handler, "retry-1", body)
+		service.submitPickUpScheduleResponse, service.submitPickUpScheduleError = created, nil
+		retry := submit(handler, "retry-1", body)
+		if service.submitPickUpScheduleCalls != 2 || retry.Code != http.StatusCreated {
+			t.Errorf("Expected the retry to be submitted again, got %d after %d calls", retry.Code, service.submitPickUpScheduleCalls)
+		}
+	})
+
+	t.Run("PositiveCase_PanicReleasesKey", func(t *testing.T) {
+		service := &mockService{submitPickUpScheduleResponse: created, submitPickUpSchedulePanic: true}
+		handler := newHandler(service)
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected the panic to reach the server")
+				}
+			}()
+			submit(handler, "retry-1", body)
+		}()
+		service.submitPickUpSchedulePanic = false
+		retry := submit(handler, "retry-1", body)
+		if service.submitPickUpScheduleCalls != 2 || retry.Code != http.StatusCreated {
+			t.Errorf("Expected the retry to be submitted again, got %d after %d calls", retry.Code, service.submitPickUpScheduleCalls)
+		}
+	})
+
+	t.Run("NegativeCase_DifferentBody", func(t *testing.T) {
+		service := &mockService{submitPickUpScheduleResponse: created}
+		handler := newHandler(service)
+
+		submit(handler, "retry-1", body)
+		rec := submit(handler, "retry-1", `{"genre": "fiction", "book_info": {"title": "OtherBook"}}`)
+
+		if rec.Code != http.StatusUnprocessableEntity || service.submitPickUpScheduleCalls != 1 {
+			t.Errorf("Expected 422 without submitting, got %d after %d calls", rec.Code, service.submitPickUpScheduleCalls)
+		}
+		var response PostResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response body: %v", err)
+		}
+		if response.Status != "422 Unprocessable Entity" || response.IsSuccess {
+			t.Errorf("Expected a failed 422 response, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_InProgress", func(t *testing.T) {
+		service := &mockService{submitPickUpScheduleResponse: created}
+		handler := newHandler(service)
+		fingerprint := requestFingerprint("POST", "/books/schedule", []byte(body))
+		if _, _, err := handler.idempotency.ReserveIdempotencyKey(context.Background(), IdempotencyRecord{Key: "retry-1", Fingerprint: fingerprint, CreatedAt: now}, now); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		rec := submit(handler, "retry-1", body)
+		if rec.Code != http.StatusConflict || service.submitPickUpScheduleCalls != 0 {
+			t.Errorf("Expected 409 without submitting, got %d after %d calls", rec.Code, service.submitPickUpScheduleCalls)
+		}
+	})
+
+	t.Run("PositiveCase_KeyExpires", func(t *testing.T) {
+		service := &mockService{submitPickUpScheduleResponse: created}
+		handler := newHandler(service)
+
+		submit(handler, "retry-1", body)
+		now = now.Add(2 * time.Hour)
+		submit(handler, "retry-1", `{"genre": "fiction", "book_info": {"title": "OtherBook"}}`)
+		if service.submitPickUpScheduleCalls != 2 {
+			t.Errorf("Expected an expired key to be used again, got %d calls", service.submitPickUpScheduleCalls)
+		}
+	})
+
+	t.Run("PositiveCase_WithoutKey", func(t *testing.T) {
+		service := &mockService{submitPickUpScheduleResponse: created}
+		handler := newHandler(service)
+
+		submit(handler, "", body)
+		submit(handler, "", body)
+		if service.submitPickUpScheduleCalls != 2 {
+			t.Errorf("Expected every request without a key to be submitted, got %d calls", service.submitPickUpScheduleCalls)
+		}
+	})
+
+	t.Run("NegativeCase_KeyTooLong", func(t *testing.T) {
+		service := &mockService{submitPickUpScheduleResponse: created}
+		rec := submit(newHandler(service), strings.Repeat("k", maxIdempotencyKeyLength+1), body)
+		if rec.Code != http.StatusBadRequest || service.submitPickUpScheduleCalls != 0 {
+			t.Errorf("Expected 400 without submitting, got %d after %d calls", rec.Code, service.submitPickUpScheduleCalls)
+		}
+	})
+}
+
+func TestBookHandler_SubmitPickUpSchedulesHandler(t *testing.T) {
+	mockService := &mockService{
+		submitBatchResponse: BatchResponse{Status: "201 CREATED", IsSuccess: true, Saved: 1, TotalData: 1},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.POST("/schedules/batch", handler.SubmitPickUpSchedulesHandler)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/schedules/batch", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		rec := post(`{"mode": "best_effort", "schedules": [{"genre": "fiction", "book_info": {"title": "TestBook"}}]}`)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("Expected status code 201, got %d", rec.Code)
+		}
+		if mockService.submitBatchInput.Mode != BatchModeBestEffort || len(mockService.submitBatchInput.Schedules) != 1 {
+			t.Errorf("Expected the batch from the body, got %+v", mockService.submitBatchInput)
+		}
+	})
+
+	t.Run("PositiveCase_PartlySaved", func(t *testing.T) {
+		mockService.submitBatchResponse = BatchResponse{Status: "207 Multi-Status", Saved: 1, Failed: 1, TotalData: 2}
+
+		rec := post(`{"mode": "best_effort", "schedules": [{}, {}]}`)
+
+		if rec.Code != http.StatusMultiStatus {
+			t.Errorf("Expected status code 207, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_RolledBack", func(t *testing.T) {
+		mockService.submitBatchResponse = BatchResponse{Status: "409 Conflict", Failed: 2, TotalData: 2}
+		mockService.submitBatchError = fmt.Errorf("pick-up schedule 1 of the batch: %w", ErrCapacityExceeded)
+
+		rec := post(`{"schedules": [{}, {}]}`)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected status code 409, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidBody", func(t *testing.T) {
+		rec := post(`{"schedules": {}}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBookHandler_GetPickUpScheduleHandler(t *testing.T) {
+	mockService := &mockService{
+		getPickUpScheduleResponse: PostResponse{
+			Status:    "200 OK",
+			IsSuccess: true,
+			Message:   "fetch pick-up schedule successfully!",
+			TotalData: 1,
+			Data:      PickUpSchedule{ID: "abc"},
+		},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/books/:genre", handler.GetBooksByGenreHandler)
+	router.GET("/books/:genre/:id", handler.GetPickUpScheduleHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/books/schedule/abc", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+
+		var response PostResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Errorf("Failed to unmarshal response body: %v", err)
+		}
+
+		if response.Data.ID != "abc" {
+			t.Errorf("Expected schedule abc, got %q", response.Data.ID)
+		}
+	})
+
+	t.Run("NegativeCase_OtherPrefix", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/books/fiction/abc", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		mockService.getPickUpScheduleResponse = PostResponse{Status: "404 Not Found"}
+		mockService.getPickUpScheduleError = ErrScheduleNotFound
+
+		req := httptest.NewRequest("GET", "/books/schedule/missing", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBookHandler_ListPickUpSchedulesHandler(t *testing.T) {
+	mockService := &mockService{
+		listPickUpSchedulesResponse: ScheduleListResponse{
+			Status:     "200 OK",
+			IsSuccess:  true,
+			Message:    "fetch pick-up schedules successfully!",
+			TotalData:  1,
+			NextCursor: "next",
+			Data:       []PickUpSchedule{{ID: "abc"}},
+		},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/schedules", handler.ListPickUpSchedulesHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/schedules?genre=love&branch_id=central&borrower_id=reader&title=heights&pick_up_from=2023-12-01&pick_up_to=2023-12-31&sort=-pick_up_date&cursor=c&limit=5", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+
+		want := ScheduleQuery{
+			Genre:      "love",
+			BranchID:   "central",
+			BorrowerID: "reader",
+			Title:      "heights",
+			PickUpFrom: "2023-12-01",
+			PickUpTo:   "2023-12-31",
+			Sort:       "-pick_up_date",
+			Cursor:     "c",
+			Limit:      5,
+		}
+		if mockService.listPickUpSchedulesQuery != want {
+			t.Errorf("Expected query %+v, got %+v", want, mockService.listPickUpSchedulesQuery)
+		}
+
+		var response ScheduleListResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Errorf("Failed to unmarshal response body: %v", err)
+		}
+
+		if response.NextCursor != "next" || len(response.Data) != 1 {
+			t.Errorf("Expected one schedule and a cursor, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidLimit", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/schedules?limit=ten", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidQuery", func(t *testing.T) {
+		mockService.listPickUpSchedulesResponse = ScheduleListResponse{Status: "400 Bad Request"}
+		mockService.listPickUpSchedulesError = fmt.Errorf("%w: unknown sort", ErrInvalidScheduleQuery)
+
+		req := httptest.NewRequest("GET", "/schedules?sort=author", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBookHandler_CancelPickUpScheduleHandler(t *testing.T) {
+	mockService := &mockService{
+		cancelPickUpScheduleResponse: PostResponse{
+			Status:    "200 OK",
+			IsSuccess: true,
+			Message:   "cancel pick-up schedule successfully!",
+			TotalData: 1,
+			Data:      PickUpSchedule{ID: "abc", Status: ScheduleStatusCancelled},
+		},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.DELETE("/books/schedule/:id", handler.CancelPickUpScheduleHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"cancelled_by": "librarian", "reason": "changed mind"}`
+		req := httptest.NewRequest("DELETE", "/books/schedule/abc", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+
+		if mockService.cancelPickUpScheduleInput.CancelledBy != "librarian" || mockService.cancelPickUpScheduleInput.Reason != "changed mind" {
+			t.Errorf("Expected cancellation from body, got %+v", mockService.cancelPickUpScheduleInput)
+		}
+	})
+
+	t.Run("NegativeCase_BadRequest", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/books/schedule/abc", bytes.NewReader([]byte("invalid request body")))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_AlreadyCancelled", func(t *testing.T) {
+		mockService.cancelPickUpScheduleResponse = PostResponse{Status: "409 Conflict"}
+		mockService.cancelPickUpScheduleError = ErrScheduleCancelled
+
+		req := httptest.NewRequest("DELETE", "/books/schedule/abc", bytes.NewReader([]byte(`{"cancelled_by": "librarian"}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected status code 409, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBookHandler_ReschedulePickUpScheduleHandler(t *testing.T) {
+	mockService := &mockService{
+		rescheduleResponse: PostResponse{
+			Status:    "200 OK",
+			IsSuccess: true,
+			Message:   "reschedule pick-up schedule successfully!",
+			TotalData: 1,
+			Data:      PickUpSchedule{ID: "abc", PickUpDate: mustParsePickUpDate("2023-12-05")},
+		},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.PATCH("/books/schedule/:id", handler.ReschedulePickUpScheduleHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"pick_up_date": "2023-12-05", "changed_by": "librarian"}`
+		req := httptest.NewRequest("PATCH", "/books/schedule/abc", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+
+		if mockService.rescheduleInput.PickUpDate.String() != "2023-12-05" || mockService.rescheduleInput.ChangedBy != "librarian" {
+			t.Errorf("Expected reschedule from body, got %+v", mockService.rescheduleInput)
+		}
+	})
+
+	t.Run("NegativeCase_BadRequest", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/books/schedule/abc", bytes.NewReader([]byte("invalid request body")))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_ValidationFailure", func(t *testing.T) {
+		mockService.rescheduleResponse = PostResponse{
+			Status: "400 Bad Request",
+			Errors: []FieldError{{Field: "changed_by", Message: "is required"}},
+		}
+		mockService.rescheduleError = &ValidationError{Fields: mockService.rescheduleResponse.Errors}
+
+		req := httptest.NewRequest("PATCH", "/books/schedule/abc", bytes.NewReader([]byte(`{"pick_up_date": "2023-12-05"}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+
+		var response PostResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || len(response.Errors) != 1 {
+			t.Errorf("Expected field errors in the body, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestBookHandler_TransitionPickUpScheduleHandler(t *testing.T) {
+	mockService := &mockService{
+		transitionResponse: PostResponse{
+			Status:    "200 OK",
+			IsSuccess: true,
+			Message:   "change pick-up schedule status successfully!",
+			TotalData: 1,
+			Data:      PickUpSchedule{ID: "abc", Status: ScheduleStatusConfirmed},
+		},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.POST("/books/schedule/:id/status", handler.TransitionPickUpScheduleHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"status": "confirmed", "changed_by": "librarian", "reason": "copy set aside"}`
+		req := httptest.NewRequest("POST", "/books/schedule/abc/status", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+
+		want := StatusTransition{Status: ScheduleStatusConfirmed, ChangedBy: "librarian", Reason: "copy set aside"}
+		if mockService.transitionInput != want {
+			t.Errorf("Expected transition from body, got %+v", mockService.transitionInput)
+		}
+	})
+
+	t.Run("NegativeCase_BadRequest", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/books/schedule/abc/status", bytes.NewReader([]byte("invalid request body")))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_IllegalTransition", func(t *testing.T) {
+		mockService.transitionResponse = PostResponse{
+			Status: "409 Conflict",
+			Errors: []FieldError{{Field: "status", Message: transitionMessage(ScheduleStatusReturned)}},
+		}
+		mockService.transitionError = &TransitionError{From: ScheduleStatusReturned, To: ScheduleStatusPickedUp}
+
+		req := httptest.NewRequest("POST", "/books/schedule/abc/status", bytes.NewReader([]byte(`{"status": "picked_up", "changed_by": "librarian"}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected status code 409, got %d", rec.Code)
+		}
+
+		var response PostResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || len(response.Errors) != 1 {
+			t.Errorf("Expected field errors in the body, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestBookHandler_ListOverdueLoansHandler(t *testing.T) {
+	mockService := &mockService{
+		listOverdueResponse: ScheduleListResponse{
+			Status:    "200 OK",
+			IsSuccess: true,
+			Message:   "fetch overdue loans successfully!",
+			TotalData: 1,
+			Data:      []PickUpSchedule{{ID: "abc", Status: ScheduleStatusOverdue}},
+		},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/loans/overdue", handler.ListOverdueLoansHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/loans/overdue?branch_id=central&genre=fiction&limit=5&cursor=abc", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+
+		want := ScheduleQuery{BranchID: "central", Genre: "fiction", Limit: 5, Cursor: "abc"}
+		if mockService.listOverdueQuery != want {
+			t.Errorf("Expected query %+v, got %+v", want, mockService.listOverdueQuery)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidLimit", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/loans/overdue?limit=many", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_ServiceFailure", func(t *testing.T) {
+		mockService.listOverdueResponse = ScheduleListResponse{Status: "500 Internal Server Error"}
+		mockService.listOverdueError = fmt.Errorf("database is down")
+
+		req := httptest.NewRequest("GET", "/loans/overdue", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status code 500, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBookHandler_GetPickUpScheduleHistoryHandler(t *testing.T) {
+	mockService := &mockService{
+		historyResponse: ScheduleHistoryResponse{
+			Status:    "200 OK",
+			IsSuccess: true,
+			Message:   "fetch pick-up schedule history successfully!",
+			TotalData: 1,
+			Data:      []ScheduleChange{{ChangedBy: "librarian", ToPickUpDate: mustParsePickUpDate("2023-12-05")}},
+		},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/books/:genre/:id/history", handler.GetPickUpScheduleHistoryHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/books/schedule/abc/history", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+
+		var response ScheduleHistoryResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		if len(response.Data) != 1 || response.Data[0].ChangedBy != "librarian" {
+			t.Errorf("Expected the history in the body, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_OtherPrefix", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/books/fiction/abc/history", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		mockService.historyResponse = ScheduleHistoryResponse{Status: "404 Not Found"}
+		mockService.historyError = ErrScheduleNotFound
+
+		req := httptest.NewRequest("GET", "/books/schedule/missing/history", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBookHandler_CalendarHandlers(t *testing.T) {
+	feed := []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")
+	mockService := &mockService{
+		calendarResponse: CalendarResponse{Status: "200 OK", IsSuccess: true, Calendar: feed},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/books/:genre/:id/calendar.ics", handler.GetPickUpScheduleCalendarHandler)
+	router.GET("/borrowers/:id/calendar.ics", handler.GetBorrowerCalendarHandler)
+	router.GET("/branches/:id/calendar.ics", handler.GetBranchCalendarHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		for path, want := range map[string]string{
+			"/books/schedule/abc/calendar.ics":      "schedule abc",
+			"/borrowers/reader/calendar.ics":        "borrower reader",
+			"/branches/central/calendar.ics?date=x": "branch central",
+		} {
+			req := httptest.NewRequest("GET", path, nil)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("%s: expected status code 200, got %d", path, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/calendar; charset=utf-8" {
+				t.Errorf("%s: expected an iCalendar content type, got %q", path, got)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), feed) {
+				t.Errorf("%s: expected the feed in the body, got %q", path, rec.Body.String())
+			}
+			if mockService.calendarOf != want {
+				t.Errorf("%s: expected the calendar of %s, got %s", path, want, mockService.calendarOf)
+			}
+		}
+		if mockService.calendarDay != "x" {
+			t.Errorf("Expected the date from the query, got %q", mockService.calendarDay)
+		}
+	})
+
+	t.Run("NegativeCase_OtherPrefix", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/books/fiction/abc/calendar.ics", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		mockService.calendarResponse = CalendarResponse{Status: "404 Not Found"}
+		mockService.calendarError = ErrBorrowerNotFound
+
+		req := httptest.NewRequest("GET", "/borrowers/missing/calendar.ics", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("Expected errors as JSON, got %q", got)
+		}
+	})
+}
+
+func TestBookHandler_GetPickUpManifestHandler(t *testing.T) {
+	manifest := Manifest{Date: "2023-12-01", Slots: []ManifestSlot{{
+		Start: time.Date(2023, 12, 1, 10, 0, 0, 0, time.UTC),
+		End:   time.Date(2023, 12, 1, 10, 30, 0, 0, time.UTC),
+		Items: []ManifestItem{{ScheduleID: "abc", Title: "Emma", Authors: []string{"Jane Austen"}, BorrowerName: "Reader", Status: ScheduleStatusConfirmed}},
+	}}}
+	mockService := &mockService{
+		manifestResponse: ManifestResponse{Status: "200 OK", IsSuccess: true, TotalData: 1, Data: manifest},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/branches/:id/manifest", handler.GetPickUpManifestHandler)
+
+	t.Run("PositiveCase_JSON", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/branches/central/manifest?date=2023-12-01", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON with status code 200, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if mockService.manifestBranchID != "central" || mockService.manifestDay != "2023-12-01" {
+			t.Errorf("Expected the manifest of central on 2023-12-01, got %q %q", mockService.manifestBranchID, mockService.manifestDay)
+		}
+	})
+
+	t.Run("PositiveCase_CSV", func(t *testing.T) {
+		for _, req := range []*http.Request{
+			httptest.NewRequest("GET", "/branches/central/manifest?format=csv", nil),
+			func() *http.Request {
+				req := httptest.NewRequest("GET", "/branches/central/manifest", nil)
+				req.Header.Set("Accept", "text/csv")
+				return req
+			}(),
+		} {
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+				t.Errorf("%s: expected CSV with status code 200, got %d %q", req.URL, rec.Code, rec.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(rec.Header().Get("Content-Disposition"), "pick-up-manifest-2023-12-01.csv") {
+				t.Errorf("%s: expected a file name, got %q", req.URL, rec.Header().Get("Content-Disposition"))
+			}
+			if want := "2023-12-01,10:00,10:30,Emma,Jane Austen,0,,,Reader,confirmed,abc"; !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("%s: expected row %q, got %q", req.URL, want, rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("NegativeCase_UnknownFormat", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/branches/central/manifest?format=xlsx", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		mockService.manifestResponse = ManifestResponse{Status: "404 Not Found"}
+		mockService.manifestError = ErrBranchNotFound
+
+		req := httptest.NewRequest("GET", "/branches/missing/manifest?format=csv", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON 404, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+	})
+}
+
+func TestBookHandler_ListSlotsHandler(t *testing.T) {
+	mockService := &mockService{
+		listSlotsResponse: SlotListResponse{
+			Status:    "200 OK",
+			IsSuccess: true,
+			Message:   "fetch pick-up slots successfully!",
+			TotalData: 1,
+			Data:      []Slot{{Capacity: 5, Booked: 2, Remaining: 3}},
+		},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/slots", handler.ListSlotsHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/slots?branch_id=central&date=2023-12-01", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+		if mockService.listSlotsBranchID != "central" || mockService.listSlotsDay != "2023-12-01" {
+			t.Errorf("Expected the branch and date from the query, got %q and %q", mockService.listSlotsBranchID, mockService.listSlotsDay)
+		}
+
+		var response SlotListResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		if len(response.Data) != 1 || response.Data[0].Remaining != 3 {
+			t.Errorf("Expected the slots in the body, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_UnknownBranch", func(t *testing.T) {
+		mockService.listSlotsResponse = SlotListResponse{Status: "404 Not Found"}
+		mockService.listSlotsError = ErrBranchNotFound
+
+		req := httptest.NewRequest("GET", "/slots?branch_id=missing&date=2023-12-01", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidDate", func(t *testing.T) {
+		mockService.listSlotsResponse = SlotListResponse{Status: "400 Bad Request"}
+		mockService.listSlotsError = &ValidationError{Fields: []FieldError{{Field: "date", Message: "must be a date like 2023-12-01"}}}
+
+		req := httptest.NewRequest("GET", "/slots", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBookHandler_PlaceHoldHandler(t *testing.T) {
+	mockService := &mockService{
+		placeHoldResponse: HoldResponse{
+			Status:    "201 CREATED",
+			IsSuccess: true,
+			Message:   "place hold successfully!",
+			TotalData: 1,
+			Data:      Hold{ID: "abc", Status: HoldStatusWaiting, Position: 2},
+		},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.POST("/holds", handler.PlaceHoldHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"genre": "fiction", "book_info": {"title": "Emma"}, "branch_id": "central"}`
+		req := httptest.NewRequest("POST", "/holds", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("Expected status code 201, got %d", rec.Code)
+		}
+
+		input := mockService.placeHoldInput
+		if input.Genre != "fiction" || input.BookInfo.Title != "Emma" || input.BranchID != "central" {
+			t.Errorf("Expected hold from body, got %+v", input)
+		}
+	})
+
+	t.Run("NegativeCase_BadRequest", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/holds", bytes.NewReader([]byte("invalid request body")))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_NotStocked", func(t *testing.T) {
+		mockService.placeHoldResponse = HoldResponse{Status: "400 Bad Request"}
+		mockService.placeHoldError = &ValidationError{Fields: []FieldError{{Field: "book_info", Message: "is not stocked at the branch"}}}
+
+		req := httptest.NewRequest("POST", "/holds", bytes.NewReader([]byte(`{"genre": "fiction"}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBookHandler_HoldHandlers(t *testing.T) {
+	mockService := &mockService{
+		getHoldResponse:     HoldResponse{Status: "200 OK", IsSuccess: true, Data: Hold{ID: "abc", Status: HoldStatusWaiting, Position: 1}},
+		confirmHoldResponse: HoldResponse{Status: "200 OK", IsSuccess: true, Data: Hold{ID: "abc", Status: HoldStatusConfirmed}},
+		cancelHoldResponse:  HoldResponse{Status: "200 OK", IsSuccess: true, Data: Hold{ID: "abc", Status: HoldStatusCancelled}},
+	}
+	handler := NewHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/holds/:id", handler.GetHoldHandler)
+	router.POST("/holds/:id/confirm", handler.ConfirmHoldHandler)
+	router.DELETE("/holds/:id", handler.CancelHoldHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		for _, method := range []string{"GET", "POST", "DELETE"} {
+			path := "/holds/abc"
+			if method == "POST" {
+				path += "/confirm"
+			}
+			mockService.holdID = ""
+
+			req := httptest.NewRequest(method, path, nil)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("Expected status code 200 for %s %s, got %d", method, path, rec.Code)
+			}
+			if mockService.holdID != "abc" {
+				t.Errorf("Expected hold abc for %s %s, got %q", method, path, mockService.holdID)
+			}
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		mockService.getHoldResponse = HoldResponse{Status: "404 Not Found"}
+		mockService.getHoldError = ErrHoldNotFound
+
+		req := httptest.NewRequest("GET", "/holds/missing", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("NegativeCase_OfferExpired", func(t *testing.T) {
+		mockService.confirmHoldResponse = HoldResponse{Status: "409 Conflict"}
+		mockService.confirmHoldError = ErrHoldExpired
+
+		req := httptest.NewRequest("POST", "/holds/abc/confirm", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected status code 409, got %d", rec.Code)
+		}
+	})
 }
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// holdQueueName is who the pick-up schedules of lapsed or cancelled offers are
+// recorded as cancelled by
+const holdQueueName = "hold queue"
+
+// RunHoldCheck expires the offers not confirmed in time and offers free copies
+// to waiting holds right away and then every interval, until ctx is done
+func RunHoldCheck(ctx context.Context, service BookService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		response, err := service.ProcessHoldsService(ctx)
+		switch {
+		case err != nil:
+			log.Printf("hold check failed: %v", err)
+		case response.TotalData > 0:
+			log.Printf("hold check changed %d holds", response.TotalData)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// serveQueue offers the free copies of the book at the branch to the holds
+// waiting for it, first come, first served, and returns the holds it offered
+// a pick-up schedule. It stops at the first hold no pick-up can be found for,
+// so a later hold never jumps the queue. Holds of borrowers at one of their
+// limits, or whose offer would duplicate or overlap a pick-up they already
+// have, are passed over and keep their place.
+func (s *bookService) serveQueue(ctx context.Context, branchID, genre string, book Book) ([]Hold, error) {
+	waiting, err := s.holds.ListHolds(ctx, HoldQuery{BranchID: branchID, Genre: genre, Book: &book, Status: HoldStatusWaiting})
+	if err != nil || len(waiting) == 0 {
+		return nil, err
+	}
+
+	branch, err := s.branches.GetBranch(ctx, branchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var offered []Hold
+	for _, hold := range waiting {
+		// The borrower gets the whole confirmation window before the pick-up
+		expiresAt := s.now().UTC().Add(s.holdConfig.ConfirmWithin)
+		date, found, err := s.freePickUpDate(ctx, branch, genre, book, expiresAt)
+		if err != nil || !found {
+			return offered, err
+		}
+
+		schedule := PickUpSchedule{Genre: hold.Genre, BookInfo: hold.BookInfo, BranchID: hold.BranchID, Timezone: branch.Timezone, BorrowerID: hold.BorrowerID, PickUpDate: date}
+		err = s.checkScheduleConflicts(ctx, schedule, branch)
+		if err == nil {
+			err = s.checkWeeklyPickUps(ctx, schedule, branch)
+		}
+		var reservations []Reservation
+		if err == nil {
+			reservations, err = s.reservationsOf(ctx, schedule, branch)
+		}
+		var saved []PickUpSchedule
+		if err == nil {
+			saved, err = s.repository.SavePickUpSchedule(ctx, schedule, reservations...)
+		}
+		err = s.conflictOf(ctx, limitOf(err, schedule), schedule, branch)
+		if errors.Is(err, ErrBorrowingLimit) || errors.Is(err, ErrDuplicateSchedule) || errors.Is(err, ErrScheduleConflict) {
+			continue
+		}
+		if errors.Is(err, ErrCapacityExceeded) {
+			// Booked by someone else in the meantime, the next check tries again
+			return offered, nil
+		}
+		if err != nil {
+			return offered, err
+		}
+		schedule = saved[len(saved)-1]
+
+		hold, err = s.holds.UpdateHold(ctx, hold.ID, func(hold *Hold) error {
+			if hold.Status != HoldStatusWaiting {
+				return ErrHoldClosed
+			}
+			hold.Status = HoldStatusOffered
+			hold.ScheduleID = schedule.ID
+			hold.ExpiresAt = &expiresAt
+			return nil
+		})
+		if err != nil {
+			// The hold left the queue meanwhile, give the copy back
+			if _, cancelErr := s.repository.CancelPickUpSchedule(ctx, schedule.ID, Cancellation{CancelledBy: holdQueueName, Reason: "hold is no longer waiting"}); cancelErr != nil {
+				return offered, cancelErr
+			}
+			if errors.Is(err, ErrHoldClosed) {
+				continue
+			}
+			return offered, err
+		}
+		s.publish(ctx, WebhookScheduleCreated, schedule)
+		offered = append(offered, hold)
+	}
+
+	return offered, nil
+}
+
+// serveQueueOf serves the queue of the book of a schedule that gave its
+// reservations back. The change is already stored, so failing to serve the
+// queue is only logged and left to the next hold check.
+func (s *bookService) serveQueueOf(ctx context.Context, schedule PickUpSchedule) {
+	if schedule.BranchID == "" {
+		return
+	}
+	if _, err := s.serveQueue(ctx, schedule.BranchID, schedule.Genre, schedule.BookInfo); err != nil {
+		log.Printf("failed to serve the hold queue of %s: %v", schedule.BookInfo.Title, err)
+	}
+}
+
+// freePickUpDate returns the earliest pick-up slot at the branch starting no
+// sooner than from that has a free place and a free copy of the book, within
+// the booking horizon and on a day the branch is not closed
+func (s *bookService) freePickUpDate(ctx context.Context, branch Branch, genre string, book Book, from time.Time) (PickUpDate, bool, error) {
+	loc, err := branch.Location()
+	if err != nil {
+		return PickUpDate{}, false, err
+	}
+	copies, err := s.branches.CountCopies(ctx, branch.ID, genre, book)
+	if err != nil || copies <= 0 {
+		return PickUpDate{}, false, err
+	}
+	closures, err := s.closures.ListClosures(ctx, branch.ID)
+	if err != nil {
+		return PickUpDate{}, false, err
+	}
+
+	loaned := loanedCopyKey(branch.ID, genre, book)
+	first := from.In(loc)
+	latest := s.now().UTC().Add(s.bookingHorizon)
+	for day := midnight(first); !day.After(latest); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc) {
+		if closureOn(closures, day) != nil {
+			continue
+		}
+
+		var starts []time.Time
+		var keys []string
+		for _, slot := range s.slots.slotsOf(day.Year(), day.Month(), day.Day(), loc) {
+			if !slot.Start.Before(from) && !slot.Start.After(latest) && branch.isOpen(slot.Start, s.slots.Length) {
+				starts = append(starts, slot.Start)
+				keys = append(keys, slotKey(branch.ID, slot.Start))
+			}
+		}
+		if len(starts) == 0 {
+			continue
+		}
+
+		// Copies on loan are taken on every day until they are returned
+		bookCopy := copyKey(branch.ID, genre, book, day.Format(pickUpDayLayout))
+		counts, err := s.repository.CountReservations(ctx, append(keys, bookCopy, loaned))
+		if err != nil {
+			return PickUpDate{}, false, err
+		}
+		if counts[bookCopy]+counts[loaned] >= copies {
+			continue
+		}
+		for i, start := range starts {
+			if counts[keys[i]] < s.slots.Capacity {
+				return PickUpDate{t: start.UTC(), hasTime: true}, true, nil
+			}
+		}
+	}
+
+	return PickUpDate{}, false, nil
+}
+
+// expireHold lapses an offer that was not confirmed in time and cancels the
+// pick-up schedule it was offered. The queue is left for the caller to serve.
+func (s *bookService) expireHold(ctx context.Context, hold Hold, now time.Time) (Hold, error) {
+	hold, err := s.holds.UpdateHold(ctx, hold.ID, func(hold *Hold) error {
+		if hold.Status != HoldStatusOffered || hold.ExpiresAt == nil || hold.ExpiresAt.After(now) {
+			return ErrHoldNotOffered
+		}
+		hold.Status = HoldStatusExpired
+		return nil
+	})
+	if err != nil {
+		return Hold{}, err
+	}
+
+	reason := fmt.Sprintf("offer not confirmed by %s", hold.ExpiresAt.UTC().Format(time.RFC3339))
+	if err := s.releaseOffer(ctx, hold, reason); err != nil {
+		return Hold{}, err
+	}
+
+	return hold, nil
+}
+
+// releaseOffer cancels the pick-up schedule offered to the hold, unless it
+// already gave its reservations back or was picked up
+func (s *bookService) releaseOffer(ctx context.Context, hold Hold, reason string) error {
+	if hold.ScheduleID == "" {
+		return nil
+	}
+
+	cancelled, err := s.repository.CancelPickUpSchedule(ctx, hold.ScheduleID, Cancellation{CancelledBy: holdQueueName, Reason: reason})
+	if errors.Is(err, ErrScheduleNotFound) || errors.Is(err, ErrScheduleCancelled) || errors.Is(err, ErrInvalidTransition) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.publish(ctx, WebhookScheduleCancelled, cancelled)
+	return nil
+}
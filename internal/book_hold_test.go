@@ -0,0 +1,337 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHoldService returns a service keeping everything in memory whose central
+// branch has one copy of the book, bookable two days ahead, with a clock the
+// test can move
+func newHoldService(book Book) (BookService, *time.Time) {
+	now := serviceNow
+	service := NewService(NewInMemoryRepository(context.Background()), newMockBranches(testBranch).stock(1, "fiction", book), newMockBorrowers(testBorrower),
+		WithClock(func() time.Time { return now }),
+		WithBookingHorizon(2*24*time.Hour),
+		WithHolds(NewInMemoryHoldRepository(), HoldConfig{ConfirmWithin: 24 * time.Hour}),
+	)
+	return service, &now
+}
+
+func TestBookService_Holds(t *testing.T) {
+	ctx := context.Background()
+	emma := Book{Title: "Emma", Author: []string{"Jane Austen"}}
+	service, now := newHoldService(emma)
+
+	// Take the only copy on both days offers can be made for
+	var scheduleIDs []string
+	for _, date := range []string{"2023-11-21T10:00:00Z", "2023-11-22T07:00:00Z"} {
+		response, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "reader", PickUpDate: mustParsePickUpDate(date)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		scheduleIDs = append(scheduleIDs, response.Data.ID)
+	}
+
+	var holds []Hold
+	for i := 0; i < 2; i++ {
+		response, err := service.PlaceHoldService(ctx, Hold{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "reader"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Status != "201 CREATED" || response.Data.Status != HoldStatusWaiting || response.Data.Position != i+1 {
+			t.Fatalf("Expected hold waiting at %d, got %+v", i+1, response)
+		}
+		holds = append(holds, response.Data)
+	}
+
+	t.Run("NegativeCase_ConfirmWithoutOffer", func(t *testing.T) {
+		response, err := service.ConfirmHoldService(ctx, holds[0].ID)
+		if !errors.Is(err, ErrHoldNotOffered) || response.Status != "409 Conflict" {
+			t.Errorf("Expected 409 ErrHoldNotOffered, got %q, %v", response.Status, err)
+		}
+	})
+
+	t.Run("PositiveCase_CancellationOffersTheCopy", func(t *testing.T) {
+		if _, err := service.CancelPickUpScheduleService(ctx, scheduleIDs[0], Cancellation{CancelledBy: "borrower"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		first, err := service.GetHoldService(ctx, holds[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if first.Data.Status != HoldStatusOffered || first.Data.ScheduleID == "" || first.Data.Position != 0 {
+			t.Fatalf("Expected the first hold to be offered a pick-up, got %+v", first.Data)
+		}
+		if want := serviceNow.Add(24 * time.Hour); first.Data.ExpiresAt == nil || !first.Data.ExpiresAt.Equal(want) {
+			t.Errorf("Expected the offer to expire at %v, got %v", want, first.Data.ExpiresAt)
+		}
+
+		offer, err := service.GetPickUpScheduleService(ctx, first.Data.ScheduleID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// The earliest slot after the confirmation window
+		if offer.Data.Status != ScheduleStatusRequested || offer.Data.PickUpDate.String() != "2023-11-21T08:00:00Z" {
+			t.Errorf("Expected a requested pick-up at 2023-11-21T08:00:00Z, got %+v", offer.Data)
+		}
+
+		second, err := service.GetHoldService(ctx, holds[1].ID)
+		if err != nil || second.Data.Position != 1 {
+			t.Errorf("Expected the second hold to move up to 1, got %+v, %v", second.Data, err)
+		}
+	})
+
+	t.Run("PositiveCase_LapsedOfferGoesToTheNextHold", func(t *testing.T) {
+		offered, err := service.GetHoldService(ctx, holds[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		*now = serviceNow.Add(25 * time.Hour)
+
+		response, err := service.ProcessHoldsService(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.TotalData != 2 || response.Data[0].Status != HoldStatusExpired || response.Data[1].ID != holds[1].ID || response.Data[1].Status != HoldStatusOffered {
+			t.Fatalf("Expected the first hold expired and the second offered, got %+v", response.Data)
+		}
+
+		lapsed, err := service.GetPickUpScheduleService(ctx, offered.Data.ScheduleID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if lapsed.Data.Status != ScheduleStatusCancelled || lapsed.Data.Cancellation.CancelledBy != holdQueueName {
+			t.Errorf("Expected the lapsed pick-up cancelled by the hold queue, got %+v", lapsed.Data)
+		}
+
+		confirmed, err := service.ConfirmHoldService(ctx, holds[1].ID)
+		if err != nil || confirmed.Data.Status != HoldStatusConfirmed {
+			t.Errorf("Expected the second hold confirmed, got %+v, %v", confirmed.Data, err)
+		}
+	})
+
+	t.Run("NegativeCase_ConfirmAfterExpiry", func(t *testing.T) {
+		response, err := service.ConfirmHoldService(ctx, holds[0].ID)
+		if !errors.Is(err, ErrHoldNotOffered) || response.Status != "409 Conflict" {
+			t.Errorf("Expected 409 ErrHoldNotOffered, got %q, %v", response.Status, err)
+		}
+	})
+
+	t.Run("NegativeCase_CancelConfirmed", func(t *testing.T) {
+		response, err := service.CancelHoldService(ctx, holds[1].ID)
+		if !errors.Is(err, ErrHoldClosed) || response.Status != "409 Conflict" {
+			t.Errorf("Expected 409 ErrHoldClosed, got %q, %v", response.Status, err)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		response, err := service.GetHoldService(ctx, "missing")
+		if !errors.Is(err, ErrHoldNotFound) || response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 ErrHoldNotFound, got %q, %v", response.Status, err)
+		}
+	})
+}
+
+func TestBookService_Holds_CopyOnLoan(t *testing.T) {
+	ctx := context.Background()
+	emma := Book{Title: "Emma", Author: []string{"Jane Austen"}}
+	// The default booking horizon has plenty of days the copy is not booked on
+	service := NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch).stock(1, "fiction", emma),
+		newMockBorrowers(testBorrower, Borrower{ID: "other", Name: "Other"}),
+		WithClock(serviceClock),
+		WithHolds(NewInMemoryHoldRepository(), HoldConfig{ConfirmWithin: 24 * time.Hour}),
+	)
+
+	loan, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "reader", PickUpDate: mustParsePickUpDate("2023-11-20T10:00:00Z")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, status := range []ScheduleStatus{ScheduleStatusConfirmed, ScheduleStatusReadyForPickUp, ScheduleStatusPickedUp} {
+		if _, err := service.TransitionPickUpScheduleService(ctx, loan.Data.ID, StatusTransition{Status: status, ChangedBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	placed, err := service.PlaceHoldService(ctx, Hold{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "other"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if placed.Data.Status != HoldStatusWaiting || placed.Data.Position != 1 {
+		t.Fatalf("Expected the hold to wait while the copy is on loan, got %+v", placed.Data)
+	}
+
+	if _, err := service.TransitionPickUpScheduleService(ctx, loan.Data.ID, StatusTransition{Status: ScheduleStatusReturned, ChangedBy: "librarian"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hold, err := service.GetHoldService(ctx, placed.Data.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if hold.Data.Status != HoldStatusOffered || hold.Data.ScheduleID == "" {
+		t.Errorf("Expected the returned copy offered to the hold, got %+v", hold.Data)
+	}
+}
+
+func TestBookService_PlaceHoldService(t *testing.T) {
+	ctx := context.Background()
+	emma := Book{Title: "Emma"}
+
+	t.Run("PositiveCase_FreeCopyIsOfferedRightAway", func(t *testing.T) {
+		service, _ := newHoldService(emma)
+
+		response, err := service.PlaceHoldService(ctx, Hold{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "reader"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Data.Status != HoldStatusOffered || response.Data.ScheduleID == "" {
+			t.Errorf("Expected an offered hold, got %+v", response.Data)
+		}
+	})
+
+	t.Run("PositiveCase_CancelOfferedHold", func(t *testing.T) {
+		service, _ := newHoldService(emma)
+
+		placed, err := service.PlaceHoldService(ctx, Hold{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "reader"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		response, err := service.CancelHoldService(ctx, placed.Data.ID)
+		if err != nil || response.Data.Status != HoldStatusCancelled {
+			t.Fatalf("Expected a cancelled hold, got %+v, %v", response.Data, err)
+		}
+
+		offer, err := service.GetPickUpScheduleService(ctx, placed.Data.ScheduleID)
+		if err != nil || offer.Data.Status != ScheduleStatusCancelled {
+			t.Errorf("Expected the offered pick-up cancelled, got %+v, %v", offer.Data, err)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidHold", func(t *testing.T) {
+		service, _ := newHoldService(emma)
+
+		for _, tc := range []struct {
+			hold  Hold
+			field string
+		}{
+			{Hold{BookInfo: emma, BranchID: "central", BorrowerID: "reader"}, "genre"},
+			{Hold{Genre: "fiction", BranchID: "central", BorrowerID: "reader"}, "book_info.title"},
+			{Hold{Genre: "fiction", BookInfo: emma, BorrowerID: "reader"}, "branch_id"},
+			{Hold{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "missing"}, "borrower_id"},
+			{Hold{Genre: "fiction", BookInfo: emma, BranchID: "missing", BorrowerID: "reader"}, "branch_id"},
+			{Hold{Genre: "fiction", BookInfo: Book{Title: "Persuasion"}, BranchID: "central", BorrowerID: "reader"}, "book_info"},
+		} {
+			response, err := service.PlaceHoldService(ctx, tc.hold)
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("Expected ValidationError for %+v, got %v", tc.hold, err)
+			}
+			if response.Status != "400 Bad Request" || len(response.Errors) != 1 || response.Errors[0].Field != tc.field {
+				t.Errorf("Expected 400 failure for %s, got %+v", tc.field, response)
+			}
+		}
+	})
+}
+
+func TestBookService_Holds_BorrowerAtLimit(t *testing.T) {
+	ctx := context.Background()
+	emma := Book{Title: "Emma"}
+	persuasion := Book{Title: "Persuasion"}
+	service := NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch).stock(1, "fiction", emma, persuasion),
+		newMockBorrowers(testBorrower, Borrower{ID: "other", Name: "Other"}, Borrower{ID: "third", Name: "Third"}),
+		WithClock(serviceClock),
+		WithBookingHorizon(2*24*time.Hour),
+		WithBorrowerLimits(BorrowerLimits{MaxActiveLoans: 2}),
+		WithHolds(NewInMemoryHoldRepository(), HoldConfig{ConfirmWithin: 24 * time.Hour}),
+	)
+
+	// Other takes the only copy of Emma on both days, and the reader takes
+	// Persuasion on both days, which is as many loans as they can have
+	var emmaIDs []string
+	for _, tc := range []struct {
+		borrowerID string
+		book       Book
+	}{{"other", emma}, {"reader", persuasion}} {
+		for _, date := range []string{"2023-11-21T10:00:00Z", "2023-11-22T07:00:00Z"} {
+			response, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BookInfo: tc.book, BranchID: "central", BorrowerID: tc.borrowerID, PickUpDate: mustParsePickUpDate(date)})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.book.Title == emma.Title {
+				emmaIDs = append(emmaIDs, response.Data.ID)
+			}
+		}
+	}
+
+	var holds []Hold
+	for _, borrowerID := range []string{"reader", "third"} {
+		response, err := service.PlaceHoldService(ctx, Hold{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: borrowerID})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		holds = append(holds, response.Data)
+	}
+
+	if _, err := service.CancelPickUpScheduleService(ctx, emmaIDs[0], Cancellation{CancelledBy: "other"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	first, err := service.GetHoldService(ctx, holds[0].ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.Data.Status != HoldStatusWaiting || first.Data.Position != 1 {
+		t.Errorf("Expected the reader at their limit to keep waiting first in line, got %+v", first.Data)
+	}
+
+	second, err := service.GetHoldService(ctx, holds[1].ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second.Data.Status != HoldStatusOffered || second.Data.ScheduleID == "" {
+		t.Errorf("Expected the next borrower to be offered the copy, got %+v", second.Data)
+	}
+}
+
+// holdCheckService counts the hold checks run through it
+type holdCheckService struct {
+	mockService
+	checks atomic.Int32
+}
+
+func (s *holdCheckService) ProcessHoldsService(ctx context.Context) (HoldListResponse, error) {
+	s.checks.Add(1)
+	return HoldListResponse{}, nil
+}
+
+func TestRunHoldCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	service := &holdCheckService{}
+
+	done := make(chan struct{})
+	go func() {
+		RunHoldCheck(ctx, service, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for service.checks.Load() < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("Expected repeated hold checks, got %d", service.checks.Load())
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the hold check to stop once the context is done")
+	}
+}
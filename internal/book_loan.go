@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// LoanConfig sets how many days a picked up book can be kept. Branches can
+// override it with their own LoanPeriods.
+type LoanConfig struct {
+	// Days is the loan period of every genre without a period of its own
+	Days int
+	// GenreDays overrides Days for single genres
+	GenreDays map[string]int
+}
+
+// DefaultLoanConfig is used unless WithLoans says otherwise
+var DefaultLoanConfig = LoanConfig{Days: 14}
+
+// DefaultOverdueCheckInterval is how often RunOverdueCheck looks for loans
+// past their due date unless told otherwise
+const DefaultOverdueCheckInterval = 15 * time.Minute
+
+// overdueCheckName is who the history records as having flagged a loan overdue
+const overdueCheckName = "overdue check"
+
+// Validate reports a loan period that is not at least a day
+func (c LoanConfig) Validate() error {
+	if c.Days < 1 {
+		return fmt.Errorf("loan period of %d days must be at least 1", c.Days)
+	}
+	for genre, days := range c.GenreDays {
+		if days < 1 {
+			return fmt.Errorf("loan period of %d days for %s must be at least 1", days, genre)
+		}
+	}
+	return nil
+}
+
+// loanDays returns the loan period of a book of the genre picked up at the
+// branch. The periods of the branch come first, the one for the genre before
+// the one for every genre, then those of the config.
+func (c LoanConfig) loanDays(branch Branch, genre string) int {
+	if days, found := branch.loanDays(genre); found {
+		return days
+	}
+	if days, found := c.GenreDays[genre]; found {
+		return days
+	}
+	return c.Days
+}
+
+// dueAt returns when a loan of days picked up at pickedUp ends, which is at
+// the end of its last day in the time zone loc
+func dueAt(pickedUp time.Time, days int, loc *time.Location) time.Time {
+	local := pickedUp.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day()+days+1, 0, 0, 0, 0, loc).UTC()
+}
+
+// RunOverdueCheck flags the loans past their due date as overdue right away
+// and then every interval, until ctx is done
+func RunOverdueCheck(ctx context.Context, service BookService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		response, err := service.MarkOverdueLoansService(ctx)
+		switch {
+		case err != nil:
+			log.Printf("overdue check failed: %v", err)
+		case response.TotalData > 0:
+			log.Printf("overdue check flagged %d loans", response.TotalData)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
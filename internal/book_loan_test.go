@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoanConfig_Validate(t *testing.T) {
+	for _, config := range []LoanConfig{
+		{Days: 1},
+		{Days: 14, GenreDays: map[string]int{"fiction": 21}},
+	} {
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected error for %+v: %v", config, err)
+		}
+	}
+
+	for _, config := range []LoanConfig{
+		{Days: 0},
+		{Days: 14, GenreDays: map[string]int{"fiction": 0}},
+		{Days: 14, GenreDays: map[string]int{"fiction": -3}},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for %+v, but got nil", config)
+		}
+	}
+}
+
+func TestLoanConfig_LoanDays(t *testing.T) {
+	config := LoanConfig{Days: 14, GenreDays: map[string]int{"fiction": 21, "love": 10}}
+	branch := Branch{LoanPeriods: []LoanPeriod{{Genre: "love", Days: 7}, {Days: 28}}}
+
+	for _, tc := range []struct {
+		branch Branch
+		genre  string
+		want   int
+	}{
+		{Branch{}, "poetry", 14},
+		{Branch{}, "fiction", 21},
+		{branch, "love", 7},
+		{branch, "fiction", 28},
+		{Branch{LoanPeriods: []LoanPeriod{{Genre: "love", Days: 7}}}, "fiction", 21},
+	} {
+		if got := config.loanDays(tc.branch, tc.genre); got != tc.want {
+			t.Errorf("Expected %d days for %s at %+v, got %d", tc.want, tc.genre, tc.branch.LoanPeriods, got)
+		}
+	}
+}
+
+func TestDueAt(t *testing.T) {
+	jakarta, err := loadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		pickedUp string
+		days     int
+		loc      *time.Location
+		want     string
+	}{
+		// Due at the end of the last day of the loan
+		{"2023-11-20T08:00:00Z", 14, time.UTC, "2023-12-05T00:00:00Z"},
+		{"2023-11-20T23:59:59Z", 1, time.UTC, "2023-11-22T00:00:00Z"},
+		// It is already the 21st in Jakarta
+		{"2023-11-20T20:00:00Z", 1, jakarta, "2023-11-22T17:00:00Z"},
+		{"2023-11-20T08:00:00Z", 1, jakarta, "2023-11-21T17:00:00Z"},
+	} {
+		pickedUp := mustParsePickUpDate(tc.pickedUp).Time()
+		if got := dueAt(pickedUp, tc.days, tc.loc).Format(time.RFC3339); got != tc.want {
+			t.Errorf("Expected a %d day loan from %s in %s to be due at %s, got %s", tc.days, tc.pickedUp, tc.loc, tc.want, got)
+		}
+	}
+}
+
+// countingService counts the overdue checks run through it
+type countingService struct {
+	mockService
+	checks atomic.Int32
+}
+
+func (s *countingService) MarkOverdueLoansService(ctx context.Context) (ScheduleListResponse, error) {
+	s.checks.Add(1)
+	return ScheduleListResponse{TotalData: 1}, nil
+}
+
+func TestRunOverdueCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	service := &countingService{}
+
+	done := make(chan struct{})
+	go func() {
+		RunOverdueCheck(ctx, service, time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for service.checks.Load() < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("Expected repeated overdue checks, got %d", service.checks.Load())
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the overdue check to stop once the context is done")
+	}
+}
+
+func TestBookService_CopyOnLoan(t *testing.T) {
+	ctx := context.Background()
+	emma := Book{Title: "Emma", Author: []string{"Jane Austen"}}
+	service := NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch).stock(1, "fiction", emma),
+		newMockBorrowers(testBorrower, Borrower{ID: "other", Name: "Other"}), WithClock(serviceClock))
+	submit := func(borrowerID, date string) (PostResponse, error) {
+		return service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: borrowerID, PickUpDate: mustParsePickUpDate(date)})
+	}
+	transition := func(id string, statuses ...ScheduleStatus) {
+		for _, status := range statuses {
+			if _, err := service.TransitionPickUpScheduleService(ctx, id, StatusTransition{Status: status, ChangedBy: "librarian"}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+
+	loan, err := submit("reader", "2023-11-21T10:00:00Z")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	transition(loan.Data.ID, ScheduleStatusConfirmed, ScheduleStatusReadyForPickUp, ScheduleStatusPickedUp)
+
+	t.Run("NegativeCase_AnotherDayRefused", func(t *testing.T) {
+		response, err := submit("other", "2023-11-22T10:00:00Z")
+		if !errors.Is(err, ErrCapacityExceeded) || response.Status != "409 Conflict" {
+			t.Errorf("Expected 409 while the only copy is on loan, got %q, %v", response.Status, err)
+		}
+	})
+
+	t.Run("PositiveCase_FreeOnceReturned", func(t *testing.T) {
+		transition(loan.Data.ID, ScheduleStatusReturned)
+		if _, err := submit("other", "2023-11-22T10:00:00Z"); err != nil {
+			t.Errorf("Expected the returned copy to be booked, got %v", err)
+		}
+	})
+}
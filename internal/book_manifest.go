@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// manifestClockLayout is how slot times are written in CSV manifests, in the
+// local time of the branch
+const manifestClockLayout = "15:04"
+
+// ManifestColumns are the header of a CSV manifest, one row per pick-up
+var ManifestColumns = []string{"date", "slot_start", "slot_end", "title", "authors", "edition", "genre", "borrower_id", "borrower_name", "status", "schedule_id"}
+
+// Manifest lists the pick-ups of a branch on one day by slot, for staff to
+// pull the books from the shelves
+type Manifest struct {
+	BranchID   string `json:"branch_id"`
+	BranchName string `json:"branch_name"`
+	// Date is the day in the time zone of the branch, in which the slot
+	// times are given too
+	Date     string         `json:"date"`
+	Timezone string         `json:"timezone"`
+	Slots    []ManifestSlot `json:"slots"`
+}
+
+// ManifestSlot is a pick-up slot with at least one pick-up
+type ManifestSlot struct {
+	Start time.Time      `json:"start"`
+	End   time.Time      `json:"end"`
+	Items []ManifestItem `json:"items"`
+}
+
+// ManifestItem is a book to pull for a pick-up
+type ManifestItem struct {
+	ScheduleID    string         `json:"schedule_id"`
+	Title         string         `json:"title"`
+	Authors       []string       `json:"authors"`
+	EditionNumber int            `json:"edition_number"`
+	Genre         string         `json:"genre"`
+	BorrowerID    string         `json:"borrower_id"`
+	BorrowerName  string         `json:"borrower_name"`
+	Status        ScheduleStatus `json:"status"`
+}
+
+type ManifestResponse struct {
+	Status    string       `json:"status"`
+	IsSuccess bool         `json:"is_success"`
+	Message   string       `json:"message"`
+	TotalData int          `json:"total_data"`
+	Data      Manifest     `json:"data"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// GetPickUpManifestService returns the pick-ups at the branch on a day as
+// 2006-01-02 in its time zone, today when day is empty, grouped by slot.
+// Cancelled pick-ups leave nothing to pull and are left out.
+func (s *bookService) GetPickUpManifestService(ctx context.Context, branchID, day string) (ManifestResponse, error) {
+	branch, err := s.branches.GetBranch(ctx, branchID)
+	if err != nil {
+		return failedManifestResponse("failed to fetch pick-up manifest", err), err
+	}
+	loc, err := branch.Location()
+	if err != nil {
+		return failedManifestResponse("failed to fetch pick-up manifest", err), err
+	}
+	start, err := s.branchDay(day, loc)
+	if err != nil {
+		return failedManifestResponse("invalid pick-up manifest", err), err
+	}
+	schedules, err := s.schedulesOnDay(ctx, branch.ID, start)
+	if err != nil {
+		return failedManifestResponse("failed to fetch pick-up manifest", err), err
+	}
+
+	manifest := Manifest{
+		BranchID:   branch.ID,
+		BranchName: branch.Name,
+		Date:       start.Format(pickUpDayLayout),
+		Timezone:   loc.String(),
+		Slots:      []ManifestSlot{},
+	}
+	borrowers := map[string]string{}
+	total := 0
+	for _, schedule := range schedules {
+		if schedule.Status == ScheduleStatusCancelled {
+			continue
+		}
+
+		name, cached := borrowers[schedule.BorrowerID]
+		if !cached {
+			borrower, err := s.borrowers.GetBorrower(ctx, schedule.BorrowerID)
+			switch {
+			case errors.Is(err, ErrBorrowerNotFound):
+			case err != nil:
+				return failedManifestResponse("failed to fetch borrower of pick-up schedule", err), err
+			default:
+				name = borrower.Name
+			}
+			borrowers[schedule.BorrowerID] = name
+		}
+
+		// Pick-ups booked before the slot length changed may start inside a
+		// slot, they are listed with the slot they fall in
+		slotStart := s.slots.slotStart(schedule.PickUpDate.Time().In(loc))
+		if last := len(manifest.Slots) - 1; last < 0 || !manifest.Slots[last].Start.Equal(slotStart) {
+			manifest.Slots = append(manifest.Slots, ManifestSlot{Start: slotStart, End: slotStart.Add(s.slots.Length)})
+		}
+		slot := &manifest.Slots[len(manifest.Slots)-1]
+		slot.Items = append(slot.Items, ManifestItem{
+			ScheduleID:    schedule.ID,
+			Title:         schedule.BookInfo.Title,
+			Authors:       append([]string{}, schedule.BookInfo.Author...),
+			EditionNumber: schedule.BookInfo.EditionNumber,
+			Genre:         schedule.Genre,
+			BorrowerID:    schedule.BorrowerID,
+			BorrowerName:  name,
+			Status:        schedule.Status,
+		})
+		total++
+	}
+
+	// Books of a slot are pulled in shelf order, roughly by genre and title
+	for _, slot := range manifest.Slots {
+		items := slot.Items
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Genre != items[j].Genre {
+				return items[i].Genre < items[j].Genre
+			}
+			return items[i].Title < items[j].Title
+		})
+	}
+
+	response := ManifestResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch pick-up manifest successfully!",
+		TotalData: total,
+		Data:      manifest,
+	}
+
+	return response, nil
+}
+
+// CSV renders the manifest with the ManifestColumns header and a row per
+// pick-up, slot by slot. Authors are joined by "; ".
+func (m Manifest) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write(ManifestColumns); err != nil {
+		return nil, err
+	}
+	for _, slot := range m.Slots {
+		for _, item := range slot.Items {
+			record := []string{
+				m.Date,
+				slot.Start.Format(manifestClockLayout),
+				slot.End.Format(manifestClockLayout),
+				item.Title,
+				strings.Join(item.Authors, "; "),
+				strconv.Itoa(item.EditionNumber),
+				item.Genre,
+				item.BorrowerID,
+				item.BorrowerName,
+				string(item.Status),
+				item.ScheduleID,
+			}
+			for i := range record {
+				record[i] = csvCell(record[i])
+			}
+			if err := writer.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// csvCell keeps a spreadsheet from running a value entered by a borrower or
+// staff, like a title starting with "=", as a formula
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// failedManifestResponse describes an error of a manifest request
+func failedManifestResponse(message string, err error) ManifestResponse {
+	response := ManifestResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   fmt.Sprintf("%s: %v", message, err),
+		Data:      Manifest{Slots: []ManifestSlot{}},
+		TotalData: 0,
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Errors = validationErr.Fields
+	}
+
+	return response
+}
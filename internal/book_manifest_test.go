@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBookService_GetPickUpManifestService(t *testing.T) {
+	ctx := context.Background()
+	jakarta := Branch{ID: "jakarta", Name: "Jakarta", Address: "Jalan Sudirman 1", Timezone: "Asia/Jakarta", OpeningHours: everyDay("00:00", "24:00")}
+	emma := Book{Title: "Emma", Author: []string{"Jane Austen"}, EditionNumber: 2}
+	sum := Book{Title: "=SUM(A1:A9)", Author: []string{"Ann One", "Bo Two"}, EditionNumber: 1}
+	atlas := Book{Title: "Atlas"}
+	writer := Borrower{ID: "writer", Name: "Writer, Jr."}
+	service := NewService(NewInMemoryRepository(ctx), newMockBranches(jakarta).stock(5, "fiction", emma, sum, atlas),
+		newMockBorrowers(testBorrower, writer), WithClock(serviceClock), WithBorrowerLimits(BorrowerLimits{}))
+	submit := func(book Book, borrowerID, date string) PickUpSchedule {
+		t.Helper()
+		response, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BranchID: "jakarta", BorrowerID: borrowerID, BookInfo: book, PickUpDate: mustParsePickUpDate(date)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return response.Data
+	}
+
+	// 06:00 in Jakarta is still the day before in UTC
+	first := submit(emma, "reader", "2023-11-22T06:00")
+	second := submit(sum, "writer", "2023-11-22T06:00")
+	cancelled := submit(atlas, "reader", "2023-11-22T10:00")
+	later := submit(atlas, "writer", "2023-11-22T10:30")
+	submit(emma, "writer", "2023-11-23T06:00")
+	if _, err := service.CancelPickUpScheduleService(ctx, cancelled.ID, Cancellation{CancelledBy: "reader"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("PositiveCase_BySlot", func(t *testing.T) {
+		response, err := service.GetPickUpManifestService(ctx, "jakarta", "2023-11-22")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		manifest := response.Data
+		if response.TotalData != 3 || manifest.Date != "2023-11-22" || manifest.Timezone != "Asia/Jakarta" || manifest.BranchName != "Jakarta" {
+			t.Fatalf("Expected 3 pick-ups on 2023-11-22 in Jakarta, got %+v", response)
+		}
+		if len(manifest.Slots) != 2 {
+			t.Fatalf("Expected 2 slots without the cancelled pick-up, got %+v", manifest.Slots)
+		}
+
+		morning := manifest.Slots[0]
+		if morning.Start.Format("2006-01-02T15:04Z07:00") != "2023-11-22T06:00+07:00" || morning.End.Format("15:04") != "06:30" {
+			t.Errorf("Expected the slot from 06:00 to 06:30 local time, got %v to %v", morning.Start, morning.End)
+		}
+		if len(morning.Items) != 2 || morning.Items[0].ScheduleID != second.ID || morning.Items[1].ScheduleID != first.ID {
+			t.Errorf("Expected both morning pick-ups by title, got %+v", morning.Items)
+		}
+		item := morning.Items[1]
+		if item.Title != "Emma" || item.Authors[0] != "Jane Austen" || item.EditionNumber != 2 || item.BorrowerName != "Reader" || item.Status != ScheduleStatusRequested {
+			t.Errorf("Expected the book and borrower of the pick-up, got %+v", item)
+		}
+		if slot := manifest.Slots[1]; len(slot.Items) != 1 || slot.Items[0].ScheduleID != later.ID || slot.Start.Format("15:04") != "10:30" {
+			t.Errorf("Expected the 10:30 pick-up, got %+v", slot)
+		}
+	})
+
+	t.Run("PositiveCase_CSV", func(t *testing.T) {
+		response, err := service.GetPickUpManifestService(ctx, "jakarta", "2023-11-22")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		rendered, err := response.Data.CSV()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		records, err := csv.NewReader(strings.NewReader(string(rendered))).ReadAll()
+		if err != nil {
+			t.Fatalf("Expected valid CSV, got %v", err)
+		}
+		if len(records) != 4 || strings.Join(records[0], ",") != strings.Join(ManifestColumns, ",") {
+			t.Fatalf("Expected a header and 3 rows, got %q", records)
+		}
+		want := []string{"2023-11-22", "06:00", "06:30", "'=SUM(A1:A9)", "Ann One; Bo Two", "1", "fiction", "writer", "Writer, Jr.", "requested", second.ID}
+		if strings.Join(records[1], "|") != strings.Join(want, "|") {
+			t.Errorf("Expected row %q, got %q", want, records[1])
+		}
+	})
+
+	t.Run("PositiveCase_EmptyToday", func(t *testing.T) {
+		response, err := service.GetPickUpManifestService(ctx, "jakarta", "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.TotalData != 0 || response.Data.Date != "2023-11-20" || response.Data.Slots == nil {
+			t.Errorf("Expected an empty manifest of today, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		response, err := service.GetPickUpManifestService(ctx, "missing", "2023-11-22")
+		if !errors.Is(err, ErrBranchNotFound) || response.Status != "404 Not Found" {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidDate", func(t *testing.T) {
+		response, err := service.GetPickUpManifestService(ctx, "jakarta", "22-11-2023")
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected *ValidationError, got %v", err)
+		}
+		if response.Status != "400 Bad Request" || len(response.Errors) != 1 || response.Errors[0].Field != "date" {
+			t.Errorf("Expected a 400 on date, got %+v", response)
+		}
+	})
+}
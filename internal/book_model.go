@@ -1,13 +1,374 @@
 package internal
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Book struct {
 	Title         string   `json:"title"`
 	Author        []string `json:"author"`
 	EditionNumber int      `json:"edition_number"`
+	// AvailableCopies is only set on books listed by genre, it is how many
+	// copies are free today across every branch
+	AvailableCopies *int `json:"available_copies,omitempty"`
+}
+
+// bookKey identifies a book of a genre the way the books table does, by its
+// title, edition and ordered authors
+func bookKey(genre string, book Book) string {
+	identity := strings.Join([]string{genre, book.Title, strconv.Itoa(book.EditionNumber), strings.Join(book.Author, "\x1f")}, "\x1e")
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ScheduleStatus is where a pick-up schedule is in its lifecycle. A schedule
+// is requested by a borrower, confirmed and made ready by staff, and then
+// either picked up and later returned, or missed as a no-show. A picked up
+// book that is not back by its due date is flagged overdue until it is
+// returned. A schedule can be cancelled until it is picked up.
+type ScheduleStatus string
+
+const (
+	ScheduleStatusRequested      ScheduleStatus = "requested"
+	ScheduleStatusConfirmed      ScheduleStatus = "confirmed"
+	ScheduleStatusReadyForPickUp ScheduleStatus = "ready_for_pick_up"
+	ScheduleStatusPickedUp       ScheduleStatus = "picked_up"
+	ScheduleStatusOverdue        ScheduleStatus = "overdue"
+	ScheduleStatusReturned       ScheduleStatus = "returned"
+	ScheduleStatusNoShow         ScheduleStatus = "no_show"
+	ScheduleStatusCancelled      ScheduleStatus = "cancelled"
+
+	// scheduleStatusActive is what requested schedules were stored as before
+	// the lifecycle existed
+	scheduleStatusActive ScheduleStatus = "active"
+)
+
+// ScheduleStatuses are all statuses a pick-up schedule can have, in lifecycle
+// order
+var ScheduleStatuses = []ScheduleStatus{
+	ScheduleStatusRequested,
+	ScheduleStatusConfirmed,
+	ScheduleStatusReadyForPickUp,
+	ScheduleStatusPickedUp,
+	ScheduleStatusOverdue,
+	ScheduleStatusReturned,
+	ScheduleStatusNoShow,
+	ScheduleStatusCancelled,
+}
+
+// scheduleTransitions are the statuses a schedule can move to from each
+// status. Returned, no-show and cancelled are final.
+var scheduleTransitions = map[ScheduleStatus][]ScheduleStatus{
+	ScheduleStatusRequested:      {ScheduleStatusConfirmed, ScheduleStatusCancelled},
+	ScheduleStatusConfirmed:      {ScheduleStatusReadyForPickUp, ScheduleStatusCancelled},
+	ScheduleStatusReadyForPickUp: {ScheduleStatusPickedUp, ScheduleStatusNoShow, ScheduleStatusCancelled},
+	ScheduleStatusPickedUp:       {ScheduleStatusReturned, ScheduleStatusOverdue},
+	ScheduleStatusOverdue:        {ScheduleStatusReturned},
+}
+
+// CanTransitionTo reports whether a schedule with the status can move to next
+func (s ScheduleStatus) CanTransitionTo(next ScheduleStatus) bool {
+	for _, allowed := range scheduleTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// isPending reports whether the pick-up of a schedule with the status has yet
+// to happen, so it can still be rescheduled
+func (s ScheduleStatus) isPending() bool {
+	return s == ScheduleStatusRequested || s == ScheduleStatusConfirmed || s == ScheduleStatusReadyForPickUp
 }
 
 type PickUpSchedule struct {
-	BookInfo   Book   `json:"book_info"`
-	PickUpDate string `json:"pick_up_date"`
-	Genre      string `json:"genre"`
+	ID         string     `json:"id"`
+	BookInfo   Book       `json:"book_info"`
+	PickUpDate PickUpDate `json:"pick_up_date"`
+	Genre      string     `json:"genre"`
+	BranchID   string     `json:"branch_id"`
+	// Timezone is the IANA time zone of the branch, the pick-up is also
+	// written in its local time as local_pick_up_date
+	Timezone     string         `json:"timezone,omitempty"`
+	BorrowerID   string         `json:"borrower_id"`
+	Status       ScheduleStatus `json:"status"`
+	Cancellation *Cancellation  `json:"cancellation,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	// DueAt is set when the book is picked up, the loan is overdue from then on
+	DueAt *time.Time `json:"due_at,omitempty"`
+}
+
+// LocalPickUpDate returns the pick-up in the local time of the branch, or ""
+// when its time zone is not known
+func (s PickUpSchedule) LocalPickUpDate() string {
+	loc, err := loadLocation(s.Timezone)
+	if err != nil {
+		return ""
+	}
+	return s.PickUpDate.Local(loc)
+}
+
+// LocalDay returns the day of the pick-up as 2006-01-02 in the local time of
+// the branch, or in UTC when its time zone is not known
+func (s PickUpSchedule) LocalDay() string {
+	loc, err := loadLocation(s.Timezone)
+	if err != nil {
+		return s.PickUpDate.Day()
+	}
+	return s.PickUpDate.DayIn(loc)
+}
+
+func (s PickUpSchedule) MarshalJSON() ([]byte, error) {
+	// schedule has the fields of PickUpSchedule without this method
+	type schedule PickUpSchedule
+	return json.Marshal(struct {
+		schedule
+		LocalPickUpDate string `json:"local_pick_up_date,omitempty"`
+	}{schedule(s), s.LocalPickUpDate()})
+}
+
+// Cancellation records who cancelled a pick-up schedule and why
+type Cancellation struct {
+	CancelledBy string    `json:"cancelled_by"`
+	Reason      string    `json:"reason"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+// StatusTransition moves a pick-up schedule to the next status of its
+// lifecycle, recording the member of staff who made the change
+type StatusTransition struct {
+	Status    ScheduleStatus `json:"status"`
+	ChangedBy string         `json:"changed_by"`
+	Reason    string         `json:"reason,omitempty"`
+	// DueAt is set by the service for a book being picked up
+	DueAt *time.Time `json:"-"`
+}
+
+// Reschedule moves a pick-up schedule to another date, another book of the
+// same genre, or both. Zero or nil fields are left unchanged.
+type Reschedule struct {
+	PickUpDate PickUpDate `json:"pick_up_date"`
+	BookInfo   *Book      `json:"book_info,omitempty"`
+	ChangedBy  string     `json:"changed_by"`
+	Reason     string     `json:"reason,omitempty"`
+}
+
+// ScheduleChange is one entry in the history of a pick-up schedule. The book
+// fields are only set when the book was swapped, and the status fields only
+// when the status moved. Cancellations are recorded as the Cancellation of
+// the schedule instead.
+type ScheduleChange struct {
+	ChangedBy      string         `json:"changed_by"`
+	ChangedAt      time.Time      `json:"changed_at"`
+	Reason         string         `json:"reason,omitempty"`
+	FromPickUpDate PickUpDate     `json:"from_pick_up_date"`
+	ToPickUpDate   PickUpDate     `json:"to_pick_up_date"`
+	FromBookInfo   *Book          `json:"from_book_info,omitempty"`
+	ToBookInfo     *Book          `json:"to_book_info,omitempty"`
+	FromStatus     ScheduleStatus `json:"from_status,omitempty"`
+	ToStatus       ScheduleStatus `json:"to_status,omitempty"`
+}
+
+// ScheduleQuery filters, sorts and pages pick-up schedules across genres.
+// Empty filters match everything.
+type ScheduleQuery struct {
+	Genre      string
+	BranchID   string
+	BorrowerID string
+	Status     ScheduleStatus
+	// Title matches schedules whose book title contains it, ignoring case
+	Title string
+	// PickUpFrom and PickUpTo bound the day of the pick-up as 2006-01-02 in
+	// the local time of its branch, both inclusive
+	PickUpFrom string
+	PickUpTo   string
+	// Sort is one of the ScheduleSortFields, prefixed with "-" for descending
+	Sort string
+	// Cursor is the NextCursor of the previous page
+	Cursor string
+	Limit  int
+}
+
+// ScheduleSortFields are the fields a ScheduleQuery can be sorted by
+var ScheduleSortFields = []string{"created_at", "pick_up_date", "title", "due_at"}
+
+type SchedulePage struct {
+	Schedules []PickUpSchedule
+	// NextCursor is empty on the last page
+	NextCursor string
+}
+
+const (
+	pickUpDayLayout  = "2006-01-02"
+	pickUpTimeLayout = "2006-01-02T15:04:05Z07:00"
+)
+
+// pickUpLayouts are the ISO 8601 forms a pick-up date is accepted in. A time
+// without an offset is taken as UTC. Fractional seconds are accepted and dropped.
+var pickUpLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// pickUpOffsetLayouts is how many of the pickUpLayouts, from the first, have
+// an offset
+const pickUpOffsetLayouts = 2
+
+// PickUpDate is the day, and optionally the time, of a pick-up. It is written
+// as 2006-01-02 when no time was given and as UTC 2006-01-02T15:04:05Z
+// otherwise, both of which sort correctly as text.
+//
+// A time given without an offset is taken as UTC until In reads it as the
+// local time of a branch.
+//
+// Text that is not a valid date is kept as is instead of failing to decode,
+// so a request can be rejected with a field error and values stored before
+// dates were checked stay readable. Err reports such values.
+type PickUpDate struct {
+	t       time.Time
+	hasTime bool
+	// floating is set for a time given without an offset
+	floating bool
+	invalid  string
+}
+
+// ParsePickUpDate parses an ISO 8601 date with an optional time
+func ParsePickUpDate(value string) (PickUpDate, error) {
+	if t, err := time.Parse(pickUpDayLayout, value); err == nil {
+		return PickUpDate{t: t}, nil
+	}
+	for i, layout := range pickUpLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return PickUpDate{t: t.UTC().Truncate(time.Second), hasTime: true, floating: i >= pickUpOffsetLayouts}, nil
+		}
+	}
+	return PickUpDate{invalid: value}, fmt.Errorf("invalid pick-up date %q", value)
+}
+
+// Time is the moment of the pick-up, midnight UTC when only a day was given
+func (d PickUpDate) Time() time.Time {
+	return d.t
+}
+
+// In returns the date with a time given without an offset read as the local
+// time in loc instead of UTC. Of the two moments an hour repeated by a
+// daylight saving change has, the earlier is taken, and a time skipped by one
+// fails. Any other date is returned as it is.
+func (d PickUpDate) In(loc *time.Location) (PickUpDate, error) {
+	if !d.floating {
+		return d, nil
+	}
+
+	at, err := localTime(d.t, loc)
+	if err != nil {
+		return d, err
+	}
+	return PickUpDate{t: at.UTC(), hasTime: true}, nil
+}
+
+// Local returns the pick-up as 2006-01-02T15:04:05+07:00 in loc, or "" when no
+// valid time was given
+func (d PickUpDate) Local(loc *time.Location) string {
+	if !d.hasTime || d.invalid != "" {
+		return ""
+	}
+	return d.t.In(loc).Format(pickUpTimeLayout)
+}
+
+// HasTime reports whether a time of day was given
+func (d PickUpDate) HasTime() bool {
+	return d.hasTime
+}
+
+// IsZero reports whether no pick-up date was given at all
+func (d PickUpDate) IsZero() bool {
+	return d.t.IsZero() && d.invalid == ""
+}
+
+// Err returns an error when the date was read from invalid text
+func (d PickUpDate) Err() error {
+	if d.invalid != "" {
+		return fmt.Errorf("invalid pick-up date %q", d.invalid)
+	}
+	return nil
+}
+
+// Day returns the day of the pick-up as 2006-01-02, or the original text of
+// an invalid date
+func (d PickUpDate) Day() string {
+	if d.invalid != "" || d.IsZero() {
+		return d.invalid
+	}
+	return d.t.Format(pickUpDayLayout)
+}
+
+// DayIn returns the day of the pick-up as 2006-01-02 in loc. A date given
+// without a time of day is already a local day and returned as it is.
+func (d PickUpDate) DayIn(loc *time.Location) string {
+	if !d.hasTime || d.invalid != "" {
+		return d.Day()
+	}
+	return d.t.In(loc).Format(pickUpDayLayout)
+}
+
+func (d PickUpDate) String() string {
+	switch {
+	case d.invalid != "" || d.IsZero():
+		return d.invalid
+	case d.hasTime:
+		return d.t.Format(pickUpTimeLayout)
+	default:
+		return d.t.Format(pickUpDayLayout)
+	}
+}
+
+// localTime returns the moment the clocks in loc show the wall clock time of
+// wall, which is given in UTC. The earlier of two such moments is returned.
+func localTime(wall time.Time, loc *time.Location) (time.Time, error) {
+	const layout = "2006-01-02T15:04:05"
+
+	// Every offset loc uses around the wall clock time is tried
+	var found time.Time
+	for _, probe := range []time.Time{wall.Add(-24 * time.Hour), wall, wall.Add(24 * time.Hour)} {
+		_, offset := probe.In(loc).Zone()
+		at := wall.Add(-time.Duration(offset) * time.Second)
+		if at.In(loc).Format(layout) == wall.Format(layout) && (found.IsZero() || at.Before(found)) {
+			found = at
+		}
+	}
+
+	if found.IsZero() {
+		return time.Time{}, fmt.Errorf("%s does not exist in %s", wall.Format(layout), loc)
+	}
+	return found, nil
+}
+
+func (d PickUpDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *PickUpDate) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		// Not a string at all, keep it for the validation error
+		*d = PickUpDate{invalid: string(data)}
+		return nil
+	}
+	if value == "" {
+		*d = PickUpDate{}
+		return nil
+	}
+
+	*d, _ = ParsePickUpDate(value)
+	return nil
 }
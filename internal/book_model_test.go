@@ -3,6 +3,7 @@ package internal
 import (
 	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -48,7 +49,7 @@ func TestPickUpScheduleSerialization(t *testing.T) {
 			Author:        []string{"Author1", "Author2"},
 			EditionNumber: 1,
 		},
-		PickUpDate: "2023-12-31",
+		PickUpDate: mustParsePickUpDate("2023-12-31"),
 		Genre:      "Fiction",
 	}
 
@@ -72,3 +73,155 @@ func TestPickUpScheduleSerialization(t *testing.T) {
 		t.Error("Original and deserialized PickUpSchedule instances are not equal")
 	}
 }
+
+func TestPickUpDate(t *testing.T) {
+	t.Run("PositiveCase_Formats", func(t *testing.T) {
+		for input, want := range map[string]string{
+			"2023-12-01":                "2023-12-01",
+			"2023-12-01T10:30":          "2023-12-01T10:30:00Z",
+			"2023-12-01T10:30:15":       "2023-12-01T10:30:15Z",
+			"2023-12-01T10:30:15.5Z":    "2023-12-01T10:30:15Z",
+			"2023-12-01T10:30+07:00":    "2023-12-01T03:30:00Z",
+			"2023-12-01T01:30:00+07:00": "2023-11-30T18:30:00Z",
+		} {
+			date, err := ParsePickUpDate(input)
+			if err != nil {
+				t.Errorf("Unexpected error for %q: %v", input, err)
+				continue
+			}
+			if date.String() != want {
+				t.Errorf("Expected %q to be stored as %q, got %q", input, want, date.String())
+			}
+		}
+	})
+
+	t.Run("NegativeCase_Malformed", func(t *testing.T) {
+		for _, input := range []string{"tomorrow", "2023-13-45", "01/12/2023", "2023-12-01 10:30"} {
+			date, err := ParsePickUpDate(input)
+			if err == nil {
+				t.Errorf("Expected error for %q, but got nil", input)
+			}
+			// The text is kept so it can be reported and stored values stay readable
+			if date.Err() == nil || date.String() != input {
+				t.Errorf("Expected %q to be kept as invalid, got %q", input, date.String())
+			}
+		}
+	})
+
+	t.Run("PositiveCase_JSON", func(t *testing.T) {
+		var schedule PickUpSchedule
+		if err := json.Unmarshal([]byte(`{"pick_up_date": "2023-12-01T10:30"}`), &schedule); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !schedule.PickUpDate.HasTime() || schedule.PickUpDate.Day() != "2023-12-01" {
+			t.Errorf("Expected a date with time, got %v", schedule.PickUpDate)
+		}
+
+		data, err := json.Marshal(schedule.PickUpDate)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(data) != `"2023-12-01T10:30:00Z"` {
+			t.Errorf("Expected UTC date time, got %s", data)
+		}
+	})
+
+	t.Run("NegativeCase_JSONDoesNotFail", func(t *testing.T) {
+		for body, wantZero := range map[string]bool{
+			`{"pick_up_date": "tomorrow"}`: false,
+			`{"pick_up_date": 20231201}`:   false,
+			`{"pick_up_date": null}`:       true,
+			`{}`:                           true,
+		} {
+			var schedule PickUpSchedule
+			if err := json.Unmarshal([]byte(body), &schedule); err != nil {
+				t.Errorf("Unexpected error for %s: %v", body, err)
+				continue
+			}
+			if schedule.PickUpDate.IsZero() != wantZero || (!wantZero && schedule.PickUpDate.Err() == nil) {
+				t.Errorf("Expected %s to be decoded as zero=%v, got %+v", body, wantZero, schedule.PickUpDate)
+			}
+		}
+	})
+}
+
+func TestPickUpDate_In(t *testing.T) {
+	newYork, err := loadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("PositiveCase_LocalTimes", func(t *testing.T) {
+		for input, want := range map[string]string{
+			"2023-12-01T10:30":       "2023-12-01T15:30:00Z",
+			"2023-07-01T10:30:15":    "2023-07-01T14:30:15Z",
+			"2023-12-01T10:30Z":      "2023-12-01T10:30:00Z",
+			"2023-12-01T10:30+07:00": "2023-12-01T03:30:00Z",
+			"2023-12-01":             "2023-12-01",
+			// The clocks go back from 02:00 to 01:00, the first 01:30 is taken
+			"2023-11-05T01:30": "2023-11-05T05:30:00Z",
+		} {
+			date, err := mustParsePickUpDate(input).In(newYork)
+			if err != nil {
+				t.Errorf("Unexpected error for %q: %v", input, err)
+				continue
+			}
+			if date.String() != want {
+				t.Errorf("Expected %q in New York to be %q, got %q", input, want, date.String())
+			}
+		}
+	})
+
+	t.Run("NegativeCase_SkippedTime", func(t *testing.T) {
+		// The clocks go forward from 02:00 to 03:00
+		if _, err := mustParsePickUpDate("2024-03-10T02:30").In(newYork); err == nil {
+			t.Error("Expected error for a skipped time, but got nil")
+		}
+	})
+
+	t.Run("PositiveCase_Local", func(t *testing.T) {
+		for input, want := range map[string]string{
+			"2024-03-10T06:30:00Z": "2024-03-10T01:30:00-05:00",
+			"2024-03-10T07:00:00Z": "2024-03-10T03:00:00-04:00",
+			"2024-03-10":           "",
+		} {
+			if got := mustParsePickUpDate(input).Local(newYork); got != want {
+				t.Errorf("Expected %q in New York to be %q, got %q", input, want, got)
+			}
+		}
+	})
+}
+
+func TestPickUpSchedule_MarshalJSON(t *testing.T) {
+	schedule := PickUpSchedule{ID: "1", PickUpDate: mustParsePickUpDate("2023-12-01T03:30:00Z"), Timezone: "Asia/Jakarta"}
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fields["pick_up_date"] != "2023-12-01T03:30:00Z" || fields["timezone"] != "Asia/Jakarta" || fields["local_pick_up_date"] != "2023-12-01T10:30:00+07:00" {
+		t.Errorf("Expected the pick-up in UTC and Jakarta time, got %s", data)
+	}
+
+	// Schedules saved before time zones were attached have no local time
+	schedule.Timezone = ""
+	if data, err = json.Marshal(schedule); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "local_pick_up_date") || strings.Contains(string(data), "timezone") {
+		t.Errorf("Expected no local time without a time zone, got %s", data)
+	}
+}
+
+// mustParsePickUpDate parses a pick-up date that is known to be valid
+func mustParsePickUpDate(value string) PickUpDate {
+	date, err := ParsePickUpDate(value)
+	if err != nil {
+		panic(err)
+	}
+	return date
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// RunReminderCheck reminds borrowers of their upcoming pick-ups right away and
+// then every interval, until ctx is done
+func RunReminderCheck(ctx context.Context, service BookService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		response, err := service.SendRemindersService(ctx)
+		switch {
+		case err != nil:
+			log.Printf("reminder check failed: %v", err)
+		case response.TotalData > 0:
+			log.Printf("reminder check reminded borrowers of %d pick-ups", response.TotalData)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendRemindersService reminds the borrowers of every pending pick-up due
+// within NotificationConfig.RemindBefore that they were not reminded of yet,
+// and returns the schedules they were reminded of. A reminder that cannot be
+// sent is tried again on the next call.
+func (s *bookService) SendRemindersService(ctx context.Context) (ScheduleListResponse, error) {
+	response := ScheduleListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "send pick-up reminders successfully!",
+		Data:      []PickUpSchedule{},
+	}
+	if s.notifications == nil {
+		return response, nil
+	}
+
+	now := s.now().UTC()
+	until := now.Add(s.notificationConfig.RemindBefore)
+	// Pick-up days are filtered roughly, the exact times are checked below
+	from, to := pickUpDaysAround(now, until)
+	schedules, err := s.allSchedules(ctx, ScheduleQuery{
+		PickUpFrom: from,
+		PickUpTo:   to,
+		Sort:       "pick_up_date",
+	})
+	if err != nil {
+		return ScheduleListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch upcoming pick-ups: %v", err),
+			Data:      []PickUpSchedule{},
+			TotalData: 0,
+		}, err
+	}
+
+	var failures []error
+	for _, schedule := range schedules {
+		at := schedule.PickUpDate.Time()
+		pending := schedule.Status.isPending() || schedule.Status == scheduleStatusActive
+		if !pending || !at.After(now) || at.After(until) {
+			continue
+		}
+
+		sent, err := s.notify(ctx, NotificationReminder, schedule)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("pick-up schedule %s: %v", schedule.ID, err))
+			continue
+		}
+		if sent {
+			response.Data = append(response.Data, schedule)
+		}
+	}
+	response.TotalData = len(response.Data)
+
+	if len(failures) > 0 {
+		err := fmt.Errorf("failed to send %d reminders: %v", len(failures), errors.Join(failures...))
+		response.Status = "500 Internal Server Error"
+		response.IsSuccess = false
+		response.Message = err.Error()
+		return response, err
+	}
+	return response, nil
+}
+
+// notifyOf tells the borrower of a schedule that was just changed. The change
+// is already stored, so failing to notify is only logged.
+func (s *bookService) notifyOf(ctx context.Context, kind NotificationKind, schedule PickUpSchedule) {
+	if _, err := s.notify(ctx, kind, schedule); err != nil {
+		log.Printf("failed to send %s of pick-up schedule %s: %v", kind, schedule.ID, err)
+	}
+}
+
+// notify sends the borrower of the schedule a notification of kind unless it
+// was sent before, and reports whether it sent one. Schedules without a
+// borrower are skipped.
+func (s *bookService) notify(ctx context.Context, kind NotificationKind, schedule PickUpSchedule) (bool, error) {
+	if s.notifications == nil || schedule.BorrowerID == "" {
+		return false, nil
+	}
+
+	borrower, err := s.borrowers.GetBorrower(ctx, schedule.BorrowerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch borrower: %v", err)
+	}
+	var branch Branch
+	if schedule.BranchID != "" {
+		branch, err = s.branches.GetBranch(ctx, schedule.BranchID)
+		if err != nil && !errors.Is(err, ErrBranchNotFound) {
+			return false, fmt.Errorf("failed to fetch branch: %v", err)
+		}
+	}
+
+	// Claiming first keeps two checks running at once from both sending it
+	now := s.now().UTC()
+	claimed, err := s.notificationLog.ClaimNotification(ctx, NotificationRecord{ScheduleID: schedule.ID, Kind: kind, SentAt: now}, now.Add(-s.notificationConfig.Retention))
+	if err != nil || !claimed {
+		return false, err
+	}
+	err = s.notifications.Send(ctx, Notification{Kind: kind, Schedule: schedule, Borrower: borrower, Branch: branch})
+	if err != nil {
+		if releaseErr := s.notificationLog.ReleaseNotification(context.WithoutCancel(ctx), schedule.ID, kind); releaseErr != nil {
+			log.Printf("failed to release %s of pick-up schedule %s: %v", kind, schedule.ID, releaseErr)
+		}
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockChannel is a NotificationChannel recording what it is sent, failing
+// while err is set
+type mockChannel struct {
+	mu   sync.Mutex
+	sent []Notification
+	err  error
+}
+
+func (m *mockChannel) Send(ctx context.Context, notification Notification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, notification)
+	return nil
+}
+
+// kinds returns the kind and schedule ID of everything sent so far
+func (m *mockChannel) kinds() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kinds []string
+	for _, notification := range m.sent {
+		kinds = append(kinds, string(notification.Kind)+" "+notification.Schedule.ID)
+	}
+	return kinds
+}
+
+func TestBookService_Notifications(t *testing.T) {
+	ctx := context.Background()
+	book := Book{Title: "TestBook"}
+	other := Book{Title: "OtherBook"}
+	newService := func(channel *mockChannel, now *time.Time) BookService {
+		return NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch).stock(5, "fiction", book, other), newMockBorrowers(testBorrower),
+			WithClock(func() time.Time { return *now }),
+			WithNotifications(channel, NewInMemoryNotificationRepository(), DefaultNotificationConfig))
+	}
+	submit := func(t *testing.T, service BookService, book Book, date string) PickUpSchedule {
+		t.Helper()
+		response, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate(date)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return response.Data
+	}
+	equal := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("PositiveCase_Confirmation", func(t *testing.T) {
+		channel := &mockChannel{}
+		now := serviceNow
+		service := newService(channel, &now)
+
+		schedule := submit(t, service, book, "2023-11-21T10:00:00Z")
+
+		if got := channel.kinds(); !equal(got, []string{"confirmation " + schedule.ID}) {
+			t.Fatalf("Expected one confirmation, got %v", got)
+		}
+		sent := channel.sent[0]
+		if sent.Borrower.Email != "reader@example.com" || sent.Branch.Address != "Main Street 1" {
+			t.Errorf("Expected the borrower and branch of the schedule, got %+v", sent)
+		}
+	})
+
+	t.Run("PositiveCase_RemindersOnce", func(t *testing.T) {
+		channel := &mockChannel{}
+		now := serviceNow
+		service := newService(channel, &now)
+		soon := submit(t, service, book, "2023-11-21T07:00:00Z")
+		later := submit(t, service, other, "2023-11-22T06:00:00Z")
+
+		response, err := service.SendRemindersService(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.TotalData != 1 || response.Data[0].ID != soon.ID {
+			t.Errorf("Expected a reminder of the pick-up within a day, got %+v", response)
+		}
+
+		// Running again a day later reminds of the other one only
+		now = now.Add(24 * time.Hour)
+		if _, err := service.SendRemindersService(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := service.SendRemindersService(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := []string{"confirmation " + soon.ID, "confirmation " + later.ID, "reminder " + soon.ID, "reminder " + later.ID}
+		if got := channel.kinds(); !equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("PositiveCase_CancelledNotReminded", func(t *testing.T) {
+		channel := &mockChannel{}
+		now := serviceNow
+		service := newService(channel, &now)
+		schedule := submit(t, service, book, "2023-11-21T07:00:00Z")
+		if _, err := service.CancelPickUpScheduleService(ctx, schedule.ID, Cancellation{CancelledBy: "reader"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		response, err := service.SendRemindersService(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.TotalData != 0 {
+			t.Errorf("Expected no reminder of a cancelled pick-up, got %+v", response.Data)
+		}
+	})
+
+	t.Run("PositiveCase_WithoutChannel", func(t *testing.T) {
+		service := NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch).stock(5, "fiction", book), newMockBorrowers(testBorrower), WithClock(serviceClock))
+		submit(t, service, book, "2023-11-21T07:00:00Z")
+
+		response, err := service.SendRemindersService(ctx)
+		if err != nil || !response.IsSuccess || response.TotalData != 0 {
+			t.Errorf("Expected nothing to be sent, got %+v (%v)", response, err)
+		}
+	})
+
+	t.Run("PositiveCase_NoConfirmationOfRolledBackBatch", func(t *testing.T) {
+		channel := &mockChannel{}
+		now := serviceNow
+		service := newService(channel, &now)
+
+		_, err := service.SubmitPickUpSchedulesService(ctx, ScheduleBatch{Schedules: []PickUpSchedule{
+			{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate("2023-11-21T10:00:00Z")},
+			// The same book on the same day is only found when saving
+			{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate("2023-11-21T14:00:00Z")},
+		}})
+		if err == nil {
+			t.Fatal("Expected error, but got nil")
+		}
+		if got := channel.kinds(); len(got) != 0 {
+			t.Errorf("Expected no confirmations, got %v", got)
+		}
+	})
+
+	t.Run("NegativeCase_FailedReminderRetried", func(t *testing.T) {
+		channel := &mockChannel{}
+		now := serviceNow
+		service := newService(channel, &now)
+		schedule := submit(t, service, book, "2023-11-21T07:00:00Z")
+
+		channel.err = errors.New("mail server down")
+		response, err := service.SendRemindersService(ctx)
+		if err == nil {
+			t.Fatal("Expected error, but got nil")
+		}
+		if response.Status != "500 Internal Server Error" || response.IsSuccess {
+			t.Errorf("Expected a failed check, got %+v", response)
+		}
+
+		channel.err = nil
+		response, err = service.SendRemindersService(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.TotalData != 1 || response.Data[0].ID != schedule.ID {
+			t.Errorf("Expected the reminder to be sent on the next check, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_FailedConfirmationKeepsSchedule", func(t *testing.T) {
+		channel := &mockChannel{err: errors.New("mail server down")}
+		now := serviceNow
+		service := newService(channel, &now)
+
+		response, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate("2023-11-21T10:00:00Z")})
+		if err != nil || !response.IsSuccess {
+			t.Errorf("Expected the schedule to be saved, got %+v (%v)", response, err)
+		}
+	})
+}
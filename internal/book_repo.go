@@ -2,46 +2,284 @@ package internal
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+var (
+	// ErrScheduleNotFound is returned when no pick-up schedule has the requested ID
+	ErrScheduleNotFound = errors.New("pick-up schedule not found")
+	// ErrInvalidScheduleQuery is returned for a ScheduleQuery that cannot be run
+	ErrInvalidScheduleQuery = errors.New("invalid schedule query")
+	// ErrScheduleCancelled is returned when changing a cancelled pick-up schedule
+	ErrScheduleCancelled = errors.New("pick-up schedule is cancelled")
+	// ErrScheduleNotPending is returned when rescheduling a pick-up schedule
+	// that was already picked up, returned or missed
+	ErrScheduleNotPending = errors.New("pick-up schedule is no longer pending")
+	// ErrInvalidTransition is matched by every *TransitionError
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrCapacityExceeded is matched by every *CapacityError
+	ErrCapacityExceeded = errors.New("capacity exceeded")
+)
+
+// Reservation claims one place of a limited resource, such as a pick-up slot,
+// for as long as the schedule holding it is not cancelled. A Limit of zero or
+// less records the claim without limiting it. Shared are keys whose holders
+// take places of the same limit, like copies on loan do on every day.
+// Excluded are keys whose holders also hold Key but take none of its places,
+// like pick-ups of a borrower at the same branch do of the borrower's time.
+type Reservation struct {
+	Key      string
+	Limit    int
+	Shared   []string
+	Excluded []string
+}
+
+// CapacityError is returned when a schedule cannot claim a reservation because
+// as many schedules as its limit already hold it
+type CapacityError struct {
+	Key   string
+	Limit int
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("%v: %s is limited to %d", ErrCapacityExceeded, e.Key, e.Limit)
+}
+
+func (e *CapacityError) Is(target error) bool {
+	return target == ErrCapacityExceeded
+}
+
+// BatchSaveError is returned when a schedule of a batch cannot be saved, so
+// none of the batch is
+type BatchSaveError struct {
+	// Index is the position of the schedule in the batch
+	Index int
+	Err   error
+}
+
+func (e *BatchSaveError) Error() string {
+	return fmt.Sprintf("pick-up schedule %d of the batch: %v", e.Index, e.Err)
+}
+
+func (e *BatchSaveError) Unwrap() error {
+	return e.Err
+}
+
+// TransitionError is returned when the lifecycle of a pick-up schedule does
+// not allow it to move from its status to another one
+type TransitionError struct {
+	From ScheduleStatus
+	To   ScheduleStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%v: a %s pick-up schedule cannot become %s", ErrInvalidTransition, e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// BookRepository stores pick-up schedules. Schedules that no longer hold their
+// reservations, because they were returned, missed or cancelled, are kept for
+// history, GetBooksByGenre and SavePickUpSchedule leave them out.
 type BookRepository interface {
 	GetBooksByGenre(ctx context.Context, genre string) ([]Book, []PickUpSchedule, error)
-	SavePickUpSchedule(schedule PickUpSchedule) ([]PickUpSchedule, error)
+	// SavePickUpSchedule assigns the schedule a new ID and timestamps, stores it
+	// as requested and returns every schedule of its genre that holds its
+	// reservations, the new one last.
+	// The reservations are claimed in the same step, it fails with a
+	// *CapacityError and stores nothing when one of them is full.
+	SavePickUpSchedule(ctx context.Context, schedule PickUpSchedule, reservations ...Reservation) ([]PickUpSchedule, error)
+	// SavePickUpSchedules saves every schedule like SavePickUpSchedule, each
+	// claiming the reservations at its index after the ones before it, and
+	// returns them as stored in order. It stores all of them or none, failing
+	// with a *BatchSaveError for the first one that cannot be saved.
+	SavePickUpSchedules(ctx context.Context, schedules []PickUpSchedule, reservations [][]Reservation) ([]PickUpSchedule, error)
+	GetPickUpScheduleByID(ctx context.Context, id string) (PickUpSchedule, error)
+	// ListPickUpSchedules returns one page of the schedules of every genre
+	// matching the query. Query.Sort and Query.Limit must already be valid.
+	ListPickUpSchedules(ctx context.Context, query ScheduleQuery) (SchedulePage, error)
+	// CancelPickUpSchedule marks the schedule cancelled, stamping the
+	// cancellation with the current time. It fails with a *TransitionError when
+	// the schedule was already picked up or missed.
+	CancelPickUpSchedule(ctx context.Context, id string, cancellation Cancellation) (PickUpSchedule, error)
+	// TransitionPickUpSchedule moves the schedule to another status of its
+	// lifecycle other than cancelled and appends the change to its history. It
+	// fails with a *TransitionError when the lifecycle does not allow the move
+	// from the status the schedule has at that moment.
+	TransitionPickUpSchedule(ctx context.Context, id string, transition StatusTransition) (PickUpSchedule, error)
+	// ReschedulePickUpSchedule moves a pending schedule to another date or
+	// book and appends the change to its history. The reservations replace the
+	// ones the schedule held, like in SavePickUpSchedule.
+	ReschedulePickUpSchedule(ctx context.Context, id string, reschedule Reschedule, reservations ...Reservation) (PickUpSchedule, error)
+	// MarkOverdue flags every picked up schedule due at or before now as
+	// overdue, appends the change to its history and returns the flagged
+	// schedules ordered by due date
+	MarkOverdue(ctx context.Context, now time.Time) ([]PickUpSchedule, error)
+	// GetPickUpScheduleHistory returns every change of the schedule, oldest first
+	GetPickUpScheduleHistory(ctx context.Context, id string) ([]ScheduleChange, error)
+	// CountReservations returns how many schedules hold each of the keys
+	CountReservations(ctx context.Context, keys []string) (map[string]int, error)
 }
 
-type InMemoryRepository struct {
-	ctx                context.Context
-	booksWithSchedules map[string]struct {
-		Books           []Book
-		PickUpSchedules []PickUpSchedule
+// scheduleRecord is everything stored about one schedule
+type scheduleRecord struct {
+	Schedule PickUpSchedule   `json:"schedule"`
+	History  []ScheduleChange `json:"history,omitempty"`
+	// Reservations are the keys of the reservations the schedule claimed,
+	// kept after it stops holding them
+	Reservations []string `json:"reservations,omitempty"`
+}
+
+// genreShard holds the books and pick-up schedules of a single genre. Every
+// shard carries its own lock so requests for unrelated genres never contend.
+type genreShard struct {
+	mu              sync.RWMutex
+	Books           []Book
+	PickUpSchedules []PickUpSchedule
+
+	// index maps schedule IDs to their position in PickUpSchedules
+	index map[string]int
+	// history holds the changes of every rescheduled schedule by ID
+	history map[string][]ScheduleChange
+	// reservations holds the reservation keys of schedules by ID
+	reservations map[string][]string
+}
+
+// put adds the schedule or replaces the stored one with the same ID
+func (d *genreShard) put(schedule PickUpSchedule) {
+	if d.index == nil {
+		d.index = make(map[string]int)
+	}
+	if position, exists := d.index[schedule.ID]; exists {
+		d.PickUpSchedules[position] = schedule
+		return
 	}
+	d.index[schedule.ID] = len(d.PickUpSchedules)
+	d.PickUpSchedules = append(d.PickUpSchedules, schedule)
+}
+
+// setHistory replaces the change history of a schedule
+func (d *genreShard) setHistory(id string, history []ScheduleChange) {
+	if len(history) == 0 {
+		delete(d.history, id)
+		return
+	}
+	if d.history == nil {
+		d.history = make(map[string][]ScheduleChange)
+	}
+	d.history[id] = history
+}
+
+// setReservations replaces the reservation keys of a schedule
+func (d *genreShard) setReservations(id string, keys []string) {
+	if len(keys) == 0 {
+		delete(d.reservations, id)
+		return
+	}
+	if d.reservations == nil {
+		d.reservations = make(map[string][]string)
+	}
+	d.reservations[id] = keys
+}
+
+// record returns everything stored about the schedule at position
+func (d *genreShard) record(position int) scheduleRecord {
+	schedule := d.PickUpSchedules[position]
+	return scheduleRecord{
+		Schedule:     schedule,
+		History:      d.history[schedule.ID],
+		Reservations: d.reservations[schedule.ID],
+	}
+}
+
+// active returns a copy of the schedules that hold their reservations
+func (d *genreShard) active() []PickUpSchedule {
+	var pickUpSchedules []PickUpSchedule
+	for _, schedule := range d.PickUpSchedules {
+		if holdsReservations(schedule.Status) {
+			pickUpSchedules = append(pickUpSchedules, schedule)
+		}
+	}
+	return pickUpSchedules
+}
+
+type InMemoryRepository struct {
+	ctx context.Context
+
+	// mu only guards the map itself; the contents of a genre are guarded by
+	// the lock of its shard.
+	mu                 sync.RWMutex
+	booksWithSchedules map[string]*genreShard
+
+	// genres maps schedule IDs to the genre they are stored under
+	genres sync.Map
+
+	// reservedMu guards reserved, the number of schedules holding each
+	// reservation key. It is only taken while holding a shard lock or none.
+	reservedMu sync.Mutex
+	reserved   map[string]int
+
+	// persist, when set, is called with every new or changed schedule record,
+	// or all records of a batch at once, while the shards are still locked.
+	// The change is only stored if it returns no error.
+	persist func(records ...scheduleRecord) error
 }
 
 func NewInMemoryRepository(ctx context.Context) *InMemoryRepository {
 	return &InMemoryRepository{
-		ctx: ctx,
-		booksWithSchedules: make(map[string]struct {
-			Books           []Book
-			PickUpSchedules []PickUpSchedule
-		}),
+		ctx:                ctx,
+		booksWithSchedules: make(map[string]*genreShard),
+		reserved:           make(map[string]int),
 	}
 }
 
+// shard returns the shard of the given genre. When create is true a missing
+// shard is added, otherwise nil is returned for unknown genres.
+func (r *InMemoryRepository) shard(genre string, create bool) *genreShard {
+	r.mu.RLock()
+	data, exists := r.booksWithSchedules[genre]
+	r.mu.RUnlock()
+	if exists || !create {
+		return data
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Another goroutine may have created the shard while we were waiting
+	if data, exists = r.booksWithSchedules[genre]; !exists {
+		data = &genreShard{}
+		r.booksWithSchedules[genre] = data
+	}
+
+	return data
+}
+
 func (r *InMemoryRepository) GetBooksByGenre(ctx context.Context, genre string) ([]Book, []PickUpSchedule, error) {
 	var newPickUpSchedule []PickUpSchedule
 
 	// Retrieve books and pick-up schedules from the cache
-	data, exists := r.booksWithSchedules[genre]
-	if exists {
-		newPickUpSchedule = append(newPickUpSchedule, data.PickUpSchedules...)
+	if data := r.shard(genre, false); data != nil {
+		data.mu.RLock()
+		newPickUpSchedule = data.active()
+		data.mu.RUnlock()
 	}
 
-	// Fetch data from API and update cache atomically
-	books, err := r.fetchBooksByGenreExternalAPI(ctx, genre)
+	// Fetch data from API outside any lock, so a slow API never blocks writers
+	books, err := fetchBooksByGenreExternalAPI(ctx, genre)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -49,27 +287,743 @@ func (r *InMemoryRepository) GetBooksByGenre(ctx context.Context, genre string)
 	return books, newPickUpSchedule, nil
 }
 
-func (r *InMemoryRepository) SavePickUpSchedule(schedule PickUpSchedule) ([]PickUpSchedule, error) {
+func (r *InMemoryRepository) SavePickUpSchedule(_ context.Context, schedule PickUpSchedule, reservations ...Reservation) ([]PickUpSchedule, error) {
 	// Assuming you have the genre information in the schedule
-	genre := schedule.Genre
+	data := r.shard(schedule.Genre, true)
+
+	data.mu.Lock()
+	defer data.mu.Unlock()
+
+	now := time.Now().UTC()
+	schedule.ID = newScheduleID()
+	schedule.Status = ScheduleStatusRequested
+	schedule.Cancellation = nil
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+
+	record := scheduleRecord{Schedule: schedule, Reservations: reservationKeys(reservations)}
+	held := heldBy(reservations, schedule.Status)
+	if err := r.claim(nil, held); err != nil {
+		return nil, err
+	}
+
+	if r.persist != nil {
+		if err := r.persist(record); err != nil {
+			_ = r.claim(reservationKeys(held), nil)
+			return nil, err
+		}
+	}
 
 	// Update the cache with the new pick-up schedule
-	if data, exists := r.booksWithSchedules[genre]; exists {
-		data.PickUpSchedules = append(data.PickUpSchedules, schedule)
-		r.booksWithSchedules[genre] = data
-	} else {
-		r.booksWithSchedules[genre] = struct {
-			Books           []Book
-			PickUpSchedules []PickUpSchedule
-		}{
-			PickUpSchedules: []PickUpSchedule{schedule},
+	data.put(schedule)
+	data.setReservations(schedule.ID, record.Reservations)
+	r.genres.Store(schedule.ID, schedule.Genre)
+
+	// Return a copy, the shard slice keeps changing after the lock is released
+	return data.active(), nil
+}
+
+func (r *InMemoryRepository) SavePickUpSchedules(_ context.Context, schedules []PickUpSchedule, reservations [][]Reservation) ([]PickUpSchedule, error) {
+	// Shards are locked in the order of their genres, like freeze does
+	shards := make(map[string]*genreShard)
+	var genres []string
+	for _, schedule := range schedules {
+		if _, exists := shards[schedule.Genre]; !exists {
+			shards[schedule.Genre] = r.shard(schedule.Genre, true)
+			genres = append(genres, schedule.Genre)
+		}
+	}
+	sort.Strings(genres)
+	for _, genre := range genres {
+		shards[genre].mu.Lock()
+		defer shards[genre].mu.Unlock()
+	}
+
+	now := time.Now().UTC()
+	records := make([]scheduleRecord, 0, len(schedules))
+	var claimed []string
+	release := func() {
+		_ = r.claim(claimed, nil)
+	}
+	for i, schedule := range schedules {
+		schedule.ID = newScheduleID()
+		schedule.Status = ScheduleStatusRequested
+		schedule.Cancellation = nil
+		schedule.CreatedAt = now
+		schedule.UpdatedAt = now
+
+		record := scheduleRecord{Schedule: schedule, Reservations: reservationKeys(reservations[i])}
+		held := heldBy(reservations[i], schedule.Status)
+		if err := r.claim(nil, held); err != nil {
+			release()
+			return nil, &BatchSaveError{Index: i, Err: err}
+		}
+		claimed = append(claimed, reservationKeys(held)...)
+		records = append(records, record)
+	}
+
+	if r.persist != nil {
+		if err := r.persist(records...); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	saved := make([]PickUpSchedule, 0, len(records))
+	for _, record := range records {
+		data := shards[record.Schedule.Genre]
+		data.put(record.Schedule)
+		data.setReservations(record.Schedule.ID, record.Reservations)
+		r.genres.Store(record.Schedule.ID, record.Schedule.Genre)
+		saved = append(saved, record.Schedule)
+	}
+
+	return saved, nil
+}
+
+func (r *InMemoryRepository) CancelPickUpSchedule(_ context.Context, id string, cancellation Cancellation) (PickUpSchedule, error) {
+	return r.update(id, nil, func(schedule *PickUpSchedule) (*ScheduleChange, error) {
+		return nil, applyCancellation(schedule, cancellation, time.Now().UTC())
+	})
+}
+
+func (r *InMemoryRepository) TransitionPickUpSchedule(_ context.Context, id string, transition StatusTransition) (PickUpSchedule, error) {
+	return r.update(id, nil, func(schedule *PickUpSchedule) (*ScheduleChange, error) {
+		change, err := applyTransition(schedule, transition, time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		return &change, nil
+	})
+}
+
+func (r *InMemoryRepository) ReschedulePickUpSchedule(_ context.Context, id string, reschedule Reschedule, reservations ...Reservation) (PickUpSchedule, error) {
+	return r.update(id, &reservations, func(schedule *PickUpSchedule) (*ScheduleChange, error) {
+		if err := checkReschedule(schedule.Status); err != nil {
+			return nil, err
+		}
+
+		change := applyReschedule(schedule, reschedule, time.Now().UTC())
+		return &change, nil
+	})
+}
+
+func (r *InMemoryRepository) MarkOverdue(_ context.Context, now time.Time) ([]PickUpSchedule, error) {
+	r.mu.RLock()
+	shards := make([]*genreShard, 0, len(r.booksWithSchedules))
+	for _, data := range r.booksWithSchedules {
+		shards = append(shards, data)
+	}
+	r.mu.RUnlock()
+
+	var ids []string
+	for _, data := range shards {
+		data.mu.RLock()
+		for _, schedule := range data.PickUpSchedules {
+			if isOverdue(schedule, now) {
+				ids = append(ids, schedule.ID)
+			}
+		}
+		data.mu.RUnlock()
+	}
+
+	return markOverdue(ids, now, func(id string, fn func(schedule *PickUpSchedule) (*ScheduleChange, error)) (PickUpSchedule, error) {
+		return r.update(id, nil, fn)
+	})
+}
+
+func (r *InMemoryRepository) GetPickUpScheduleHistory(_ context.Context, id string) ([]ScheduleChange, error) {
+	genre, exists := r.genres.Load(id)
+	if !exists {
+		return nil, ErrScheduleNotFound
+	}
+
+	data := r.shard(genre.(string), false)
+	if data == nil {
+		return nil, ErrScheduleNotFound
+	}
+
+	data.mu.RLock()
+	defer data.mu.RUnlock()
+
+	if _, exists := data.index[id]; !exists {
+		return nil, ErrScheduleNotFound
+	}
+
+	return append([]ScheduleChange(nil), data.history[id]...), nil
+}
+
+func (r *InMemoryRepository) CountReservations(_ context.Context, keys []string) (map[string]int, error) {
+	r.reservedMu.Lock()
+	defer r.reservedMu.Unlock()
+
+	counts := make(map[string]int, len(keys))
+	for _, key := range keys {
+		counts[key] = r.reserved[key]
+	}
+
+	return counts, nil
+}
+
+// update applies fn to a copy of the stored schedule while its shard is
+// locked, and stores the result unless fn, claiming the reservations or
+// persist fails. A change returned by fn is appended to the history of the
+// schedule. Non-nil reservations replace the ones the schedule holds.
+func (r *InMemoryRepository) update(id string, reservations *[]Reservation, fn func(schedule *PickUpSchedule) (*ScheduleChange, error)) (PickUpSchedule, error) {
+	genre, exists := r.genres.Load(id)
+	if !exists {
+		return PickUpSchedule{}, ErrScheduleNotFound
+	}
+
+	data := r.shard(genre.(string), false)
+	if data == nil {
+		return PickUpSchedule{}, ErrScheduleNotFound
+	}
+
+	data.mu.Lock()
+	defer data.mu.Unlock()
+
+	position, exists := data.index[id]
+	if !exists {
+		return PickUpSchedule{}, ErrScheduleNotFound
+	}
+
+	record := data.record(position)
+	held := heldReservations(record)
+
+	change, err := fn(&record.Schedule)
+	if err != nil {
+		return PickUpSchedule{}, err
+	}
+
+	if change != nil {
+		// Copy so a failed persist leaves the stored history untouched
+		record.History = append(append([]ScheduleChange(nil), record.History...), *change)
+	}
+
+	wanted := unlimited(record.Reservations)
+	if reservations != nil {
+		wanted = *reservations
+		record.Reservations = reservationKeys(wanted)
+	}
+	wanted = heldBy(wanted, record.Schedule.Status)
+	if err := r.claim(held, wanted); err != nil {
+		return PickUpSchedule{}, err
+	}
+
+	if r.persist != nil {
+		if err := r.persist(record); err != nil {
+			_ = r.claim(reservationKeys(wanted), unlimited(held))
+			return PickUpSchedule{}, err
+		}
+	}
+
+	schedule := record.Schedule
+	data.put(schedule)
+	data.setHistory(id, record.History)
+	data.setReservations(id, record.Reservations)
+
+	return schedule, nil
+}
+
+func (r *InMemoryRepository) ListPickUpSchedules(_ context.Context, query ScheduleQuery) (SchedulePage, error) {
+	field, descending := parseScheduleSort(query.Sort)
+	after, err := decodeScheduleCursor(query.Cursor, query.Sort)
+	if err != nil {
+		return SchedulePage{}, err
+	}
+
+	r.mu.RLock()
+	shards := make([]*genreShard, 0, len(r.booksWithSchedules))
+	for genre, data := range r.booksWithSchedules {
+		if query.Genre == "" || query.Genre == genre {
+			shards = append(shards, data)
+		}
+	}
+	r.mu.RUnlock()
+
+	title := strings.ToLower(query.Title)
+
+	var matches []PickUpSchedule
+	for _, data := range shards {
+		data.mu.RLock()
+		for _, schedule := range data.PickUpSchedules {
+			switch {
+			case query.BranchID != "" && schedule.BranchID != query.BranchID:
+			case query.BorrowerID != "" && schedule.BorrowerID != query.BorrowerID:
+			case query.Status != "" && schedule.Status != query.Status:
+			case title != "" && !strings.Contains(strings.ToLower(schedule.BookInfo.Title), title):
+			case query.PickUpFrom != "" && schedule.LocalDay() < query.PickUpFrom:
+			case query.PickUpTo != "" && schedule.LocalDay() > query.PickUpTo:
+			case after != nil && !after.precedes(schedule, field, descending):
+			default:
+				matches = append(matches, schedule)
+			}
+		}
+		data.mu.RUnlock()
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return scheduleLess(matches[i], matches[j], field, descending)
+	})
+
+	return newSchedulePage(matches, query), nil
+}
+
+// restore puts an already persisted record back without calling persist or
+// checking reservation limits, replacing an earlier version of it.
+func (r *InMemoryRepository) restore(record scheduleRecord) {
+	schedule := record.Schedule
+
+	// Schedules persisted before statuses or the lifecycle existed are requested
+	if schedule.Status == "" || schedule.Status == scheduleStatusActive {
+		schedule.Status = ScheduleStatusRequested
+		record.Schedule = schedule
+	}
+
+	data := r.shard(schedule.Genre, true)
+
+	data.mu.Lock()
+	defer data.mu.Unlock()
+
+	var held []string
+	if position, exists := data.index[schedule.ID]; exists {
+		held = heldReservations(data.record(position))
+	}
+	_ = r.claim(held, unlimited(heldReservations(record)))
+
+	data.put(schedule)
+	data.setHistory(schedule.ID, record.History)
+	data.setReservations(schedule.ID, record.Reservations)
+	r.genres.Store(schedule.ID, schedule.Genre)
+}
+
+// claim moves the reservation counts of one schedule from the keys it held to
+// the reservations it holds now. Keys it did not hold yet are checked against
+// their limit first, and nothing changes when one of them is full.
+func (r *InMemoryRepository) claim(held []string, wanted []Reservation) error {
+	r.reservedMu.Lock()
+	defer r.reservedMu.Unlock()
+
+	isHeld := make(map[string]bool, len(held))
+	for _, key := range held {
+		isHeld[key] = true
+	}
+	for _, reservation := range wanted {
+		if isHeld[reservation.Key] || reservation.Limit <= 0 {
+			continue
 		}
+		holders := r.reserved[reservation.Key]
+		for _, key := range reservation.Shared {
+			holders += r.reserved[key]
+		}
+		for _, key := range reservation.Excluded {
+			holders -= r.reserved[key]
+		}
+		if holders >= reservation.Limit {
+			return &CapacityError{Key: reservation.Key, Limit: reservation.Limit}
+		}
+	}
+
+	for _, key := range held {
+		if r.reserved[key]--; r.reserved[key] <= 0 {
+			delete(r.reserved, key)
+		}
+	}
+	for _, reservation := range wanted {
+		r.reserved[reservation.Key]++
 	}
 
-	return r.booksWithSchedules[genre].PickUpSchedules, nil
+	return nil
+}
+
+func (r *InMemoryRepository) GetPickUpScheduleByID(_ context.Context, id string) (PickUpSchedule, error) {
+	genre, exists := r.genres.Load(id)
+	if !exists {
+		return PickUpSchedule{}, ErrScheduleNotFound
+	}
+
+	data := r.shard(genre.(string), false)
+	if data == nil {
+		return PickUpSchedule{}, ErrScheduleNotFound
+	}
+
+	data.mu.RLock()
+	defer data.mu.RUnlock()
+
+	position, exists := data.index[id]
+	if !exists {
+		return PickUpSchedule{}, ErrScheduleNotFound
+	}
+
+	return data.PickUpSchedules[position], nil
+}
+
+// freeze read-locks the whole repository and returns a copy of every stored
+// record. Writers stay blocked until unfreeze is called. Shards are locked in
+// genre order so concurrent freezes cannot deadlock each other.
+func (r *InMemoryRepository) freeze() (records []scheduleRecord, unfreeze func()) {
+	r.mu.RLock()
+
+	genres := make([]string, 0, len(r.booksWithSchedules))
+	for genre := range r.booksWithSchedules {
+		genres = append(genres, genre)
+	}
+	sort.Strings(genres)
+
+	for _, genre := range genres {
+		data := r.booksWithSchedules[genre]
+		data.mu.RLock()
+		for position := range data.PickUpSchedules {
+			records = append(records, data.record(position))
+		}
+	}
+
+	return records, func() {
+		for _, genre := range genres {
+			r.booksWithSchedules[genre].mu.RUnlock()
+		}
+		r.mu.RUnlock()
+	}
+}
+
+// releasingStatuses are the statuses of schedules that gave their
+// reservations back
+var releasingStatuses = []ScheduleStatus{ScheduleStatusReturned, ScheduleStatusNoShow, ScheduleStatusCancelled}
+
+// holdsReservations reports whether a schedule with the status keeps its
+// reservations claimed
+func holdsReservations(status ScheduleStatus) bool {
+	for _, releasing := range releasingStatuses {
+		if status == releasing {
+			return false
+		}
+	}
+	return true
+}
+
+// onLoanStatuses are the statuses of schedules whose book is on loan
+var onLoanStatuses = []ScheduleStatus{ScheduleStatusPickedUp, ScheduleStatusOverdue}
+
+// isOnLoan reports whether the book of a schedule with the status is on loan
+func isOnLoan(status ScheduleStatus) bool {
+	for _, onLoan := range onLoanStatuses {
+		if status == onLoan {
+			return true
+		}
+	}
+	return false
+}
+
+// holdsReservation reports whether a schedule with the status holds the
+// reservation with the key. A copy is taken on its pick-up day by copyKey
+// until it is picked up, and then on every day by loanedCopyKey until it is
+// returned. A pick-up counts towards its week by weekKey until it is
+// cancelled, returned and missed ones included.
+func holdsReservation(key string, status ScheduleStatus) bool {
+	switch {
+	case strings.HasPrefix(key, weekKeyPrefix):
+		return status != ScheduleStatusCancelled
+	case !holdsReservations(status):
+		return false
+	case strings.HasPrefix(key, loanedCopyKeyPrefix):
+		return isOnLoan(status)
+	case strings.HasPrefix(key, copyKeyPrefix):
+		return !isOnLoan(status)
+	default:
+		return true
+	}
+}
+
+// heldReservations returns the reservation keys the record currently holds
+func heldReservations(record scheduleRecord) []string {
+	return reservationKeys(heldBy(unlimited(record.Reservations), record.Schedule.Status))
+}
+
+// heldBy returns the reservations a schedule with the status holds
+func heldBy(reservations []Reservation, status ScheduleStatus) []Reservation {
+	var held []Reservation
+	for _, reservation := range reservations {
+		if holdsReservation(reservation.Key, status) {
+			held = append(held, reservation)
+		}
+	}
+	return held
+}
+
+func reservationKeys(reservations []Reservation) []string {
+	var keys []string
+	for _, reservation := range reservations {
+		keys = append(keys, reservation.Key)
+	}
+	return keys
+}
+
+// unlimited turns keys back into reservations without a limit
+func unlimited(keys []string) []Reservation {
+	var reservations []Reservation
+	for _, key := range keys {
+		reservations = append(reservations, Reservation{Key: key})
+	}
+	return reservations
+}
+
+// checkTransition returns why a schedule with the status from cannot move to
+// to: ErrScheduleCancelled for a cancelled schedule and a *TransitionError
+// for any other move its lifecycle does not allow
+func checkTransition(from, to ScheduleStatus) error {
+	switch {
+	case from == ScheduleStatusCancelled:
+		return ErrScheduleCancelled
+	case !from.CanTransitionTo(to):
+		return &TransitionError{From: from, To: to}
+	default:
+		return nil
+	}
+}
+
+// checkReschedule returns why a schedule with the status cannot be moved to
+// another date or book
+func checkReschedule(status ScheduleStatus) error {
+	switch {
+	case status == ScheduleStatusCancelled:
+		return ErrScheduleCancelled
+	case !status.isPending():
+		return ErrScheduleNotPending
+	default:
+		return nil
+	}
+}
+
+// applyCancellation cancels the schedule, stamping the cancellation with now
+func applyCancellation(schedule *PickUpSchedule, cancellation Cancellation, now time.Time) error {
+	if err := checkTransition(schedule.Status, ScheduleStatusCancelled); err != nil {
+		return err
+	}
+
+	cancellation.CancelledAt = now
+	schedule.Status = ScheduleStatusCancelled
+	schedule.Cancellation = &cancellation
+	schedule.UpdatedAt = now
+
+	return nil
+}
+
+// applyTransition moves the schedule to the status of the transition and
+// returns the history entry describing the move. Cancellations go through
+// applyCancellation instead.
+func applyTransition(schedule *PickUpSchedule, transition StatusTransition, now time.Time) (ScheduleChange, error) {
+	if transition.Status == ScheduleStatusCancelled {
+		return ScheduleChange{}, fmt.Errorf("cancellations are not status transitions")
+	}
+	if err := checkTransition(schedule.Status, transition.Status); err != nil {
+		return ScheduleChange{}, err
+	}
+
+	change := ScheduleChange{
+		ChangedBy:      transition.ChangedBy,
+		ChangedAt:      now,
+		Reason:         transition.Reason,
+		FromPickUpDate: schedule.PickUpDate,
+		ToPickUpDate:   schedule.PickUpDate,
+		FromStatus:     schedule.Status,
+		ToStatus:       transition.Status,
+	}
+	schedule.Status = transition.Status
+	schedule.UpdatedAt = now
+	if transition.DueAt != nil {
+		due := *transition.DueAt
+		schedule.DueAt = &due
+	}
+
+	return change, nil
+}
+
+// errNotOverdue is returned by applyOverdue for a schedule that is not a loan
+// past its due date (anymore)
+var errNotOverdue = errors.New("pick-up schedule is not overdue")
+
+// isOverdue reports whether the schedule is a picked up book due at or
+// before now
+func isOverdue(schedule PickUpSchedule, now time.Time) bool {
+	return schedule.Status == ScheduleStatusPickedUp && schedule.DueAt != nil && !schedule.DueAt.After(now)
+}
+
+// applyOverdue flags the schedule overdue when it is due at or before now and
+// returns the history entry describing the change
+func applyOverdue(schedule *PickUpSchedule, now time.Time) (*ScheduleChange, error) {
+	if !isOverdue(*schedule, now) {
+		return nil, errNotOverdue
+	}
+
+	change, err := applyTransition(schedule, StatusTransition{
+		Status:    ScheduleStatusOverdue,
+		ChangedBy: overdueCheckName,
+		Reason:    "not returned by " + schedule.DueAt.Format(time.RFC3339),
+	}, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// markOverdue flags the schedules with the ids through update, skipping the
+// ones that were returned or given another due date in the meantime, and
+// returns the flagged schedules ordered by due date
+func markOverdue(ids []string, now time.Time, update func(id string, fn func(schedule *PickUpSchedule) (*ScheduleChange, error)) (PickUpSchedule, error)) ([]PickUpSchedule, error) {
+	flagged := []PickUpSchedule{}
+	for _, id := range ids {
+		schedule, err := update(id, func(schedule *PickUpSchedule) (*ScheduleChange, error) {
+			return applyOverdue(schedule, now)
+		})
+		switch {
+		case errors.Is(err, errNotOverdue):
+		case err != nil:
+			return flagged, err
+		default:
+			flagged = append(flagged, schedule)
+		}
+	}
+
+	sort.Slice(flagged, func(i, j int) bool {
+		return scheduleLess(flagged[i], flagged[j], "due_at", false)
+	})
+
+	return flagged, nil
+}
+
+// applyReschedule moves the schedule to the requested date and book and
+// returns the history entry describing the move
+func applyReschedule(schedule *PickUpSchedule, reschedule Reschedule, now time.Time) ScheduleChange {
+	change := ScheduleChange{
+		ChangedBy:      reschedule.ChangedBy,
+		ChangedAt:      now,
+		Reason:         reschedule.Reason,
+		FromPickUpDate: schedule.PickUpDate,
+		ToPickUpDate:   schedule.PickUpDate,
+	}
+
+	if !reschedule.PickUpDate.IsZero() {
+		schedule.PickUpDate = reschedule.PickUpDate
+		change.ToPickUpDate = reschedule.PickUpDate
+	}
+	if reschedule.BookInfo != nil {
+		from, to := schedule.BookInfo, *reschedule.BookInfo
+		change.FromBookInfo = &from
+		change.ToBookInfo = &to
+		schedule.BookInfo = to
+	}
+	schedule.UpdatedAt = now
+
+	return change
+}
+
+// parseScheduleSort splits a ScheduleQuery.Sort into field and direction,
+// defaulting to oldest first
+func parseScheduleSort(value string) (field string, descending bool) {
+	if value == "" {
+		return "created_at", false
+	}
+	if strings.HasPrefix(value, "-") {
+		return value[1:], true
+	}
+	return value, false
+}
+
+// scheduleSortValue is the value a schedule is sorted by, compared as a string
+func scheduleSortValue(schedule PickUpSchedule, field string) string {
+	switch field {
+	case "pick_up_date":
+		return schedule.PickUpDate.String()
+	case "title":
+		return schedule.BookInfo.Title
+	case "due_at":
+		if schedule.DueAt == nil {
+			return ""
+		}
+		return formatTime(*schedule.DueAt)
+	default:
+		return formatTime(schedule.CreatedAt)
+	}
+}
+
+// scheduleLess orders schedules by the sort field, ties broken by ID
+func scheduleLess(a, b PickUpSchedule, field string, descending bool) bool {
+	return sortsBefore(scheduleSortValue(a, field), a.ID, scheduleSortValue(b, field), b.ID, descending)
+}
+
+func sortsBefore(valueA, idA, valueB, idB string, descending bool) bool {
+	if valueA == valueB {
+		return idA < idB
+	}
+	return (valueA < valueB) != descending
+}
+
+// scheduleCursor points behind the last schedule of a page. It is bound to
+// the sort it was created with.
+type scheduleCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// precedes reports whether the cursor sorts before the schedule
+func (c *scheduleCursor) precedes(schedule PickUpSchedule, field string, descending bool) bool {
+	return sortsBefore(c.Value, c.ID, scheduleSortValue(schedule, field), schedule.ID, descending)
+}
+
+func encodeScheduleCursor(schedule PickUpSchedule, sort string) string {
+	field, _ := parseScheduleSort(sort)
+	data, _ := json.Marshal(scheduleCursor{Sort: sort, Value: scheduleSortValue(schedule, field), ID: schedule.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeScheduleCursor returns nil for the first page
+func decodeScheduleCursor(cursor, sort string) (*scheduleCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidScheduleQuery)
+	}
+
+	var c scheduleCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidScheduleQuery)
+	}
+	if c.Sort != sort {
+		return nil, fmt.Errorf("%w: cursor belongs to sort %q", ErrInvalidScheduleQuery, c.Sort)
+	}
+
+	return &c, nil
+}
+
+// newSchedulePage cuts a sorted list of schedules behind the cursor down to
+// the limit, with a cursor for the next page when more schedules follow
+func newSchedulePage(schedules []PickUpSchedule, query ScheduleQuery) SchedulePage {
+	if len(schedules) <= query.Limit {
+		return SchedulePage{Schedules: schedules}
+	}
+
+	schedules = schedules[:query.Limit]
+	return SchedulePage{
+		Schedules:  schedules,
+		NextCursor: encodeScheduleCursor(schedules[len(schedules)-1], query.Sort),
+	}
+}
+
+// newScheduleID returns a random 128-bit identifier in hex
+func newScheduleID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate schedule id: %v", err))
+	}
+	return hex.EncodeToString(id[:])
 }
 
-func (r *InMemoryRepository) fetchBooksByGenreExternalAPI(ctx context.Context, genre string) ([]Book, error) {
+func fetchBooksByGenreExternalAPI(ctx context.Context, genre string) ([]Book, error) {
 	// Build the URL with the specified genre
 	url := fmt.Sprintf("https://openlibrary.org/subjects/%s.json", genre)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
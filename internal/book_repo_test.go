@@ -2,9 +2,13 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/jarcoal/httpmock"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestInMemoryRepository_GetBooksByGenre(t *testing.T) {
@@ -54,15 +58,12 @@ func TestInMemoryRepository_GetBooksByGenre(t *testing.T) {
 
 	t.Run("PositiveCase_CacheExist", func(t *testing.T) {
 		// Add data to the cache
-		repo.booksWithSchedules[genre] = struct {
-			Books           []Book
-			PickUpSchedules []PickUpSchedule
-		}{
+		repo.booksWithSchedules[genre] = &genreShard{
 			Books: []Book{},
 			PickUpSchedules: []PickUpSchedule{{BookInfo: Book{
-				"Book Cache",
-				[]string{"Author1", "Author2"},
-				1,
+				Title:         "Book Cache",
+				Author:        []string{"Author1", "Author2"},
+				EditionNumber: 1,
 			}, Genre: genre}},
 		}
 
@@ -84,10 +85,7 @@ func TestInMemoryRepository_GetBooksByGenre(t *testing.T) {
 
 	t.Run("PositiveCase_CacheNotExist", func(t *testing.T) {
 		// Clear the cache to simulate cache not containing any data
-		repo.booksWithSchedules = make(map[string]struct {
-			Books           []Book
-			PickUpSchedules []PickUpSchedule
-		})
+		repo.booksWithSchedules = make(map[string]*genreShard)
 
 		// Perform the test
 		books, pickUpSchedules, err := repo.GetBooksByGenre(ctx, genre)
@@ -123,7 +121,7 @@ func TestInMemoryRepository_SavePickUpSchedule(t *testing.T) {
 		}
 
 		// Perform the test
-		pickUpSchedules, err := repo.SavePickUpSchedule(schedule)
+		pickUpSchedules, err := repo.SavePickUpSchedule(ctx, schedule)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -135,3 +133,1100 @@ func TestInMemoryRepository_SavePickUpSchedule(t *testing.T) {
 	})
 
 }
+
+func TestInMemoryRepository_ConcurrentAccess(t *testing.T) {
+	// Create a new httpmock instance
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	genres := []string{"fiction", "love", "horror", "science"}
+	mockResponseBody := `{"works": [{"title": "MockBook", "authors": [{"key": "authors/001AAS", "name": "authors"}], "edition_count": 1}]}`
+	for _, genre := range genres {
+		mockURL := fmt.Sprintf("https://openlibrary.org/subjects/%s.json", genre)
+		httpmock.RegisterResponder("GET", mockURL, httpmock.NewStringResponder(200, mockResponseBody))
+	}
+
+	// Initialize the repository
+	ctx := context.Background()
+	repo := NewInMemoryRepository(ctx)
+
+	const workers = 16
+	const perWorker = 50
+
+	t.Run("ParallelSaveAndGet", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, workers*perWorker*2)
+
+		for w := 0; w < workers; w++ {
+			wg.Add(2)
+
+			// Writers hammer every genre
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					schedule := PickUpSchedule{
+						Genre: genres[(w+i)%len(genres)],
+						BookInfo: Book{
+							Title:         fmt.Sprintf("Book %d-%d", w, i),
+							Author:        []string{"Author"},
+							EditionNumber: 1,
+						},
+					}
+					pickUpSchedules, err := repo.SavePickUpSchedule(ctx, schedule)
+					if err != nil {
+						errs <- err
+						continue
+					}
+					if len(pickUpSchedules) == 0 {
+						errs <- fmt.Errorf("expected saved schedule in result")
+					}
+				}
+			}(w)
+
+			// Readers run next to them
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					if _, _, err := repo.GetBooksByGenre(ctx, genres[(w+i)%len(genres)]); err != nil {
+						errs <- err
+					}
+				}
+			}(w)
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		// Every write must have landed exactly once
+		total := 0
+		for _, genre := range genres {
+			_, pickUpSchedules, err := repo.GetBooksByGenre(ctx, genre)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			total += len(pickUpSchedules)
+		}
+		if total != workers*perWorker {
+			t.Errorf("Expected %d pick-up schedules, got %d", workers*perWorker, total)
+		}
+	})
+
+	t.Run("ParallelSaveSameGenre", func(t *testing.T) {
+		repo := NewInMemoryRepository(ctx)
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					pickUpSchedules, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "love"})
+					if err != nil {
+						t.Errorf("Unexpected error: %v", err)
+						return
+					}
+
+					// Mutating the returned slice must not affect the repository
+					pickUpSchedules[0].Genre = "mutated"
+				}
+			}()
+		}
+		wg.Wait()
+
+		_, pickUpSchedules, err := repo.GetBooksByGenre(ctx, "love")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(pickUpSchedules) != workers*perWorker {
+			t.Errorf("Expected %d pick-up schedules, got %d", workers*perWorker, len(pickUpSchedules))
+		}
+		for _, schedule := range pickUpSchedules {
+			if schedule.Genre != "love" {
+				t.Fatalf("Expected stored genre love, got %s", schedule.Genre)
+			}
+		}
+	})
+}
+
+func TestInMemoryRepository_Suite(t *testing.T) {
+	testBookRepository(t, func(t *testing.T) BookRepository {
+		return NewInMemoryRepository(context.Background())
+	})
+}
+
+// testBookRepository runs the behaviour every BookRepository implementation
+// shares. newRepo must return an empty repository.
+func testBookRepository[R BookRepository](t *testing.T, newRepo func(t *testing.T) R) {
+	// Create a new httpmock instance
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	mockResponseBody := `{"works": [{"title": "MockBook", "authors": [{"key": "authors/001AAS", "name": "authors"}], "edition_count": 1}]}`
+	for _, genre := range []string{"fiction", "love"} {
+		mockURL := fmt.Sprintf("https://openlibrary.org/subjects/%s.json", genre)
+		httpmock.RegisterResponder("GET", mockURL, httpmock.NewStringResponder(200, mockResponseBody))
+	}
+	httpmock.RegisterResponder("GET", "https://openlibrary.org/subjects/broken.json", httpmock.NewStringResponder(500, "Internal Server Error"))
+
+	ctx := context.Background()
+	schedule := PickUpSchedule{
+		Genre: "fiction",
+		BookInfo: Book{
+			Title:         "TestBook",
+			Author:        []string{"Author1", "Author2"},
+			EditionNumber: 2,
+		},
+		PickUpDate: mustParsePickUpDate("2023-12-01"),
+	}
+
+	t.Run("SavePickUpSchedule_ReturnsGenreSchedules", func(t *testing.T) {
+		repo := newRepo(t)
+
+		pickUpSchedules, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(pickUpSchedules) != 1 || !sameSchedule(pickUpSchedules[0], schedule) {
+			t.Fatalf("Expected the saved schedule, got %v", pickUpSchedules)
+		}
+
+		second := schedule
+		second.BookInfo = Book{Title: "SecondBook", Author: []string{"Author3"}, EditionNumber: 1}
+		pickUpSchedules, err = repo.SavePickUpSchedule(ctx, second)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(pickUpSchedules) != 2 || !sameSchedule(pickUpSchedules[1], second) {
+			t.Errorf("Expected both schedules in save order, got %v", pickUpSchedules)
+		}
+	})
+
+	t.Run("SavePickUpSchedules_AllOrNothing", func(t *testing.T) {
+		repo := newRepo(t)
+		second := schedule
+		second.Genre = "love"
+		second.BookInfo = Book{Title: "SecondBook", Author: []string{"Author3"}, EditionNumber: 1}
+		slot := []Reservation{{Key: "slot", Limit: 2}}
+
+		saved, err := repo.SavePickUpSchedules(ctx, []PickUpSchedule{schedule, second}, [][]Reservation{slot, slot})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(saved) != 2 || saved[0].ID == "" || saved[0].Status != ScheduleStatusRequested || !sameSchedule(saved[1], second) {
+			t.Fatalf("Expected both schedules in batch order, got %v", saved)
+		}
+
+		// The slot has no place left for the second schedule of this batch
+		_, err = repo.SavePickUpSchedules(ctx, []PickUpSchedule{schedule, second}, [][]Reservation{{{Key: "other", Limit: 1}}, slot})
+		var batchErr *BatchSaveError
+		if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, ErrCapacityExceeded) {
+			t.Fatalf("Expected a *BatchSaveError for the second schedule, got %v", err)
+		}
+
+		page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{Sort: "created_at", Limit: maxScheduleLimit})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(page.Schedules) != 2 {
+			t.Errorf("Expected nothing of the failed batch to be stored, got %v", page.Schedules)
+		}
+		counts, err := repo.CountReservations(ctx, []string{"other", "slot"})
+		if err != nil || counts["other"] != 0 || counts["slot"] != 2 {
+			t.Errorf("Expected only the first batch to hold reservations, got %v (%v)", counts, err)
+		}
+	})
+
+	t.Run("SavePickUpSchedule_AssignsIDAndTimestamps", func(t *testing.T) {
+		repo := newRepo(t)
+
+		before := time.Now().UTC().Add(-time.Second)
+		first, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		second, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		saved := second[len(second)-1]
+		if saved.ID == "" || saved.ID == first[0].ID {
+			t.Errorf("Expected a new unique id, got %q and %q", first[0].ID, saved.ID)
+		}
+		if saved.CreatedAt.Before(before) || !saved.UpdatedAt.Equal(saved.CreatedAt) {
+			t.Errorf("Expected fresh timestamps, got created %v and updated %v", saved.CreatedAt, saved.UpdatedAt)
+		}
+		if saved.Status != ScheduleStatusRequested {
+			t.Errorf("Expected status active, got %q", saved.Status)
+		}
+	})
+
+	t.Run("GetPickUpScheduleByID_Found", func(t *testing.T) {
+		repo := newRepo(t)
+
+		pickUpSchedules, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		found, err := repo.GetPickUpScheduleByID(ctx, pickUpSchedules[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(found, pickUpSchedules[0]) {
+			t.Errorf("Expected %v, got %v", pickUpSchedules[0], found)
+		}
+	})
+
+	t.Run("GetPickUpScheduleByID_NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetPickUpScheduleByID(ctx, "missing"); !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("SavePickUpSchedule_KeepsTimezone", func(t *testing.T) {
+		repo := newRepo(t)
+
+		local := schedule
+		local.PickUpDate = mustParsePickUpDate("2023-12-01T03:30:00Z")
+		local.Timezone = "Asia/Jakarta"
+		pickUpSchedules, err := repo.SavePickUpSchedule(ctx, local)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		found, err := repo.GetPickUpScheduleByID(ctx, pickUpSchedules[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if found.Timezone != local.Timezone || found.LocalPickUpDate() != "2023-12-01T10:30:00+07:00" {
+			t.Errorf("Expected the pick-up in Asia/Jakarta, got %s in %q", found.LocalPickUpDate(), found.Timezone)
+		}
+	})
+
+	t.Run("SavePickUpSchedule_SameBookTwice", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 2; i++ {
+			if _, err := repo.SavePickUpSchedule(ctx, schedule); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		_, pickUpSchedules, err := repo.GetBooksByGenre(ctx, "fiction")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(pickUpSchedules) != 2 {
+			t.Errorf("Expected 2 pick-up schedules, got %d", len(pickUpSchedules))
+		}
+	})
+
+	t.Run("GetBooksByGenre_MergesStoredSchedules", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.SavePickUpSchedule(ctx, schedule); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		books, pickUpSchedules, err := repo.GetBooksByGenre(ctx, "fiction")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(books) != 1 || books[0].Title != "MockBook" {
+			t.Errorf("Expected the book from the API, got %v", books)
+		}
+		if len(pickUpSchedules) != 1 || !sameSchedule(pickUpSchedules[0], schedule) {
+			t.Errorf("Expected the stored schedule, got %v", pickUpSchedules)
+		}
+	})
+
+	t.Run("GetBooksByGenre_GenresAreSeparate", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.SavePickUpSchedule(ctx, schedule); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		_, pickUpSchedules, err := repo.GetBooksByGenre(ctx, "love")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(pickUpSchedules) != 0 {
+			t.Errorf("Expected 0 pick-up schedules, got %d", len(pickUpSchedules))
+		}
+	})
+
+	t.Run("GetBooksByGenre_APIFailure", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, _, err := repo.GetBooksByGenre(ctx, "broken"); err == nil {
+			t.Error("Expected error, but got nil")
+		}
+	})
+
+	t.Run("ListPickUpSchedules_Filters", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for _, s := range []PickUpSchedule{
+			{Genre: "love", BookInfo: Book{Title: "Wuthering Heights"}, PickUpDate: mustParsePickUpDate("2023-12-01")},
+			{Genre: "love", BookInfo: Book{Title: "Rose in Bloom"}, PickUpDate: mustParsePickUpDate("2023-12-05")},
+			{Genre: "fiction", BookInfo: Book{Title: "Heights of Fiction"}, PickUpDate: mustParsePickUpDate("2023-12-10")},
+			{Genre: "fiction", BookInfo: Book{Title: "100% Real_Story"}, PickUpDate: mustParsePickUpDate("2023-12-10")},
+		} {
+			if _, err := repo.SavePickUpSchedule(ctx, s); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		for name, tc := range map[string]struct {
+			query  ScheduleQuery
+			titles []string
+		}{
+			"All":       {ScheduleQuery{}, []string{"Wuthering Heights", "Rose in Bloom", "Heights of Fiction", "100% Real_Story"}},
+			"Genre":     {ScheduleQuery{Genre: "love"}, []string{"Wuthering Heights", "Rose in Bloom"}},
+			"Title":     {ScheduleQuery{Title: "HEIGHTS"}, []string{"Wuthering Heights", "Heights of Fiction"}},
+			"Wildcards": {ScheduleQuery{Title: "0% real_"}, []string{"100% Real_Story"}},
+			"NoMatch":   {ScheduleQuery{Title: "heights_"}, nil},
+			"DateRange": {ScheduleQuery{PickUpFrom: "2023-12-02", PickUpTo: "2023-12-05"}, []string{"Rose in Bloom"}},
+			"Combined":  {ScheduleQuery{Genre: "fiction", Title: "heights", PickUpFrom: "2023-12-10"}, []string{"Heights of Fiction"}},
+		} {
+			tc.query.Limit = 10
+			page, err := repo.ListPickUpSchedules(ctx, tc.query)
+			if err != nil {
+				t.Fatalf("%s: Unexpected error: %v", name, err)
+			}
+
+			var titles []string
+			for _, s := range page.Schedules {
+				titles = append(titles, s.BookInfo.Title)
+			}
+			if !reflect.DeepEqual(titles, tc.titles) || page.NextCursor != "" {
+				t.Errorf("%s: Expected %v on a single page, got %v with cursor %q", name, tc.titles, titles, page.NextCursor)
+			}
+		}
+	})
+
+	t.Run("ListPickUpSchedules_SortAndPaginate", func(t *testing.T) {
+		repo := newRepo(t)
+
+		// Two schedules share a date so the tie breaker is exercised
+		for _, date := range []string{"2023-12-03", "2023-12-01", "2023-12-02", "2023-12-02", "2023-12-04"} {
+			if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "love", BookInfo: Book{Title: "Book " + date}, PickUpDate: mustParsePickUpDate(date)}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		for _, sort := range []string{"pick_up_date", "-pick_up_date", "title", "-created_at"} {
+			// Walk every page of two and check the order against a single page
+			all, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{Sort: sort, Limit: 10})
+			if err != nil {
+				t.Fatalf("%s: Unexpected error: %v", sort, err)
+			}
+
+			var walked []PickUpSchedule
+			query := ScheduleQuery{Sort: sort, Limit: 2}
+			for pages := 0; pages < 5; pages++ {
+				page, err := repo.ListPickUpSchedules(ctx, query)
+				if err != nil {
+					t.Fatalf("%s: Unexpected error: %v", sort, err)
+				}
+				walked = append(walked, page.Schedules...)
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+
+			if len(all.Schedules) != 5 || !reflect.DeepEqual(walked, all.Schedules) {
+				t.Errorf("%s: Expected pages to add up to %v, got %v", sort, all.Schedules, walked)
+			}
+			for i := 1; i < len(all.Schedules); i++ {
+				field, descending := parseScheduleSort(sort)
+				if scheduleLess(all.Schedules[i], all.Schedules[i-1], field, descending) {
+					t.Errorf("%s: Schedules out of order at %d", sort, i)
+				}
+			}
+		}
+
+		ascending, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{Sort: "pick_up_date", Limit: 10})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if first := ascending.Schedules[0].PickUpDate.String(); first != "2023-12-01" {
+			t.Errorf("Expected earliest pick-up first, got %s", first)
+		}
+	})
+
+	t.Run("ListPickUpSchedules_DateRangeByDay", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for _, date := range []string{"2023-11-30T23:59:59Z", "2023-12-01T00:00:00Z", "2023-12-01T18:30:00Z", "2023-12-02"} {
+			if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "love", BookInfo: Book{Title: date}, PickUpDate: mustParsePickUpDate(date)}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		// Times later on the last day are still inside the range
+		page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{PickUpFrom: "2023-12-01", PickUpTo: "2023-12-01", Sort: "pick_up_date", Limit: 10})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var titles []string
+		for _, found := range page.Schedules {
+			titles = append(titles, found.BookInfo.Title)
+		}
+		if !reflect.DeepEqual(titles, []string{"2023-12-01T00:00:00Z", "2023-12-01T18:30:00Z"}) {
+			t.Errorf("Expected the schedules of 2023-12-01, got %v", titles)
+		}
+	})
+
+	t.Run("ListPickUpSchedules_DateRangeByLocalDay", func(t *testing.T) {
+		repo := newRepo(t)
+
+		// 2023-11-22T00:30 in Kathmandu, still the day before in UTC
+		saved, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "love", BookInfo: Book{Title: "Local"}, Timezone: "Asia/Kathmandu", PickUpDate: mustParsePickUpDate("2023-11-21T18:45:00Z")})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for day, want := range map[string]int{"2023-11-21": 0, "2023-11-22": 1} {
+			page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{PickUpFrom: day, PickUpTo: day, Limit: 10})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(page.Schedules) != want || (want == 1 && page.Schedules[0].ID != saved[0].ID) {
+				t.Errorf("Expected %d schedules on %s, got %v", want, day, page.Schedules)
+			}
+		}
+	})
+
+	t.Run("ListPickUpSchedules_InvalidCursor", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 3; i++ {
+			if _, err := repo.SavePickUpSchedule(ctx, schedule); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{Sort: "title", Limit: 1})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for name, query := range map[string]ScheduleQuery{
+			"Malformed": {Cursor: "not a cursor!", Limit: 1},
+			"OtherSort": {Cursor: page.NextCursor, Sort: "-title", Limit: 1},
+		} {
+			if _, err := repo.ListPickUpSchedules(ctx, query); !errors.Is(err, ErrInvalidScheduleQuery) {
+				t.Errorf("%s: Expected ErrInvalidScheduleQuery, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("CancelPickUpSchedule_KeptForHistory", func(t *testing.T) {
+		repo := newRepo(t)
+
+		saved, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		kept, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		id := saved[0].ID
+
+		cancelled, err := repo.CancelPickUpSchedule(ctx, id, Cancellation{CancelledBy: "librarian", Reason: "changed mind"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cancelled.Status != ScheduleStatusCancelled || cancelled.Cancellation == nil ||
+			cancelled.Cancellation.CancelledBy != "librarian" || cancelled.Cancellation.Reason != "changed mind" ||
+			cancelled.Cancellation.CancelledAt.IsZero() || !cancelled.UpdatedAt.Equal(cancelled.Cancellation.CancelledAt) {
+			t.Errorf("Expected a recorded cancellation, got %+v", cancelled)
+		}
+
+		found, err := repo.GetPickUpScheduleByID(ctx, id)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(found, cancelled) {
+			t.Errorf("Expected cancelled schedule %+v, got %+v", cancelled, found)
+		}
+
+		// Cancelled schedules no longer count for the genre
+		_, pickUpSchedules, err := repo.GetBooksByGenre(ctx, "fiction")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(pickUpSchedules) != 1 || pickUpSchedules[0].ID != kept[1].ID {
+			t.Errorf("Expected only the active schedule, got %v", pickUpSchedules)
+		}
+		afterSave, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(afterSave) != 2 {
+			t.Errorf("Expected 2 active schedules, got %d", len(afterSave))
+		}
+
+		page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{Status: ScheduleStatusCancelled, Limit: 10})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(page.Schedules) != 1 || page.Schedules[0].ID != id {
+			t.Errorf("Expected the cancelled schedule, got %v", page.Schedules)
+		}
+	})
+
+	t.Run("CancelPickUpSchedule_AlreadyCancelled", func(t *testing.T) {
+		repo := newRepo(t)
+
+		saved, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.CancelPickUpSchedule(ctx, saved[0].ID, Cancellation{CancelledBy: "first"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, err := repo.CancelPickUpSchedule(ctx, saved[0].ID, Cancellation{CancelledBy: "second"}); !errors.Is(err, ErrScheduleCancelled) {
+			t.Errorf("Expected ErrScheduleCancelled, got %v", err)
+		}
+
+		found, err := repo.GetPickUpScheduleByID(ctx, saved[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if found.Cancellation.CancelledBy != "first" {
+			t.Errorf("Expected the first cancellation to be kept, got %+v", found.Cancellation)
+		}
+	})
+
+	t.Run("CancelPickUpSchedule_NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.CancelPickUpSchedule(ctx, "missing", Cancellation{CancelledBy: "librarian"}); !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("TransitionPickUpSchedule_RecordsHistory", func(t *testing.T) {
+		repo := newRepo(t)
+
+		saved, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		id := saved[0].ID
+
+		for _, status := range []ScheduleStatus{ScheduleStatusConfirmed, ScheduleStatusReadyForPickUp, ScheduleStatusPickedUp} {
+			if _, err := repo.TransitionPickUpSchedule(ctx, id, StatusTransition{Status: status, ChangedBy: "librarian"}); err != nil {
+				t.Fatalf("Unexpected error moving to %s: %v", status, err)
+			}
+		}
+		returned, err := repo.TransitionPickUpSchedule(ctx, id, StatusTransition{Status: ScheduleStatusReturned, ChangedBy: "front desk", Reason: "dropped in the box"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if returned.Status != ScheduleStatusReturned || returned.UpdatedAt.Before(returned.CreatedAt) {
+			t.Errorf("Expected returned schedule, got %+v", returned)
+		}
+		if found, err := repo.GetPickUpScheduleByID(ctx, id); err != nil || !reflect.DeepEqual(found, returned) {
+			t.Errorf("Expected stored schedule %+v, got %+v, %v", returned, found, err)
+		}
+
+		history, err := repo.GetPickUpScheduleHistory(ctx, id)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(history) != 4 {
+			t.Fatalf("Expected 4 changes, got %v", history)
+		}
+		if history[0].FromStatus != ScheduleStatusRequested || history[0].ToStatus != ScheduleStatusConfirmed {
+			t.Errorf("Expected confirmation first, got %+v", history[0])
+		}
+		last := history[3]
+		if last.FromStatus != ScheduleStatusPickedUp || last.ToStatus != ScheduleStatusReturned ||
+			last.ChangedBy != "front desk" || last.Reason != "dropped in the box" ||
+			last.FromPickUpDate.String() != "2023-12-01" || last.ToPickUpDate.String() != "2023-12-01" || last.ToBookInfo != nil {
+			t.Errorf("Expected return last, got %+v", last)
+		}
+	})
+
+	t.Run("TransitionPickUpSchedule_Illegal", func(t *testing.T) {
+		repo := newRepo(t)
+
+		saved, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		id := saved[0].ID
+
+		_, err = repo.TransitionPickUpSchedule(ctx, id, StatusTransition{Status: ScheduleStatusPickedUp, ChangedBy: "librarian"})
+		var transitionErr *TransitionError
+		if !errors.As(err, &transitionErr) || transitionErr.From != ScheduleStatusRequested || !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("Expected TransitionError from requested, got %v", err)
+		}
+		if history, err := repo.GetPickUpScheduleHistory(ctx, id); err != nil || len(history) != 0 {
+			t.Errorf("Expected the rejected move not to be recorded, got %v, %v", history, err)
+		}
+
+		if _, err := repo.CancelPickUpSchedule(ctx, id, Cancellation{CancelledBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.TransitionPickUpSchedule(ctx, id, StatusTransition{Status: ScheduleStatusConfirmed, ChangedBy: "librarian"}); !errors.Is(err, ErrScheduleCancelled) {
+			t.Errorf("Expected ErrScheduleCancelled, got %v", err)
+		}
+		if _, err := repo.TransitionPickUpSchedule(ctx, "missing", StatusTransition{Status: ScheduleStatusConfirmed, ChangedBy: "librarian"}); !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("TransitionPickUpSchedule_ReleasesReservations", func(t *testing.T) {
+		repo := newRepo(t)
+		slot := Reservation{Key: "slot:2023-12-01T10:00:00Z", Limit: 2}
+
+		var ids []string
+		for _, title := range []string{"First", "Second"} {
+			saved, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: title}}, slot)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ids = append(ids, saved[len(saved)-1].ID)
+		}
+
+		// A picked up book still holds its place, a no-show gives it back
+		for _, status := range []ScheduleStatus{ScheduleStatusConfirmed, ScheduleStatusReadyForPickUp} {
+			for _, id := range ids {
+				if _, err := repo.TransitionPickUpSchedule(ctx, id, StatusTransition{Status: status, ChangedBy: "librarian"}); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+		}
+		if _, err := repo.TransitionPickUpSchedule(ctx, ids[0], StatusTransition{Status: ScheduleStatusPickedUp, ChangedBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.TransitionPickUpSchedule(ctx, ids[1], StatusTransition{Status: ScheduleStatusNoShow, ChangedBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		counts, err := repo.CountReservations(ctx, []string{slot.Key})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if counts[slot.Key] != 1 {
+			t.Errorf("Expected the picked up schedule to keep its place, got %v", counts)
+		}
+
+		if _, err := repo.TransitionPickUpSchedule(ctx, ids[0], StatusTransition{Status: ScheduleStatusReturned, ChangedBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if counts, err := repo.CountReservations(ctx, []string{slot.Key}); err != nil || counts[slot.Key] != 0 {
+			t.Errorf("Expected the return to release the place, got %v, %v", counts, err)
+		}
+
+		page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{Status: ScheduleStatusNoShow, Limit: 10})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(page.Schedules) != 1 || page.Schedules[0].ID != ids[1] {
+			t.Errorf("Expected the no-show schedule, got %v", page.Schedules)
+		}
+	})
+
+	t.Run("TransitionPickUpSchedule_CopyOnLoanTakesEveryDay", func(t *testing.T) {
+		repo := newRepo(t)
+		loaned := "on-loan:central:book"
+		copyOn := func(day string) Reservation {
+			return Reservation{Key: "copy:central:book:" + day, Limit: 1, Shared: []string{loaned}}
+		}
+
+		saved, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "First"}}, copyOn("2023-11-21"), Reservation{Key: loaned})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		id := saved[len(saved)-1].ID
+		if counts, err := repo.CountReservations(ctx, []string{loaned}); err != nil || counts[loaned] != 0 {
+			t.Errorf("Expected no copy on loan before the pick-up, got %v, %v", counts, err)
+		}
+
+		for _, status := range []ScheduleStatus{ScheduleStatusConfirmed, ScheduleStatusReadyForPickUp, ScheduleStatusPickedUp} {
+			if _, err := repo.TransitionPickUpSchedule(ctx, id, StatusTransition{Status: status, ChangedBy: "librarian"}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		counts, err := repo.CountReservations(ctx, []string{loaned, copyOn("2023-11-21").Key})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if counts[loaned] != 1 || counts[copyOn("2023-11-21").Key] != 0 {
+			t.Errorf("Expected the copy to move from its day to the loan, got %v", counts)
+		}
+		_, err = repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Second"}}, copyOn("2023-11-22"), Reservation{Key: loaned})
+		if !errors.Is(err, ErrCapacityExceeded) {
+			t.Fatalf("Expected the copy on loan to be taken on another day, got %v", err)
+		}
+
+		if _, err := repo.TransitionPickUpSchedule(ctx, id, StatusTransition{Status: ScheduleStatusReturned, ChangedBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Second"}}, copyOn("2023-11-22"), Reservation{Key: loaned}); err != nil {
+			t.Errorf("Expected the returned copy to be free, got %v", err)
+		}
+	})
+
+	t.Run("TransitionPickUpSchedule_WeekHeldUntilCancelled", func(t *testing.T) {
+		repo := newRepo(t)
+		week := Reservation{Key: weekKey("reader", time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC)), Limit: 2}
+
+		var ids []string
+		for _, title := range []string{"First", "Second"} {
+			saved, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: title}}, week)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ids = append(ids, saved[len(saved)-1].ID)
+		}
+
+		// A missed pick-up still counts towards its week, a cancelled one does not
+		for _, status := range []ScheduleStatus{ScheduleStatusConfirmed, ScheduleStatusReadyForPickUp, ScheduleStatusNoShow} {
+			if _, err := repo.TransitionPickUpSchedule(ctx, ids[0], StatusTransition{Status: status, ChangedBy: "librarian"}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Third"}}, week); !errors.Is(err, ErrCapacityExceeded) {
+			t.Fatalf("Expected the missed pick-up to keep its place in the week, got %v", err)
+		}
+
+		if _, err := repo.CancelPickUpSchedule(ctx, ids[1], Cancellation{CancelledBy: "reader"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if counts, err := repo.CountReservations(ctx, []string{week.Key}); err != nil || counts[week.Key] != 1 {
+			t.Errorf("Expected the cancellation to release its place in the week, got %v, %v", counts, err)
+		}
+	})
+
+	t.Run("MarkOverdue_FlagsLoansPastDue", func(t *testing.T) {
+		repo := newRepo(t)
+		now := time.Date(2023, 12, 20, 12, 0, 0, 0, time.UTC)
+
+		// Due yesterday, in a week, and a week ago
+		var ids []string
+		for _, due := range []time.Time{now.Add(-24 * time.Hour), now.Add(7 * 24 * time.Hour), now.Add(-7 * 24 * time.Hour)} {
+			saved, err := repo.SavePickUpSchedule(ctx, schedule)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			id := saved[len(saved)-1].ID
+			ids = append(ids, id)
+
+			for _, status := range []ScheduleStatus{ScheduleStatusConfirmed, ScheduleStatusReadyForPickUp} {
+				if _, err := repo.TransitionPickUpSchedule(ctx, id, StatusTransition{Status: status, ChangedBy: "librarian"}); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			due := due
+			pickedUp, err := repo.TransitionPickUpSchedule(ctx, id, StatusTransition{Status: ScheduleStatusPickedUp, ChangedBy: "librarian", DueAt: &due})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if pickedUp.DueAt == nil || !pickedUp.DueAt.Equal(due) {
+				t.Errorf("Expected due date %v, got %v", due, pickedUp.DueAt)
+			}
+		}
+
+		flagged, err := repo.MarkOverdue(ctx, now)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(flagged) != 2 || flagged[0].ID != ids[2] || flagged[1].ID != ids[0] || flagged[0].Status != ScheduleStatusOverdue {
+			t.Fatalf("Expected the two loans past due, oldest first, got %+v", flagged)
+		}
+		if found, err := repo.GetPickUpScheduleByID(ctx, ids[0]); err != nil || !reflect.DeepEqual(found, flagged[1]) {
+			t.Errorf("Expected stored schedule %+v, got %+v, %v", flagged[1], found, err)
+		}
+
+		history, err := repo.GetPickUpScheduleHistory(ctx, ids[0])
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		last := history[len(history)-1]
+		if last.FromStatus != ScheduleStatusPickedUp || last.ToStatus != ScheduleStatusOverdue || last.ChangedBy != overdueCheckName {
+			t.Errorf("Expected the overdue check in the history, got %+v", last)
+		}
+
+		// Loans are only flagged once
+		if again, err := repo.MarkOverdue(ctx, now); err != nil || len(again) != 0 {
+			t.Errorf("Expected nothing left to flag, got %v, %v", again, err)
+		}
+
+		page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{Status: ScheduleStatusOverdue, Sort: "-due_at", Limit: 10})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(page.Schedules) != 2 || page.Schedules[0].ID != ids[0] || page.Schedules[1].ID != ids[2] {
+			t.Errorf("Expected overdue loans latest due first, got %v", page.Schedules)
+		}
+
+		if _, err := repo.TransitionPickUpSchedule(ctx, ids[0], StatusTransition{Status: ScheduleStatusReturned, ChangedBy: "front desk"}); err != nil {
+			t.Errorf("Unexpected error returning an overdue loan: %v", err)
+		}
+	})
+
+	t.Run("ReschedulePickUpSchedule_RecordsHistory", func(t *testing.T) {
+		repo := newRepo(t)
+
+		saved, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		id := saved[0].ID
+
+		if _, err := repo.ReschedulePickUpSchedule(ctx, id, Reschedule{PickUpDate: mustParsePickUpDate("2023-12-05"), ChangedBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		swapped := Book{Title: "OtherBook", Author: []string{"Author3"}, EditionNumber: 1}
+		moved, err := repo.ReschedulePickUpSchedule(ctx, id, Reschedule{BookInfo: &swapped, ChangedBy: "borrower", Reason: "wrong book"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if moved.PickUpDate.String() != "2023-12-05" || !reflect.DeepEqual(moved.BookInfo, swapped) {
+			t.Errorf("Expected moved schedule, got %+v", moved)
+		}
+		if found, err := repo.GetPickUpScheduleByID(ctx, id); err != nil || !reflect.DeepEqual(found.BookInfo, swapped) {
+			t.Errorf("Expected stored schedule to be moved, got %+v, %v", found, err)
+		}
+
+		history, err := repo.GetPickUpScheduleHistory(ctx, id)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(history) != 2 {
+			t.Fatalf("Expected 2 changes, got %v", history)
+		}
+		if history[0].ChangedBy != "librarian" || history[0].FromPickUpDate.String() != "2023-12-01" || history[0].ToPickUpDate.String() != "2023-12-05" || history[0].ToBookInfo != nil {
+			t.Errorf("Expected date change first, got %+v", history[0])
+		}
+		if history[1].ChangedBy != "borrower" || history[1].Reason != "wrong book" ||
+			!reflect.DeepEqual(*history[1].FromBookInfo, schedule.BookInfo) || !reflect.DeepEqual(*history[1].ToBookInfo, swapped) {
+			t.Errorf("Expected book swap second, got %+v", history[1])
+		}
+		if history[1].ChangedAt.IsZero() || history[1].ChangedAt.Before(history[0].ChangedAt) {
+			t.Errorf("Expected changes stamped in order, got %v and %v", history[0].ChangedAt, history[1].ChangedAt)
+		}
+	})
+
+	t.Run("ReschedulePickUpSchedule_Cancelled", func(t *testing.T) {
+		repo := newRepo(t)
+
+		saved, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.CancelPickUpSchedule(ctx, saved[0].ID, Cancellation{CancelledBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, err := repo.ReschedulePickUpSchedule(ctx, saved[0].ID, Reschedule{PickUpDate: mustParsePickUpDate("2023-12-05"), ChangedBy: "librarian"}); !errors.Is(err, ErrScheduleCancelled) {
+			t.Errorf("Expected ErrScheduleCancelled, got %v", err)
+		}
+		if history, err := repo.GetPickUpScheduleHistory(ctx, saved[0].ID); err != nil || len(history) != 0 {
+			t.Errorf("Expected empty history, got %v, %v", history, err)
+		}
+	})
+
+	t.Run("ReschedulePickUpSchedule_NotPending", func(t *testing.T) {
+		repo := newRepo(t)
+
+		saved, err := repo.SavePickUpSchedule(ctx, schedule)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, status := range []ScheduleStatus{ScheduleStatusConfirmed, ScheduleStatusReadyForPickUp, ScheduleStatusPickedUp} {
+			if _, err := repo.TransitionPickUpSchedule(ctx, saved[0].ID, StatusTransition{Status: status, ChangedBy: "librarian"}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		if _, err := repo.ReschedulePickUpSchedule(ctx, saved[0].ID, Reschedule{PickUpDate: mustParsePickUpDate("2023-12-05"), ChangedBy: "librarian"}); !errors.Is(err, ErrScheduleNotPending) {
+			t.Errorf("Expected ErrScheduleNotPending, got %v", err)
+		}
+		if _, err := repo.CancelPickUpSchedule(ctx, saved[0].ID, Cancellation{CancelledBy: "librarian"}); !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("Expected ErrInvalidTransition, got %v", err)
+		}
+	})
+
+	t.Run("ReschedulePickUpSchedule_NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.ReschedulePickUpSchedule(ctx, "missing", Reschedule{PickUpDate: mustParsePickUpDate("2023-12-05"), ChangedBy: "librarian"}); !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+		if _, err := repo.GetPickUpScheduleHistory(ctx, "missing"); !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("SavePickUpSchedule_ReservationLimit", func(t *testing.T) {
+		repo := newRepo(t)
+		slot := Reservation{Key: "slot:2023-12-01T10:00:00Z", Limit: 2}
+
+		// The limit holds across genres
+		var ids []string
+		for _, genre := range []string{"fiction", "love"} {
+			saved, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: genre, BookInfo: Book{Title: genre}}, slot)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ids = append(ids, saved[len(saved)-1].ID)
+		}
+
+		_, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Third"}}, slot)
+		var capacityErr *CapacityError
+		if !errors.As(err, &capacityErr) || capacityErr.Key != slot.Key || !errors.Is(err, ErrCapacityExceeded) {
+			t.Fatalf("Expected CapacityError for %s, got %v", slot.Key, err)
+		}
+		if page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{Limit: 10}); err != nil || len(page.Schedules) != 2 {
+			t.Errorf("Expected the rejected schedule not to be stored, got %v, %v", page.Schedules, err)
+		}
+
+		// Cancelling releases the place
+		if _, err := repo.CancelPickUpSchedule(ctx, ids[0], Cancellation{CancelledBy: "librarian"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		counts, err := repo.CountReservations(ctx, []string{slot.Key, "slot:unused"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(counts, map[string]int{slot.Key: 1, "slot:unused": 0}) {
+			t.Errorf("Expected one holder left, got %v", counts)
+		}
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Third"}}, slot); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ReschedulePickUpSchedule_MovesReservation", func(t *testing.T) {
+		repo := newRepo(t)
+		morning := Reservation{Key: "slot:2023-12-01T10:00:00Z", Limit: 1}
+		evening := Reservation{Key: "slot:2023-12-01T18:00:00Z", Limit: 1}
+
+		first, err := repo.SavePickUpSchedule(ctx, schedule, morning)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		second, err := repo.SavePickUpSchedule(ctx, schedule, evening)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		firstID, secondID := first[0].ID, second[1].ID
+
+		if _, err := repo.ReschedulePickUpSchedule(ctx, secondID, Reschedule{PickUpDate: mustParsePickUpDate("2023-12-01T10:00:00Z"), ChangedBy: "librarian"}, morning); !errors.Is(err, ErrCapacityExceeded) {
+			t.Fatalf("Expected ErrCapacityExceeded, got %v", err)
+		}
+		if history, err := repo.GetPickUpScheduleHistory(ctx, secondID); err != nil || len(history) != 0 {
+			t.Errorf("Expected the rejected move not to be recorded, got %v, %v", history, err)
+		}
+
+		// Keeping its own slot never counts against the schedule itself
+		if _, err := repo.ReschedulePickUpSchedule(ctx, firstID, Reschedule{BookInfo: &Book{Title: "Swapped"}, ChangedBy: "librarian"}, morning); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.ReschedulePickUpSchedule(ctx, firstID, Reschedule{PickUpDate: mustParsePickUpDate("2023-12-02T10:00:00Z"), ChangedBy: "librarian"}, Reservation{Key: "slot:2023-12-02T10:00:00Z", Limit: 1}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.ReschedulePickUpSchedule(ctx, secondID, Reschedule{PickUpDate: mustParsePickUpDate("2023-12-01T10:00:00Z"), ChangedBy: "librarian"}, morning); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		counts, err := repo.CountReservations(ctx, []string{morning.Key, evening.Key, "slot:2023-12-02T10:00:00Z"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(counts, map[string]int{morning.Key: 1, evening.Key: 0, "slot:2023-12-02T10:00:00Z": 1}) {
+			t.Errorf("Expected the reservations to follow the moves, got %v", counts)
+		}
+	})
+
+	t.Run("SavePickUpSchedule_ConcurrentReservations", func(t *testing.T) {
+		repo := newRepo(t)
+		slot := Reservation{Key: "slot:2023-12-01T10:00:00Z", Limit: 5}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		saved, rejected := 0, 0
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(genre string) {
+				defer wg.Done()
+				_, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: genre, BookInfo: Book{Title: "Concurrent"}}, slot)
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					saved++
+				case errors.Is(err, ErrCapacityExceeded):
+					rejected++
+				default:
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}([]string{"fiction", "love"}[i%2])
+		}
+		wg.Wait()
+
+		if saved != slot.Limit || rejected != 20-slot.Limit {
+			t.Errorf("Expected %d saved and %d rejected, got %d and %d", slot.Limit, 20-slot.Limit, saved, rejected)
+		}
+	})
+
+	t.Run("SavePickUpSchedule_Concurrent", func(t *testing.T) {
+		repo := newRepo(t)
+
+		const workers = 8
+		const perWorker = 10
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					if _, err := repo.SavePickUpSchedule(ctx, schedule); err != nil {
+						t.Errorf("Unexpected error: %v", err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		_, pickUpSchedules, err := repo.GetBooksByGenre(ctx, "fiction")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(pickUpSchedules) != workers*perWorker {
+			t.Errorf("Expected %d pick-up schedules, got %d", workers*perWorker, len(pickUpSchedules))
+		}
+	})
+}
+
+// sameSchedule compares the fields a client submits, ignoring what the
+// repository assigns on save
+func sameSchedule(got, want PickUpSchedule) bool {
+	want.ID = got.ID
+	want.Status = got.Status
+	want.CreatedAt = got.CreatedAt
+	want.UpdatedAt = got.UpdatedAt
+	return reflect.DeepEqual(got, want)
+}
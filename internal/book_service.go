@@ -2,12 +2,62 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
 )
 
 type BookService interface {
 	GetBooksByGenreService(ctx context.Context, genre string) (Response, error)
-	SubmitPickUpScheduleService(schedule PickUpSchedule) (PostResponse, error)
+	SubmitPickUpScheduleService(ctx context.Context, schedule PickUpSchedule) (PostResponse, error)
+	SubmitPickUpSchedulesService(ctx context.Context, batch ScheduleBatch) (BatchResponse, error)
+	GetPickUpScheduleService(ctx context.Context, id string) (PostResponse, error)
+	ListPickUpSchedulesService(ctx context.Context, query ScheduleQuery) (ScheduleListResponse, error)
+	CancelPickUpScheduleService(ctx context.Context, id string, cancellation Cancellation) (PostResponse, error)
+	ReschedulePickUpScheduleService(ctx context.Context, id string, reschedule Reschedule) (PostResponse, error)
+	TransitionPickUpScheduleService(ctx context.Context, id string, transition StatusTransition) (PostResponse, error)
+	MarkOverdueLoansService(ctx context.Context) (ScheduleListResponse, error)
+	ListOverdueLoansService(ctx context.Context, query ScheduleQuery) (ScheduleListResponse, error)
+	GetPickUpScheduleHistoryService(ctx context.Context, id string) (ScheduleHistoryResponse, error)
+	GetPickUpScheduleCalendarService(ctx context.Context, id string) (CalendarResponse, error)
+	GetBorrowerCalendarService(ctx context.Context, borrowerID string) (CalendarResponse, error)
+	GetBranchCalendarService(ctx context.Context, branchID, day string) (CalendarResponse, error)
+	GetPickUpManifestService(ctx context.Context, branchID, day string) (ManifestResponse, error)
+	ListSlotsService(ctx context.Context, branchID, day string) (SlotListResponse, error)
+	PlaceHoldService(ctx context.Context, hold Hold) (HoldResponse, error)
+	GetHoldService(ctx context.Context, id string) (HoldResponse, error)
+	ConfirmHoldService(ctx context.Context, id string) (HoldResponse, error)
+	CancelHoldService(ctx context.Context, id string) (HoldResponse, error)
+	ProcessHoldsService(ctx context.Context) (HoldListResponse, error)
+	SendRemindersService(ctx context.Context) (ScheduleListResponse, error)
+}
+
+// ErrInvalidCancellation is returned for a cancellation that does not say who cancelled
+var ErrInvalidCancellation = errors.New("cancelled_by is required")
+
+// FieldError explains why one field of a request was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every rejected field of a request
+type ValidationError struct {
+	Fields []FieldError
+	// NextOpenDate is set when a pick-up falls on a closure of its branch, it
+	// is the first day after it the branch takes pick-ups again
+	NextOpenDate string
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+" "+field.Message)
+	}
+	return "invalid request: " + strings.Join(messages, ", ")
 }
 
 type Response struct {
@@ -24,18 +74,204 @@ type PostResponse struct {
 	Message   string         `json:"message"`
 	TotalData int            `json:"total_data"`
 	Data      PickUpSchedule `json:"data"`
+	Errors    []FieldError   `json:"errors,omitempty"`
+	// ExistingScheduleID points to the schedule of the borrower a rejected
+	// one would duplicate or overlap
+	ExistingScheduleID string `json:"existing_schedule_id,omitempty"`
+	// NextOpenDate suggests another day for a pick-up on a closed day
+	NextOpenDate string `json:"next_open_date,omitempty"`
+}
+
+type ScheduleListResponse struct {
+	Status     string           `json:"status"`
+	IsSuccess  bool             `json:"is_success"`
+	Message    string           `json:"message"`
+	TotalData  int              `json:"total_data"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	Data       []PickUpSchedule `json:"data"`
+}
+
+type ScheduleHistoryResponse struct {
+	Status    string           `json:"status"`
+	IsSuccess bool             `json:"is_success"`
+	Message   string           `json:"message"`
+	TotalData int              `json:"total_data"`
+	Data      []ScheduleChange `json:"data"`
+}
+
+type SlotListResponse struct {
+	Status    string       `json:"status"`
+	IsSuccess bool         `json:"is_success"`
+	Message   string       `json:"message"`
+	TotalData int          `json:"total_data"`
+	Data      []Slot       `json:"data"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Closure is set when the branch is closed on the day
+	Closure *Closure `json:"closure,omitempty"`
+}
+
+type HoldResponse struct {
+	Status    string       `json:"status"`
+	IsSuccess bool         `json:"is_success"`
+	Message   string       `json:"message"`
+	TotalData int          `json:"total_data"`
+	Data      Hold         `json:"data"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
+type HoldListResponse struct {
+	Status    string `json:"status"`
+	IsSuccess bool   `json:"is_success"`
+	Message   string `json:"message"`
+	TotalData int    `json:"total_data"`
+	Data      []Hold `json:"data"`
+}
+
+const (
+	defaultScheduleLimit = 20
+	maxScheduleLimit     = 100
+
+	// DefaultBookingHorizon is how far ahead pick-ups can be booked unless
+	// WithBookingHorizon says otherwise
+	DefaultBookingHorizon = 90 * 24 * time.Hour
+)
+
 type bookService struct {
 	repository BookRepository
+	branches   BranchRepository
+	borrowers  BorrowerRepository
+	holds      HoldRepository
+	closures   ClosureRepository
+
+	now            func() time.Time
+	bookingHorizon time.Duration
+	slots          SlotConfig
+	loans          LoanConfig
+	holdConfig     HoldConfig
+	limits         BorrowerLimits
+
+	// notifications is nil unless WithNotifications is given
+	notifications      NotificationChannel
+	notificationLog    NotificationRepository
+	notificationConfig NotificationConfig
+
+	// webhooks is nil unless WithWebhooks is given
+	webhooks WebhookPublisher
+	// stream is nil unless WithEventStream is given
+	stream *ScheduleEventStream
+}
+
+// ServiceOption configures the BookService returned by NewService
+type ServiceOption func(s *bookService)
+
+// WithBookingHorizon limits how far ahead of now a pick-up can be booked
+func WithBookingHorizon(horizon time.Duration) ServiceOption {
+	return func(s *bookService) {
+		s.bookingHorizon = horizon
+	}
 }
 
-func NewService(repository BookRepository) BookService {
-	return &bookService{
-		repository: repository,
+// WithSlots sets the length and capacity of pick-up slots. The config must
+// pass SlotConfig.Validate.
+func WithSlots(config SlotConfig) ServiceOption {
+	return func(s *bookService) {
+		s.slots = config
 	}
 }
 
+// WithLoans sets how long picked up books can be kept where the branch sets
+// no loan period. The config must pass LoanConfig.Validate.
+func WithLoans(config LoanConfig) ServiceOption {
+	return func(s *bookService) {
+		s.loans = config
+	}
+}
+
+// WithBorrowerLimits caps how much a single borrower can book. The limits
+// must pass BorrowerLimits.Validate.
+func WithBorrowerLimits(limits BorrowerLimits) ServiceOption {
+	return func(s *bookService) {
+		s.limits = limits
+	}
+}
+
+// WithHolds stores the holds queueing for books in repository instead of
+// memory and sets how long offers stay open. The config must pass
+// HoldConfig.Validate.
+func WithHolds(repository HoldRepository, config HoldConfig) ServiceOption {
+	return func(s *bookService) {
+		s.holds = repository
+		s.holdConfig = config
+	}
+}
+
+// WithClosures reads the days branches are closed on from repository, no
+// pick-ups are booked on them
+func WithClosures(repository ClosureRepository) ServiceOption {
+	return func(s *bookService) {
+		s.closures = repository
+	}
+}
+
+// WithNotifications sends borrowers a confirmation of every pick-up schedule
+// they submit and a reminder ahead of the pick-up through channel, keeping
+// track of what was sent in repository. The config must pass
+// NotificationConfig.Validate.
+func WithNotifications(channel NotificationChannel, repository NotificationRepository, config NotificationConfig) ServiceOption {
+	return func(s *bookService) {
+		s.notifications = channel
+		s.notificationLog = repository
+		s.notificationConfig = config
+	}
+}
+
+// WithWebhooks tells publisher about every pick-up schedule that is created,
+// changed or cancelled
+func WithWebhooks(publisher WebhookPublisher) ServiceOption {
+	return func(s *bookService) {
+		s.webhooks = publisher
+	}
+}
+
+// WithEventStream sends every pick-up schedule that is created, changed or
+// cancelled to the subscribers of stream
+func WithEventStream(stream *ScheduleEventStream) ServiceOption {
+	return func(s *bookService) {
+		s.stream = stream
+	}
+}
+
+// WithClock replaces time.Now, which decides what counts as the past
+func WithClock(now func() time.Time) ServiceOption {
+	return func(s *bookService) {
+		s.now = now
+	}
+}
+
+// NewService returns a BookService storing schedules in repository, which
+// have to reference one of the branches and one of the borrowers
+func NewService(repository BookRepository, branches BranchRepository, borrowers BorrowerRepository, options ...ServiceOption) BookService {
+	s := &bookService{
+		repository:     repository,
+		branches:       branches,
+		borrowers:      borrowers,
+		holds:          NewInMemoryHoldRepository(),
+		closures:       NewInMemoryClosureRepository(),
+		now:            time.Now,
+		bookingHorizon: DefaultBookingHorizon,
+		slots:          DefaultSlotConfig,
+		loans:          DefaultLoanConfig,
+		holdConfig:     DefaultHoldConfig,
+		limits:         DefaultBorrowerLimits,
+
+		notificationConfig: DefaultNotificationConfig,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
 func (s *bookService) GetBooksByGenreService(ctx context.Context, genre string) (Response, error) {
 	books, pickUpSchedules, err := s.repository.GetBooksByGenre(ctx, genre)
 	if err != nil {
@@ -48,6 +284,17 @@ func (s *bookService) GetBooksByGenreService(ctx context.Context, genre string)
 		}, err
 	}
 
+	available, err := s.availableCopies(ctx, genre)
+	if err != nil {
+		return Response{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch available copies: %v", err),
+			Data:      []Book{},
+			TotalData: 0,
+		}, err
+	}
+
 	totalData := len(books) + len(pickUpSchedules)
 
 	// Prepare the response structure
@@ -71,28 +318,1307 @@ func (s *bookService) GetBooksByGenreService(ctx context.Context, genre string)
 		})
 	}
 
+	for i := range response.Data {
+		copies := available[bookKey(genre, response.Data[i])]
+		response.Data[i].AvailableCopies = &copies
+	}
+
+	return response, nil
+}
+
+func (s *bookService) SubmitPickUpScheduleService(ctx context.Context, schedule PickUpSchedule) (PostResponse, error) {
+	response, err := s.submitPickUpSchedule(ctx, schedule)
+	if err == nil {
+		s.publish(ctx, WebhookScheduleCreated, response.Data)
+		s.notifyOf(ctx, NotificationConfirmation, response.Data)
+	}
+	return response, err
+}
+
+// submitPickUpSchedule saves a new schedule without telling the borrower or
+// the webhooks
+func (s *bookService) submitPickUpSchedule(ctx context.Context, schedule PickUpSchedule) (PostResponse, error) {
+	// Availability is computed, never taken from the request
+	schedule.BookInfo.AvailableCopies = nil
+
+	branch, err := s.validatePickUpSchedule(ctx, &schedule)
+	if err != nil {
+		return invalidPostResponse(err), err
+	}
+
+	reservations, err := s.checkNewPickUpSchedule(ctx, schedule, branch)
+	var pickUpSchedule []PickUpSchedule
+	if err == nil {
+		pickUpSchedule, err = s.repository.SavePickUpSchedule(ctx, schedule, reservations...)
+	}
+	if err = s.conflictOf(ctx, limitOf(err, schedule), schedule, branch); err != nil {
+		return unsavedPostResponse(err), err
+	}
+
+	// The repository returns the schedules of the genre with the new one last
+	response := PostResponse{
+		Status:    "201 CREATED",
+		IsSuccess: true,
+		Message:   "save new data books successfully!",
+		TotalData: len(pickUpSchedule),
+		Data:      pickUpSchedule[len(pickUpSchedule)-1],
+	}
+
 	return response, nil
 }
 
-func (s *bookService) SubmitPickUpScheduleService(schedule PickUpSchedule) (PostResponse, error) {
-	pickUpSchedule, err := s.repository.SavePickUpSchedule(schedule)
+// checkNewPickUpSchedule checks a validated new schedule against the pick-ups
+// the borrower already has, and the schedules of batch saved along with it,
+// and returns the reservations it claims
+func (s *bookService) checkNewPickUpSchedule(ctx context.Context, schedule PickUpSchedule, branch Branch, batch ...PickUpSchedule) ([]Reservation, error) {
+	err := s.checkScheduleConflicts(ctx, schedule, branch, batch...)
+	if err == nil {
+		err = s.checkWeeklyPickUps(ctx, schedule, branch, batch...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.reservationsOf(ctx, schedule, branch)
+}
+
+// unsavedPostResponse describes why a valid new schedule was not saved
+func unsavedPostResponse(err error) PostResponse {
+	if isConflict(err) {
+		return conflictPostResponse(err)
+	}
+	return PostResponse{
+		Status:    "500 Internal Server Error",
+		IsSuccess: false,
+		Message:   fmt.Sprintf("failed to save new data books: %v", err),
+		Data:      PickUpSchedule{},
+		TotalData: 0,
+	}
+}
+
+func (s *bookService) GetPickUpScheduleService(ctx context.Context, id string) (PostResponse, error) {
+	pickUpSchedule, err := s.repository.GetPickUpScheduleByID(ctx, id)
+	if errors.Is(err, ErrScheduleNotFound) {
+		return PostResponse{
+			Status:    "404 Not Found",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("pick-up schedule %s not found", id),
+			Data:      PickUpSchedule{},
+			TotalData: 0,
+		}, err
+	}
 	if err != nil {
 		return PostResponse{
 			Status:    "500 Internal Server Error",
 			IsSuccess: false,
-			Message:   fmt.Sprintf("failed to save new data books: %v", err),
+			Message:   fmt.Sprintf("failed to fetch pick-up schedule: %v", err),
 			Data:      PickUpSchedule{},
 			TotalData: 0,
 		}, err
 	}
 
 	response := PostResponse{
-		Status:    "201 CREATED",
+		Status:    "200 OK",
 		IsSuccess: true,
-		Message:   "save new data books successfully!",
-		TotalData: len(pickUpSchedule),
-		Data:      pickUpSchedule[0],
+		Message:   "fetch pick-up schedule successfully!",
+		TotalData: 1,
+		Data:      pickUpSchedule,
+	}
+
+	return response, nil
+}
+
+func (s *bookService) ListPickUpSchedulesService(ctx context.Context, query ScheduleQuery) (ScheduleListResponse, error) {
+	query, err := normalizeScheduleQuery(query)
+	if err != nil {
+		return ScheduleListResponse{
+			Status:    "400 Bad Request",
+			IsSuccess: false,
+			Message:   err.Error(),
+			Data:      []PickUpSchedule{},
+			TotalData: 0,
+		}, err
+	}
+
+	page, err := s.repository.ListPickUpSchedules(ctx, query)
+	if err != nil {
+		// A cursor is only checked by the repository
+		status := "500 Internal Server Error"
+		if errors.Is(err, ErrInvalidScheduleQuery) {
+			status = "400 Bad Request"
+		}
+		return ScheduleListResponse{
+			Status:    status,
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch pick-up schedules: %v", err),
+			Data:      []PickUpSchedule{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := ScheduleListResponse{
+		Status:     "200 OK",
+		IsSuccess:  true,
+		Message:    "fetch pick-up schedules successfully!",
+		TotalData:  len(page.Schedules),
+		NextCursor: page.NextCursor,
+		Data:       []PickUpSchedule{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	response.Data = append(response.Data, page.Schedules...)
+
+	return response, nil
+}
+
+func (s *bookService) CancelPickUpScheduleService(ctx context.Context, id string, cancellation Cancellation) (PostResponse, error) {
+	cancellation.CancelledBy = strings.TrimSpace(cancellation.CancelledBy)
+	if cancellation.CancelledBy == "" {
+		return PostResponse{
+			Status:    "400 Bad Request",
+			IsSuccess: false,
+			Message:   ErrInvalidCancellation.Error(),
+			Data:      PickUpSchedule{},
+			TotalData: 0,
+		}, ErrInvalidCancellation
+	}
+
+	pickUpSchedule, err := s.repository.GetPickUpScheduleByID(ctx, id)
+	if err == nil {
+		err = checkTransition(pickUpSchedule.Status, ScheduleStatusCancelled)
+	}
+	if err == nil {
+		pickUpSchedule, err = s.repository.CancelPickUpSchedule(ctx, id, cancellation)
+	}
+	if err != nil {
+		return PostResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to cancel pick-up schedule: %v", err),
+			Data:      PickUpSchedule{},
+			TotalData: 0,
+		}, err
+	}
+
+	s.publish(ctx, WebhookScheduleCancelled, pickUpSchedule)
+	// The copy is free again for the next hold in the queue
+	s.serveQueueOf(ctx, pickUpSchedule)
+
+	response := PostResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "cancel pick-up schedule successfully!",
+		TotalData: 1,
+		Data:      pickUpSchedule,
+	}
+
+	return response, nil
+}
+
+func (s *bookService) ReschedulePickUpScheduleService(ctx context.Context, id string, reschedule Reschedule) (PostResponse, error) {
+	reschedule.ChangedBy = strings.TrimSpace(reschedule.ChangedBy)
+	if reschedule.BookInfo != nil {
+		book := *reschedule.BookInfo
+		book.AvailableCopies = nil
+		reschedule.BookInfo = &book
+	}
+
+	var fields []FieldError
+	if reschedule.ChangedBy == "" {
+		fields = append(fields, FieldError{Field: "changed_by", Message: "is required"})
+	}
+	if reschedule.PickUpDate.IsZero() && reschedule.BookInfo == nil {
+		fields = append(fields, FieldError{Field: "pick_up_date", Message: "or book_info is required"})
+	}
+	if len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return invalidPostResponse(err), err
+	}
+
+	pickUpSchedule, err := s.repository.GetPickUpScheduleByID(ctx, id)
+	if err != nil {
+		return PostResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to reschedule pick-up schedule: %v", err),
+			Data:      PickUpSchedule{},
+			TotalData: 0,
+		}, err
+	}
+
+	if err := checkReschedule(pickUpSchedule.Status); err != nil {
+		return PostResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to reschedule pick-up schedule: %v", err),
+			Data:      PickUpSchedule{},
+			TotalData: 0,
+		}, err
+	}
+
+	// The moved schedule has to pass the same rules as a new submission
+	previous := pickUpSchedule
+	applyReschedule(&pickUpSchedule, reschedule, s.now().UTC())
+	branch, err := s.validatePickUpSchedule(ctx, &pickUpSchedule)
+	if err != nil {
+		return invalidPostResponse(err), err
+	}
+	if !reschedule.PickUpDate.IsZero() {
+		reschedule.PickUpDate = pickUpSchedule.PickUpDate
+	}
+
+	err = s.checkScheduleConflicts(ctx, pickUpSchedule, branch)
+	if err == nil {
+		err = s.checkWeeklyPickUps(ctx, pickUpSchedule, branch)
+	}
+	var reservations []Reservation
+	if err == nil {
+		reservations, err = s.reservationsOf(ctx, pickUpSchedule, branch)
+	}
+	if err == nil {
+		pickUpSchedule, err = s.repository.ReschedulePickUpSchedule(ctx, id, reschedule, reservations...)
+	}
+	err = s.conflictOf(ctx, limitOf(err, previous), pickUpSchedule, branch)
+	if isConflict(err) {
+		return conflictPostResponse(err), err
+	}
+	if err != nil {
+		return PostResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to reschedule pick-up schedule: %v", err),
+			Data:      PickUpSchedule{},
+			TotalData: 0,
+		}, err
+	}
+
+	s.publish(ctx, WebhookScheduleUpdated, pickUpSchedule)
+	// Moving away from a book or day may free a copy for the queue
+	s.serveQueueOf(ctx, previous)
+
+	response := PostResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "reschedule pick-up schedule successfully!",
+		TotalData: 1,
+		Data:      pickUpSchedule,
+	}
+
+	return response, nil
+}
+
+func (s *bookService) TransitionPickUpScheduleService(ctx context.Context, id string, transition StatusTransition) (PostResponse, error) {
+	transition.ChangedBy = strings.TrimSpace(transition.ChangedBy)
+
+	var fields []FieldError
+	switch {
+	case !isScheduleStatus(transition.Status):
+		fields = append(fields, FieldError{Field: "status", Message: fmt.Sprintf("must be one of %v", ScheduleStatuses)})
+	case transition.Status == ScheduleStatusOverdue:
+		fields = append(fields, FieldError{Field: "status", Message: "is set by the overdue check once a loan is past its due date"})
+	}
+	if transition.ChangedBy == "" {
+		fields = append(fields, FieldError{Field: "changed_by", Message: "is required"})
+	}
+	if len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return invalidPostResponse(err), err
+	}
+
+	// Staff move schedules along the lifecycle, the repository checks the move
+	// again against the status the schedule has when it is stored
+	pickUpSchedule, err := s.repository.GetPickUpScheduleByID(ctx, id)
+	if err == nil {
+		err = checkTransition(pickUpSchedule.Status, transition.Status)
+	}
+	if err == nil && transition.Status == ScheduleStatusPickedUp {
+		var due time.Time
+		due, err = s.dueAt(ctx, pickUpSchedule)
+		transition.DueAt = &due
+	}
+	if err == nil && transition.Status == ScheduleStatusCancelled {
+		pickUpSchedule, err = s.repository.CancelPickUpSchedule(ctx, id, Cancellation{CancelledBy: transition.ChangedBy, Reason: transition.Reason})
+	} else if err == nil {
+		pickUpSchedule, err = s.repository.TransitionPickUpSchedule(ctx, id, transition)
+	}
+	if err != nil {
+		response := PostResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to change pick-up schedule status: %v", err),
+			Data:      PickUpSchedule{},
+			TotalData: 0,
+		}
+		var transitionErr *TransitionError
+		if errors.As(err, &transitionErr) {
+			response.Errors = []FieldError{{Field: "status", Message: transitionMessage(transitionErr.From)}}
+		}
+		return response, err
+	}
+
+	if pickUpSchedule.Status == ScheduleStatusCancelled {
+		s.publish(ctx, WebhookScheduleCancelled, pickUpSchedule)
+	} else {
+		s.publish(ctx, WebhookScheduleUpdated, pickUpSchedule)
+	}
+	if !holdsReservations(pickUpSchedule.Status) {
+		s.serveQueueOf(ctx, pickUpSchedule)
+	}
+
+	response := PostResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "change pick-up schedule status successfully!",
+		TotalData: 1,
+		Data:      pickUpSchedule,
+	}
+
+	return response, nil
+}
+
+// dueAt returns when the loan of the schedule ends if its book is picked up
+// now. Schedules saved before branches existed count days in UTC.
+func (s *bookService) dueAt(ctx context.Context, schedule PickUpSchedule) (time.Time, error) {
+	var branch Branch
+	loc := time.UTC
+	if schedule.BranchID != "" {
+		var err error
+		if branch, err = s.branches.GetBranch(ctx, schedule.BranchID); err != nil {
+			return time.Time{}, fmt.Errorf("failed to fetch branch: %v", err)
+		}
+		if loc, err = branch.Location(); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return dueAt(s.now(), s.loans.loanDays(branch, schedule.Genre), loc), nil
+}
+
+func (s *bookService) MarkOverdueLoansService(ctx context.Context) (ScheduleListResponse, error) {
+	flagged, err := s.repository.MarkOverdue(ctx, s.now())
+	if err != nil {
+		return ScheduleListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to flag overdue loans: %v", err),
+			Data:      []PickUpSchedule{},
+			TotalData: 0,
+		}, err
+	}
+
+	for _, schedule := range flagged {
+		s.publish(ctx, WebhookScheduleUpdated, schedule)
+	}
+
+	response := ScheduleListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "flag overdue loans successfully!",
+		TotalData: len(flagged),
+		Data:      []PickUpSchedule{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	response.Data = append(response.Data, flagged...)
+
+	return response, nil
+}
+
+func (s *bookService) ListOverdueLoansService(ctx context.Context, query ScheduleQuery) (ScheduleListResponse, error) {
+	// Flag what fell due since the last check, so the list is never behind
+	if response, err := s.MarkOverdueLoansService(ctx); err != nil {
+		return response, err
+	}
+
+	query.Status = ScheduleStatusOverdue
+	if query.Sort == "" {
+		query.Sort = "due_at"
 	}
 
+	response, err := s.ListPickUpSchedulesService(ctx, query)
+	if err != nil {
+		return response, err
+	}
+	response.Message = "fetch overdue loans successfully!"
+
 	return response, nil
 }
+
+// transitionMessage explains which statuses a schedule with the status can
+// move to
+func transitionMessage(from ScheduleStatus) string {
+	// Only the overdue check flags loans overdue
+	var next []ScheduleStatus
+	for _, status := range scheduleTransitions[from] {
+		if status != ScheduleStatusOverdue {
+			next = append(next, status)
+		}
+	}
+	if len(next) == 0 {
+		return fmt.Sprintf("cannot change once the pick-up schedule is %s", from)
+	}
+	return fmt.Sprintf("must be one of %v while the pick-up schedule is %s", next, from)
+}
+
+func (s *bookService) GetPickUpScheduleHistoryService(ctx context.Context, id string) (ScheduleHistoryResponse, error) {
+	history, err := s.repository.GetPickUpScheduleHistory(ctx, id)
+	if err != nil {
+		return ScheduleHistoryResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch pick-up schedule history: %v", err),
+			Data:      []ScheduleChange{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := ScheduleHistoryResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch pick-up schedule history successfully!",
+		TotalData: len(history),
+		Data:      []ScheduleChange{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	response.Data = append(response.Data, history...)
+
+	return response, nil
+}
+
+func (s *bookService) ListSlotsService(ctx context.Context, branchID, day string) (SlotListResponse, error) {
+	var fields []FieldError
+	if strings.TrimSpace(branchID) == "" {
+		fields = append(fields, FieldError{Field: "branch_id", Message: "is required"})
+	}
+	date, err := time.Parse(pickUpDayLayout, day)
+	if err != nil {
+		fields = append(fields, FieldError{Field: "date", Message: "must be a date like 2023-12-01"})
+	}
+	if len(fields) > 0 {
+		validationErr := &ValidationError{Fields: fields}
+		return SlotListResponse{
+			Status:    "400 Bad Request",
+			IsSuccess: false,
+			Message:   validationErr.Error(),
+			Data:      []Slot{},
+			TotalData: 0,
+			Errors:    validationErr.Fields,
+		}, validationErr
+	}
+
+	branch, err := s.branches.GetBranch(ctx, branchID)
+	if err != nil {
+		return SlotListResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch pick-up slots: %v", err),
+			Data:      []Slot{},
+			TotalData: 0,
+		}, err
+	}
+	loc, err := branch.Location()
+	if err != nil {
+		return SlotListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch pick-up slots: %v", err),
+			Data:      []Slot{},
+			TotalData: 0,
+		}, err
+	}
+
+	closures, err := s.closures.ListClosures(ctx, branch.ID)
+	if err != nil {
+		return SlotListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch pick-up slots: %v", err),
+			Data:      []Slot{},
+			TotalData: 0,
+		}, err
+	}
+	closure := closureOn(closures, date)
+
+	// Only the slots the branch is open for can be booked
+	slots := []Slot{}
+	for _, slot := range s.slots.slotsOf(date.Year(), date.Month(), date.Day(), loc) {
+		if closure == nil && branch.isOpen(slot.Start, s.slots.Length) {
+			slots = append(slots, slot)
+		}
+	}
+	keys := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		keys = append(keys, slotKey(branch.ID, slot.Start))
+	}
+
+	counts, err := s.repository.CountReservations(ctx, keys)
+	if err != nil {
+		return SlotListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch pick-up slots: %v", err),
+			Data:      []Slot{},
+			TotalData: 0,
+		}, err
+	}
+
+	for i := range slots {
+		slots[i].Booked = counts[keys[i]]
+		// The capacity may have been lowered after the slot was booked
+		if slots[i].Remaining = slots[i].Capacity - slots[i].Booked; slots[i].Remaining < 0 {
+			slots[i].Remaining = 0
+		}
+	}
+
+	response := SlotListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch pick-up slots successfully!",
+		TotalData: len(slots),
+		Data:      slots,
+		Closure:   closure,
+	}
+
+	return response, nil
+}
+
+func (s *bookService) PlaceHoldService(ctx context.Context, hold Hold) (HoldResponse, error) {
+	// Availability is computed, never taken from the request
+	hold.BookInfo.AvailableCopies = nil
+
+	if err := s.validateHold(ctx, hold); err != nil {
+		response := HoldResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   err.Error(),
+			Data:      Hold{},
+			TotalData: 0,
+		}
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			response.Errors = validationErr.Fields
+		}
+		return response, err
+	}
+
+	created, err := s.holds.CreateHold(ctx, hold)
+	if err != nil {
+		return HoldResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to place hold: %v", err),
+			Data:      Hold{},
+			TotalData: 0,
+		}, err
+	}
+
+	// A copy may be free already, the hold is then offered it right away
+	if _, err := s.serveQueue(ctx, created.BranchID, created.Genre, created.BookInfo); err != nil {
+		log.Printf("failed to serve the hold queue of %s: %v", created.BookInfo.Title, err)
+	}
+	if current, err := s.holds.GetHold(ctx, created.ID); err == nil {
+		created = current
+	}
+
+	response := HoldResponse{
+		Status:    "201 CREATED",
+		IsSuccess: true,
+		Message:   "place hold successfully!",
+		TotalData: 1,
+		Data:      created,
+	}
+
+	return response, nil
+}
+
+func (s *bookService) GetHoldService(ctx context.Context, id string) (HoldResponse, error) {
+	hold, err := s.holds.GetHold(ctx, id)
+	if err != nil {
+		return HoldResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch hold: %v", err),
+			Data:      Hold{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := HoldResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch hold successfully!",
+		TotalData: 1,
+		Data:      hold,
+	}
+
+	return response, nil
+}
+
+func (s *bookService) ConfirmHoldService(ctx context.Context, id string) (HoldResponse, error) {
+	now := s.now()
+	hold, err := s.holds.UpdateHold(ctx, id, func(hold *Hold) error {
+		switch {
+		case hold.Status != HoldStatusOffered:
+			return ErrHoldNotOffered
+		case hold.ExpiresAt != nil && !hold.ExpiresAt.After(now):
+			return ErrHoldExpired
+		}
+		hold.Status = HoldStatusConfirmed
+		return nil
+	})
+	if err != nil {
+		return HoldResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to confirm hold: %v", err),
+			Data:      Hold{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := HoldResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "confirm hold successfully!",
+		TotalData: 1,
+		Data:      hold,
+	}
+
+	return response, nil
+}
+
+func (s *bookService) CancelHoldService(ctx context.Context, id string) (HoldResponse, error) {
+	hold, err := s.holds.UpdateHold(ctx, id, func(hold *Hold) error {
+		if hold.Status != HoldStatusWaiting && hold.Status != HoldStatusOffered {
+			return ErrHoldClosed
+		}
+		hold.Status = HoldStatusCancelled
+		return nil
+	})
+	if err == nil {
+		// An offered copy goes to the next hold in the queue
+		err = s.releaseOffer(ctx, hold, "hold cancelled")
+	}
+	if err != nil {
+		return HoldResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to cancel hold: %v", err),
+			Data:      Hold{},
+			TotalData: 0,
+		}, err
+	}
+	if _, err := s.serveQueue(ctx, hold.BranchID, hold.Genre, hold.BookInfo); err != nil {
+		log.Printf("failed to serve the hold queue of %s: %v", hold.BookInfo.Title, err)
+	}
+
+	response := HoldResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "cancel hold successfully!",
+		TotalData: 1,
+		Data:      hold,
+	}
+
+	return response, nil
+}
+
+func (s *bookService) ProcessHoldsService(ctx context.Context) (HoldListResponse, error) {
+	changed, err := s.processHolds(ctx)
+	if err != nil {
+		return HoldListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to process holds: %v", err),
+			Data:      []Hold{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := HoldListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "process holds successfully!",
+		TotalData: len(changed),
+		Data:      []Hold{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	response.Data = append(response.Data, changed...)
+
+	return response, nil
+}
+
+// processHolds expires the offers past their deadline and then serves every
+// queue with a waiting hold, returning the holds it expired or offered a
+// pick-up schedule
+func (s *bookService) processHolds(ctx context.Context) ([]Hold, error) {
+	now := s.now()
+
+	offered, err := s.holds.ListHolds(ctx, HoldQuery{Status: HoldStatusOffered})
+	if err != nil {
+		return nil, err
+	}
+	var changed []Hold
+	for _, hold := range offered {
+		if hold.ExpiresAt == nil || hold.ExpiresAt.After(now) {
+			continue
+		}
+		expired, err := s.expireHold(ctx, hold, now)
+		if errors.Is(err, ErrHoldNotOffered) {
+			// Confirmed or cancelled in the meantime
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		changed = append(changed, expired)
+	}
+
+	waiting, err := s.holds.ListHolds(ctx, HoldQuery{Status: HoldStatusWaiting})
+	if err != nil {
+		return changed, err
+	}
+	served := make(map[string]bool)
+	for _, hold := range waiting {
+		if served[hold.queueKey()] {
+			continue
+		}
+		served[hold.queueKey()] = true
+
+		holds, err := s.serveQueue(ctx, hold.BranchID, hold.Genre, hold.BookInfo)
+		if err != nil {
+			return changed, err
+		}
+		changed = append(changed, holds...)
+	}
+
+	return changed, nil
+}
+
+// validateHold checks the book, branch and borrower of a hold the way a
+// submitted schedule is checked, and that the branch stocks the book at all
+func (s *bookService) validateHold(ctx context.Context, hold Hold) error {
+	var fields []FieldError
+	if strings.TrimSpace(hold.Genre) == "" {
+		fields = append(fields, FieldError{Field: "genre", Message: "is required"})
+	}
+	if strings.TrimSpace(hold.BookInfo.Title) == "" {
+		fields = append(fields, FieldError{Field: "book_info.title", Message: "is required"})
+	}
+
+	field, err := s.checkBorrower(ctx, hold.BorrowerID)
+	if err != nil {
+		return err
+	}
+	if field != nil {
+		fields = append(fields, *field)
+	}
+
+	if strings.TrimSpace(hold.BranchID) == "" {
+		fields = append(fields, FieldError{Field: "branch_id", Message: "is required"})
+	} else {
+		_, err := s.branches.GetBranch(ctx, hold.BranchID)
+		switch {
+		case errors.Is(err, ErrBranchNotFound):
+			fields = append(fields, FieldError{Field: "branch_id", Message: "does not match a branch"})
+		case err != nil:
+			return err
+		case len(fields) == 0:
+			// Without any copy the queue would never move
+			copies, err := s.branches.CountCopies(ctx, hold.BranchID, hold.Genre, hold.BookInfo)
+			if err != nil {
+				return err
+			}
+			if copies <= 0 {
+				fields = append(fields, FieldError{Field: "book_info", Message: "is not stocked at the branch"})
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// reservationsOf returns what a valid schedule at the branch has to claim to
+// be stored: a place in its slot, a copy of its book for the local day, one of
+// the active loans and of the weekly pick-ups of its borrower, and the book on
+// the day and the time of its slot of the borrower, so that limits, duplicates
+// and overlaps hold for schedules saved at the same moment as well. It fails with a *CapacityError when the branch
+// has no copy of the book at all.
+func (s *bookService) reservationsOf(ctx context.Context, schedule PickUpSchedule, branch Branch) ([]Reservation, error) {
+	loc, err := branch.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	copies, err := s.branches.CountCopies(ctx, branch.ID, schedule.Genre, schedule.BookInfo)
+	if err != nil {
+		return nil, err
+	}
+	day := schedule.PickUpDate.Time().In(loc).Format(pickUpDayLayout)
+	week, _ := weekOf(schedule.PickUpDate.Time(), loc)
+	// Copies on loan are taken on the day as well, the loan is claimed now
+	// and held once the book is picked up
+	loaned := loanedCopyKey(branch.ID, schedule.Genre, schedule.BookInfo)
+	bookCopy := Reservation{Key: copyKey(branch.ID, schedule.Genre, schedule.BookInfo, day), Limit: copies, Shared: []string{loaned}}
+	// A limit of zero would not limit the reservation at all
+	if copies <= 0 {
+		return nil, &CapacityError{Key: bookCopy.Key, Limit: 0}
+	}
+
+	reservations := []Reservation{
+		{Key: slotKey(branch.ID, schedule.PickUpDate.Time()), Limit: s.slots.Capacity},
+		bookCopy,
+		{Key: loaned},
+	}
+	if schedule.BorrowerID != "" {
+		reservations = append(reservations,
+			Reservation{Key: loanKey(schedule.BorrowerID), Limit: s.limits.MaxActiveLoans},
+			Reservation{Key: weekKey(schedule.BorrowerID, week), Limit: s.limits.MaxPickUpsPerWeek},
+			Reservation{Key: pickUpDayKey(schedule.BorrowerID, schedule.Genre, schedule.BookInfo, day), Limit: 1},
+		)
+		// Pick-ups saved without a time have no slot to overlap
+		if schedule.PickUpDate.HasTime() {
+			reservations = append(reservations, s.slots.pickUpTimeReservations(schedule.BorrowerID, branch.ID, schedule.PickUpDate.Time())...)
+		}
+	}
+
+	return reservations, nil
+}
+
+// checkWeeklyPickUps fails with a *LimitError when the borrower of the
+// schedule already has as many pick-ups in its week as allowed, not counting
+// the schedule itself. Returned and missed pick-ups still count, only
+// cancelled ones do not, and so do the schedules of batch saved along with a
+// new one.
+func (s *bookService) checkWeeklyPickUps(ctx context.Context, schedule PickUpSchedule, branch Branch, batch ...PickUpSchedule) error {
+	max := s.limits.MaxPickUpsPerWeek
+	if max <= 0 || schedule.BorrowerID == "" {
+		return nil
+	}
+
+	loc, err := branch.Location()
+	if err != nil {
+		return err
+	}
+	start, end := weekOf(schedule.PickUpDate.Time(), loc)
+
+	schedules, err := s.borrowerSchedules(ctx, schedule.BorrowerID, start, end, batch...)
+	if err != nil {
+		return fmt.Errorf("failed to count pick-ups: %v", err)
+	}
+	count := 0
+	for _, other := range schedules {
+		if (other.ID == "" || other.ID != schedule.ID) && other.Status != ScheduleStatusCancelled {
+			count++
+		}
+	}
+
+	if count >= max {
+		return &LimitError{BorrowerID: schedule.BorrowerID, Limit: limitPickUpsPerWeek, Max: max, Week: start.Format(pickUpDayLayout)}
+	}
+	return nil
+}
+
+// pickUpDaysAround returns the local pick-up days a ScheduleQuery has to
+// cover to find every pick-up from start until end at branches in any time
+// zone. A local day is never more than a day away from the UTC one.
+func pickUpDaysAround(start, end time.Time) (string, string) {
+	return start.UTC().AddDate(0, 0, -1).Format(pickUpDayLayout), end.UTC().AddDate(0, 0, 1).Format(pickUpDayLayout)
+}
+
+// borrowerSchedules returns every schedule of the borrower picked up from
+// start until end, among the stored ones and the ones of batch
+func (s *bookService) borrowerSchedules(ctx context.Context, borrowerID string, start, end time.Time, batch ...PickUpSchedule) ([]PickUpSchedule, error) {
+	// Pick-up days are filtered roughly, the exact times are checked below
+	from, to := pickUpDaysAround(start, end)
+	all, err := s.allSchedules(ctx, ScheduleQuery{
+		BorrowerID: borrowerID,
+		PickUpFrom: from,
+		PickUpTo:   to,
+		Sort:       "pick_up_date",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules []PickUpSchedule
+	for _, schedule := range append(all, batch...) {
+		at := schedule.PickUpDate.Time()
+		if schedule.BorrowerID == borrowerID && !at.Before(start) && at.Before(end) {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules, nil
+}
+
+// limitOf returns the *LimitError a full loan or week reservation of the
+// borrower of the schedule stands for, and any other error as it is
+func limitOf(err error, schedule PickUpSchedule) error {
+	var capacityErr *CapacityError
+	if schedule.BorrowerID == "" || !errors.As(err, &capacityErr) {
+		return err
+	}
+
+	if capacityErr.Key == loanKey(schedule.BorrowerID) {
+		return &LimitError{BorrowerID: schedule.BorrowerID, Limit: limitActiveLoans, Max: capacityErr.Limit}
+	}
+	if week := strings.TrimPrefix(capacityErr.Key, weekKeyPrefix+schedule.BorrowerID+":"); week != capacityErr.Key {
+		return &LimitError{BorrowerID: schedule.BorrowerID, Limit: limitPickUpsPerWeek, Max: capacityErr.Limit, Week: week}
+	}
+	return err
+}
+
+// availableCopies returns how many copies of each book of the genre are free
+// today across every branch by bookKey, where today is the local day of each
+// branch
+func (s *bookService) availableCopies(ctx context.Context, genre string) (map[string]int, error) {
+	items, err := s.branches.ListInventory(ctx, "", genre)
+	if err != nil {
+		return nil, err
+	}
+	branches, err := s.branches.ListBranches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	locations := make(map[string]*time.Location, len(branches))
+	for _, branch := range branches {
+		// A branch with an unknown time zone cannot be booked at all
+		if loc, err := branch.Location(); err == nil {
+			locations[branch.ID] = loc
+		}
+	}
+
+	now := s.now()
+	keys := make([]string, len(items))
+	loaned := make([]string, len(items))
+	for i, item := range items {
+		if loc, exists := locations[item.BranchID]; exists {
+			keys[i] = copyKey(item.BranchID, item.Genre, item.Book, now.In(loc).Format(pickUpDayLayout))
+			loaned[i] = loanedCopyKey(item.BranchID, item.Genre, item.Book)
+		}
+	}
+
+	counts, err := s.repository.CountReservations(ctx, append(append([]string(nil), keys...), loaned...))
+	if err != nil {
+		return nil, err
+	}
+
+	available := make(map[string]int)
+	for i, item := range items {
+		if keys[i] == "" {
+			continue
+		}
+		// The copies may have been lowered after they were booked
+		if free := item.Copies - counts[keys[i]] - counts[loaned[i]]; free > 0 {
+			available[bookKey(genre, item.Book)] += free
+		}
+	}
+
+	return available, nil
+}
+
+// validatePickUpSchedule checks the rules every submitted or rescheduled
+// schedule has to follow and returns its branch. A pick-up time given without
+// an offset is read as local time at the branch, and the schedule is given the
+// time zone of the branch. Rejected fields are returned as a
+// *ValidationError, any other error means the branch could not be loaded.
+func (s *bookService) validatePickUpSchedule(ctx context.Context, schedule *PickUpSchedule) (Branch, error) {
+	var fields []FieldError
+	if strings.TrimSpace(schedule.Genre) == "" {
+		fields = append(fields, FieldError{Field: "genre", Message: "is required"})
+	}
+	if strings.TrimSpace(schedule.BookInfo.Title) == "" {
+		fields = append(fields, FieldError{Field: "book_info.title", Message: "is required"})
+	}
+
+	var branch *Branch
+	if strings.TrimSpace(schedule.BranchID) == "" {
+		fields = append(fields, FieldError{Field: "branch_id", Message: "is required"})
+	} else {
+		found, err := s.branches.GetBranch(ctx, schedule.BranchID)
+		switch {
+		case errors.Is(err, ErrBranchNotFound):
+			fields = append(fields, FieldError{Field: "branch_id", Message: "does not match a branch"})
+		case err != nil:
+			return Branch{}, err
+		default:
+			branch = &found
+		}
+	}
+
+	message := ""
+	if branch != nil {
+		// An unknown time zone is reported by checkPickUpDate
+		if loc, err := branch.Location(); err == nil {
+			if schedule.PickUpDate, err = schedule.PickUpDate.In(loc); err != nil {
+				message = fmt.Sprintf("is skipped by a daylight saving time change in %s", loc)
+			}
+			schedule.Timezone = branch.Timezone
+		}
+	}
+	if message == "" {
+		message = s.checkPickUpDate(schedule.PickUpDate, branch)
+	}
+	var nextOpenDate string
+	if message == "" && branch != nil {
+		var err error
+		if message, nextOpenDate, err = s.checkClosures(ctx, schedule.PickUpDate.Time(), *branch); err != nil {
+			return Branch{}, err
+		}
+	}
+	if message != "" {
+		fields = append(fields, FieldError{Field: "pick_up_date", Message: message})
+	}
+
+	field, err := s.checkBorrower(ctx, schedule.BorrowerID)
+	if err != nil {
+		return Branch{}, err
+	}
+	if field != nil {
+		fields = append(fields, *field)
+	}
+
+	if len(fields) > 0 {
+		return Branch{}, &ValidationError{Fields: fields, NextOpenDate: nextOpenDate}
+	}
+	return *branch, nil
+}
+
+// checkBorrower returns the field error of a missing or unknown borrower, or
+// nil when the borrower exists
+func (s *bookService) checkBorrower(ctx context.Context, id string) (*FieldError, error) {
+	if strings.TrimSpace(id) == "" {
+		return &FieldError{Field: "borrower_id", Message: "is required"}, nil
+	}
+
+	_, err := s.borrowers.GetBorrower(ctx, id)
+	switch {
+	case errors.Is(err, ErrBorrowerNotFound):
+		return &FieldError{Field: "borrower_id", Message: "does not match a borrower"}, nil
+	case err != nil:
+		return nil, err
+	default:
+		return nil, nil
+	}
+}
+
+// checkPickUpDate returns why the date cannot be booked at the branch, or ""
+// when it can. Without a branch only the rules that do not depend on it are
+// checked, slots then start at midnight UTC.
+func (s *bookService) checkPickUpDate(date PickUpDate, branch *Branch) string {
+	loc := time.UTC
+	if branch != nil {
+		var err error
+		if loc, err = branch.Location(); err != nil {
+			return "cannot be booked at a branch with an unknown time zone"
+		}
+	}
+
+	switch {
+	case date.IsZero():
+		return "is required"
+	case date.Err() != nil:
+		return "must be an ISO 8601 date and time, like 2023-12-01T10:30:00+07:00"
+	case !date.HasTime():
+		return "must include the time of a pick-up slot, like 2023-12-01T10:30:00Z"
+	case !s.slots.startsSlot(date.Time(), loc):
+		return fmt.Sprintf("must be the start of a %d-minute pick-up slot", s.slots.Length/time.Minute)
+	}
+
+	now := s.now().UTC()
+	latest := now.Add(s.bookingHorizon)
+
+	switch {
+	case date.Time().Before(now):
+		return "must not be in the past"
+	case date.Time().After(latest):
+		return fmt.Sprintf("must not be after %s", latest.Format(pickUpTimeLayout))
+	case branch != nil && !branch.isOpen(date.Time(), s.slots.Length):
+		return fmt.Sprintf("must be within the opening hours of %s", branch.Name)
+	default:
+		return ""
+	}
+}
+
+// checkClosures returns why the branch takes no pick-ups on the local day of
+// at and the next day it does, or "" when it is not closed that day
+func (s *bookService) checkClosures(ctx context.Context, at time.Time, branch Branch) (string, string, error) {
+	loc, err := branch.Location()
+	if err != nil {
+		return "", "", err
+	}
+	closures, err := s.closures.ListClosures(ctx, branch.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch closures: %v", err)
+	}
+
+	day := at.In(loc)
+	closure := closureOn(closures, day)
+	if closure == nil {
+		return "", "", nil
+	}
+
+	message := fmt.Sprintf("falls on a day %s is closed", branch.Name)
+	if closure.Reason != "" {
+		message += fmt.Sprintf(" (%s)", closure.Reason)
+	}
+	next := s.nextOpenDate(branch, closures, day)
+	if next != "" {
+		message += fmt.Sprintf(", the next open date is %s", next)
+	}
+	return message, next, nil
+}
+
+// nextOpenDate returns the first day after day, in its location, that the
+// branch is not closed on and has a slot it is open for, or "" when there is
+// none within a year
+func (s *bookService) nextOpenDate(branch Branch, closures []Closure, day time.Time) string {
+	for i := 1; i <= 366; i++ {
+		next := time.Date(day.Year(), day.Month(), day.Day()+i, 0, 0, 0, 0, day.Location())
+		if closureOn(closures, next) != nil {
+			continue
+		}
+		for _, slot := range s.slots.slotsOf(next.Year(), next.Month(), next.Day(), day.Location()) {
+			if branch.isOpen(slot.Start, s.slots.Length) {
+				return next.Format(pickUpDayLayout)
+			}
+		}
+	}
+	return ""
+}
+
+// invalidPostResponse explains why a schedule was rejected before reaching the
+// repository
+func invalidPostResponse(err error) PostResponse {
+	response := PostResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   err.Error(),
+		Data:      PickUpSchedule{},
+		TotalData: 0,
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Errors = validationErr.Fields
+		response.NextOpenDate = validationErr.NextOpenDate
+	}
+
+	return response
+}
+
+// isConflict reports whether a schedule could not be saved because of a
+// capacity, a borrowing limit or another schedule of the borrower
+func isConflict(err error) bool {
+	return errors.Is(err, ErrCapacityExceeded) || errors.Is(err, ErrBorrowingLimit) ||
+		errors.Is(err, ErrDuplicateSchedule) || errors.Is(err, ErrScheduleConflict)
+}
+
+// conflictPostResponse explains which capacity, borrowing limit or schedule
+// of the borrower a schedule ran into
+func conflictPostResponse(err error) PostResponse {
+	response := PostResponse{
+		Status:    "409 Conflict",
+		IsSuccess: false,
+		Message:   err.Error(),
+		Data:      PickUpSchedule{},
+		TotalData: 0,
+	}
+
+	var capacityErr *CapacityError
+	var limitErr *LimitError
+	var conflictErr *ScheduleConflictError
+	switch {
+	case errors.As(err, &conflictErr) && conflictErr.Duplicate:
+		response.ExistingScheduleID = conflictErr.Existing.ID
+		response.Errors = []FieldError{{Field: "book_info", Message: fmt.Sprintf("is already booked by the borrower on that day in pick-up schedule %s", conflictErr.Existing.ID)}}
+	case errors.As(err, &conflictErr):
+		response.ExistingScheduleID = conflictErr.Existing.ID
+		response.Errors = []FieldError{{Field: "pick_up_date", Message: fmt.Sprintf("overlaps pick-up schedule %s of the borrower at another branch", conflictErr.Existing.ID)}}
+	case errors.As(err, &limitErr) && limitErr.Week != "":
+		response.Errors = []FieldError{{Field: "borrower_id", Message: fmt.Sprintf("already has %d pick-ups in the week of %s, the most allowed", limitErr.Max, limitErr.Week)}}
+	case errors.As(err, &limitErr):
+		response.Errors = []FieldError{{Field: "borrower_id", Message: fmt.Sprintf("already has %d active loans, the most allowed", limitErr.Max)}}
+	case !errors.As(err, &capacityErr):
+	case strings.HasPrefix(capacityErr.Key, "slot:"):
+		response.Message = "the pick-up slot is fully booked"
+		response.Errors = []FieldError{{Field: "pick_up_date", Message: "has no free place left in its slot"}}
+	case strings.HasPrefix(capacityErr.Key, "copy:"):
+		response.Message = "no copy of the book is free at the branch on that day"
+		response.Errors = []FieldError{{Field: "book_info", Message: "has no free copy at the branch on the pick-up day"}}
+	}
+
+	return response
+}
+
+// statusCodeOf maps errors returned by validation or the repository to an
+// HTTP status code. It is the only such mapping, statusOf derives from it.
+func statusCodeOf(err error) int {
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrScheduleNotFound), errors.Is(err, ErrBranchNotFound), errors.Is(err, ErrHoldNotFound),
+		errors.Is(err, ErrBorrowerNotFound), errors.Is(err, ErrClosureNotFound), errors.Is(err, ErrWebhookNotFound),
+		errors.Is(err, ErrWebhookDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidScheduleQuery), errors.Is(err, ErrInvalidCancellation), errors.Is(err, ErrInvalidIdempotencyKey):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrScheduleCancelled), errors.Is(err, ErrScheduleNotPending),
+		errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrCapacityExceeded),
+		errors.Is(err, ErrHoldNotOffered), errors.Is(err, ErrHoldExpired), errors.Is(err, ErrHoldClosed),
+		errors.Is(err, ErrBorrowingLimit), errors.Is(err, ErrDuplicateSchedule), errors.Is(err, ErrScheduleConflict),
+		errors.Is(err, ErrIdempotencyKeyInFlight), errors.Is(err, ErrWebhookDeliveryPending):
+		return http.StatusConflict
+	case errors.Is(err, ErrWebhookDeliveryFailed):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// statusOf is the response status for an error returned by validation or the
+// repository, like "404 Not Found"
+func statusOf(err error) string {
+	code := statusCodeOf(err)
+	return fmt.Sprintf("%d %s", code, http.StatusText(code))
+}
+
+// normalizeScheduleQuery validates the query and fills in the default limit
+func normalizeScheduleQuery(query ScheduleQuery) (ScheduleQuery, error) {
+	if query.Status != "" && !isScheduleStatus(query.Status) {
+		return query, fmt.Errorf("%w: status must be one of %v", ErrInvalidScheduleQuery, ScheduleStatuses)
+	}
+
+	field, _ := parseScheduleSort(query.Sort)
+	known := false
+	for _, sortField := range ScheduleSortFields {
+		known = known || sortField == field
+	}
+	if !known {
+		return query, fmt.Errorf("%w: sort must be one of %v, optionally prefixed with -", ErrInvalidScheduleQuery, ScheduleSortFields)
+	}
+
+	for name, value := range map[string]string{"pick_up_from": query.PickUpFrom, "pick_up_to": query.PickUpTo} {
+		if _, err := time.Parse("2006-01-02", value); value != "" && err != nil {
+			return query, fmt.Errorf("%w: %s must be a date like 2006-01-02", ErrInvalidScheduleQuery, name)
+		}
+	}
+
+	switch {
+	case query.Limit == 0:
+		query.Limit = defaultScheduleLimit
+	case query.Limit < 0 || query.Limit > maxScheduleLimit:
+		return query, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidScheduleQuery, maxScheduleLimit)
+	}
+
+	return query, nil
+}
+
+func isScheduleStatus(status ScheduleStatus) bool {
+	for _, known := range ScheduleStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
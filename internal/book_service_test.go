@@ -2,8 +2,14 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 type mockRepository struct {
@@ -12,16 +18,103 @@ type mockRepository struct {
 	getBooksByGenreError       error
 	savePickUpScheduleResponse []PickUpSchedule
 	savePickUpScheduleError    error
+	getPickUpScheduleResponse  PickUpSchedule
+	getPickUpScheduleError     error
+	listPickUpSchedulesPage    SchedulePage
+	listPickUpSchedulesError   error
+	listPickUpSchedulesQuery   ScheduleQuery
+	cancelPickUpScheduleError  error
+	cancelPickUpScheduleInput  Cancellation
+	rescheduleError            error
+	rescheduleInput            *Reschedule
+	transitionError            error
+	transitionInput            *StatusTransition
+	markOverdueResponse        []PickUpSchedule
+	markOverdueError           error
+	markOverdueNow             time.Time
+	historyResponse            []ScheduleChange
+	historyError               error
+	savedReservations          []Reservation
+	rescheduledReservations    []Reservation
+	reservationCounts          map[string]int
+	countReservationsError     error
 }
 
 func (m *mockRepository) GetBooksByGenre(ctx context.Context, genre string) ([]Book, []PickUpSchedule, error) {
 	return m.getBooksByGenreResponse, m.getPickUpSchedulesResponse, m.getBooksByGenreError
 }
 
-func (m *mockRepository) SavePickUpSchedule(schedule PickUpSchedule) ([]PickUpSchedule, error) {
+func (m *mockRepository) SavePickUpSchedule(_ context.Context, schedule PickUpSchedule, reservations ...Reservation) ([]PickUpSchedule, error) {
+	m.savedReservations = reservations
 	return m.savePickUpScheduleResponse, m.savePickUpScheduleError
 }
 
+func (m *mockRepository) SavePickUpSchedules(ctx context.Context, schedules []PickUpSchedule, reservations [][]Reservation) ([]PickUpSchedule, error) {
+	if m.savePickUpScheduleError != nil {
+		return nil, m.savePickUpScheduleError
+	}
+	return schedules, nil
+}
+
+func (m *mockRepository) GetPickUpScheduleByID(ctx context.Context, id string) (PickUpSchedule, error) {
+	return m.getPickUpScheduleResponse, m.getPickUpScheduleError
+}
+
+func (m *mockRepository) CancelPickUpSchedule(ctx context.Context, id string, cancellation Cancellation) (PickUpSchedule, error) {
+	m.cancelPickUpScheduleInput = cancellation
+	if m.cancelPickUpScheduleError != nil {
+		return PickUpSchedule{}, m.cancelPickUpScheduleError
+	}
+	return PickUpSchedule{ID: id, Status: ScheduleStatusCancelled, Cancellation: &cancellation}, nil
+}
+
+func (m *mockRepository) ReschedulePickUpSchedule(ctx context.Context, id string, reschedule Reschedule, reservations ...Reservation) (PickUpSchedule, error) {
+	m.rescheduleInput = &reschedule
+	m.rescheduledReservations = reservations
+	if m.rescheduleError != nil {
+		return PickUpSchedule{}, m.rescheduleError
+	}
+	schedule := m.getPickUpScheduleResponse
+	applyReschedule(&schedule, reschedule, time.Now())
+	return schedule, nil
+}
+
+func (m *mockRepository) TransitionPickUpSchedule(ctx context.Context, id string, transition StatusTransition) (PickUpSchedule, error) {
+	m.transitionInput = &transition
+	if m.transitionError != nil {
+		return PickUpSchedule{}, m.transitionError
+	}
+	schedule := m.getPickUpScheduleResponse
+	schedule.Status = transition.Status
+	return schedule, nil
+}
+
+func (m *mockRepository) MarkOverdue(ctx context.Context, now time.Time) ([]PickUpSchedule, error) {
+	m.markOverdueNow = now
+	return m.markOverdueResponse, m.markOverdueError
+}
+
+func (m *mockRepository) GetPickUpScheduleHistory(ctx context.Context, id string) ([]ScheduleChange, error) {
+	return m.historyResponse, m.historyError
+}
+
+func (m *mockRepository) CountReservations(ctx context.Context, keys []string) (map[string]int, error) {
+	return m.reservationCounts, m.countReservationsError
+}
+
+func (m *mockRepository) ListPickUpSchedules(ctx context.Context, query ScheduleQuery) (SchedulePage, error) {
+	m.listPickUpSchedulesQuery = query
+	return m.listPickUpSchedulesPage, m.listPickUpSchedulesError
+}
+
+// serviceNow is the current time of the services under test, so the pick-up
+// dates of the tests never fall into the past
+var serviceNow = time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)
+
+func serviceClock() time.Time {
+	return serviceNow
+}
+
 func TestBookService_GetBooksByGenreService(t *testing.T) {
 	// Positive case: Books exist in the cache
 	mockRepo := &mockRepository{
@@ -46,7 +139,8 @@ func TestBookService_GetBooksByGenreService(t *testing.T) {
 		},
 	}
 
-	service := NewService(mockRepo)
+	branches := newMockBranches(testBranch).stock(2, "fiction", Book{Title: "MockBook1"}, Book{Title: "PickUpBook1", Author: []string{"Author1"}, EditionNumber: 1})
+	service := NewService(mockRepo, branches, newMockBorrowers(testBorrower), WithClock(serviceClock))
 
 	t.Run("PositiveCase_CacheHit_WithPickUpSchedules", func(t *testing.T) {
 		// Perform the test
@@ -82,6 +176,39 @@ func TestBookService_GetBooksByGenreService(t *testing.T) {
 		}
 	})
 
+	t.Run("PositiveCase_AvailableCopies", func(t *testing.T) {
+		mockRepo.reservationCounts = map[string]int{copyKey("central", "fiction", Book{Title: "MockBook1"}, "2023-11-20"): 1}
+		defer func() {
+			mockRepo.reservationCounts = nil
+		}()
+
+		response, err := service.GetBooksByGenreService(context.Background(), "fiction")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for i, want := range []int{1, 0, 2, 0} {
+			if got := response.Data[i].AvailableCopies; got == nil || *got != want {
+				t.Errorf("Expected %d available copies of %s, got %v", want, response.Data[i].Title, got)
+			}
+		}
+	})
+
+	t.Run("NegativeCase_InventoryFailure", func(t *testing.T) {
+		branches.listInventoryError = fmt.Errorf("database is down")
+		defer func() {
+			branches.listInventoryError = nil
+		}()
+
+		response, err := service.GetBooksByGenreService(context.Background(), "fiction")
+		if err == nil {
+			t.Error("Expected error, but got nil")
+		}
+		if response.Status != "500 Internal Server Error" {
+			t.Errorf("Expected 500 failure, got %q", response.Status)
+		}
+	})
+
 	// Negative case: Books do not exist in the cache, API request fails
 	mockRepo.getBooksByGenreError = fmt.Errorf("API request failed")
 	t.Run("NegativeCase_CacheMiss_APIFailure", func(t *testing.T) {
@@ -102,13 +229,18 @@ func TestBookService_SubmitPickUpScheduleService(t *testing.T) {
 	mockRepo := &mockRepository{
 		savePickUpScheduleResponse: []PickUpSchedule{{BookInfo: Book{Title: "MockBook"}}},
 	}
+	testBook := Book{Title: "TestBook", Author: []string{"TestAuthor"}, EditionNumber: 1}
+	branches := newMockBranches(testBranch).stock(2, "fiction", testBook, Book{Title: "TestBook"})
 
-	service := NewService(mockRepo)
+	service := NewService(mockRepo, branches, newMockBorrowers(testBorrower), WithClock(serviceClock))
 
 	t.Run("PositiveCase", func(t *testing.T) {
 		// Create a pick-up schedule
 		schedule := PickUpSchedule{
-			Genre: "fiction",
+			Genre:      "fiction",
+			BranchID:   "central",
+			BorrowerID: "reader",
+			PickUpDate: mustParsePickUpDate("2023-12-01T10:00:00Z"),
 			BookInfo: Book{
 				Title:         "TestBook",
 				Author:        []string{"TestAuthor"},
@@ -117,7 +249,7 @@ func TestBookService_SubmitPickUpScheduleService(t *testing.T) {
 		}
 
 		// Perform the test
-		response, err := service.SubmitPickUpScheduleService(schedule)
+		response, err := service.SubmitPickUpScheduleService(context.Background(), schedule)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -130,6 +262,90 @@ func TestBookService_SubmitPickUpScheduleService(t *testing.T) {
 		if len(response.Data.BookInfo.Title) == 0 {
 			t.Errorf("Expected non-empty title, got empty")
 		}
+
+		want := []Reservation{
+			{Key: "slot:central:2023-12-01T10:00:00Z", Limit: DefaultSlotConfig.Capacity},
+			{Key: copyKey("central", "fiction", testBook, "2023-12-01"), Limit: 2, Shared: []string{loanedCopyKey("central", "fiction", testBook)}},
+			{Key: loanedCopyKey("central", "fiction", testBook)},
+			{Key: "loan:reader", Limit: DefaultBorrowerLimits.MaxActiveLoans},
+			{Key: "week:reader:2023-11-27", Limit: DefaultBorrowerLimits.MaxPickUpsPerWeek},
+			{Key: pickUpDayKey("reader", "fiction", testBook, "2023-12-01"), Limit: 1},
+			{Key: "pick-up-time:reader:2023-12-01T10:00:00Z", Limit: 1, Excluded: []string{"pick-up-time:reader:2023-12-01T10:00:00Z:central"}},
+			{Key: "pick-up-time:reader:2023-12-01T10:00:00Z:central"},
+			{Key: "pick-up-time:reader:2023-12-01T10:15:00Z", Limit: 1, Excluded: []string{"pick-up-time:reader:2023-12-01T10:15:00Z:central"}},
+			{Key: "pick-up-time:reader:2023-12-01T10:15:00Z:central"},
+		}
+		if !reflect.DeepEqual(mockRepo.savedReservations, want) {
+			t.Errorf("Expected the slot, a copy, a loan, the week and the pick-up time of the borrower to be reserved, got %v", mockRepo.savedReservations)
+		}
+	})
+
+	t.Run("NegativeCase_BookNotStocked", func(t *testing.T) {
+		mockRepo.savedReservations = nil
+
+		response, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: Book{Title: "OtherBook"}, PickUpDate: mustParsePickUpDate("2023-12-01T10:00:00Z")})
+		if !errors.Is(err, ErrCapacityExceeded) {
+			t.Errorf("Expected ErrCapacityExceeded, got %v", err)
+		}
+
+		if response.Status != "409 Conflict" || len(response.Errors) != 1 || response.Errors[0].Field != "book_info" {
+			t.Errorf("Expected 409 failure pointing at book_info, got %+v", response)
+		}
+		if mockRepo.savedReservations != nil {
+			t.Error("Expected the schedule not to reach the repository")
+		}
+	})
+
+	t.Run("NegativeCase_NoFreeCopy", func(t *testing.T) {
+		mockRepo := &mockRepository{
+			savePickUpScheduleError: &CapacityError{Key: copyKey("central", "fiction", testBook, "2023-12-01"), Limit: 2},
+		}
+		service := NewService(mockRepo, branches, newMockBorrowers(testBorrower), WithClock(serviceClock))
+
+		response, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: testBook, PickUpDate: mustParsePickUpDate("2023-12-01T10:00:00Z")})
+		if !errors.Is(err, ErrCapacityExceeded) {
+			t.Errorf("Expected ErrCapacityExceeded, got %v", err)
+		}
+
+		if response.Status != "409 Conflict" || len(response.Errors) != 1 || response.Errors[0].Field != "book_info" {
+			t.Errorf("Expected 409 failure pointing at book_info, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_SlotFull", func(t *testing.T) {
+		mockRepo := &mockRepository{
+			savePickUpScheduleError: &CapacityError{Key: "slot:central:2023-12-01T10:00:00Z", Limit: 5},
+		}
+		service := NewService(mockRepo, branches, newMockBorrowers(testBorrower), WithClock(serviceClock))
+
+		response, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: Book{Title: "TestBook"}, PickUpDate: mustParsePickUpDate("2023-12-01T10:00:00Z")})
+		if !errors.Is(err, ErrCapacityExceeded) {
+			t.Errorf("Expected ErrCapacityExceeded, got %v", err)
+		}
+
+		if response.Status != "409 Conflict" || len(response.Errors) != 1 || response.Errors[0].Field != "pick_up_date" {
+			t.Errorf("Expected 409 failure pointing at pick_up_date, got %+v", response)
+		}
+	})
+
+	t.Run("PositiveCase_ReturnsSavedSchedule", func(t *testing.T) {
+		// The repository returns every schedule of the genre, the new one last
+		mockRepo := &mockRepository{
+			savePickUpScheduleResponse: []PickUpSchedule{
+				{ID: "older", BookInfo: Book{Title: "OlderBook"}},
+				{ID: "saved", BookInfo: Book{Title: "TestBook"}},
+			},
+		}
+		service := NewService(mockRepo, branches, newMockBorrowers(testBorrower), WithClock(serviceClock))
+
+		response, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: Book{Title: "TestBook"}, PickUpDate: mustParsePickUpDate("2023-12-01T10:00:00Z")})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.Data.ID != "saved" {
+			t.Errorf("Expected the saved schedule in the response, got %q", response.Data.ID)
+		}
 	})
 
 	t.Run("NegativeCase_SaveFailure", func(t *testing.T) {
@@ -138,7 +354,10 @@ func TestBookService_SubmitPickUpScheduleService(t *testing.T) {
 
 		// Create a pick-up schedule
 		schedule := PickUpSchedule{
-			Genre: "fiction",
+			Genre:      "fiction",
+			BranchID:   "central",
+			BorrowerID: "reader",
+			PickUpDate: mustParsePickUpDate("2023-12-01T10:00:00Z"),
 			BookInfo: Book{
 				Title:         "TestBook",
 				Author:        []string{"TestAuthor"},
@@ -147,7 +366,7 @@ func TestBookService_SubmitPickUpScheduleService(t *testing.T) {
 		}
 
 		// Perform the test
-		response, err := service.SubmitPickUpScheduleService(schedule)
+		response, err := service.SubmitPickUpScheduleService(context.Background(), schedule)
 		if err == nil {
 			t.Error("Expected error, but got nil")
 		}
@@ -158,3 +377,1090 @@ func TestBookService_SubmitPickUpScheduleService(t *testing.T) {
 		}
 	})
 }
+
+func TestBookService_SubmitPickUpScheduleService_Validation(t *testing.T) {
+	mockRepo := &mockRepository{
+		savePickUpScheduleResponse: []PickUpSchedule{{BookInfo: Book{Title: "MockBook"}}},
+	}
+	branches := newMockBranches(testBranch).stock(1, "fiction", Book{Title: "TestBook"})
+	service := NewService(mockRepo, branches, newMockBorrowers(testBorrower), WithClock(serviceClock), WithBookingHorizon(30*24*time.Hour))
+
+	t.Run("NegativeCase_MissingFields", func(t *testing.T) {
+		response, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{BookInfo: Book{Title: " "}})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ValidationError, got %v", err)
+		}
+		if response.Status != "400 Bad Request" || len(response.Errors) != 5 {
+			t.Fatalf("Expected 400 failure with five field errors, got %+v", response)
+		}
+		for i, field := range []string{"genre", "book_info.title", "branch_id", "pick_up_date", "borrower_id"} {
+			if response.Errors[i].Field != field {
+				t.Errorf("Expected %s error, got %v", field, response.Errors)
+			}
+		}
+	})
+
+	t.Run("PickUpDates", func(t *testing.T) {
+		for date, wantMessage := range map[string]string{
+			"2023-11-20T08:00:00Z":      "",
+			"2023-11-20T15:30:00+07:00": "",
+			"2023-12-20T08:00:00Z":      "",
+			"2023-11-20T07:30:00Z":      "must not be in the past",
+			"2023-12-20T08:30:00Z":      "must not be after 2023-12-20T08:00:00Z",
+			"2023-12-01":                "must include the time of a pick-up slot, like 2023-12-01T10:30:00Z",
+			"2023-12-01T10:15:00Z":      "must be the start of a 30-minute pick-up slot",
+			"2023-12-01T10:00:30Z":      "must be the start of a 30-minute pick-up slot",
+			"2023-13-45":                "must be an ISO 8601 date and time, like 2023-12-01T10:30:00+07:00",
+			"tomorrow":                  "must be an ISO 8601 date and time, like 2023-12-01T10:30:00+07:00",
+		} {
+			var pickUpDate PickUpDate
+			if err := json.Unmarshal([]byte(`"`+date+`"`), &pickUpDate); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			response, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: Book{Title: "TestBook"}, PickUpDate: pickUpDate})
+			if wantMessage == "" {
+				if err != nil {
+					t.Errorf("Unexpected error for %s: %v", date, err)
+				}
+				continue
+			}
+
+			if len(response.Errors) != 1 || response.Errors[0].Field != "pick_up_date" || response.Errors[0].Message != wantMessage {
+				t.Errorf("Expected pick_up_date %q for %s, got %v", wantMessage, date, response.Errors)
+			}
+		}
+	})
+}
+
+func TestBookService_SubmitPickUpScheduleService_Branches(t *testing.T) {
+	jakarta := Branch{ID: "jakarta", Name: "Jakarta", Timezone: "Asia/Jakarta", OpeningHours: []OpeningHours{
+		{Weekday: "tuesday", Opens: "09:00", Closes: "12:00"},
+		{Weekday: "tuesday", Opens: "13:00", Closes: "17:00"},
+	}}
+	kathmandu := Branch{ID: "kathmandu", Name: "Kathmandu", Timezone: "Asia/Kathmandu", OpeningHours: everyDay("00:00", "24:00")}
+
+	mockRepo := &mockRepository{
+		savePickUpScheduleResponse: []PickUpSchedule{{BookInfo: Book{Title: "MockBook"}}},
+	}
+	branches := newMockBranches(jakarta, kathmandu).stock(1, "fiction", Book{Title: "TestBook"})
+	service := NewService(mockRepo, branches, newMockBorrowers(testBorrower), WithClock(serviceClock))
+
+	t.Run("PickUpDates", func(t *testing.T) {
+		for _, tc := range []struct {
+			branchID, date, field, message string
+		}{
+			{"jakarta", "2023-11-21T09:00:00+07:00", "", ""},
+			{"jakarta", "2023-11-21T09:30:00Z", "", ""},
+			{"jakarta", "2023-11-21T11:30:00+07:00", "", ""},
+			{"jakarta", "2023-11-21T12:00:00+07:00", "pick_up_date", "must be within the opening hours of Jakarta"},
+			{"jakarta", "2023-11-21T16:30:00+07:00", "", ""},
+			{"jakarta", "2023-11-21T17:00:00+07:00", "pick_up_date", "must be within the opening hours of Jakarta"},
+			{"jakarta", "2023-11-21T08:30:00+07:00", "pick_up_date", "must be within the opening hours of Jakarta"},
+			{"jakarta", "2023-11-22T10:00:00+07:00", "pick_up_date", "must be within the opening hours of Jakarta"},
+			{"kathmandu", "2023-11-21T10:30:00+05:45", "", ""},
+			{"kathmandu", "2023-11-21T10:00:00Z", "pick_up_date", "must be the start of a 30-minute pick-up slot"},
+			{"missing", "2023-11-21T10:00:00Z", "branch_id", "does not match a branch"},
+		} {
+			response, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{Genre: "fiction", BranchID: tc.branchID, BorrowerID: "reader", BookInfo: Book{Title: "TestBook"}, PickUpDate: mustParsePickUpDate(tc.date)})
+			if tc.field == "" {
+				if err != nil {
+					t.Errorf("Unexpected error for %s at %s: %v", tc.date, tc.branchID, err)
+				}
+				continue
+			}
+
+			if len(response.Errors) != 1 || response.Errors[0].Field != tc.field || response.Errors[0].Message != tc.message {
+				t.Errorf("Expected %s %q for %s at %s, got %v", tc.field, tc.message, tc.date, tc.branchID, response.Errors)
+			}
+		}
+	})
+
+	t.Run("PositiveCase_ReservesSlotOfBranch", func(t *testing.T) {
+		_, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{Genre: "fiction", BranchID: "jakarta", BorrowerID: "reader", BookInfo: Book{Title: "TestBook"}, PickUpDate: mustParsePickUpDate("2023-11-21T09:00:00+07:00")})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := []Reservation{
+			{Key: "slot:jakarta:2023-11-21T02:00:00Z", Limit: DefaultSlotConfig.Capacity},
+			{Key: copyKey("jakarta", "fiction", Book{Title: "TestBook"}, "2023-11-21"), Limit: 1, Shared: []string{loanedCopyKey("jakarta", "fiction", Book{Title: "TestBook"})}},
+			{Key: loanedCopyKey("jakarta", "fiction", Book{Title: "TestBook"})},
+			{Key: "loan:reader", Limit: DefaultBorrowerLimits.MaxActiveLoans},
+			{Key: "week:reader:2023-11-20", Limit: DefaultBorrowerLimits.MaxPickUpsPerWeek},
+			{Key: pickUpDayKey("reader", "fiction", Book{Title: "TestBook"}, "2023-11-21"), Limit: 1},
+		}
+		want = append(want, DefaultSlotConfig.pickUpTimeReservations("reader", "jakarta", time.Date(2023, 11, 21, 2, 0, 0, 0, time.UTC))...)
+		if !reflect.DeepEqual(mockRepo.savedReservations, want) {
+			t.Errorf("Expected the slot and a copy at the branch to be reserved, got %v", mockRepo.savedReservations)
+		}
+	})
+
+	t.Run("PositiveCase_ReservesCopyForLocalDay", func(t *testing.T) {
+		// Half past midnight in Kathmandu is still the previous day in UTC
+		_, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{Genre: "fiction", BranchID: "kathmandu", BorrowerID: "reader", BookInfo: Book{Title: "TestBook"}, PickUpDate: mustParsePickUpDate("2023-11-22T00:30:00+05:45")})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := copyKey("kathmandu", "fiction", Book{Title: "TestBook"}, "2023-11-22")
+		if len(mockRepo.savedReservations) < 2 || mockRepo.savedReservations[1].Key != want {
+			t.Errorf("Expected a copy for the local day to be reserved, got %v", mockRepo.savedReservations)
+		}
+	})
+
+	t.Run("NegativeCase_BranchRepositoryFailure", func(t *testing.T) {
+		branches.getBranchError = fmt.Errorf("database is down")
+		defer func() {
+			branches.getBranchError = nil
+		}()
+
+		response, err := service.SubmitPickUpScheduleService(context.Background(), PickUpSchedule{Genre: "fiction", BranchID: "jakarta", BorrowerID: "reader", BookInfo: Book{Title: "TestBook"}, PickUpDate: mustParsePickUpDate("2023-11-21T09:00:00+07:00")})
+		if err == nil {
+			t.Error("Expected error, but got nil")
+		}
+		if response.Status != "500 Internal Server Error" {
+			t.Errorf("Expected 500 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestBookService_SubmitPickUpScheduleService_TimeZones(t *testing.T) {
+	ctx := context.Background()
+	jakarta := Branch{ID: "jakarta", Name: "Jakarta", Timezone: "Asia/Jakarta", OpeningHours: everyDay("00:00", "24:00")}
+	newYork := Branch{ID: "new-york", Name: "New York", Timezone: "America/New_York", OpeningHours: everyDay("00:00", "24:00")}
+	book := Book{Title: "TestBook"}
+	// New York moves its clocks forward on 2024-03-10
+	service := NewService(NewInMemoryRepository(ctx), newMockBranches(jakarta, newYork).stock(5, "fiction", book),
+		newMockBorrowers(testBorrower), WithClock(serviceClock), WithBookingHorizon(200*24*time.Hour))
+	submit := func(branchID, date string) (PostResponse, error) {
+		return service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BranchID: branchID, BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate(date)})
+	}
+
+	t.Run("PositiveCase_LocalTimes", func(t *testing.T) {
+		for _, tc := range []struct {
+			branchID, date, stored, local string
+		}{
+			// A time without an offset is local time at the branch
+			{"jakarta", "2023-11-21T10:00", "2023-11-21T03:00:00Z", "2023-11-21T10:00:00+07:00"},
+			{"jakarta", "2023-11-22T10:30:00+05:00", "2023-11-22T05:30:00Z", "2023-11-22T12:30:00+07:00"},
+			{"new-york", "2024-03-09T10:00", "2024-03-09T15:00:00Z", "2024-03-09T10:00:00-05:00"},
+			{"new-york", "2024-03-10T10:00", "2024-03-10T14:00:00Z", "2024-03-10T10:00:00-04:00"},
+		} {
+			branch := map[string]Branch{"jakarta": jakarta, "new-york": newYork}[tc.branchID]
+			response, err := submit(tc.branchID, tc.date)
+			if err != nil {
+				t.Errorf("Unexpected error for %s at %s: %v", tc.date, tc.branchID, err)
+				continue
+			}
+			schedule := response.Data
+			if schedule.PickUpDate.String() != tc.stored || schedule.Timezone != branch.Timezone || schedule.LocalPickUpDate() != tc.local {
+				t.Errorf("Expected %s at %s to be stored as %s and shown as %s, got %s in %s shown as %s",
+					tc.date, tc.branchID, tc.stored, tc.local, schedule.PickUpDate, schedule.Timezone, schedule.LocalPickUpDate())
+			}
+		}
+	})
+
+	t.Run("NegativeCase_SkippedByDaylightSaving", func(t *testing.T) {
+		response, err := submit("new-york", "2024-03-10T02:30")
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected *ValidationError, got %v", err)
+		}
+		want := "is skipped by a daylight saving time change in America/New_York"
+		if len(response.Errors) != 1 || response.Errors[0].Field != "pick_up_date" || response.Errors[0].Message != want {
+			t.Errorf("Expected pick_up_date %q, got %v", want, response.Errors)
+		}
+	})
+
+	t.Run("PositiveCase_Reschedule", func(t *testing.T) {
+		created, err := submit("jakarta", "2023-11-28T10:00:00+07:00")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		response, err := service.ReschedulePickUpScheduleService(ctx, created.Data.ID, Reschedule{PickUpDate: mustParsePickUpDate("2023-11-29T09:00"), ChangedBy: "staff"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Data.PickUpDate.String() != "2023-11-29T02:00:00Z" || response.Data.LocalPickUpDate() != "2023-11-29T09:00:00+07:00" {
+			t.Errorf("Expected the new time to be local to Jakarta, got %s", response.Data.PickUpDate)
+		}
+	})
+}
+
+func TestBookService_SubmitPickUpScheduleService_BorrowerLimits(t *testing.T) {
+	ctx := context.Background()
+	emma := Book{Title: "Emma"}
+	other := Borrower{ID: "other", Name: "Other"}
+	newLimitedService := func(limits BorrowerLimits) BookService {
+		return NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch).stock(5, "fiction", emma), newMockBorrowers(testBorrower, other),
+			WithClock(serviceClock), WithBorrowerLimits(limits))
+	}
+	submit := func(service BookService, borrowerID, date string) (PostResponse, error) {
+		return service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: borrowerID, PickUpDate: mustParsePickUpDate(date)})
+	}
+
+	t.Run("ActiveLoans", func(t *testing.T) {
+		service := newLimitedService(BorrowerLimits{MaxActiveLoans: 2})
+
+		var scheduleIDs []string
+		for _, date := range []string{"2023-11-21T10:00:00Z", "2023-11-22T10:00:00Z"} {
+			response, err := submit(service, "reader", date)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			scheduleIDs = append(scheduleIDs, response.Data.ID)
+		}
+
+		response, err := submit(service, "reader", "2023-11-23T10:00:00Z")
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != limitActiveLoans || limitErr.Max != 2 {
+			t.Fatalf("Expected an active loans LimitError, got %v", err)
+		}
+		if response.Status != "409 Conflict" || len(response.Errors) != 1 || response.Errors[0].Field != "borrower_id" {
+			t.Errorf("Expected 409 failure on borrower_id, got %+v", response)
+		}
+
+		if _, err := submit(service, "other", "2023-11-23T10:00:00Z"); err != nil {
+			t.Errorf("Expected another borrower to be unaffected, got %v", err)
+		}
+
+		if _, err := service.CancelPickUpScheduleService(ctx, scheduleIDs[0], Cancellation{CancelledBy: "reader"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := submit(service, "reader", "2023-11-23T10:00:00Z"); err != nil {
+			t.Errorf("Expected the cancelled loan to free a place, got %v", err)
+		}
+	})
+
+	t.Run("PickUpsPerWeek", func(t *testing.T) {
+		service := newLimitedService(BorrowerLimits{MaxPickUpsPerWeek: 2})
+
+		for _, date := range []string{"2023-11-21T10:00:00Z", "2023-11-22T10:00:00Z"} {
+			if _, err := submit(service, "reader", date); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		response, err := submit(service, "reader", "2023-11-26T10:00:00Z")
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != limitPickUpsPerWeek || limitErr.Week != "2023-11-20" {
+			t.Fatalf("Expected a weekly LimitError for the week of 2023-11-20, got %v", err)
+		}
+		if response.Status != "409 Conflict" || len(response.Errors) != 1 || response.Errors[0].Field != "borrower_id" {
+			t.Errorf("Expected 409 failure on borrower_id, got %+v", response)
+		}
+
+		if _, err := submit(service, "reader", "2023-11-27T10:00:00Z"); err != nil {
+			t.Errorf("Expected the next week to be free, got %v", err)
+		}
+	})
+
+	// Pick-ups saved at the same moment must not all be counted before any
+	// of them is stored
+	for _, store := range []struct {
+		name string
+		open func(t *testing.T) BookRepository
+	}{
+		{"InMemory", func(t *testing.T) BookRepository { return NewInMemoryRepository(ctx) }},
+		{"SQL", func(t *testing.T) BookRepository {
+			repo, err := NewSQLRepository(ctx, openTestDB(t))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			return repo
+		}},
+	} {
+		t.Run("PickUpsPerWeek_Concurrent_"+store.name, func(t *testing.T) {
+			service := NewService(store.open(t), newMockBranches(testBranch).stock(5, "fiction", emma), newMockBorrowers(testBorrower),
+				WithClock(serviceClock), WithBorrowerLimits(BorrowerLimits{MaxPickUpsPerWeek: 2}))
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 7)
+			for day := 20; day <= 26; day++ {
+				wg.Add(1)
+				go func(day int) {
+					defer wg.Done()
+					_, err := submit(service, "reader", fmt.Sprintf("2023-11-%dT10:00:00Z", day))
+					errs <- err
+				}(day)
+			}
+			wg.Wait()
+			close(errs)
+
+			saved := 0
+			for err := range errs {
+				var limitErr *LimitError
+				switch {
+				case err == nil:
+					saved++
+				case !errors.As(err, &limitErr) || limitErr.Limit != limitPickUpsPerWeek || limitErr.Week != "2023-11-20":
+					t.Errorf("Expected a weekly LimitError for the week of 2023-11-20, got %v", err)
+				}
+			}
+			if saved != 2 {
+				t.Errorf("Expected exactly 2 pick-ups to be saved in the week, got %d", saved)
+			}
+		})
+	}
+
+	t.Run("NegativeCase_UnknownBorrower", func(t *testing.T) {
+		service := newLimitedService(DefaultBorrowerLimits)
+
+		response, err := submit(service, "missing", "2023-11-21T10:00:00Z")
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ValidationError, got %v", err)
+		}
+		if response.Status != "400 Bad Request" || len(response.Errors) != 1 || response.Errors[0].Field != "borrower_id" || response.Errors[0].Message != "does not match a borrower" {
+			t.Errorf("Expected 400 failure on borrower_id, got %+v", response)
+		}
+	})
+}
+
+func TestBookService_Closures(t *testing.T) {
+	ctx := context.Background()
+	emma := Book{Title: "Emma"}
+	closures := NewInMemoryClosureRepository()
+	for _, closure := range []Closure{
+		{BranchID: "central", StartDate: "2023-11-21", EndDate: "2023-11-22", Recurrence: ClosureOnce, Reason: "stock-take"},
+		{BranchID: "central", StartDate: "2020-11-23", EndDate: "2020-11-23", Recurrence: ClosureYearly, Reason: "founding day"},
+		{BranchID: "north", StartDate: "2023-11-24", EndDate: "2023-11-24", Recurrence: ClosureOnce},
+	} {
+		if _, err := closures.CreateClosure(ctx, closure); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	service := NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch).stock(1, "fiction", emma), newMockBorrowers(testBorrower),
+		WithClock(serviceClock),
+		WithClosures(closures),
+		WithHolds(NewInMemoryHoldRepository(), HoldConfig{ConfirmWithin: 24 * time.Hour}),
+	)
+
+	t.Run("NegativeCase_ClosedDay", func(t *testing.T) {
+		for _, date := range []string{"2023-11-21T10:00:00Z", "2023-11-22T23:30:00Z", "2023-11-23T10:00:00Z"} {
+			response, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "reader", PickUpDate: mustParsePickUpDate(date)})
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.NextOpenDate != "2023-11-24" {
+				t.Errorf("Expected ValidationError suggesting 2023-11-24 for %s, got %v", date, err)
+			}
+			if response.Status != "400 Bad Request" || response.NextOpenDate != "2023-11-24" || len(response.Errors) != 1 || response.Errors[0].Field != "pick_up_date" {
+				t.Errorf("Expected 400 failure with the next open date for %s, got %+v", date, response)
+			}
+		}
+
+		response, _ := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "reader", PickUpDate: mustParsePickUpDate("2023-11-21T10:00:00Z")})
+		if want := "falls on a day Central is closed (stock-take), the next open date is 2023-11-24"; len(response.Errors) != 1 || response.Errors[0].Message != want {
+			t.Errorf("Expected %q, got %v", want, response.Errors)
+		}
+	})
+
+	t.Run("PositiveCase_ClosedSlots", func(t *testing.T) {
+		response, err := service.ListSlotsService(ctx, "central", "2023-11-22")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.TotalData != 0 || response.Closure == nil || response.Closure.Reason != "stock-take" {
+			t.Errorf("Expected no slots on the stock-take, got %+v", response)
+		}
+
+		response, err = service.ListSlotsService(ctx, "central", "2023-11-24")
+		if err != nil || response.TotalData != 48 || response.Closure != nil {
+			t.Errorf("Expected every slot on an open day, got %d slots, %v", response.TotalData, err)
+		}
+	})
+
+	t.Run("PositiveCase_HoldOfferedAfterClosure", func(t *testing.T) {
+		response, err := service.PlaceHoldService(ctx, Hold{Genre: "fiction", BookInfo: emma, BranchID: "central", BorrowerID: "reader"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		offer, err := service.GetPickUpScheduleService(ctx, response.Data.ScheduleID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if offer.Data.PickUpDate.String() != "2023-11-24T00:00:00Z" {
+			t.Errorf("Expected the offer on the first open day, got %s", offer.Data.PickUpDate)
+		}
+	})
+}
+
+func TestBookService_GetPickUpScheduleService(t *testing.T) {
+	mockRepo := &mockRepository{
+		getPickUpScheduleResponse: PickUpSchedule{ID: "abc", BookInfo: Book{Title: "MockBook"}},
+	}
+
+	service := NewService(mockRepo, newMockBranches(testBranch), newMockBorrowers(testBorrower))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.GetPickUpScheduleService(context.Background(), "abc")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data.ID != "abc" {
+			t.Errorf("Expected schedule abc, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		mockRepo.getPickUpScheduleError = ErrScheduleNotFound
+
+		response, err := service.GetPickUpScheduleService(context.Background(), "missing")
+		if !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+
+		if response.IsSuccess != false || response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 failure, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_RepositoryFailure", func(t *testing.T) {
+		mockRepo.getPickUpScheduleError = fmt.Errorf("database is down")
+
+		response, err := service.GetPickUpScheduleService(context.Background(), "abc")
+		if err == nil {
+			t.Error("Expected error, but got nil")
+		}
+
+		if response.Status != "500 Internal Server Error" {
+			t.Errorf("Expected 500 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestBookService_ListPickUpSchedulesService(t *testing.T) {
+	mockRepo := &mockRepository{
+		listPickUpSchedulesPage: SchedulePage{
+			Schedules:  []PickUpSchedule{{ID: "a"}, {ID: "b"}},
+			NextCursor: "next",
+		},
+	}
+
+	service := NewService(mockRepo, newMockBranches(testBranch), newMockBorrowers(testBorrower))
+
+	t.Run("PositiveCase_DefaultLimit", func(t *testing.T) {
+		response, err := service.ListPickUpSchedulesService(context.Background(), ScheduleQuery{Sort: "-title"})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.TotalData != 2 || response.NextCursor != "next" {
+			t.Errorf("Expected two schedules and a cursor, got %+v", response)
+		}
+
+		if mockRepo.listPickUpSchedulesQuery.Limit != defaultScheduleLimit {
+			t.Errorf("Expected default limit %d, got %d", defaultScheduleLimit, mockRepo.listPickUpSchedulesQuery.Limit)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidQuery", func(t *testing.T) {
+		for name, query := range map[string]ScheduleQuery{
+			"UnknownSort":   {Sort: "author"},
+			"InvalidDate":   {PickUpFrom: "tomorrow"},
+			"LimitTooHigh":  {Limit: maxScheduleLimit + 1},
+			"NegativeLimit": {Limit: -1},
+		} {
+			response, err := service.ListPickUpSchedulesService(context.Background(), query)
+			if !errors.Is(err, ErrInvalidScheduleQuery) {
+				t.Errorf("%s: Expected ErrInvalidScheduleQuery, got %v", name, err)
+			}
+
+			if response.IsSuccess != false || response.Status != "400 Bad Request" {
+				t.Errorf("%s: Expected 400 failure, got %+v", name, response)
+			}
+		}
+	})
+
+	t.Run("NegativeCase_RepositoryFailure", func(t *testing.T) {
+		mockRepo.listPickUpSchedulesError = fmt.Errorf("database is down")
+
+		response, err := service.ListPickUpSchedulesService(context.Background(), ScheduleQuery{})
+		if err == nil {
+			t.Error("Expected error, but got nil")
+		}
+
+		if response.Status != "500 Internal Server Error" {
+			t.Errorf("Expected 500 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestBookService_CancelPickUpScheduleService(t *testing.T) {
+	mockRepo := &mockRepository{
+		getPickUpScheduleResponse: PickUpSchedule{ID: "abc", Status: ScheduleStatusConfirmed},
+	}
+
+	service := NewService(mockRepo, newMockBranches(testBranch), newMockBorrowers(testBorrower))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.CancelPickUpScheduleService(context.Background(), "abc", Cancellation{CancelledBy: " librarian ", Reason: "lost card"})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data.Status != ScheduleStatusCancelled {
+			t.Errorf("Expected cancelled schedule, got %+v", response)
+		}
+
+		if mockRepo.cancelPickUpScheduleInput.CancelledBy != "librarian" {
+			t.Errorf("Expected trimmed cancelled_by, got %q", mockRepo.cancelPickUpScheduleInput.CancelledBy)
+		}
+	})
+
+	t.Run("NegativeCase_MissingCancelledBy", func(t *testing.T) {
+		response, err := service.CancelPickUpScheduleService(context.Background(), "abc", Cancellation{CancelledBy: "  "})
+		if !errors.Is(err, ErrInvalidCancellation) {
+			t.Errorf("Expected ErrInvalidCancellation, got %v", err)
+		}
+
+		if response.Status != "400 Bad Request" {
+			t.Errorf("Expected 400 failure, got %q", response.Status)
+		}
+	})
+
+	t.Run("NegativeCase_RepositoryErrors", func(t *testing.T) {
+		for status, repoErr := range map[string]error{
+			"404 Not Found":             ErrScheduleNotFound,
+			"409 Conflict":              ErrScheduleCancelled,
+			"500 Internal Server Error": fmt.Errorf("database is down"),
+		} {
+			mockRepo.cancelPickUpScheduleError = repoErr
+
+			response, err := service.CancelPickUpScheduleService(context.Background(), "abc", Cancellation{CancelledBy: "librarian"})
+			if !errors.Is(err, repoErr) {
+				t.Errorf("Expected %v, got %v", repoErr, err)
+			}
+
+			if response.IsSuccess != false || response.Status != status {
+				t.Errorf("Expected %s failure, got %+v", status, response)
+			}
+		}
+	})
+
+	t.Run("NegativeCase_PickedUp", func(t *testing.T) {
+		mockRepo.cancelPickUpScheduleError = nil
+		mockRepo.cancelPickUpScheduleInput = Cancellation{}
+		mockRepo.getPickUpScheduleResponse.Status = ScheduleStatusPickedUp
+		defer func() {
+			mockRepo.getPickUpScheduleResponse.Status = ScheduleStatusConfirmed
+		}()
+
+		response, err := service.CancelPickUpScheduleService(context.Background(), "abc", Cancellation{CancelledBy: "librarian"})
+		if !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("Expected ErrInvalidTransition, got %v", err)
+		}
+
+		if response.Status != "409 Conflict" || mockRepo.cancelPickUpScheduleInput.CancelledBy != "" {
+			t.Errorf("Expected 409 failure before the repository, got %+v", response)
+		}
+	})
+}
+
+func TestBookService_TransitionPickUpScheduleService(t *testing.T) {
+	mockRepo := &mockRepository{
+		getPickUpScheduleResponse: PickUpSchedule{ID: "abc", Genre: "fiction", BranchID: "central", Status: ScheduleStatusReadyForPickUp},
+	}
+	jakarta := Branch{ID: "jakarta", Timezone: "Asia/Jakarta", LoanPeriods: []LoanPeriod{{Genre: "fiction", Days: 7}}}
+
+	service := NewService(mockRepo, newMockBranches(testBranch, jakarta), newMockBorrowers(testBorrower), WithClock(serviceClock), WithLoans(LoanConfig{Days: 21}))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.TransitionPickUpScheduleService(context.Background(), "abc", StatusTransition{Status: ScheduleStatusPickedUp, ChangedBy: " front desk "})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data.Status != ScheduleStatusPickedUp {
+			t.Errorf("Expected picked up schedule, got %+v", response)
+		}
+		if mockRepo.transitionInput.ChangedBy != "front desk" {
+			t.Errorf("Expected trimmed changed_by, got %q", mockRepo.transitionInput.ChangedBy)
+		}
+
+		// Picked up on 2023-11-20 with the loan period of the service
+		if due := mockRepo.transitionInput.DueAt; due == nil || !due.Equal(time.Date(2023, 12, 12, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected the loan to be due at the end of 2023-12-11, got %v", due)
+		}
+	})
+
+	t.Run("PositiveCase_BranchLoanPeriod", func(t *testing.T) {
+		mockRepo.getPickUpScheduleResponse.BranchID = "jakarta"
+		defer func() {
+			mockRepo.getPickUpScheduleResponse.BranchID = "central"
+		}()
+
+		if _, err := service.TransitionPickUpScheduleService(context.Background(), "abc", StatusTransition{Status: ScheduleStatusPickedUp, ChangedBy: "front desk"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// 7 days from 2023-11-20 in Jakarta end at 17:00 UTC
+		if due := mockRepo.transitionInput.DueAt; due == nil || !due.Equal(time.Date(2023, 11, 27, 17, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected the loan to be due at the end of 2023-11-27 in Jakarta, got %v", due)
+		}
+	})
+
+	t.Run("PositiveCase_NoDueDateBeforePickUp", func(t *testing.T) {
+		mockRepo.getPickUpScheduleResponse.Status = ScheduleStatusRequested
+		defer func() {
+			mockRepo.getPickUpScheduleResponse.Status = ScheduleStatusReadyForPickUp
+		}()
+
+		if _, err := service.TransitionPickUpScheduleService(context.Background(), "abc", StatusTransition{Status: ScheduleStatusConfirmed, ChangedBy: "front desk"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if mockRepo.transitionInput.DueAt != nil {
+			t.Errorf("Expected no due date, got %v", mockRepo.transitionInput.DueAt)
+		}
+	})
+
+	t.Run("NegativeCase_Overdue", func(t *testing.T) {
+		mockRepo.transitionInput = nil
+
+		response, err := service.TransitionPickUpScheduleService(context.Background(), "abc", StatusTransition{Status: ScheduleStatusOverdue, ChangedBy: "front desk"})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || len(response.Errors) != 1 || response.Errors[0].Field != "status" {
+			t.Errorf("Expected ValidationError for status, got %v", err)
+		}
+		if mockRepo.transitionInput != nil {
+			t.Error("Expected staff not to flag loans overdue")
+		}
+	})
+
+	t.Run("PositiveCase_Cancelled", func(t *testing.T) {
+		mockRepo.transitionInput = nil
+
+		response, err := service.TransitionPickUpScheduleService(context.Background(), "abc", StatusTransition{Status: ScheduleStatusCancelled, ChangedBy: "front desk", Reason: "lost card"})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.Data.Status != ScheduleStatusCancelled || mockRepo.cancelPickUpScheduleInput.Reason != "lost card" || mockRepo.transitionInput != nil {
+			t.Errorf("Expected the schedule to be cancelled, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidRequest", func(t *testing.T) {
+		mockRepo.transitionInput = nil
+
+		response, err := service.TransitionPickUpScheduleService(context.Background(), "abc", StatusTransition{Status: "lost"})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || len(response.Errors) != 2 || response.Errors[0].Field != "status" {
+			t.Errorf("Expected ValidationError for status and changed_by, got %v", err)
+		}
+		if mockRepo.transitionInput != nil {
+			t.Error("Expected invalid transition not to reach the repository")
+		}
+	})
+
+	t.Run("NegativeCase_IllegalTransitions", func(t *testing.T) {
+		for _, tc := range []struct {
+			from, to ScheduleStatus
+			message  string
+		}{
+			{ScheduleStatusRequested, ScheduleStatusPickedUp, "must be one of [confirmed cancelled] while the pick-up schedule is requested"},
+			{ScheduleStatusConfirmed, ScheduleStatusNoShow, "must be one of [ready_for_pick_up cancelled] while the pick-up schedule is confirmed"},
+			{ScheduleStatusPickedUp, ScheduleStatusCancelled, "must be one of [returned] while the pick-up schedule is picked_up"},
+			{ScheduleStatusOverdue, ScheduleStatusPickedUp, "must be one of [returned] while the pick-up schedule is overdue"},
+			{ScheduleStatusReturned, ScheduleStatusPickedUp, "cannot change once the pick-up schedule is returned"},
+			{ScheduleStatusNoShow, ScheduleStatusReadyForPickUp, "cannot change once the pick-up schedule is no_show"},
+		} {
+			mockRepo.getPickUpScheduleResponse.Status = tc.from
+			mockRepo.transitionInput = nil
+
+			response, err := service.TransitionPickUpScheduleService(context.Background(), "abc", StatusTransition{Status: tc.to, ChangedBy: "front desk"})
+			if !errors.Is(err, ErrInvalidTransition) {
+				t.Errorf("Expected ErrInvalidTransition from %s to %s, got %v", tc.from, tc.to, err)
+			}
+
+			if response.Status != "409 Conflict" || len(response.Errors) != 1 || response.Errors[0].Message != tc.message {
+				t.Errorf("Expected 409 failure %q from %s to %s, got %+v", tc.message, tc.from, tc.to, response)
+			}
+			if mockRepo.transitionInput != nil {
+				t.Errorf("Expected the move from %s to %s not to reach the repository", tc.from, tc.to)
+			}
+		}
+	})
+
+	t.Run("NegativeCase_RepositoryErrors", func(t *testing.T) {
+		mockRepo.getPickUpScheduleResponse.Status = ScheduleStatusRequested
+
+		for status, repoErr := range map[string]error{
+			"409 Conflict":              &TransitionError{From: ScheduleStatusCancelled, To: ScheduleStatusConfirmed},
+			"500 Internal Server Error": fmt.Errorf("database is down"),
+		} {
+			mockRepo.transitionError = repoErr
+
+			response, err := service.TransitionPickUpScheduleService(context.Background(), "abc", StatusTransition{Status: ScheduleStatusConfirmed, ChangedBy: "front desk"})
+			if !errors.Is(err, repoErr) {
+				t.Errorf("Expected %v, got %v", repoErr, err)
+			}
+
+			if response.IsSuccess != false || response.Status != status {
+				t.Errorf("Expected %s failure, got %+v", status, response)
+			}
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		mockRepo.getPickUpScheduleError = ErrScheduleNotFound
+
+		response, err := service.TransitionPickUpScheduleService(context.Background(), "missing", StatusTransition{Status: ScheduleStatusConfirmed, ChangedBy: "front desk"})
+		if !errors.Is(err, ErrScheduleNotFound) || response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 failure, got %v %+v", err, response)
+		}
+	})
+}
+
+func TestBookService_MarkOverdueLoansService(t *testing.T) {
+	due := serviceNow.Add(-time.Hour)
+	mockRepo := &mockRepository{
+		markOverdueResponse: []PickUpSchedule{{ID: "abc", Status: ScheduleStatusOverdue, DueAt: &due}},
+	}
+
+	service := NewService(mockRepo, newMockBranches(testBranch), newMockBorrowers(testBorrower), WithClock(serviceClock))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.MarkOverdueLoansService(context.Background())
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.TotalData != 1 || response.Data[0].ID != "abc" {
+			t.Errorf("Expected the flagged loan, got %+v", response)
+		}
+		if !mockRepo.markOverdueNow.Equal(serviceNow) {
+			t.Errorf("Expected loans due by the service clock, got %v", mockRepo.markOverdueNow)
+		}
+	})
+
+	t.Run("NegativeCase", func(t *testing.T) {
+		mockRepo.markOverdueError = fmt.Errorf("database is down")
+		defer func() {
+			mockRepo.markOverdueError = nil
+		}()
+
+		response, err := service.MarkOverdueLoansService(context.Background())
+		if err == nil || response.Status != "500 Internal Server Error" || response.Data == nil {
+			t.Errorf("Expected 500 failure, got %v %+v", err, response)
+		}
+	})
+}
+
+func TestBookService_ListOverdueLoansService(t *testing.T) {
+	due := serviceNow.Add(-time.Hour)
+	mockRepo := &mockRepository{
+		listPickUpSchedulesPage: SchedulePage{Schedules: []PickUpSchedule{{ID: "abc", Status: ScheduleStatusOverdue, DueAt: &due}}},
+	}
+
+	service := NewService(mockRepo, newMockBranches(testBranch), newMockBorrowers(testBorrower), WithClock(serviceClock))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.ListOverdueLoansService(context.Background(), ScheduleQuery{BranchID: "central", Status: ScheduleStatusRequested})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.TotalData != 1 || response.Message != "fetch overdue loans successfully!" {
+			t.Errorf("Expected the overdue loans, got %+v", response)
+		}
+		if !mockRepo.markOverdueNow.Equal(serviceNow) {
+			t.Error("Expected loans to be flagged before listing them")
+		}
+
+		want := ScheduleQuery{BranchID: "central", Status: ScheduleStatusOverdue, Sort: "due_at", Limit: defaultScheduleLimit}
+		if mockRepo.listPickUpSchedulesQuery != want {
+			t.Errorf("Expected query %+v, got %+v", want, mockRepo.listPickUpSchedulesQuery)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidQuery", func(t *testing.T) {
+		response, err := service.ListOverdueLoansService(context.Background(), ScheduleQuery{Limit: 1000})
+		if !errors.Is(err, ErrInvalidScheduleQuery) || response.Status != "400 Bad Request" {
+			t.Errorf("Expected 400 failure, got %v %+v", err, response)
+		}
+	})
+
+	t.Run("NegativeCase_MarkFailure", func(t *testing.T) {
+		mockRepo.markOverdueError = fmt.Errorf("database is down")
+		mockRepo.listPickUpSchedulesQuery = ScheduleQuery{}
+
+		response, err := service.ListOverdueLoansService(context.Background(), ScheduleQuery{})
+		if err == nil || response.Status != "500 Internal Server Error" {
+			t.Errorf("Expected 500 failure, got %v %+v", err, response)
+		}
+		if mockRepo.listPickUpSchedulesQuery != (ScheduleQuery{}) {
+			t.Error("Expected nothing to be listed when flagging fails")
+		}
+	})
+}
+
+func TestBookService_ReschedulePickUpScheduleService(t *testing.T) {
+	mockRepo := &mockRepository{
+		getPickUpScheduleResponse: PickUpSchedule{ID: "abc", Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: Book{Title: "MockBook"}, PickUpDate: mustParsePickUpDate("2023-12-01T10:00:00Z"), Status: ScheduleStatusRequested},
+	}
+
+	service := NewService(mockRepo, newMockBranches(testBranch).stock(1, "fiction", Book{Title: "MockBook"}), newMockBorrowers(testBorrower), WithClock(serviceClock))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.ReschedulePickUpScheduleService(context.Background(), "abc", Reschedule{PickUpDate: mustParsePickUpDate("2023-12-05T10:30:00Z"), ChangedBy: " librarian "})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data.PickUpDate.String() != "2023-12-05T10:30:00Z" {
+			t.Errorf("Expected moved schedule, got %+v", response)
+		}
+
+		if mockRepo.rescheduleInput.ChangedBy != "librarian" {
+			t.Errorf("Expected trimmed changed_by, got %q", mockRepo.rescheduleInput.ChangedBy)
+		}
+
+		want := []string{"slot:central:2023-12-05T10:30:00Z", copyKey("central", "fiction", Book{Title: "MockBook"}, "2023-12-05"), loanedCopyKey("central", "fiction", Book{Title: "MockBook"}), "loan:reader", "week:reader:2023-12-04",
+			pickUpDayKey("reader", "fiction", Book{Title: "MockBook"}, "2023-12-05"),
+			"pick-up-time:reader:2023-12-05T10:30:00Z", "pick-up-time:reader:2023-12-05T10:30:00Z:central",
+			"pick-up-time:reader:2023-12-05T10:45:00Z", "pick-up-time:reader:2023-12-05T10:45:00Z:central",
+		}
+		if !reflect.DeepEqual(reservationKeys(mockRepo.rescheduledReservations), want) {
+			t.Errorf("Expected the new slot and a copy on the new day to be reserved, got %v", mockRepo.rescheduledReservations)
+		}
+	})
+
+	t.Run("NegativeCase_SwappedBookNotStocked", func(t *testing.T) {
+		mockRepo.rescheduleInput = nil
+
+		response, err := service.ReschedulePickUpScheduleService(context.Background(), "abc", Reschedule{BookInfo: &Book{Title: "OtherBook"}, ChangedBy: "librarian"})
+		if !errors.Is(err, ErrCapacityExceeded) {
+			t.Errorf("Expected ErrCapacityExceeded, got %v", err)
+		}
+		if response.Status != "409 Conflict" || mockRepo.rescheduleInput != nil {
+			t.Errorf("Expected 409 failure before the repository, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidRequest", func(t *testing.T) {
+		mockRepo.rescheduleInput = nil
+
+		response, err := service.ReschedulePickUpScheduleService(context.Background(), "abc", Reschedule{})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || len(validationErr.Fields) != 2 {
+			t.Errorf("Expected ValidationError for changed_by and pick_up_date, got %v", err)
+		}
+		if response.Status != "400 Bad Request" || len(response.Errors) != 2 {
+			t.Errorf("Expected 400 failure with field errors, got %+v", response)
+		}
+		if mockRepo.rescheduleInput != nil {
+			t.Error("Expected invalid reschedule not to reach the repository")
+		}
+	})
+
+	t.Run("NegativeCase_SwappedBookFailsSubmissionRules", func(t *testing.T) {
+		mockRepo.rescheduleInput = nil
+
+		response, err := service.ReschedulePickUpScheduleService(context.Background(), "abc", Reschedule{BookInfo: &Book{}, ChangedBy: "librarian"})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "book_info.title" {
+			t.Errorf("Expected ValidationError for book_info.title, got %v", err)
+		}
+		if response.IsSuccess != false || mockRepo.rescheduleInput != nil {
+			t.Errorf("Expected rejected reschedule, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_PastDate", func(t *testing.T) {
+		mockRepo.rescheduleInput = nil
+
+		response, err := service.ReschedulePickUpScheduleService(context.Background(), "abc", Reschedule{PickUpDate: mustParsePickUpDate("2023-11-01T10:00:00Z"), ChangedBy: "librarian"})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "pick_up_date" {
+			t.Errorf("Expected ValidationError for pick_up_date, got %v", err)
+		}
+		if response.IsSuccess != false || mockRepo.rescheduleInput != nil {
+			t.Errorf("Expected rejected reschedule, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_RepositoryErrors", func(t *testing.T) {
+		for status, repoErr := range map[string]error{
+			"404 Not Found":             ErrScheduleNotFound,
+			"409 Conflict":              ErrScheduleCancelled,
+			"500 Internal Server Error": fmt.Errorf("database is down"),
+		} {
+			mockRepo.rescheduleError = repoErr
+
+			response, err := service.ReschedulePickUpScheduleService(context.Background(), "abc", Reschedule{PickUpDate: mustParsePickUpDate("2023-12-05T10:30:00Z"), ChangedBy: "librarian"})
+			if !errors.Is(err, repoErr) {
+				t.Errorf("Expected %v, got %v", repoErr, err)
+			}
+
+			if response.IsSuccess != false || response.Status != status {
+				t.Errorf("Expected %s failure, got %+v", status, response)
+			}
+		}
+	})
+}
+
+func TestBookService_GetPickUpScheduleHistoryService(t *testing.T) {
+	mockRepo := &mockRepository{}
+
+	service := NewService(mockRepo, newMockBranches(testBranch), newMockBorrowers(testBorrower))
+
+	t.Run("PositiveCase_Empty", func(t *testing.T) {
+		response, err := service.GetPickUpScheduleHistoryService(context.Background(), "abc")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data == nil || response.TotalData != 0 {
+			t.Errorf("Expected empty non-nil history, got %+v", response)
+		}
+	})
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		mockRepo.historyResponse = []ScheduleChange{{ChangedBy: "librarian"}, {ChangedBy: "borrower"}}
+
+		response, err := service.GetPickUpScheduleHistoryService(context.Background(), "abc")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.TotalData != 2 || response.Data[1].ChangedBy != "borrower" {
+			t.Errorf("Expected both changes, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		mockRepo.historyError = ErrScheduleNotFound
+
+		response, err := service.GetPickUpScheduleHistoryService(context.Background(), "missing")
+		if !errors.Is(err, ErrScheduleNotFound) {
+			t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+		}
+
+		if response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestBookService_ListSlotsService(t *testing.T) {
+	jakarta := Branch{ID: "jakarta", Name: "Jakarta", Timezone: "Asia/Jakarta", OpeningHours: []OpeningHours{
+		{Weekday: "friday", Opens: "09:00", Closes: "12:00"},
+		{Weekday: "friday", Opens: "13:00", Closes: "15:00"},
+	}}
+	mockRepo := &mockRepository{
+		reservationCounts: map[string]int{"slot:jakarta:2023-12-01T02:00:00Z": 2, "slot:jakarta:2023-12-01T03:00:00Z": 4},
+	}
+
+	service := NewService(mockRepo, newMockBranches(testBranch, jakarta), newMockBorrowers(testBorrower), WithSlots(SlotConfig{Length: time.Hour, Capacity: 3}))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.ListSlotsService(context.Background(), "central", "2023-12-01")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if response.TotalData != 24 || len(response.Data) != 24 {
+			t.Fatalf("Expected 24 hourly slots, got %d", len(response.Data))
+		}
+		if slot := response.Data[10]; !slot.Start.Equal(time.Date(2023, 12, 1, 10, 0, 0, 0, time.UTC)) || slot.Remaining != 3 {
+			t.Errorf("Expected empty 10:00 slot, got %+v", slot)
+		}
+	})
+
+	t.Run("PositiveCase_OpeningHours", func(t *testing.T) {
+		response, err := service.ListSlotsService(context.Background(), "jakarta", "2023-12-01")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if response.TotalData != 5 || len(response.Data) != 5 {
+			t.Fatalf("Expected 5 slots within opening hours, got %+v", response.Data)
+		}
+		if slot := response.Data[0]; !slot.Start.Equal(time.Date(2023, 12, 1, 2, 0, 0, 0, time.UTC)) || slot.Booked != 2 || slot.Remaining != 1 {
+			t.Errorf("Expected 09:00 local slot with 1 place left, got %+v", slot)
+		}
+		if slot := response.Data[1]; slot.Booked != 4 || slot.Remaining != 0 {
+			t.Errorf("Expected overbooked 10:00 local slot with no place left, got %+v", slot)
+		}
+		if slot := response.Data[3]; !slot.Start.Equal(time.Date(2023, 12, 1, 6, 0, 0, 0, time.UTC)) || !slot.End.Equal(slot.Start.Add(time.Hour)) {
+			t.Errorf("Expected 13:00 local slot after the break, got %+v", slot)
+		}
+	})
+
+	t.Run("PositiveCase_Closed", func(t *testing.T) {
+		response, err := service.ListSlotsService(context.Background(), "jakarta", "2023-12-02")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if response.Data == nil || len(response.Data) != 0 {
+			t.Errorf("Expected no slots on a closed day, got %+v", response.Data)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidRequest", func(t *testing.T) {
+		response, err := service.ListSlotsService(context.Background(), "", "tomorrow")
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError, got %v", err)
+		}
+		if response.Status != "400 Bad Request" || len(response.Errors) != 2 || response.Errors[0].Field != "branch_id" || response.Errors[1].Field != "date" {
+			t.Errorf("Expected 400 failure for branch_id and date, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_UnknownBranch", func(t *testing.T) {
+		response, err := service.ListSlotsService(context.Background(), "missing", "2023-12-01")
+		if !errors.Is(err, ErrBranchNotFound) {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+		if response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 failure, got %q", response.Status)
+		}
+	})
+
+	t.Run("NegativeCase_RepositoryFailure", func(t *testing.T) {
+		mockRepo.countReservationsError = fmt.Errorf("database is down")
+
+		response, err := service.ListSlotsService(context.Background(), "central", "2023-12-01")
+		if err == nil {
+			t.Error("Expected error, but got nil")
+		}
+		if response.Status != "500 Internal Server Error" {
+			t.Errorf("Expected 500 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestStatusOf(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code int
+		want string
+	}{
+		{&ValidationError{}, http.StatusBadRequest, "400 Bad Request"},
+		{fmt.Errorf("%w: bad sort", ErrInvalidScheduleQuery), http.StatusBadRequest, "400 Bad Request"},
+		{ErrScheduleNotFound, http.StatusNotFound, "404 Not Found"},
+		{&CapacityError{Key: "slot:central", Limit: 1}, http.StatusConflict, "409 Conflict"},
+		{ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, "422 Unprocessable Entity"},
+		{ErrWebhookDeliveryFailed, http.StatusBadGateway, "502 Bad Gateway"},
+		{errors.New("database is down"), http.StatusInternalServerError, "500 Internal Server Error"},
+	} {
+		if code, status := statusCodeOf(tc.err), statusOf(tc.err); code != tc.code || status != tc.want {
+			t.Errorf("Expected %d %q for %v, got %d %q", tc.code, tc.want, tc.err, code, status)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// SlotConfig describes the pick-up slots every day of a branch is split into,
+// starting at midnight in its time zone. Slots follow the local clock, so on a
+// day with a daylight saving change the slots of the skipped hour do not
+// exist and those of a repeated hour exist twice.
+type SlotConfig struct {
+	// Length is how long one slot lasts. It must divide a day into whole
+	// minutes.
+	Length time.Duration
+	// Capacity is the maximum number of pick-ups booked into one slot
+	Capacity int
+}
+
+// DefaultSlotConfig is used unless WithSlots says otherwise
+var DefaultSlotConfig = SlotConfig{Length: 30 * time.Minute, Capacity: 5}
+
+// Slot is one bookable window of a day and how many pick-ups it still takes
+type Slot struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Capacity  int       `json:"capacity"`
+	Booked    int       `json:"booked"`
+	Remaining int       `json:"remaining"`
+}
+
+// Validate reports a configuration that cannot split a day into slots
+func (c SlotConfig) Validate() error {
+	if c.Length < time.Minute || c.Length%time.Minute != 0 || (24*time.Hour)%c.Length != 0 {
+		return fmt.Errorf("slot length %v must be whole minutes that divide a day", c.Length)
+	}
+	if c.Capacity < 1 {
+		return fmt.Errorf("slot capacity %d must be at least 1", c.Capacity)
+	}
+	return nil
+}
+
+// startsSlot reports whether t is the start of a slot in the time zone loc
+func (c SlotConfig) startsSlot(t time.Time, loc *time.Location) bool {
+	return clockOf(t.In(loc))%c.Length == 0
+}
+
+// slotStart returns the start of the slot t falls in, in the location of t
+func (c SlotConfig) slotStart(t time.Time) time.Time {
+	return t.Add(-(clockOf(t) % c.Length))
+}
+
+// step returns the longest time every slot start lies on a multiple of, in
+// UTC and any time zone. Zone offsets and daylight saving changes move the
+// clock by multiples of 15 minutes, so it divides that and the length.
+func (c SlotConfig) step() time.Duration {
+	step := 15 * time.Minute
+	for c.Length%step != 0 || (15*time.Minute)%step != 0 {
+		step -= time.Minute
+	}
+	return step
+}
+
+// clockOf returns the time of day t shows on the clock of its location
+func clockOf(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// slotsOf returns the empty slots of the day starting at midnight in the time
+// zone loc, given as year, month and day
+func (c SlotConfig) slotsOf(year int, month time.Month, day int, loc *time.Location) []Slot {
+	step := c.step()
+	var slots []Slot
+	first := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	next := time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	for start := first; start.Before(next); start = start.Add(step) {
+		if !c.startsSlot(start, loc) {
+			continue
+		}
+		slots = append(slots, Slot{
+			Start:     start,
+			End:       start.Add(c.Length),
+			Capacity:  c.Capacity,
+			Remaining: c.Capacity,
+		})
+	}
+	return slots
+}
+
+// midnight returns the start of the day of t in its location
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// slotKey is the reservation key of the slot of a branch starting at start
+func slotKey(branchID string, start time.Time) string {
+	return "slot:" + branchID + ":" + start.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlotConfig_Validate(t *testing.T) {
+	for _, config := range []SlotConfig{
+		{Length: 30 * time.Minute, Capacity: 1},
+		{Length: 24 * time.Hour, Capacity: 10},
+	} {
+		if err := config.Validate(); err != nil {
+			t.Errorf("Unexpected error for %+v: %v", config, err)
+		}
+	}
+
+	for _, config := range []SlotConfig{
+		{Length: 0, Capacity: 1},
+		{Length: 30 * time.Second, Capacity: 1},
+		{Length: 7 * time.Minute, Capacity: 1},
+		{Length: 90*time.Second + time.Hour, Capacity: 1},
+		{Length: 30 * time.Minute, Capacity: 0},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for %+v, but got nil", config)
+		}
+	}
+}
+
+func TestSlotConfig_StartsSlot(t *testing.T) {
+	config := SlotConfig{Length: 45 * time.Minute, Capacity: 1}
+
+	for value, want := range map[string]bool{
+		"2023-12-01T00:00:00Z":      true,
+		"2023-12-01T00:45:00Z":      true,
+		"2023-12-01T01:30:00Z":      true,
+		"2023-12-01T08:30:00+07:00": true,
+		"2023-12-01T01:00:00Z":      false,
+		"2023-12-01T00:45:01Z":      false,
+	} {
+		date := mustParsePickUpDate(value)
+		if got := config.startsSlot(date.Time(), time.UTC); got != want {
+			t.Errorf("Expected startsSlot(%s) to be %v, got %v", value, want, got)
+		}
+	}
+
+	// Slots start at local midnight, which is not on a UTC slot boundary in
+	// Kathmandu
+	kathmandu, err := loadLocation("Asia/Kathmandu")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for value, want := range map[string]bool{
+		"2023-12-01T10:30:00+05:45": true,
+		"2023-12-01T04:45:00Z":      true,
+		"2023-12-01T04:30:00Z":      false,
+	} {
+		date := mustParsePickUpDate(value)
+		if got := config.startsSlot(date.Time(), kathmandu); got != want {
+			t.Errorf("Expected startsSlot(%s) in Kathmandu to be %v, got %v", value, want, got)
+		}
+	}
+}
+
+func TestSlotConfig_SlotStart(t *testing.T) {
+	config := SlotConfig{Length: 45 * time.Minute, Capacity: 1}
+
+	for value, want := range map[string]string{
+		"2023-12-01T00:45:00Z":      "2023-12-01T00:45:00Z",
+		"2023-12-01T01:00:00Z":      "2023-12-01T00:45:00Z",
+		"2023-12-01T01:29:59Z":      "2023-12-01T00:45:00Z",
+		"2023-12-01T08:40:00+07:00": "2023-12-01T01:30:00Z",
+	} {
+		if got := config.slotStart(mustParsePickUpDate(value).Time()); !got.Equal(mustParsePickUpDate(want).Time()) {
+			t.Errorf("Expected the slot of %s to start at %s, got %v", value, want, got)
+		}
+	}
+
+	// Slots follow the clock of the location of the time
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	at := mustParsePickUpDate("2023-12-01T08:40:00+07:00").Time().In(jakarta)
+	if got, want := config.slotStart(at), mustParsePickUpDate("2023-12-01T08:15:00+07:00").Time(); !got.Equal(want) {
+		t.Errorf("Expected the slot in Jakarta to start at %v, got %v", want, got)
+	}
+}
+
+func TestSlotConfig_SlotsOf(t *testing.T) {
+	config := SlotConfig{Length: time.Hour, Capacity: 2}
+
+	jakarta, err := loadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	slots := config.slotsOf(2023, time.December, 1, jakarta)
+	if len(slots) != 24 {
+		t.Fatalf("Expected 24 slots, got %d", len(slots))
+	}
+	if !slots[0].Start.Equal(time.Date(2023, 11, 30, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the first slot at local midnight, got %v", slots[0].Start)
+	}
+	if slots[23].Capacity != 2 || slots[23].Remaining != 2 || !slots[23].End.Equal(time.Date(2023, 12, 1, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected an empty last slot ending at the next local midnight, got %+v", slots[23])
+	}
+}
+
+func TestSlotConfig_DaylightSaving(t *testing.T) {
+	newYork, err := loadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := SlotConfig{Length: 2 * time.Hour, Capacity: 1}
+
+	// Slots follow the local clock after the clocks go forward at 02:00
+	for value, want := range map[string]bool{
+		"2024-03-10T04:00:00-04:00": true,
+		"2024-03-10T10:00:00-04:00": true,
+		"2024-03-10T09:00:00-04:00": false,
+	} {
+		if got := config.startsSlot(mustParsePickUpDate(value).Time(), newYork); got != want {
+			t.Errorf("Expected startsSlot(%s) in New York to be %v, got %v", value, want, got)
+		}
+	}
+
+	for _, tc := range []struct {
+		day   int
+		month time.Month
+		slots int
+		local []string
+	}{
+		// 02:00 does not exist on the day the clocks go forward
+		{10, time.March, 11, []string{"00:00-05:00", "04:00-04:00", "06:00-04:00"}},
+		// 01:00 to 02:00 happens twice on the day the clocks go back, with
+		// hourly slots 01:00 does too
+		{3, time.November, 12, []string{"00:00-04:00", "02:00-05:00", "04:00-05:00"}},
+	} {
+		slots := config.slotsOf(2024, tc.month, tc.day, newYork)
+		if len(slots) != tc.slots {
+			t.Errorf("Expected %d slots on %s %d, got %d", tc.slots, tc.month, tc.day, len(slots))
+			continue
+		}
+		for i, want := range tc.local {
+			if got := slots[i].Start.In(newYork).Format("15:04Z07:00"); got != want {
+				t.Errorf("Expected slot %d on %s %d to start at %s, got %s", i, tc.month, tc.day, want, got)
+			}
+		}
+	}
+
+	hourly := SlotConfig{Length: time.Hour, Capacity: 1}
+	if slots := hourly.slotsOf(2024, time.November, 3, newYork); len(slots) != 25 {
+		t.Errorf("Expected 25 hourly slots on the day the clocks go back, got %d", len(slots))
+	}
+	if slots := hourly.slotsOf(2024, time.March, 10, newYork); len(slots) != 23 {
+		t.Errorf("Expected 23 hourly slots on the day the clocks go forward, got %d", len(slots))
+	}
+}
@@ -0,0 +1,977 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// SQLRepository is a BookRepository on top of database/sql. The queries are
+// written for SQLite, and the schema is created and upgraded by the versioned
+// migrations embedded from the migrations directory.
+type SQLRepository struct {
+	db *sql.DB
+}
+
+// querier is what *sql.DB and *sql.Tx have in common
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// NewSQLRepository applies every pending migration to db before returning
+func NewSQLRepository(ctx context.Context, db *sql.DB) (*SQLRepository, error) {
+	r := &SQLRepository{
+		db: db,
+	}
+
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+	if err := r.fillPickUpDays(ctx); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// fillPickUpDays sets the local pick-up day of the schedules saved before it
+// was stored. SQLite knows no time zones, so migrations cannot work it out.
+func (r *SQLRepository) fillPickUpDays(ctx context.Context) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT public_id, pick_up_date, timezone FROM pick_up_schedules
+			WHERE pick_up_day = ''`)
+		if err != nil {
+			return fmt.Errorf("failed to load pick-up days: %v", err)
+		}
+		var schedules []PickUpSchedule
+		for rows.Next() {
+			var schedule PickUpSchedule
+			var pickUpDate string
+			if err := rows.Scan(&schedule.ID, &pickUpDate, &schedule.Timezone); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("failed to read pick-up day: %v", err)
+			}
+			schedule.PickUpDate, _ = ParsePickUpDate(pickUpDate)
+			schedules = append(schedules, schedule)
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read pick-up days: %v", err)
+		}
+
+		for _, schedule := range schedules {
+			_, err := tx.ExecContext(ctx, `UPDATE pick_up_schedules SET pick_up_day = ? WHERE public_id = ?`, schedule.LocalDay(), schedule.ID)
+			if err != nil {
+				return fmt.Errorf("failed to save pick-up day: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLRepository) GetBooksByGenre(ctx context.Context, genre string) ([]Book, []PickUpSchedule, error) {
+	pickUpSchedules, err := r.schedulesByGenre(ctx, r.db, genre)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	books, err := fetchBooksByGenreExternalAPI(ctx, genre)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return books, pickUpSchedules, nil
+}
+
+func (r *SQLRepository) SavePickUpSchedule(ctx context.Context, schedule PickUpSchedule, reservations ...Reservation) ([]PickUpSchedule, error) {
+	var pickUpSchedules []PickUpSchedule
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := r.insertSchedule(ctx, tx, schedule, time.Now().UTC(), reservations); err != nil {
+			return err
+		}
+
+		var err error
+		pickUpSchedules, err = r.schedulesByGenre(ctx, tx, schedule.Genre)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pickUpSchedules, nil
+}
+
+func (r *SQLRepository) SavePickUpSchedules(ctx context.Context, schedules []PickUpSchedule, reservations [][]Reservation) ([]PickUpSchedule, error) {
+	var saved []PickUpSchedule
+
+	now := time.Now().UTC()
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		saved = make([]PickUpSchedule, 0, len(schedules))
+		for i, schedule := range schedules {
+			id, err := r.insertSchedule(ctx, tx, schedule, now, reservations[i])
+			if errors.Is(err, ErrCapacityExceeded) {
+				return &BatchSaveError{Index: i, Err: err}
+			}
+			if err != nil {
+				return err
+			}
+
+			stored, err := r.scheduleByID(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			saved = append(saved, stored)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// insertSchedule stores a new requested schedule created at now, claiming
+// the reservations, and returns the ID it was assigned
+func (r *SQLRepository) insertSchedule(ctx context.Context, tx *sql.Tx, schedule PickUpSchedule, now time.Time, reservations []Reservation) (string, error) {
+	schedule.ID = newScheduleID()
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+
+	bookID, err := upsertBook(ctx, tx, schedule.Genre, schedule.BookInfo)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO pick_up_schedules (public_id, book_id, branch_id, borrower_id, pick_up_date, pick_up_day, timezone, status, created_at, updated_at)
+		VALUES (?, ?, (SELECT id FROM branches WHERE public_id = ?), (SELECT id FROM borrowers WHERE public_id = ?), ?, ?, ?, ?, ?, ?)`,
+		schedule.ID, bookID, schedule.BranchID, schedule.BorrowerID, schedule.PickUpDate.String(), schedule.LocalDay(), schedule.Timezone, ScheduleStatusRequested,
+		formatTime(schedule.CreatedAt), formatTime(schedule.UpdatedAt))
+	if err != nil {
+		return "", fmt.Errorf("failed to insert pick-up schedule: %v", err)
+	}
+
+	if err := r.claimReservations(ctx, tx, schedule.ID, reservations); err != nil {
+		return "", err
+	}
+
+	return schedule.ID, nil
+}
+
+func (r *SQLRepository) GetPickUpScheduleByID(ctx context.Context, id string) (PickUpSchedule, error) {
+	return r.scheduleByID(ctx, r.db, id)
+}
+
+func (r *SQLRepository) CancelPickUpSchedule(ctx context.Context, id string, cancellation Cancellation) (PickUpSchedule, error) {
+	return r.update(ctx, id, nil, func(schedule *PickUpSchedule) (*ScheduleChange, error) {
+		return nil, applyCancellation(schedule, cancellation, time.Now().UTC())
+	})
+}
+
+func (r *SQLRepository) TransitionPickUpSchedule(ctx context.Context, id string, transition StatusTransition) (PickUpSchedule, error) {
+	return r.update(ctx, id, nil, func(schedule *PickUpSchedule) (*ScheduleChange, error) {
+		change, err := applyTransition(schedule, transition, time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		return &change, nil
+	})
+}
+
+func (r *SQLRepository) ReschedulePickUpSchedule(ctx context.Context, id string, reschedule Reschedule, reservations ...Reservation) (PickUpSchedule, error) {
+	return r.update(ctx, id, &reservations, func(schedule *PickUpSchedule) (*ScheduleChange, error) {
+		if err := checkReschedule(schedule.Status); err != nil {
+			return nil, err
+		}
+
+		change := applyReschedule(schedule, reschedule, time.Now().UTC())
+		return &change, nil
+	})
+}
+
+func (r *SQLRepository) MarkOverdue(ctx context.Context, now time.Time) ([]PickUpSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT public_id FROM pick_up_schedules
+		WHERE status = ? AND due_at <= ?
+		ORDER BY id`, ScheduleStatusPickedUp, formatTime(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load overdue pick-up schedules: %v", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to read overdue pick-up schedule: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read overdue pick-up schedules: %v", err)
+	}
+
+	// Each schedule is checked again in the transaction flagging it
+	return markOverdue(ids, now, func(id string, fn func(schedule *PickUpSchedule) (*ScheduleChange, error)) (PickUpSchedule, error) {
+		return r.update(ctx, id, nil, fn)
+	})
+}
+
+func (r *SQLRepository) GetPickUpScheduleHistory(ctx context.Context, id string) ([]ScheduleChange, error) {
+	var history []ScheduleChange
+
+	// Read the schedule and its changes from the same snapshot
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := r.scheduleByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		var err error
+		history, err = r.scheduleChanges(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (r *SQLRepository) CountReservations(ctx context.Context, keys []string) (map[string]int, error) {
+	counts := make(map[string]int, len(keys))
+	if len(keys) == 0 {
+		return counts, nil
+	}
+
+	held, args := reservationHeldCondition()
+	for _, key := range keys {
+		counts[key] = 0
+		args = append(args, key)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.reservation_key, COUNT(*)
+		FROM schedule_reservations r
+		JOIN pick_up_schedules s ON s.id = r.schedule_id
+		WHERE `+held+` AND r.reservation_key IN (`+placeholders+`)
+		GROUP BY r.reservation_key`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reservations: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, fmt.Errorf("failed to read reservation count: %v", err)
+		}
+		counts[key] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reservation counts: %v", err)
+	}
+
+	return counts, nil
+}
+
+// update applies fn to the stored schedule and writes the result back in the
+// same transaction, unless fn fails. A change returned by fn is added to the
+// history of the schedule. Non-nil reservations replace the ones the schedule
+// holds.
+func (r *SQLRepository) update(ctx context.Context, id string, reservations *[]Reservation, fn func(schedule *PickUpSchedule) (*ScheduleChange, error)) (PickUpSchedule, error) {
+	var schedule PickUpSchedule
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if schedule, err = r.scheduleByID(ctx, tx, id); err != nil {
+			return err
+		}
+		change, err := fn(&schedule)
+		if err != nil {
+			return err
+		}
+
+		bookID, err := upsertBook(ctx, tx, schedule.Genre, schedule.BookInfo)
+		if err != nil {
+			return err
+		}
+
+		var cancelledBy, cancelReason, cancelledAt sql.NullString
+		if c := schedule.Cancellation; c != nil {
+			cancelledBy = sql.NullString{String: c.CancelledBy, Valid: true}
+			cancelReason = sql.NullString{String: c.Reason, Valid: true}
+			cancelledAt = sql.NullString{String: formatTime(c.CancelledAt), Valid: true}
+		}
+
+		var dueAt sql.NullString
+		if schedule.DueAt != nil {
+			dueAt = sql.NullString{String: formatTime(*schedule.DueAt), Valid: true}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE pick_up_schedules
+			SET book_id = ?, pick_up_date = ?, pick_up_day = ?, status = ?, cancelled_by = ?, cancel_reason = ?, cancelled_at = ?, due_at = ?, updated_at = ?
+			WHERE public_id = ?`,
+			bookID, schedule.PickUpDate.String(), schedule.LocalDay(), schedule.Status, cancelledBy, cancelReason, cancelledAt, dueAt,
+			formatTime(schedule.UpdatedAt), schedule.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update pick-up schedule: %v", err)
+		}
+
+		if reservations != nil {
+			if err := r.claimReservations(ctx, tx, schedule.ID, *reservations); err != nil {
+				return err
+			}
+		}
+
+		if change != nil {
+			return r.insertScheduleChange(ctx, tx, schedule, *change)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return PickUpSchedule{}, err
+	}
+
+	return schedule, nil
+}
+
+// claimReservations replaces the reservations of the schedule. Keys it does
+// not hold yet are checked against their limit, counting the schedules that
+// hold their reservations.
+func (r *SQLRepository) claimReservations(ctx context.Context, tx *sql.Tx, id string, reservations []Reservation) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT r.reservation_key
+		FROM schedule_reservations r
+		JOIN pick_up_schedules s ON s.id = r.schedule_id
+		WHERE s.public_id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to load reservations: %v", err)
+	}
+	held := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to read reservation: %v", err)
+		}
+		held[key] = true
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read reservations: %v", err)
+	}
+
+	for _, reservation := range reservations {
+		if held[reservation.Key] || reservation.Limit <= 0 {
+			continue
+		}
+
+		holders, err := countHolders(ctx, tx, append([]string{reservation.Key}, reservation.Shared...))
+		if err != nil {
+			return err
+		}
+		excluded, err := countHolders(ctx, tx, reservation.Excluded)
+		if err != nil {
+			return err
+		}
+		if holders-excluded >= reservation.Limit {
+			return &CapacityError{Key: reservation.Key, Limit: reservation.Limit}
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM schedule_reservations
+		WHERE schedule_id = (SELECT id FROM pick_up_schedules WHERE public_id = ?)`, id)
+	if err != nil {
+		return fmt.Errorf("failed to release reservations: %v", err)
+	}
+	for _, reservation := range reservations {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO schedule_reservations (schedule_id, reservation_key)
+			SELECT id, ? FROM pick_up_schedules WHERE public_id = ?
+			ON CONFLICT DO NOTHING`, reservation.Key, id)
+		if err != nil {
+			return fmt.Errorf("failed to save reservation: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// countHolders returns how many reservations of the keys are held
+func countHolders(ctx context.Context, tx *sql.Tx, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+
+	var holders int
+	held, heldArgs := reservationHeldCondition()
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM schedule_reservations r
+		JOIN pick_up_schedules s ON s.id = r.schedule_id
+		WHERE r.reservation_key IN (`+placeholders+`) AND `+held,
+		append(args, heldArgs...)...).Scan(&holders)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reservations: %v", err)
+	}
+	return holders, nil
+}
+
+func (r *SQLRepository) insertScheduleChange(ctx context.Context, tx *sql.Tx, schedule PickUpSchedule, change ScheduleChange) error {
+	fromBookID, err := r.optionalBookID(ctx, tx, schedule.Genre, change.FromBookInfo)
+	if err != nil {
+		return err
+	}
+	toBookID, err := r.optionalBookID(ctx, tx, schedule.Genre, change.ToBookInfo)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO schedule_changes (schedule_id, changed_by, changed_at, reason, from_pick_up_date, to_pick_up_date, from_book_id, to_book_id, from_status, to_status)
+		SELECT id, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, '')
+		FROM pick_up_schedules
+		WHERE public_id = ?`,
+		change.ChangedBy, formatTime(change.ChangedAt), change.Reason, change.FromPickUpDate.String(), change.ToPickUpDate.String(),
+		fromBookID, toBookID, change.FromStatus, change.ToStatus, schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert schedule change: %v", err)
+	}
+
+	return nil
+}
+
+// optionalBookID is upsertBook for a book that may be missing
+func (r *SQLRepository) optionalBookID(ctx context.Context, q querier, genre string, book *Book) (sql.NullInt64, error) {
+	if book == nil {
+		return sql.NullInt64{}, nil
+	}
+
+	id, err := upsertBook(ctx, q, genre, *book)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	return sql.NullInt64{Int64: id, Valid: true}, nil
+}
+
+// scheduleChanges loads the history of a schedule, oldest first
+func (r *SQLRepository) scheduleChanges(ctx context.Context, q querier, id string) ([]ScheduleChange, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT c.changed_by, c.changed_at, c.reason, c.from_pick_up_date, c.to_pick_up_date,
+			fb.id, fb.title, fb.edition_number, tb.id, tb.title, tb.edition_number,
+			COALESCE(c.from_status, ''), COALESCE(c.to_status, '')
+		FROM schedule_changes c
+		JOIN pick_up_schedules s ON s.id = c.schedule_id
+		LEFT JOIN books fb ON fb.id = c.from_book_id
+		LEFT JOIN books tb ON tb.id = c.to_book_id
+		WHERE s.public_id = ?
+		ORDER BY c.id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedule changes: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	history := []ScheduleChange{}
+	var bookIDs []int64
+	for rows.Next() {
+		var change ScheduleChange
+		var changedAt, fromPickUpDate, toPickUpDate string
+		var fromID, toID, fromEdition, toEdition sql.NullInt64
+		var fromTitle, toTitle sql.NullString
+		err := rows.Scan(&change.ChangedBy, &changedAt, &change.Reason, &fromPickUpDate, &toPickUpDate,
+			&fromID, &fromTitle, &fromEdition, &toID, &toTitle, &toEdition, &change.FromStatus, &change.ToStatus)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schedule change: %v", err)
+		}
+		if change.ChangedAt, err = parseTime(changedAt); err != nil {
+			return nil, err
+		}
+		change.FromPickUpDate, _ = ParsePickUpDate(fromPickUpDate)
+		change.ToPickUpDate, _ = ParsePickUpDate(toPickUpDate)
+		if fromID.Valid {
+			change.FromBookInfo = &Book{Title: fromTitle.String, EditionNumber: int(fromEdition.Int64)}
+			bookIDs = append(bookIDs, fromID.Int64)
+		}
+		if toID.Valid {
+			change.ToBookInfo = &Book{Title: toTitle.String, EditionNumber: int(toEdition.Int64)}
+			bookIDs = append(bookIDs, toID.Int64)
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schedule changes: %v", err)
+	}
+
+	authors, err := authorsOf(ctx, q, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Book IDs were collected in the same order the books appear
+	next := 0
+	for i := range history {
+		for _, book := range []*Book{history[i].FromBookInfo, history[i].ToBookInfo} {
+			if book != nil {
+				book.Author = authors[bookIDs[next]]
+				next++
+			}
+		}
+	}
+
+	return history, nil
+}
+
+func (r *SQLRepository) scheduleByID(ctx context.Context, q querier, id string) (PickUpSchedule, error) {
+	pickUpSchedules, err := r.querySchedules(ctx, q, `s.public_id = ?`, id)
+	if err != nil {
+		return PickUpSchedule{}, err
+	}
+	if len(pickUpSchedules) == 0 {
+		return PickUpSchedule{}, ErrScheduleNotFound
+	}
+
+	return pickUpSchedules[0], nil
+}
+
+// scheduleSortColumns maps the sort fields of a ScheduleQuery to columns
+var scheduleSortColumns = map[string]string{
+	"created_at":   "s.created_at",
+	"pick_up_date": "s.pick_up_date",
+	"title":        "b.title",
+	"due_at":       "COALESCE(s.due_at, '')",
+}
+
+func (r *SQLRepository) ListPickUpSchedules(ctx context.Context, query ScheduleQuery) (SchedulePage, error) {
+	field, descending := parseScheduleSort(query.Sort)
+	column, exists := scheduleSortColumns[field]
+	if !exists {
+		return SchedulePage{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidScheduleQuery, field)
+	}
+	after, err := decodeScheduleCursor(query.Cursor, query.Sort)
+	if err != nil {
+		return SchedulePage{}, err
+	}
+
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if query.Genre != "" {
+		conditions = append(conditions, "g.name = ?")
+		args = append(args, query.Genre)
+	}
+	if query.BranchID != "" {
+		conditions = append(conditions, "br.public_id = ?")
+		args = append(args, query.BranchID)
+	}
+	if query.BorrowerID != "" {
+		conditions = append(conditions, "bo.public_id = ?")
+		args = append(args, query.BorrowerID)
+	}
+	if query.Status != "" {
+		conditions = append(conditions, "s.status = ?")
+		args = append(args, query.Status)
+	}
+	if query.Title != "" {
+		conditions = append(conditions, `LOWER(b.title) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(strings.ToLower(query.Title))+"%")
+	}
+	if query.PickUpFrom != "" {
+		conditions = append(conditions, "s.pick_up_day >= ?")
+		args = append(args, query.PickUpFrom)
+	}
+	if query.PickUpTo != "" {
+		conditions = append(conditions, "s.pick_up_day <= ?")
+		args = append(args, query.PickUpTo)
+	}
+
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+	if after != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s %s ? OR (%s = ? AND s.public_id > ?))", column, comparison, column))
+		args = append(args, after.Value, after.Value, after.ID)
+	}
+
+	where := strings.Join(conditions, " AND ")
+	order := fmt.Sprintf("%s %s, s.public_id ASC", column, direction)
+
+	// One extra row tells whether another page follows
+	pickUpSchedules, err := r.querySchedulesOrdered(ctx, r.db, where, order, query.Limit+1, args...)
+	if err != nil {
+		return SchedulePage{}, err
+	}
+
+	return newSchedulePage(pickUpSchedules, query), nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// inTx runs fn in a transaction that is committed when fn returns no error
+func (r *SQLRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return runInTx(ctx, r.db, fn)
+}
+
+// runInTx runs fn in a transaction of db that is committed when fn returns no
+// error
+func runInTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// upsertBook returns the id of the book, creating the genre and book if needed
+func upsertBook(ctx context.Context, q querier, genre string, book Book) (int64, error) {
+	_, err := q.ExecContext(ctx, `INSERT INTO genres (name) VALUES (?) ON CONFLICT (name) DO NOTHING`, genre)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save genre: %v", err)
+	}
+
+	var genreID int64
+	if err := q.QueryRowContext(ctx, `SELECT id FROM genres WHERE name = ?`, genre).Scan(&genreID); err != nil {
+		return 0, fmt.Errorf("failed to load genre: %v", err)
+	}
+
+	authorsKey := strings.Join(book.Author, "\x1f")
+
+	var bookID int64
+	err = q.QueryRowContext(ctx,
+		`SELECT id FROM books WHERE genre_id = ? AND title = ? AND edition_number = ? AND authors_key = ?`,
+		genreID, book.Title, book.EditionNumber, authorsKey).Scan(&bookID)
+	if err == nil {
+		return bookID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to load book: %v", err)
+	}
+
+	result, err := q.ExecContext(ctx,
+		`INSERT INTO books (genre_id, title, edition_number, authors_key) VALUES (?, ?, ?, ?)`,
+		genreID, book.Title, book.EditionNumber, authorsKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save book: %v", err)
+	}
+	if bookID, err = result.LastInsertId(); err != nil {
+		return 0, fmt.Errorf("failed to save book: %v", err)
+	}
+
+	for position, author := range book.Author {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO book_authors (book_id, position, name) VALUES (?, ?, ?)`,
+			bookID, position, author)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save book author: %v", err)
+		}
+	}
+
+	return bookID, nil
+}
+
+// schedulesByGenre returns the schedules of a genre that hold their
+// reservations
+func (r *SQLRepository) schedulesByGenre(ctx context.Context, q querier, genre string) ([]PickUpSchedule, error) {
+	held, args := heldCondition()
+	return r.querySchedules(ctx, q, `g.name = ? AND `+held, append([]interface{}{genre}, args...)...)
+}
+
+// heldCondition is holdsReservations as a condition on the schedule s, with
+// its arguments
+func heldCondition() (string, []interface{}) {
+	args := make([]interface{}, 0, len(releasingStatuses))
+	for _, status := range releasingStatuses {
+		args = append(args, status)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+
+	return `s.status NOT IN (` + placeholders + `)`, args
+}
+
+// reservationHeldCondition is holdsReservation as a condition on the
+// reservation r of the schedule s, with its arguments
+func reservationHeldCondition() (string, []interface{}) {
+	held, args := heldCondition()
+	onLoan := make([]interface{}, 0, len(onLoanStatuses))
+	for _, status := range onLoanStatuses {
+		onLoan = append(onLoan, status)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(onLoan)), ", ")
+
+	condition := `CASE WHEN r.reservation_key LIKE ? THEN s.status <> ? ELSE ` + held + ` AND CASE
+		WHEN r.reservation_key LIKE ? THEN s.status IN (` + placeholders + `)
+		WHEN r.reservation_key LIKE ? THEN s.status NOT IN (` + placeholders + `)
+		ELSE 1 END END`
+	args = append([]interface{}{weekKeyPrefix + "%", ScheduleStatusCancelled}, args...)
+	args = append(args, loanedCopyKeyPrefix+"%")
+	args = append(args, onLoan...)
+	args = append(args, copyKeyPrefix+"%")
+	args = append(args, onLoan...)
+	return condition, args
+}
+
+// querySchedules loads the schedules matching the where clause, which can
+// refer to the schedule, book, genre, branch and borrower as s, b, g, br and
+// bo, in insertion order
+func (r *SQLRepository) querySchedules(ctx context.Context, q querier, where string, args ...interface{}) ([]PickUpSchedule, error) {
+	return r.querySchedulesOrdered(ctx, q, where, "s.id", -1, args...)
+}
+
+// querySchedulesOrdered is querySchedules with an ORDER BY clause and a limit,
+// which is ignored when negative
+func (r *SQLRepository) querySchedulesOrdered(ctx context.Context, q querier, where, order string, limit int, args ...interface{}) ([]PickUpSchedule, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT s.public_id, b.id, b.title, b.edition_number, s.pick_up_date, g.name, COALESCE(br.public_id, ''), s.timezone, COALESCE(bo.public_id, ''), s.status,
+			s.cancelled_by, s.cancel_reason, s.cancelled_at, s.due_at, s.created_at, s.updated_at
+		FROM pick_up_schedules s
+		JOIN books b ON b.id = s.book_id
+		JOIN genres g ON g.id = b.genre_id
+		LEFT JOIN branches br ON br.id = s.branch_id
+		LEFT JOIN borrowers bo ON bo.id = s.borrower_id
+		WHERE `+where+`
+		ORDER BY `+order+`
+		LIMIT `+strconv.Itoa(limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pick-up schedules: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var pickUpSchedules []PickUpSchedule
+	var bookIDs []int64
+	for rows.Next() {
+		var bookID int64
+		var pickUpDate, createdAt, updatedAt string
+		var cancelledBy, cancelReason, cancelledAt, dueAt sql.NullString
+		var schedule PickUpSchedule
+		err := rows.Scan(&schedule.ID, &bookID, &schedule.BookInfo.Title, &schedule.BookInfo.EditionNumber,
+			&pickUpDate, &schedule.Genre, &schedule.BranchID, &schedule.Timezone, &schedule.BorrowerID, &schedule.Status,
+			&cancelledBy, &cancelReason, &cancelledAt, &dueAt, &createdAt, &updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pick-up schedule: %v", err)
+		}
+		// Dates stored before they were checked are kept as they are
+		schedule.PickUpDate, _ = ParsePickUpDate(pickUpDate)
+		if cancelledAt.Valid {
+			schedule.Cancellation = &Cancellation{CancelledBy: cancelledBy.String, Reason: cancelReason.String}
+			if schedule.Cancellation.CancelledAt, err = parseTime(cancelledAt.String); err != nil {
+				return nil, err
+			}
+		}
+		if dueAt.Valid {
+			due, err := parseTime(dueAt.String)
+			if err != nil {
+				return nil, err
+			}
+			schedule.DueAt = &due
+		}
+		if schedule.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		if schedule.UpdatedAt, err = parseTime(updatedAt); err != nil {
+			return nil, err
+		}
+		pickUpSchedules = append(pickUpSchedules, schedule)
+		bookIDs = append(bookIDs, bookID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pick-up schedules: %v", err)
+	}
+
+	authors, err := authorsOf(ctx, q, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pickUpSchedules {
+		pickUpSchedules[i].BookInfo.Author = authors[bookIDs[i]]
+	}
+
+	return pickUpSchedules, nil
+}
+
+func authorsOf(ctx context.Context, q querier, bookIDs []int64) (map[int64][]string, error) {
+	authors := make(map[int64][]string)
+	if len(bookIDs) == 0 {
+		return authors, nil
+	}
+
+	var args []interface{}
+	for _, id := range bookIDs {
+		if _, seen := authors[id]; !seen {
+			authors[id] = nil
+			args = append(args, id)
+		}
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT book_id, name
+		FROM book_authors
+		WHERE book_id IN (`+placeholders+`)
+		ORDER BY book_id, position`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load book authors: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		var bookID int64
+		var name string
+		if err := rows.Scan(&bookID, &name); err != nil {
+			return nil, fmt.Errorf("failed to read book author: %v", err)
+		}
+		authors[bookID] = append(authors[bookID], name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read book authors: %v", err)
+	}
+
+	return authors, nil
+}
+
+// storedTimeLayout is RFC 3339 with a fixed number of fraction digits, so
+// stored timestamps sort correctly as text
+const storedTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// formatTime is how timestamps are stored
+func formatTime(t time.Time) string {
+	return t.UTC().Format(storedTimeLayout)
+}
+
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse stored time %q: %v", value, err)
+	}
+	return t, nil
+}
+
+// migration is one embedded file named <version>_<name>.sql
+type migration struct {
+	version int
+	name    string
+	query   string
+}
+
+// migrate applies every embedded migration newer than the schema version of
+// db, each one in its own transaction. Every SQL repository runs it, so the
+// ones sharing a database find nothing left to apply.
+func migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations
+		(
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	var current int
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to load schema version: %v", err)
+	}
+
+	pending, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		if m.version <= current {
+			continue
+		}
+
+		err := runInTx(ctx, db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, m.query); err != nil {
+				return fmt.Errorf("failed to apply migration %04d_%s: %v", m.version, m.name, err)
+			}
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				m.version, m.name, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	var result []migration
+	for _, entry := range entries {
+		version, name, found := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		number, err := strconv.Atoi(version)
+		if !found || err != nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		query, err := fs.ReadFile(migrations, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %v", entry.Name(), err)
+		}
+
+		result = append(result, migration{version: number, name: name, query: string(query)})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].version < result[j].version
+	})
+
+	return result, nil
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB opens an empty SQLite database that lives as long as the test
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", filepath.Join(t.TempDir(), "books.db"))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	// SQLite allows a single writer, serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return db
+}
+
+func TestSQLRepository_Suite(t *testing.T) {
+	testBookRepository(t, sqlStoreOf(NewSQLRepository))
+}
+
+func TestSQLRepository_Migrations(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	t.Run("PositiveCase_AppliesEveryMigration", func(t *testing.T) {
+		if _, err := NewSQLRepository(ctx, db); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		embedded, err := loadMigrations()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var version, count int
+		if err := db.QueryRow(`SELECT MAX(version), COUNT(*) FROM schema_migrations`).Scan(&version, &count); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if version != embedded[len(embedded)-1].version || count != len(embedded) {
+			t.Errorf("Expected %d migrations up to version %d, got %d up to %d",
+				len(embedded), embedded[len(embedded)-1].version, count, version)
+		}
+	})
+
+	t.Run("PositiveCase_Idempotent", func(t *testing.T) {
+		repo, err := NewSQLRepository(ctx, db)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Kept"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// Opening the same database again must keep the data
+		reopened, err := NewSQLRepository(ctx, db)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		pickUpSchedules, err := reopened.schedulesByGenre(ctx, db, "fiction")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(pickUpSchedules) != 1 {
+			t.Errorf("Expected 1 pick-up schedule, got %d", len(pickUpSchedules))
+		}
+	})
+}
+
+func TestSQLRepository_FillPickUpDays(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	repo, err := NewSQLRepository(ctx, db)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saved, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: Book{Title: "Local"}, Timezone: "Asia/Kathmandu", PickUpDate: mustParsePickUpDate("2023-11-21T18:45:00Z")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// As saved before the local day was stored
+	if _, err := db.Exec(`UPDATE pick_up_schedules SET pick_up_day = ''`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := NewSQLRepository(ctx, db); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var day string
+	if err := db.QueryRow(`SELECT pick_up_day FROM pick_up_schedules WHERE public_id = ?`, saved[0].ID).Scan(&day); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if day != "2023-11-22" {
+		t.Errorf("Expected the local day 2023-11-22 to be filled in, got %q", day)
+	}
+}
+
+func TestSQLRepository_SavePickUpSchedule(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	repo, err := NewSQLRepository(ctx, db)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("PositiveCase_BooksAndGenresNormalized", func(t *testing.T) {
+		schedule := PickUpSchedule{
+			Genre:    "fiction",
+			BookInfo: Book{Title: "TestBook", Author: []string{"Author1", "Author2"}, EditionNumber: 1},
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := repo.SavePickUpSchedule(ctx, schedule); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		var genres, books, authors, schedules int
+		err := db.QueryRow(`SELECT
+			(SELECT COUNT(*) FROM genres),
+			(SELECT COUNT(*) FROM books),
+			(SELECT COUNT(*) FROM book_authors),
+			(SELECT COUNT(*) FROM pick_up_schedules)`).Scan(&genres, &books, &authors, &schedules)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if genres != 1 || books != 1 || authors != 2 || schedules != 3 {
+			t.Errorf("Expected 1 genre, 1 book, 2 authors and 3 schedules, got %d, %d, %d and %d",
+				genres, books, authors, schedules)
+		}
+	})
+
+	t.Run("NegativeCase_RolledBack", func(t *testing.T) {
+		// Break the schedules table so the insert fails after the book was saved
+		if _, err := db.Exec(`ALTER TABLE pick_up_schedules RENAME TO pick_up_schedules_broken`); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer func() {
+			_, _ = db.Exec(`ALTER TABLE pick_up_schedules_broken RENAME TO pick_up_schedules`)
+		}()
+
+		_, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "horror", BookInfo: Book{Title: "Lost"}})
+		if err == nil {
+			t.Fatal("Expected error, but got nil")
+		}
+
+		var genres int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM genres WHERE name = 'horror'`).Scan(&genres); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if genres != 0 {
+			t.Error("Expected the genre insert to be rolled back")
+		}
+	})
+}
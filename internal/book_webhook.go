@@ -0,0 +1,21 @@
+package internal
+
+import (
+	"context"
+	"log"
+)
+
+// publish tells the webhooks and the event stream about a schedule that was
+// just changed. The change is already stored, so failing to queue the event
+// is only logged.
+func (s *bookService) publish(ctx context.Context, eventType WebhookEventType, schedule PickUpSchedule) {
+	if s.stream != nil {
+		_ = s.stream.PublishScheduleEvent(ctx, eventType, schedule)
+	}
+	if s.webhooks == nil {
+		return
+	}
+	if err := s.webhooks.PublishScheduleEvent(ctx, eventType, schedule); err != nil {
+		log.Printf("failed to publish %s of pick-up schedule %s: %v", eventType, schedule.ID, err)
+	}
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// mockPublisher is a WebhookPublisher recording the events it is told about
+type mockPublisher struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (m *mockPublisher) PublishScheduleEvent(ctx context.Context, eventType WebhookEventType, schedule PickUpSchedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, string(eventType)+" "+schedule.ID+" "+string(schedule.Status))
+	return nil
+}
+
+func TestBookService_Webhooks(t *testing.T) {
+	ctx := context.Background()
+	book := Book{Title: "TestBook"}
+	newService := func(publisher *mockPublisher) BookService {
+		return NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch).stock(5, "fiction", book), newMockBorrowers(testBorrower),
+			WithClock(serviceClock), WithWebhooks(publisher))
+	}
+	equal := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("PositiveCase_ScheduleLifecycle", func(t *testing.T) {
+		publisher := &mockPublisher{}
+		service := newService(publisher)
+
+		submitted, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate("2023-11-21T10:00:00Z")})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		id := submitted.Data.ID
+		if _, err := service.ReschedulePickUpScheduleService(ctx, id, Reschedule{PickUpDate: mustParsePickUpDate("2023-11-22T10:00:00Z"), ChangedBy: "reader"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := service.TransitionPickUpScheduleService(ctx, id, StatusTransition{Status: ScheduleStatusConfirmed, ChangedBy: "desk"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := service.CancelPickUpScheduleService(ctx, id, Cancellation{CancelledBy: "reader"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := []string{
+			"schedule.created " + id + " requested",
+			"schedule.updated " + id + " requested",
+			"schedule.updated " + id + " confirmed",
+			"schedule.cancelled " + id + " cancelled",
+		}
+		if !equal(publisher.events, want) {
+			t.Errorf("Expected %v, got %v", want, publisher.events)
+		}
+	})
+
+	t.Run("NegativeCase_NoEventOfRejectedChange", func(t *testing.T) {
+		publisher := &mockPublisher{}
+		service := newService(publisher)
+
+		if _, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate("2023-11-01T10:00:00Z")}); err == nil {
+			t.Fatal("Expected error, but got nil")
+		}
+		if _, err := service.CancelPickUpScheduleService(ctx, "missing", Cancellation{CancelledBy: "reader"}); err == nil {
+			t.Fatal("Expected error, but got nil")
+		}
+		if len(publisher.events) != 0 {
+			t.Errorf("Expected no events, got %v", publisher.events)
+		}
+	})
+
+	t.Run("PositiveCase_NoEventOfRolledBackBatch", func(t *testing.T) {
+		publisher := &mockPublisher{}
+		service := newService(publisher)
+
+		_, err := service.SubmitPickUpSchedulesService(ctx, ScheduleBatch{Schedules: []PickUpSchedule{
+			{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate("2023-11-21T10:00:00Z")},
+			// The same book on the same day is only found when saving
+			{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate("2023-11-21T14:00:00Z")},
+		}})
+		if err == nil {
+			t.Fatal("Expected error, but got nil")
+		}
+		if len(publisher.events) != 0 {
+			t.Errorf("Expected no events, got %v", publisher.events)
+		}
+	})
+}
+
+func TestBookService_EventStream(t *testing.T) {
+	ctx := context.Background()
+	book := Book{Title: "TestBook"}
+	stream := NewScheduleEventStream(DefaultEventBufferSize)
+	service := NewService(NewInMemoryRepository(ctx), newMockBranches(testBranch).stock(5, "fiction", book), newMockBorrowers(testBorrower),
+		WithClock(serviceClock), WithEventStream(stream))
+	_, events, _, cancel := stream.Subscribe(ScheduleEventFilter{Genre: "fiction"}, "")
+	defer cancel()
+
+	submitted, err := service.SubmitPickUpScheduleService(ctx, PickUpSchedule{Genre: "fiction", BranchID: "central", BorrowerID: "reader", BookInfo: book, PickUpDate: mustParsePickUpDate("2023-11-21T10:00:00Z")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := service.CancelPickUpScheduleService(ctx, submitted.Data.ID, Cancellation{CancelledBy: "reader"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, want := range []WebhookEventType{WebhookScheduleCreated, WebhookScheduleCancelled} {
+		if event := <-events; event.Type != want || event.Data.ID != submitted.Data.ID {
+			t.Errorf("Expected %s of %s, got %+v", want, submitted.Data.ID, event)
+		}
+	}
+}
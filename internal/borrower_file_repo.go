@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const fileBorrowersName = "borrowers.json"
+
+// FileBorrowerRepository is a BorrowerRepository that keeps every borrower in
+// memory and rewrites all of them to a single file on every change, like
+// FileBranchRepository
+type FileBorrowerRepository struct {
+	*InMemoryBorrowerRepository
+
+	dir string
+}
+
+// borrowersRecord is the content of the borrowers file
+type borrowersRecord struct {
+	Borrowers []Borrower `json:"borrowers"`
+}
+
+func NewFileBorrowerRepository(dir string) (*FileBorrowerRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	r := &FileBorrowerRepository{
+		InMemoryBorrowerRepository: NewInMemoryBorrowerRepository(),
+		dir:                        dir,
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, fileBorrowersName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read borrowers: %v", err)
+	default:
+		var record borrowersRecord
+		if err := decodeRecord(data, &record); err != nil {
+			return nil, fmt.Errorf("corrupt borrowers file: %v", err)
+		}
+		r.restore(record.Borrowers)
+	}
+	r.persist = r.writeBorrowers
+
+	return r, nil
+}
+
+// writeBorrowers is the persist hook of the embedded InMemoryBorrowerRepository
+func (r *FileBorrowerRepository) writeBorrowers(borrowers []Borrower) error {
+	data, err := encodeRecord(borrowersRecord{Borrowers: borrowers})
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(r.dir, fileBorrowersName, "borrowers", data)
+}
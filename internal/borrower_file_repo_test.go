@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFileBorrowerRepository_Suite(t *testing.T) {
+	testBorrowerRepository(t, fileStoreOf(NewFileBorrowerRepository))
+}
+
+func TestFileBorrowerRepository_Restart(t *testing.T) {
+	ctx := context.Background()
+
+	testFileRestart(t, NewFileBorrowerRepository, fileBorrowersName, func(t *testing.T, repo *FileBorrowerRepository) func(t *testing.T, reopened *FileBorrowerRepository) {
+		created, err := repo.CreateBorrower(ctx, Borrower{Name: "Reader", Email: "reader@example.com"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return func(t *testing.T, reopened *FileBorrowerRepository) {
+			fetched, err := reopened.GetBorrower(ctx, created.ID)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !sameBorrower(fetched, created) {
+				t.Errorf("Expected %+v after restart, got %+v", created, fetched)
+			}
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type BorrowerHandler interface {
+	ListBorrowersHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetBorrowerHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CreateBorrowerHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+}
+
+type borrowerHandler struct {
+	service BorrowerService
+}
+
+func NewBorrowerHandler(service BorrowerService) BorrowerHandler {
+	return &borrowerHandler{
+		service: service,
+	}
+}
+
+// ListBorrowersHandler serves GET /borrowers
+func (h *borrowerHandler) ListBorrowersHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	borrowers, err := h.service.ListBorrowersService(r.Context())
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), borrowers)
+		return
+	}
+	writeJSON(w, http.StatusOK, borrowers)
+}
+
+// GetBorrowerHandler serves GET /borrowers/:id
+func (h *borrowerHandler) GetBorrowerHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	borrower, err := h.service.GetBorrowerService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), borrower)
+		return
+	}
+	writeJSON(w, http.StatusOK, borrower)
+}
+
+// CreateBorrowerHandler serves POST /borrowers with a Borrower body
+func (h *borrowerHandler) CreateBorrowerHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var borrower Borrower
+	if err := json.NewDecoder(r.Body).Decode(&borrower); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	created, err := h.service.CreateBorrowerService(r.Context(), borrower)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), created)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
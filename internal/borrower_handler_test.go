@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type mockBorrowerService struct {
+	listBorrowersResponse  BorrowerListResponse
+	listBorrowersError     error
+	getBorrowerResponse    BorrowerResponse
+	getBorrowerError       error
+	createBorrowerResponse BorrowerResponse
+	createBorrowerError    error
+	createBorrowerInput    Borrower
+}
+
+func (m *mockBorrowerService) ListBorrowersService(ctx context.Context) (BorrowerListResponse, error) {
+	return m.listBorrowersResponse, m.listBorrowersError
+}
+
+func (m *mockBorrowerService) GetBorrowerService(ctx context.Context, id string) (BorrowerResponse, error) {
+	return m.getBorrowerResponse, m.getBorrowerError
+}
+
+func (m *mockBorrowerService) CreateBorrowerService(ctx context.Context, borrower Borrower) (BorrowerResponse, error) {
+	m.createBorrowerInput = borrower
+	return m.createBorrowerResponse, m.createBorrowerError
+}
+
+func TestBorrowerHandler_ListBorrowersHandler(t *testing.T) {
+	mockService := &mockBorrowerService{
+		listBorrowersResponse: BorrowerListResponse{
+			Status:    "200 OK",
+			IsSuccess: true,
+			Message:   "fetch borrowers successfully!",
+			TotalData: 1,
+			Data:      []Borrower{testBorrower},
+		},
+	}
+	handler := NewBorrowerHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/borrowers", handler.ListBorrowersHandler)
+
+	req := httptest.NewRequest("GET", "/borrowers", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", rec.Code)
+	}
+
+	var response BorrowerListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Email != testBorrower.Email {
+		t.Errorf("Expected the borrower in the body, got %+v", response)
+	}
+}
+
+func TestBorrowerHandler_GetBorrowerHandler(t *testing.T) {
+	mockService := &mockBorrowerService{
+		getBorrowerResponse: BorrowerResponse{Status: "404 Not Found"},
+		getBorrowerError:    ErrBorrowerNotFound,
+	}
+	handler := NewBorrowerHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/borrowers/:id", handler.GetBorrowerHandler)
+
+	req := httptest.NewRequest("GET", "/borrowers/missing", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404, got %d", rec.Code)
+	}
+}
+
+func TestBorrowerHandler_CreateBorrowerHandler(t *testing.T) {
+	mockService := &mockBorrowerService{
+		createBorrowerResponse: BorrowerResponse{Status: "201 CREATED", IsSuccess: true, Data: Borrower{ID: "new"}},
+	}
+	handler := NewBorrowerHandler(mockService)
+
+	router := httprouter.New()
+	router.POST("/borrowers", handler.CreateBorrowerHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"name": "Emma", "email": "emma@example.com"}`
+		req := httptest.NewRequest("POST", "/borrowers", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("Expected status code 201, got %d", rec.Code)
+		}
+		if input := mockService.createBorrowerInput; input.Name != "Emma" || input.Email != "emma@example.com" {
+			t.Errorf("Expected borrower from body, got %+v", input)
+		}
+	})
+
+	t.Run("NegativeCase_ValidationFailure", func(t *testing.T) {
+		mockService.createBorrowerResponse = BorrowerResponse{Status: "400 Bad Request", Errors: []FieldError{{Field: "name", Message: "is required"}}}
+		mockService.createBorrowerError = &ValidationError{Fields: mockService.createBorrowerResponse.Errors}
+
+		req := httptest.NewRequest("POST", "/borrowers", bytes.NewReader([]byte(`{}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
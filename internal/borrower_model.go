@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Borrower is the library member a pick-up schedule or hold is booked for
+type Borrower struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BorrowerLimits caps how much a single borrower can book. A limit of zero
+// leaves that part unlimited.
+type BorrowerLimits struct {
+	// MaxActiveLoans is how many schedules a borrower can have from the
+	// request until the book is returned, leaving out the cancelled and
+	// missed ones
+	MaxActiveLoans int
+	// MaxPickUpsPerWeek is how many pick-ups a borrower can have scheduled
+	// in one week from Monday to Sunday, in the local time of the branch,
+	// leaving out the cancelled ones
+	MaxPickUpsPerWeek int
+}
+
+// DefaultBorrowerLimits are used unless WithBorrowerLimits says otherwise
+var DefaultBorrowerLimits = BorrowerLimits{MaxActiveLoans: 5, MaxPickUpsPerWeek: 3}
+
+// Validate reports negative limits
+func (l BorrowerLimits) Validate() error {
+	if l.MaxActiveLoans < 0 {
+		return fmt.Errorf("maximum of %d active loans must not be negative", l.MaxActiveLoans)
+	}
+	if l.MaxPickUpsPerWeek < 0 {
+		return fmt.Errorf("maximum of %d pick-ups per week must not be negative", l.MaxPickUpsPerWeek)
+	}
+	return nil
+}
+
+// ErrBorrowingLimit is matched by every *LimitError
+var ErrBorrowingLimit = errors.New("borrowing limit reached")
+
+// LimitError is returned when a schedule would take a borrower past one of
+// their BorrowerLimits
+type LimitError struct {
+	BorrowerID string
+	// Limit names the limit, "active loans" or "pick-ups per week"
+	Limit string
+	Max   int
+	// Week is the Monday of the week that is full, for the weekly limit
+	Week string
+}
+
+func (e *LimitError) Error() string {
+	if e.Week != "" {
+		return fmt.Sprintf("%v: borrower %s already has %d %s in the week of %s", ErrBorrowingLimit, e.BorrowerID, e.Max, e.Limit, e.Week)
+	}
+	return fmt.Sprintf("%v: borrower %s already has %d %s", ErrBorrowingLimit, e.BorrowerID, e.Max, e.Limit)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrBorrowingLimit
+}
+
+const (
+	limitActiveLoans    = "active loans"
+	limitPickUpsPerWeek = "pick-ups per week"
+)
+
+// loanKey is the reservation key every active loan of a borrower holds
+func loanKey(borrowerID string) string {
+	return "loan:" + borrowerID
+}
+
+// weekKeyPrefix starts the reservation keys of weekKey
+const weekKeyPrefix = "week:"
+
+// weekKey is the reservation key every pick-up of a borrower in the local
+// week starting at start holds until it is cancelled
+func weekKey(borrowerID string, start time.Time) string {
+	return weekKeyPrefix + borrowerID + ":" + start.Format(pickUpDayLayout)
+}
+
+// weekOf returns the Monday the local week of t starts on and the Monday
+// after it, both at midnight in loc
+func weekOf(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	day := midnight(t.In(loc))
+	// time.Weekday starts on Sunday, weeks here start on Monday
+	offset := (int(day.Weekday()) + 6) % 7
+	start := time.Date(day.Year(), day.Month(), day.Day()-offset, 0, 0, 0, 0, loc)
+	return start, time.Date(start.Year(), start.Month(), start.Day()+7, 0, 0, 0, 0, loc)
+}
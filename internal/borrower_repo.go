@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrBorrowerNotFound is returned when no borrower has the requested ID
+var ErrBorrowerNotFound = errors.New("borrower not found")
+
+// BorrowerRepository stores the borrowers pick-ups are booked for
+type BorrowerRepository interface {
+	// ListBorrowers returns every borrower ordered by name
+	ListBorrowers(ctx context.Context) ([]Borrower, error)
+	GetBorrower(ctx context.Context, id string) (Borrower, error)
+	// CreateBorrower assigns the borrower a new ID and timestamps and stores it
+	CreateBorrower(ctx context.Context, borrower Borrower) (Borrower, error)
+}
+
+type InMemoryBorrowerRepository struct {
+	mu        sync.RWMutex
+	borrowers map[string]Borrower
+
+	// persist, when set, is called with every borrower including the new one
+	// while mu is held. The borrower is only stored if it returns no error.
+	persist func(borrowers []Borrower) error
+}
+
+func NewInMemoryBorrowerRepository() *InMemoryBorrowerRepository {
+	return &InMemoryBorrowerRepository{
+		borrowers: make(map[string]Borrower),
+	}
+}
+
+func (r *InMemoryBorrowerRepository) ListBorrowers(_ context.Context) ([]Borrower, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sorted(nil), nil
+}
+
+func (r *InMemoryBorrowerRepository) GetBorrower(_ context.Context, id string) (Borrower, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	borrower, exists := r.borrowers[id]
+	if !exists {
+		return Borrower{}, ErrBorrowerNotFound
+	}
+
+	return borrower, nil
+}
+
+func (r *InMemoryBorrowerRepository) CreateBorrower(_ context.Context, borrower Borrower) (Borrower, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	borrower.ID = newBorrowerID()
+	borrower.CreatedAt = now
+	borrower.UpdatedAt = now
+
+	if r.persist != nil {
+		if err := r.persist(r.sorted(&borrower)); err != nil {
+			return Borrower{}, err
+		}
+	}
+
+	r.borrowers[borrower.ID] = borrower
+
+	return borrower, nil
+}
+
+// restore puts already persisted borrowers back without calling persist
+func (r *InMemoryBorrowerRepository) restore(borrowers []Borrower) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, borrower := range borrowers {
+		r.borrowers[borrower.ID] = borrower
+	}
+}
+
+// sorted returns every borrower ordered by name, with added among them when
+// it is not nil. mu must be held.
+func (r *InMemoryBorrowerRepository) sorted(added *Borrower) []Borrower {
+	borrowers := make([]Borrower, 0, len(r.borrowers)+1)
+	for _, borrower := range r.borrowers {
+		borrowers = append(borrowers, borrower)
+	}
+	if added != nil {
+		borrowers = append(borrowers, *added)
+	}
+
+	sort.Slice(borrowers, func(i, j int) bool {
+		if borrowers[i].Name == borrowers[j].Name {
+			return borrowers[i].ID < borrowers[j].ID
+		}
+		return borrowers[i].Name < borrowers[j].Name
+	})
+
+	return borrowers
+}
+
+// newBorrowerID returns a random identifier of the same form as schedule IDs
+func newBorrowerID() string {
+	return newScheduleID()
+}
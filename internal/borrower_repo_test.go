@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInMemoryBorrowerRepository_Suite(t *testing.T) {
+	testBorrowerRepository(t, func(t *testing.T) BorrowerRepository {
+		return NewInMemoryBorrowerRepository()
+	})
+}
+
+// testBorrowerRepository checks the behaviour every BorrowerRepository shares.
+// newRepo must return an empty repository.
+func testBorrowerRepository[R BorrowerRepository](t *testing.T, newRepo func(t *testing.T) R) {
+	ctx := context.Background()
+
+	t.Run("CreateBorrower_AssignsIDAndTimestamps", func(t *testing.T) {
+		repo := newRepo(t)
+
+		created, err := repo.CreateBorrower(ctx, Borrower{Name: "Reader", Email: "reader@example.com"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if created.ID == "" || created.CreatedAt.IsZero() || !created.UpdatedAt.Equal(created.CreatedAt) {
+			t.Errorf("Expected ID and timestamps, got %+v", created)
+		}
+
+		fetched, err := repo.GetBorrower(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !sameBorrower(fetched, created) {
+			t.Errorf("Expected %+v, got %+v", created, fetched)
+		}
+	})
+
+	t.Run("GetBorrower_NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetBorrower(ctx, "missing"); !errors.Is(err, ErrBorrowerNotFound) {
+			t.Errorf("Expected ErrBorrowerNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ListBorrowers_OrderedByName", func(t *testing.T) {
+		repo := newRepo(t)
+
+		borrowers, err := repo.ListBorrowers(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if borrowers == nil || len(borrowers) != 0 {
+			t.Errorf("Expected an empty non-nil list, got %v", borrowers)
+		}
+
+		for _, name := range []string{"Marianne", "Anne", "Emma"} {
+			if _, err := repo.CreateBorrower(ctx, Borrower{Name: name}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		borrowers, err = repo.ListBorrowers(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var names []string
+		for _, borrower := range borrowers {
+			names = append(names, borrower.Name)
+		}
+		if !reflect.DeepEqual(names, []string{"Anne", "Emma", "Marianne"}) {
+			t.Errorf("Expected borrowers ordered by name, got %v", names)
+		}
+	})
+}
+
+func sameBorrower(got, want Borrower) bool {
+	return got.ID == want.ID && got.Name == want.Name && got.Email == want.Email &&
+		got.CreatedAt.Equal(want.CreatedAt) && got.UpdatedAt.Equal(want.UpdatedAt)
+}
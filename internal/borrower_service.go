@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+type BorrowerService interface {
+	ListBorrowersService(ctx context.Context) (BorrowerListResponse, error)
+	GetBorrowerService(ctx context.Context, id string) (BorrowerResponse, error)
+	CreateBorrowerService(ctx context.Context, borrower Borrower) (BorrowerResponse, error)
+}
+
+type BorrowerResponse struct {
+	Status    string       `json:"status"`
+	IsSuccess bool         `json:"is_success"`
+	Message   string       `json:"message"`
+	TotalData int          `json:"total_data"`
+	Data      Borrower     `json:"data"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+type BorrowerListResponse struct {
+	Status    string     `json:"status"`
+	IsSuccess bool       `json:"is_success"`
+	Message   string     `json:"message"`
+	TotalData int        `json:"total_data"`
+	Data      []Borrower `json:"data"`
+}
+
+type borrowerService struct {
+	repository BorrowerRepository
+}
+
+func NewBorrowerService(repository BorrowerRepository) BorrowerService {
+	return &borrowerService{
+		repository: repository,
+	}
+}
+
+func (s *borrowerService) ListBorrowersService(ctx context.Context) (BorrowerListResponse, error) {
+	borrowers, err := s.repository.ListBorrowers(ctx)
+	if err != nil {
+		return BorrowerListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch borrowers: %v", err),
+			Data:      []Borrower{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := BorrowerListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch borrowers successfully!",
+		TotalData: len(borrowers),
+		Data:      []Borrower{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	response.Data = append(response.Data, borrowers...)
+
+	return response, nil
+}
+
+func (s *borrowerService) GetBorrowerService(ctx context.Context, id string) (BorrowerResponse, error) {
+	borrower, err := s.repository.GetBorrower(ctx, id)
+	if err != nil {
+		return failedBorrowerResponse("failed to fetch borrower", err), err
+	}
+
+	response := BorrowerResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch borrower successfully!",
+		TotalData: 1,
+		Data:      borrower,
+	}
+
+	return response, nil
+}
+
+func (s *borrowerService) CreateBorrowerService(ctx context.Context, borrower Borrower) (BorrowerResponse, error) {
+	borrower.Name = strings.TrimSpace(borrower.Name)
+	borrower.Email = strings.TrimSpace(borrower.Email)
+
+	var fields []FieldError
+	if borrower.Name == "" {
+		fields = append(fields, FieldError{Field: "name", Message: "is required"})
+	}
+	// The email is optional, but has to be a bare address when given
+	if address, err := mail.ParseAddress(borrower.Email); borrower.Email != "" && (err != nil || address.Address != borrower.Email) {
+		fields = append(fields, FieldError{Field: "email", Message: "must be an email address, like reader@example.com"})
+	}
+	if len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return failedBorrowerResponse("invalid borrower", err), err
+	}
+
+	borrower, err := s.repository.CreateBorrower(ctx, borrower)
+	if err != nil {
+		return failedBorrowerResponse("failed to save borrower", err), err
+	}
+
+	response := BorrowerResponse{
+		Status:    "201 CREATED",
+		IsSuccess: true,
+		Message:   "save borrower successfully!",
+		TotalData: 1,
+		Data:      borrower,
+	}
+
+	return response, nil
+}
+
+// failedBorrowerResponse describes an error of a single borrower request
+func failedBorrowerResponse(message string, err error) BorrowerResponse {
+	response := BorrowerResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   fmt.Sprintf("%s: %v", message, err),
+		Data:      Borrower{},
+		TotalData: 0,
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Errors = validationErr.Fields
+	}
+
+	return response
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type mockBorrowerRepository struct {
+	borrowers           map[string]Borrower
+	listBorrowersError  error
+	getBorrowerError    error
+	createBorrowerError error
+	createBorrowerInput *Borrower
+}
+
+func (m *mockBorrowerRepository) ListBorrowers(ctx context.Context) ([]Borrower, error) {
+	var borrowers []Borrower
+	for _, borrower := range m.borrowers {
+		borrowers = append(borrowers, borrower)
+	}
+	return borrowers, m.listBorrowersError
+}
+
+func (m *mockBorrowerRepository) GetBorrower(ctx context.Context, id string) (Borrower, error) {
+	if m.getBorrowerError != nil {
+		return Borrower{}, m.getBorrowerError
+	}
+	borrower, exists := m.borrowers[id]
+	if !exists {
+		return Borrower{}, ErrBorrowerNotFound
+	}
+	return borrower, nil
+}
+
+func (m *mockBorrowerRepository) CreateBorrower(ctx context.Context, borrower Borrower) (Borrower, error) {
+	m.createBorrowerInput = &borrower
+	borrower.ID = "new"
+	return borrower, m.createBorrowerError
+}
+
+// testBorrower is who the schedules and holds under test are booked for
+var testBorrower = Borrower{ID: "reader", Name: "Reader", Email: "reader@example.com"}
+
+// newMockBorrowers returns a repository holding the borrowers
+func newMockBorrowers(borrowers ...Borrower) *mockBorrowerRepository {
+	m := &mockBorrowerRepository{borrowers: make(map[string]Borrower)}
+	for _, borrower := range borrowers {
+		m.borrowers[borrower.ID] = borrower
+	}
+	return m
+}
+
+func TestBorrowerService_ListBorrowersService(t *testing.T) {
+	mockRepo := newMockBorrowers()
+	service := NewBorrowerService(mockRepo)
+
+	t.Run("PositiveCase_Empty", func(t *testing.T) {
+		response, err := service.ListBorrowersService(context.Background())
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data == nil || response.TotalData != 0 {
+			t.Errorf("Expected empty non-nil list, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_RepositoryFailure", func(t *testing.T) {
+		mockRepo.listBorrowersError = fmt.Errorf("database is down")
+
+		response, err := service.ListBorrowersService(context.Background())
+		if err == nil {
+			t.Error("Expected error, but got nil")
+		}
+
+		if response.Status != "500 Internal Server Error" {
+			t.Errorf("Expected 500 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestBorrowerService_GetBorrowerService(t *testing.T) {
+	service := NewBorrowerService(newMockBorrowers(testBorrower))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.GetBorrowerService(context.Background(), "reader")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data.Name != "Reader" {
+			t.Errorf("Expected the reader, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		response, err := service.GetBorrowerService(context.Background(), "missing")
+		if !errors.Is(err, ErrBorrowerNotFound) {
+			t.Errorf("Expected ErrBorrowerNotFound, got %v", err)
+		}
+
+		if response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestBorrowerService_CreateBorrowerService(t *testing.T) {
+	mockRepo := newMockBorrowers()
+	service := NewBorrowerService(mockRepo)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.CreateBorrowerService(context.Background(), Borrower{Name: " Reader ", Email: " reader@example.com "})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.Status != "201 CREATED" || response.Data.ID != "new" {
+			t.Errorf("Expected created borrower, got %+v", response)
+		}
+		if mockRepo.createBorrowerInput.Name != "Reader" || mockRepo.createBorrowerInput.Email != "reader@example.com" {
+			t.Errorf("Expected trimmed name and email, got %+v", mockRepo.createBorrowerInput)
+		}
+	})
+
+	t.Run("PositiveCase_WithoutEmail", func(t *testing.T) {
+		if _, err := service.CreateBorrowerService(context.Background(), Borrower{Name: "Reader"}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidBorrower", func(t *testing.T) {
+		mockRepo.createBorrowerInput = nil
+
+		response, err := service.CreateBorrowerService(context.Background(), Borrower{Email: "Reader <reader@example.com>"})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || len(validationErr.Fields) != 2 {
+			t.Errorf("Expected ValidationError for name and email, got %v", err)
+		}
+		if response.Status != "400 Bad Request" || len(response.Errors) != 2 {
+			t.Errorf("Expected 400 failure with field errors, got %+v", response)
+		}
+		if mockRepo.createBorrowerInput != nil {
+			t.Error("Expected invalid borrower not to reach the repository")
+		}
+	})
+
+	t.Run("NegativeCase_RepositoryFailure", func(t *testing.T) {
+		mockRepo.createBorrowerError = fmt.Errorf("database is down")
+
+		response, err := service.CreateBorrowerService(context.Background(), Borrower{Name: "Reader"})
+		if err == nil {
+			t.Error("Expected error, but got nil")
+		}
+
+		if response.Status != "500 Internal Server Error" {
+			t.Errorf("Expected 500 failure, got %q", response.Status)
+		}
+	})
+}
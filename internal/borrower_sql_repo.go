@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLBorrowerRepository is a BorrowerRepository on top of database/sql,
+// sharing the schema and migrations of SQLRepository
+type SQLBorrowerRepository struct {
+	db *sql.DB
+}
+
+// NewSQLBorrowerRepository applies every pending migration to db before
+// returning
+func NewSQLBorrowerRepository(ctx context.Context, db *sql.DB) (*SQLBorrowerRepository, error) {
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return &SQLBorrowerRepository{db: db}, nil
+}
+
+func (r *SQLBorrowerRepository) ListBorrowers(ctx context.Context) ([]Borrower, error) {
+	return r.queryBorrowers(ctx, `1 = 1`)
+}
+
+func (r *SQLBorrowerRepository) GetBorrower(ctx context.Context, id string) (Borrower, error) {
+	borrowers, err := r.queryBorrowers(ctx, `public_id = ?`, id)
+	if err != nil {
+		return Borrower{}, err
+	}
+	if len(borrowers) == 0 {
+		return Borrower{}, ErrBorrowerNotFound
+	}
+
+	return borrowers[0], nil
+}
+
+func (r *SQLBorrowerRepository) CreateBorrower(ctx context.Context, borrower Borrower) (Borrower, error) {
+	now := time.Now().UTC()
+	borrower.ID = newBorrowerID()
+	borrower.CreatedAt = now
+	borrower.UpdatedAt = now
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO borrowers (public_id, name, email, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		borrower.ID, borrower.Name, borrower.Email, formatTime(borrower.CreatedAt), formatTime(borrower.UpdatedAt))
+	if err != nil {
+		return Borrower{}, fmt.Errorf("failed to insert borrower: %v", err)
+	}
+
+	return borrower, nil
+}
+
+// queryBorrowers loads the borrowers matching the where clause ordered by name
+func (r *SQLBorrowerRepository) queryBorrowers(ctx context.Context, where string, args ...interface{}) ([]Borrower, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT public_id, name, email, created_at, updated_at
+		FROM borrowers
+		WHERE `+where+`
+		ORDER BY name, public_id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load borrowers: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	borrowers := []Borrower{}
+	for rows.Next() {
+		var createdAt, updatedAt string
+		var borrower Borrower
+		if err := rows.Scan(&borrower.ID, &borrower.Name, &borrower.Email, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read borrower: %v", err)
+		}
+		if borrower.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		if borrower.UpdatedAt, err = parseTime(updatedAt); err != nil {
+			return nil, err
+		}
+		borrowers = append(borrowers, borrower)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read borrowers: %v", err)
+	}
+
+	return borrowers, nil
+}
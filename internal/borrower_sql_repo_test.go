@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSQLBorrowerRepository_Suite(t *testing.T) {
+	testBorrowerRepository(t, sqlStoreOf(NewSQLBorrowerRepository))
+}
+
+func TestSQLBorrowerRepository_Schedules(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	borrowers, err := NewSQLBorrowerRepository(ctx, db)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repo, err := NewSQLRepository(ctx, db)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	emma, err := borrowers.CreateBorrower(ctx, Borrower{Name: "Emma"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	anne, err := borrowers.CreateBorrower(ctx, Borrower{Name: "Anne"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, borrowerID := range []string{emma.ID, anne.ID, emma.ID} {
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BorrowerID: borrowerID, BookInfo: Book{Title: "Shared"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	t.Run("PositiveCase_FilterByBorrower", func(t *testing.T) {
+		page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{BorrowerID: emma.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(page.Schedules) != 2 {
+			t.Fatalf("Expected 2 schedules of %s, got %d", emma.Name, len(page.Schedules))
+		}
+		for _, schedule := range page.Schedules {
+			if schedule.BorrowerID != emma.ID {
+				t.Errorf("Expected borrower %s, got %q", emma.ID, schedule.BorrowerID)
+			}
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const fileBranchesName = "branches.json"
+
+// FileBranchRepository is a BranchRepository that keeps every branch and its
+// inventory in memory and rewrites all of them to a single file on every
+// change. Both change rarely, so unlike schedules they need no log.
+type FileBranchRepository struct {
+	*InMemoryBranchRepository
+
+	dir string
+}
+
+// branchesRecord is the content of the branches file
+type branchesRecord struct {
+	Branches  []Branch        `json:"branches"`
+	Inventory []InventoryItem `json:"inventory"`
+}
+
+func NewFileBranchRepository(dir string) (*FileBranchRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	r := &FileBranchRepository{
+		InMemoryBranchRepository: NewInMemoryBranchRepository(),
+		dir:                      dir,
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, fileBranchesName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read branches: %v", err)
+	default:
+		var record branchesRecord
+		if err := decodeRecord(data, &record); err != nil {
+			return nil, fmt.Errorf("corrupt branches file: %v", err)
+		}
+		r.restore(record.Branches, record.Inventory)
+	}
+	r.persist = r.writeBranches
+
+	return r, nil
+}
+
+// writeBranches is the persist hook of the embedded InMemoryBranchRepository
+func (r *FileBranchRepository) writeBranches(branches []Branch, inventory []InventoryItem) error {
+	data, err := encodeRecord(branchesRecord{Branches: branches, Inventory: inventory})
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(r.dir, fileBranchesName, "branches", data)
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFileBranchRepository_Suite(t *testing.T) {
+	testBranchRepository(t, fileStoreOf(NewFileBranchRepository))
+}
+
+func TestFileBranchRepository_Restart(t *testing.T) {
+	ctx := context.Background()
+
+	testFileRestart(t, NewFileBranchRepository, fileBranchesName, func(t *testing.T, repo *FileBranchRepository) func(t *testing.T, reopened *FileBranchRepository) {
+		created, err := repo.CreateBranch(ctx, Branch{Name: "Central", Address: "Main Street 1", Timezone: "Asia/Jakarta"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		updated, err := repo.SetOpeningHours(ctx, created.ID, everyDay("09:00", "17:00"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		stocked, err := repo.SetCopies(ctx, InventoryItem{BranchID: created.ID, Genre: "fiction", Book: Book{Title: "Emma", Author: []string{"Jane Austen"}}, Copies: 2})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return func(t *testing.T, reopened *FileBranchRepository) {
+			fetched, err := reopened.GetBranch(ctx, created.ID)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !sameBranch(fetched, updated) {
+				t.Errorf("Expected %+v after restart, got %+v", updated, fetched)
+			}
+
+			copies, err := reopened.CountCopies(ctx, created.ID, stocked.Genre, stocked.Book)
+			if err != nil || copies != 2 {
+				t.Errorf("Expected 2 copies after restart, got %d, %v", copies, err)
+			}
+		}
+	})
+}
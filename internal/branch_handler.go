@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type BranchHandler interface {
+	ListBranchesHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetBranchHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CreateBranchHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	SetOpeningHoursHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	SetLoanPeriodsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ListInventoryHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	SetCopiesHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+}
+
+type branchHandler struct {
+	service BranchService
+}
+
+func NewBranchHandler(service BranchService) BranchHandler {
+	return &branchHandler{
+		service: service,
+	}
+}
+
+// ListBranchesHandler serves GET /branches
+func (h *branchHandler) ListBranchesHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	branches, err := h.service.ListBranchesService(r.Context())
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), branches)
+		return
+	}
+	writeJSON(w, http.StatusOK, branches)
+}
+
+// GetBranchHandler serves GET /branches/:id
+func (h *branchHandler) GetBranchHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	branch, err := h.service.GetBranchService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), branch)
+		return
+	}
+	writeJSON(w, http.StatusOK, branch)
+}
+
+// CreateBranchHandler serves POST /branches with a Branch body
+func (h *branchHandler) CreateBranchHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var branch Branch
+	if err := json.NewDecoder(r.Body).Decode(&branch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	created, err := h.service.CreateBranchService(r.Context(), branch)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), created)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// SetOpeningHoursHandler serves PUT /branches/:id/hours, replacing every
+// opening window with the ones in the body: {"opening_hours": [...]}. An
+// empty list closes the branch.
+func (h *branchHandler) SetOpeningHoursHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var body struct {
+		OpeningHours *[]OpeningHours `json:"opening_hours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	// A missing list is more likely a mistake than a request to close
+	if body.OpeningHours == nil {
+		http.Error(w, "opening_hours is required", http.StatusBadRequest)
+		return
+	}
+
+	branch, err := h.service.SetOpeningHoursService(r.Context(), params.ByName("id"), *body.OpeningHours)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), branch)
+		return
+	}
+	writeJSON(w, http.StatusOK, branch)
+}
+
+// SetLoanPeriodsHandler serves PUT /branches/:id/loan-periods, replacing
+// every loan period with the ones in the body: {"loan_periods": [...]}. An
+// empty list leaves the loan periods to the service configuration.
+func (h *branchHandler) SetLoanPeriodsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var body struct {
+		LoanPeriods *[]LoanPeriod `json:"loan_periods"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	if body.LoanPeriods == nil {
+		http.Error(w, "loan_periods is required", http.StatusBadRequest)
+		return
+	}
+
+	branch, err := h.service.SetLoanPeriodsService(r.Context(), params.ByName("id"), *body.LoanPeriods)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), branch)
+		return
+	}
+	writeJSON(w, http.StatusOK, branch)
+}
+
+// ListInventoryHandler serves GET /branches/:id/inventory
+func (h *branchHandler) ListInventoryHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	inventory, err := h.service.ListInventoryService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), inventory)
+		return
+	}
+	writeJSON(w, http.StatusOK, inventory)
+}
+
+// SetCopiesHandler serves PUT /branches/:id/inventory, setting how many copies
+// of one book the branch has: {"genre": ..., "book": {...}, "copies": 2}
+func (h *branchHandler) SetCopiesHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var body struct {
+		Genre  string `json:"genre"`
+		Book   Book   `json:"book"`
+		Copies *int   `json:"copies"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	// A missing count would otherwise silently take every copy away
+	if body.Copies == nil {
+		http.Error(w, "copies is required", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.service.SetCopiesService(r.Context(), params.ByName("id"), InventoryItem{Genre: body.Genre, Book: body.Book, Copies: *body.Copies})
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), item)
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
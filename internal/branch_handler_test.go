@@ -0,0 +1,342 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type mockBranchService struct {
+	listBranchesResponse    BranchListResponse
+	listBranchesError       error
+	getBranchResponse       BranchResponse
+	getBranchError          error
+	createBranchResponse    BranchResponse
+	createBranchError       error
+	createBranchInput       Branch
+	setOpeningHoursResponse BranchResponse
+	setOpeningHoursError    error
+	setOpeningHoursID       string
+	setOpeningHoursInput    []OpeningHours
+	setLoanPeriodsResponse  BranchResponse
+	setLoanPeriodsError     error
+	setLoanPeriodsID        string
+	setLoanPeriodsInput     []LoanPeriod
+	listInventoryResponse   InventoryListResponse
+	listInventoryError      error
+	setCopiesResponse       InventoryResponse
+	setCopiesError          error
+	setCopiesID             string
+	setCopiesInput          *InventoryItem
+}
+
+func (m *mockBranchService) ListBranchesService(ctx context.Context) (BranchListResponse, error) {
+	return m.listBranchesResponse, m.listBranchesError
+}
+
+func (m *mockBranchService) GetBranchService(ctx context.Context, id string) (BranchResponse, error) {
+	return m.getBranchResponse, m.getBranchError
+}
+
+func (m *mockBranchService) CreateBranchService(ctx context.Context, branch Branch) (BranchResponse, error) {
+	m.createBranchInput = branch
+	return m.createBranchResponse, m.createBranchError
+}
+
+func (m *mockBranchService) SetOpeningHoursService(ctx context.Context, id string, hours []OpeningHours) (BranchResponse, error) {
+	m.setOpeningHoursID = id
+	m.setOpeningHoursInput = hours
+	return m.setOpeningHoursResponse, m.setOpeningHoursError
+}
+
+func (m *mockBranchService) SetLoanPeriodsService(ctx context.Context, id string, periods []LoanPeriod) (BranchResponse, error) {
+	m.setLoanPeriodsID = id
+	m.setLoanPeriodsInput = periods
+	return m.setLoanPeriodsResponse, m.setLoanPeriodsError
+}
+
+func (m *mockBranchService) ListInventoryService(ctx context.Context, id string) (InventoryListResponse, error) {
+	return m.listInventoryResponse, m.listInventoryError
+}
+
+func (m *mockBranchService) SetCopiesService(ctx context.Context, id string, item InventoryItem) (InventoryResponse, error) {
+	m.setCopiesID = id
+	m.setCopiesInput = &item
+	return m.setCopiesResponse, m.setCopiesError
+}
+
+func TestBranchHandler_ListBranchesHandler(t *testing.T) {
+	mockService := &mockBranchService{
+		listBranchesResponse: BranchListResponse{
+			Status:    "200 OK",
+			IsSuccess: true,
+			Message:   "fetch branches successfully!",
+			TotalData: 1,
+			Data:      []Branch{testBranch},
+		},
+	}
+	handler := NewBranchHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/branches", handler.ListBranchesHandler)
+
+	req := httptest.NewRequest("GET", "/branches", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", rec.Code)
+	}
+
+	var response BranchListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(response.Data) != 1 || len(response.Data[0].OpeningHours) != 7 {
+		t.Errorf("Expected the branch with its hours in the body, got %+v", response)
+	}
+}
+
+func TestBranchHandler_GetBranchHandler(t *testing.T) {
+	mockService := &mockBranchService{
+		getBranchResponse: BranchResponse{Status: "404 Not Found"},
+		getBranchError:    ErrBranchNotFound,
+	}
+	handler := NewBranchHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/branches/:id", handler.GetBranchHandler)
+
+	req := httptest.NewRequest("GET", "/branches/missing", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404, got %d", rec.Code)
+	}
+}
+
+func TestBranchHandler_CreateBranchHandler(t *testing.T) {
+	mockService := &mockBranchService{
+		createBranchResponse: BranchResponse{Status: "201 CREATED", IsSuccess: true, Data: Branch{ID: "new"}},
+	}
+	handler := NewBranchHandler(mockService)
+
+	router := httprouter.New()
+	router.POST("/branches", handler.CreateBranchHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"name": "North", "address": "North Street 2", "timezone": "Asia/Jakarta", "opening_hours": [{"weekday": "monday", "opens": "09:00", "closes": "17:00"}]}`
+		req := httptest.NewRequest("POST", "/branches", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("Expected status code 201, got %d", rec.Code)
+		}
+
+		input := mockService.createBranchInput
+		if input.Timezone != "Asia/Jakarta" || len(input.OpeningHours) != 1 || input.OpeningHours[0].Closes != "17:00" {
+			t.Errorf("Expected branch from body, got %+v", input)
+		}
+	})
+
+	t.Run("NegativeCase_ValidationFailure", func(t *testing.T) {
+		mockService.createBranchResponse = BranchResponse{Status: "400 Bad Request", Errors: []FieldError{{Field: "name", Message: "is required"}}}
+		mockService.createBranchError = &ValidationError{Fields: mockService.createBranchResponse.Errors}
+
+		req := httptest.NewRequest("POST", "/branches", bytes.NewReader([]byte(`{}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBranchHandler_SetOpeningHoursHandler(t *testing.T) {
+	mockService := &mockBranchService{
+		setOpeningHoursResponse: BranchResponse{Status: "200 OK", IsSuccess: true, Data: testBranch},
+	}
+	handler := NewBranchHandler(mockService)
+
+	router := httprouter.New()
+	router.PUT("/branches/:id/hours", handler.SetOpeningHoursHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"opening_hours": [{"weekday": "sunday", "opens": "10:00", "closes": "14:00"}]}`
+		req := httptest.NewRequest("PUT", "/branches/central/hours", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+		if mockService.setOpeningHoursID != "central" || len(mockService.setOpeningHoursInput) != 1 {
+			t.Errorf("Expected hours of central from body, got %q %+v", mockService.setOpeningHoursID, mockService.setOpeningHoursInput)
+		}
+	})
+
+	t.Run("NegativeCase_MissingHours", func(t *testing.T) {
+		mockService.setOpeningHoursInput = nil
+
+		req := httptest.NewRequest("PUT", "/branches/central/hours", bytes.NewReader([]byte(`{}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+		if mockService.setOpeningHoursInput != nil {
+			t.Error("Expected the request not to reach the service")
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		mockService.setOpeningHoursResponse = BranchResponse{Status: "404 Not Found"}
+		mockService.setOpeningHoursError = ErrBranchNotFound
+
+		req := httptest.NewRequest("PUT", "/branches/missing/hours", bytes.NewReader([]byte(`{"opening_hours": []}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status code 404, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBranchHandler_SetLoanPeriodsHandler(t *testing.T) {
+	mockService := &mockBranchService{
+		setLoanPeriodsResponse: BranchResponse{Status: "200 OK", IsSuccess: true, Data: testBranch},
+	}
+	handler := NewBranchHandler(mockService)
+
+	router := httprouter.New()
+	router.PUT("/branches/:id/loan-periods", handler.SetLoanPeriodsHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"loan_periods": [{"genre": "fiction", "days": 21}, {"days": 14}]}`
+		req := httptest.NewRequest("PUT", "/branches/central/loan-periods", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+		want := []LoanPeriod{{Genre: "fiction", Days: 21}, {Days: 14}}
+		if mockService.setLoanPeriodsID != "central" || !reflect.DeepEqual(mockService.setLoanPeriodsInput, want) {
+			t.Errorf("Expected loan periods of central from body, got %q %+v", mockService.setLoanPeriodsID, mockService.setLoanPeriodsInput)
+		}
+	})
+
+	t.Run("NegativeCase_MissingPeriods", func(t *testing.T) {
+		mockService.setLoanPeriodsInput = nil
+
+		req := httptest.NewRequest("PUT", "/branches/central/loan-periods", bytes.NewReader([]byte(`{}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+		if mockService.setLoanPeriodsInput != nil {
+			t.Error("Expected the request not to reach the service")
+		}
+	})
+
+	t.Run("NegativeCase_ValidationFailure", func(t *testing.T) {
+		mockService.setLoanPeriodsResponse = BranchResponse{
+			Status: "400 Bad Request",
+			Errors: []FieldError{{Field: "loan_periods[0].days", Message: "must be at least 1"}},
+		}
+		mockService.setLoanPeriodsError = &ValidationError{Fields: mockService.setLoanPeriodsResponse.Errors}
+
+		req := httptest.NewRequest("PUT", "/branches/central/loan-periods", bytes.NewReader([]byte(`{"loan_periods": [{"days": 0}]}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestBranchHandler_ListInventoryHandler(t *testing.T) {
+	mockService := &mockBranchService{
+		listInventoryResponse: InventoryListResponse{Status: "404 Not Found", Data: []InventoryItem{}},
+		listInventoryError:    ErrBranchNotFound,
+	}
+	handler := NewBranchHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/branches/:id/inventory", handler.ListInventoryHandler)
+
+	req := httptest.NewRequest("GET", "/branches/missing/inventory", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404, got %d", rec.Code)
+	}
+}
+
+func TestBranchHandler_SetCopiesHandler(t *testing.T) {
+	mockService := &mockBranchService{
+		setCopiesResponse: InventoryResponse{Status: "200 OK", IsSuccess: true},
+	}
+	handler := NewBranchHandler(mockService)
+
+	router := httprouter.New()
+	router.PUT("/branches/:id/inventory", handler.SetCopiesHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"genre": "fiction", "book": {"title": "Wuthering Heights", "author": ["Emily Bronte"], "edition_number": 1}, "copies": 0}`
+		req := httptest.NewRequest("PUT", "/branches/central/inventory", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+		input := mockService.setCopiesInput
+		if mockService.setCopiesID != "central" || input.Genre != "fiction" || input.Book.Title != "Wuthering Heights" || input.Copies != 0 {
+			t.Errorf("Expected the item of central from body, got %q %+v", mockService.setCopiesID, input)
+		}
+	})
+
+	t.Run("NegativeCase_MissingCopies", func(t *testing.T) {
+		mockService.setCopiesInput = nil
+
+		req := httptest.NewRequest("PUT", "/branches/central/inventory", bytes.NewReader([]byte(`{"genre": "fiction", "book": {"title": "Wuthering Heights"}}`)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+		if mockService.setCopiesInput != nil {
+			t.Error("Expected the request not to reach the service")
+		}
+	})
+}
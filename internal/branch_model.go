@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Branch time zones have to load on hosts without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// Branch is a library location pick-ups are scheduled at. Its opening hours
+// are in the local time of its time zone.
+type Branch struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	// Timezone is an IANA time zone name, like Asia/Jakarta
+	Timezone     string         `json:"timezone"`
+	OpeningHours []OpeningHours `json:"opening_hours"`
+	LoanPeriods  []LoanPeriod   `json:"loan_periods"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// OpeningHours is one window a branch is open on a day of the week, from
+// Opens up to Closes as 15:04 in local time. A day can have several windows,
+// and Closes is 24:00 for a branch open until midnight.
+type OpeningHours struct {
+	Weekday string `json:"weekday"`
+	Opens   string `json:"opens"`
+	Closes  string `json:"closes"`
+}
+
+// LoanPeriod is how many days a book picked up at a branch can be kept. A
+// period without a genre applies to every genre the branch has no period for.
+type LoanPeriod struct {
+	Genre string `json:"genre,omitempty"`
+	Days  int    `json:"days"`
+}
+
+// loanDays returns the loan period the branch sets for the genre
+func (b Branch) loanDays(genre string) (int, bool) {
+	days, found := 0, false
+	for _, period := range b.LoanPeriods {
+		switch period.Genre {
+		case genre:
+			return period.Days, true
+		case "":
+			days, found = period.Days, true
+		}
+	}
+	return days, found
+}
+
+// InventoryItem is how many copies of a book of a genre a branch has. A copy
+// is taken for the whole local day of every pick-up of the book that is not
+// cancelled, and on every day while it is on loan.
+type InventoryItem struct {
+	BranchID  string    `json:"branch_id"`
+	Genre     string    `json:"genre"`
+	Book      Book      `json:"book"`
+	Copies    int       `json:"copies"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const (
+	copyKeyPrefix       = "copy:"
+	loanedCopyKeyPrefix = "on-loan:"
+)
+
+// copyKey is the reservation key of the copies of a book at a branch on a day
+// as 2006-01-02 in the local time of the branch
+func copyKey(branchID, genre string, book Book, day string) string {
+	return copyKeyPrefix + branchID + ":" + bookKey(genre, book) + ":" + day
+}
+
+// loanedCopyKey is the reservation key of the copies of a book at a branch
+// that are on loan. It is not tied to a day, a copy on loan is taken on every
+// day until it is returned.
+func loanedCopyKey(branchID, genre string, book Book) string {
+	return loanedCopyKeyPrefix + branchID + ":" + bookKey(genre, book)
+}
+
+// Weekdays are the names OpeningHours.Weekday accepts, in time.Weekday order
+var Weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// parseWeekday returns the time.Weekday of a name in Weekdays
+func parseWeekday(name string) (time.Weekday, bool) {
+	for weekday, known := range Weekdays {
+		if name == known {
+			return time.Weekday(weekday), true
+		}
+	}
+	return 0, false
+}
+
+// parseClock parses a time of day like 09:30 into minutes since midnight.
+// 24:00 is accepted as the end of the day.
+func parseClock(value string) (int, error) {
+	hours, minutes, found := strings.Cut(value, ":")
+	if !found || len(hours) != 2 || len(minutes) != 2 {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+
+	h, hoursErr := strconv.Atoi(hours)
+	m, minutesErr := strconv.Atoi(minutes)
+	if hoursErr != nil || minutesErr != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+
+	return h*60 + m, nil
+}
+
+// Location loads the time zone of the branch
+func (b Branch) Location() (*time.Location, error) {
+	return loadLocation(b.Timezone)
+}
+
+// isOpen reports whether the branch is open for the whole length of time
+// starting at start
+func (b Branch) isOpen(start time.Time, length time.Duration) bool {
+	loc, err := b.Location()
+	if err != nil {
+		return false
+	}
+
+	local := start.In(loc)
+	from := local.Hour()*60 + local.Minute()
+	to := from + int(length/time.Minute)
+
+	for _, hours := range b.OpeningHours {
+		weekday, _ := parseWeekday(hours.Weekday)
+		opens, opensErr := parseClock(hours.Opens)
+		closes, closesErr := parseClock(hours.Closes)
+		if opensErr == nil && closesErr == nil && weekday == local.Weekday() && opens <= from && to <= closes {
+			return true
+		}
+	}
+
+	return false
+}
+
+// locations caches loaded time zones by name
+var locations sync.Map
+
+// loadLocation is time.LoadLocation without its "" and "Local" shortcuts,
+// which do not name a time zone
+func loadLocation(name string) (*time.Location, error) {
+	if cached, exists := locations.Load(name); exists {
+		return cached.(*time.Location), nil
+	}
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	locations.Store(name, loc)
+
+	return loc, nil
+}
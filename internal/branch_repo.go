@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBranchNotFound is returned when no branch has the requested ID
+var ErrBranchNotFound = errors.New("branch not found")
+
+// BranchRepository stores the library branches pick-ups are scheduled at
+type BranchRepository interface {
+	// ListBranches returns every branch ordered by name
+	ListBranches(ctx context.Context) ([]Branch, error)
+	GetBranch(ctx context.Context, id string) (Branch, error)
+	// CreateBranch assigns the branch a new ID and timestamps and stores it
+	CreateBranch(ctx context.Context, branch Branch) (Branch, error)
+	// SetOpeningHours replaces the weekly opening hours of the branch
+	SetOpeningHours(ctx context.Context, id string, hours []OpeningHours) (Branch, error)
+	// SetLoanPeriods replaces the loan periods of the branch
+	SetLoanPeriods(ctx context.Context, id string, periods []LoanPeriod) (Branch, error)
+	// SetCopies stores how many copies of a book the branch of the item has,
+	// replacing the earlier count, and stamps the item with the time
+	SetCopies(ctx context.Context, item InventoryItem) (InventoryItem, error)
+	// ListInventory returns the inventory ordered by branch, genre and book.
+	// Empty filters match everything.
+	ListInventory(ctx context.Context, branchID, genre string) ([]InventoryItem, error)
+	// CountCopies returns how many copies of the book the branch has, 0 when
+	// it has none
+	CountCopies(ctx context.Context, branchID, genre string, book Book) (int, error)
+}
+
+type InMemoryBranchRepository struct {
+	mu       sync.RWMutex
+	branches map[string]Branch
+	// inventory holds the items of each branch by bookKey
+	inventory map[string]map[string]InventoryItem
+
+	// persist, when set, is called with every branch and inventory item
+	// including the new or changed one while mu is held. The change is only
+	// stored if it returns no error.
+	persist func(branches []Branch, inventory []InventoryItem) error
+}
+
+func NewInMemoryBranchRepository() *InMemoryBranchRepository {
+	return &InMemoryBranchRepository{
+		branches:  make(map[string]Branch),
+		inventory: make(map[string]map[string]InventoryItem),
+	}
+}
+
+func (r *InMemoryBranchRepository) ListBranches(_ context.Context) ([]Branch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sorted(nil), nil
+}
+
+func (r *InMemoryBranchRepository) GetBranch(_ context.Context, id string) (Branch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	branch, exists := r.branches[id]
+	if !exists {
+		return Branch{}, ErrBranchNotFound
+	}
+
+	return cloneBranch(branch), nil
+}
+
+func (r *InMemoryBranchRepository) CreateBranch(_ context.Context, branch Branch) (Branch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	branch = cloneBranch(branch)
+	branch.ID = newBranchID()
+	branch.CreatedAt = now
+	branch.UpdatedAt = now
+
+	return r.store(branch)
+}
+
+func (r *InMemoryBranchRepository) SetOpeningHours(_ context.Context, id string, hours []OpeningHours) (Branch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	branch, exists := r.branches[id]
+	if !exists {
+		return Branch{}, ErrBranchNotFound
+	}
+
+	branch.OpeningHours = append([]OpeningHours{}, hours...)
+	branch.UpdatedAt = time.Now().UTC()
+
+	return r.store(branch)
+}
+
+func (r *InMemoryBranchRepository) SetLoanPeriods(_ context.Context, id string, periods []LoanPeriod) (Branch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	branch, exists := r.branches[id]
+	if !exists {
+		return Branch{}, ErrBranchNotFound
+	}
+
+	branch.LoanPeriods = append([]LoanPeriod{}, periods...)
+	branch.UpdatedAt = time.Now().UTC()
+
+	return r.store(branch)
+}
+
+func (r *InMemoryBranchRepository) SetCopies(_ context.Context, item InventoryItem) (InventoryItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.branches[item.BranchID]; !exists {
+		return InventoryItem{}, ErrBranchNotFound
+	}
+
+	item = cloneInventoryItem(item)
+	item.UpdatedAt = time.Now().UTC()
+
+	if r.persist != nil {
+		if err := r.persist(r.sorted(nil), r.items("", "", &item)); err != nil {
+			return InventoryItem{}, err
+		}
+	}
+
+	r.keep(item)
+
+	return cloneInventoryItem(item), nil
+}
+
+func (r *InMemoryBranchRepository) ListInventory(_ context.Context, branchID, genre string) ([]InventoryItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.items(branchID, genre, nil), nil
+}
+
+func (r *InMemoryBranchRepository) CountCopies(_ context.Context, branchID, genre string, book Book) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.inventory[branchID][bookKey(genre, book)].Copies, nil
+}
+
+// store persists and keeps the branch, mu must be held for writing
+func (r *InMemoryBranchRepository) store(branch Branch) (Branch, error) {
+	if r.persist != nil {
+		if err := r.persist(r.sorted(&branch), r.items("", "", nil)); err != nil {
+			return Branch{}, err
+		}
+	}
+
+	r.branches[branch.ID] = branch
+
+	return cloneBranch(branch), nil
+}
+
+// keep stores the item without persisting it, mu must be held for writing
+func (r *InMemoryBranchRepository) keep(item InventoryItem) {
+	items, exists := r.inventory[item.BranchID]
+	if !exists {
+		items = make(map[string]InventoryItem)
+		r.inventory[item.BranchID] = items
+	}
+	items[bookKey(item.Genre, item.Book)] = item
+}
+
+// restore puts already persisted branches and inventory back without calling
+// persist
+func (r *InMemoryBranchRepository) restore(branches []Branch, inventory []InventoryItem) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, branch := range branches {
+		r.branches[branch.ID] = cloneBranch(branch)
+	}
+	for _, item := range inventory {
+		r.keep(cloneInventoryItem(item))
+	}
+}
+
+// sorted returns a copy of every branch ordered by name, with changed in
+// place of the stored branch with the same ID when it is not nil
+func (r *InMemoryBranchRepository) sorted(changed *Branch) []Branch {
+	branches := make([]Branch, 0, len(r.branches)+1)
+	for id, branch := range r.branches {
+		if changed == nil || id != changed.ID {
+			branches = append(branches, cloneBranch(branch))
+		}
+	}
+	if changed != nil {
+		branches = append(branches, cloneBranch(*changed))
+	}
+
+	sort.Slice(branches, func(i, j int) bool {
+		if branches[i].Name == branches[j].Name {
+			return branches[i].ID < branches[j].ID
+		}
+		return branches[i].Name < branches[j].Name
+	})
+
+	return branches
+}
+
+// items returns a copy of the inventory items matching the filters ordered by
+// branch, genre and book, with changed in place of the stored item of the same
+// book at the same branch when it is not nil
+func (r *InMemoryBranchRepository) items(branchID, genre string, changed *InventoryItem) []InventoryItem {
+	items := []InventoryItem{}
+	for id, stocked := range r.inventory {
+		for key, item := range stocked {
+			if changed != nil && id == changed.BranchID && key == bookKey(changed.Genre, changed.Book) {
+				continue
+			}
+			if (branchID == "" || id == branchID) && (genre == "" || item.Genre == genre) {
+				items = append(items, cloneInventoryItem(item))
+			}
+		}
+	}
+	if changed != nil {
+		items = append(items, cloneInventoryItem(*changed))
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		switch {
+		case a.BranchID != b.BranchID:
+			return a.BranchID < b.BranchID
+		case a.Genre != b.Genre:
+			return a.Genre < b.Genre
+		case a.Book.Title != b.Book.Title:
+			return a.Book.Title < b.Book.Title
+		case a.Book.EditionNumber != b.Book.EditionNumber:
+			return a.Book.EditionNumber < b.Book.EditionNumber
+		default:
+			return strings.Join(a.Book.Author, "\x1f") < strings.Join(b.Book.Author, "\x1f")
+		}
+	})
+
+	return items
+}
+
+// cloneInventoryItem copies the item so callers cannot change stored authors
+func cloneInventoryItem(item InventoryItem) InventoryItem {
+	item.Book.Author = append([]string(nil), item.Book.Author...)
+	item.Book.AvailableCopies = nil
+	return item
+}
+
+// cloneBranch copies the branch so callers cannot change stored opening hours
+// or loan periods
+func cloneBranch(branch Branch) Branch {
+	branch.OpeningHours = append([]OpeningHours{}, branch.OpeningHours...)
+	branch.LoanPeriods = append([]LoanPeriod{}, branch.LoanPeriods...)
+	return branch
+}
+
+// newBranchID returns a random identifier of the same form as schedule IDs
+func newBranchID() string {
+	return newScheduleID()
+}
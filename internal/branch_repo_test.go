@@ -0,0 +1,261 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInMemoryBranchRepository_Suite(t *testing.T) {
+	testBranchRepository(t, func(t *testing.T) BranchRepository {
+		return NewInMemoryBranchRepository()
+	})
+}
+
+// testBranchRepository checks the behaviour every BranchRepository shares.
+// newRepo must return an empty repository.
+func testBranchRepository[R BranchRepository](t *testing.T, newRepo func(t *testing.T) R) {
+	ctx := context.Background()
+
+	t.Run("CreateBranch_AssignsIDAndTimestamps", func(t *testing.T) {
+		repo := newRepo(t)
+
+		created, err := repo.CreateBranch(ctx, Branch{Name: "Central", Address: "Main Street 1", Timezone: "Asia/Jakarta", OpeningHours: everyDay("09:00", "17:00"), LoanPeriods: []LoanPeriod{{Days: 21}}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if created.ID == "" || created.CreatedAt.IsZero() || !created.UpdatedAt.Equal(created.CreatedAt) {
+			t.Errorf("Expected ID and timestamps, got %+v", created)
+		}
+
+		fetched, err := repo.GetBranch(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !sameBranch(fetched, created) {
+			t.Errorf("Expected %+v, got %+v", created, fetched)
+		}
+	})
+
+	t.Run("GetBranch_NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetBranch(ctx, "missing"); !errors.Is(err, ErrBranchNotFound) {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ListBranches_OrderedByName", func(t *testing.T) {
+		repo := newRepo(t)
+
+		branches, err := repo.ListBranches(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if branches == nil || len(branches) != 0 {
+			t.Errorf("Expected an empty non-nil list, got %v", branches)
+		}
+
+		for _, name := range []string{"North", "Central", "South"} {
+			if _, err := repo.CreateBranch(ctx, Branch{Name: name, Address: name + " Street", Timezone: "UTC"}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		branches, err = repo.ListBranches(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var names []string
+		for _, branch := range branches {
+			names = append(names, branch.Name)
+		}
+		if !reflect.DeepEqual(names, []string{"Central", "North", "South"}) {
+			t.Errorf("Expected branches ordered by name, got %v", names)
+		}
+	})
+
+	t.Run("SetOpeningHours_ReplacesHours", func(t *testing.T) {
+		repo := newRepo(t)
+
+		created, err := repo.CreateBranch(ctx, Branch{Name: "Central", Address: "Main Street 1", Timezone: "UTC", OpeningHours: everyDay("09:00", "17:00")})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		hours := []OpeningHours{{Weekday: "monday", Opens: "08:00", Closes: "12:00"}, {Weekday: "monday", Opens: "13:00", Closes: "24:00"}}
+		updated, err := repo.SetOpeningHours(ctx, created.ID, hours)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(updated.OpeningHours, hours) || updated.UpdatedAt.Before(created.UpdatedAt) {
+			t.Errorf("Expected the new hours, got %+v", updated)
+		}
+
+		fetched, err := repo.GetBranch(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(fetched.OpeningHours, hours) {
+			t.Errorf("Expected the new hours to be stored, got %v", fetched.OpeningHours)
+		}
+
+		closed, err := repo.SetOpeningHours(ctx, created.ID, []OpeningHours{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if closed.OpeningHours == nil || len(closed.OpeningHours) != 0 {
+			t.Errorf("Expected an empty non-nil list of hours, got %v", closed.OpeningHours)
+		}
+	})
+
+	t.Run("SetOpeningHours_NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.SetOpeningHours(ctx, "missing", nil); !errors.Is(err, ErrBranchNotFound) {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+	})
+
+	t.Run("SetLoanPeriods_ReplacesPeriods", func(t *testing.T) {
+		repo := newRepo(t)
+
+		created, err := repo.CreateBranch(ctx, Branch{Name: "Central", Address: "Main Street 1", Timezone: "UTC", LoanPeriods: []LoanPeriod{{Days: 21}}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		periods := []LoanPeriod{{Genre: "love", Days: 7}, {Days: 14}}
+		updated, err := repo.SetLoanPeriods(ctx, created.ID, periods)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(updated.LoanPeriods, periods) || updated.UpdatedAt.Before(created.UpdatedAt) {
+			t.Errorf("Expected the new loan periods, got %+v", updated)
+		}
+
+		fetched, err := repo.GetBranch(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !sameBranch(fetched, updated) {
+			t.Errorf("Expected %+v to be stored, got %+v", updated, fetched)
+		}
+
+		cleared, err := repo.SetLoanPeriods(ctx, created.ID, []LoanPeriod{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cleared.LoanPeriods == nil || len(cleared.LoanPeriods) != 0 {
+			t.Errorf("Expected an empty non-nil list of loan periods, got %v", cleared.LoanPeriods)
+		}
+
+		if _, err := repo.SetLoanPeriods(ctx, "missing", nil); !errors.Is(err, ErrBranchNotFound) {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+	})
+
+	t.Run("SetCopies_ReplacesCount", func(t *testing.T) {
+		repo := newRepo(t)
+
+		central, err := repo.CreateBranch(ctx, Branch{Name: "Central", Address: "Main Street 1", Timezone: "UTC"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		book := Book{Title: "Wuthering Heights", Author: []string{"Emily Bronte"}, EditionNumber: 1}
+
+		copies, err := repo.CountCopies(ctx, central.ID, "fiction", book)
+		if err != nil || copies != 0 {
+			t.Errorf("Expected no copies before stocking, got %d, %v", copies, err)
+		}
+
+		for _, count := range []int{3, 1} {
+			item, err := repo.SetCopies(ctx, InventoryItem{BranchID: central.ID, Genre: "fiction", Book: book, Copies: count})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if item.Copies != count || item.UpdatedAt.IsZero() {
+				t.Errorf("Expected %d stamped copies, got %+v", count, item)
+			}
+		}
+
+		copies, err = repo.CountCopies(ctx, central.ID, "fiction", book)
+		if err != nil || copies != 1 {
+			t.Errorf("Expected the latest count of 1, got %d, %v", copies, err)
+		}
+
+		// Another edition is another book
+		other := Book{Title: book.Title, Author: book.Author, EditionNumber: 2}
+		if copies, err := repo.CountCopies(ctx, central.ID, "fiction", other); err != nil || copies != 0 {
+			t.Errorf("Expected no copies of another edition, got %d, %v", copies, err)
+		}
+	})
+
+	t.Run("SetCopies_NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.SetCopies(ctx, InventoryItem{BranchID: "missing", Genre: "fiction", Book: Book{Title: "Wuthering Heights"}, Copies: 1})
+		if !errors.Is(err, ErrBranchNotFound) {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ListInventory_Filtered", func(t *testing.T) {
+		repo := newRepo(t)
+
+		items, err := repo.ListInventory(ctx, "", "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if items == nil || len(items) != 0 {
+			t.Errorf("Expected an empty non-nil list, got %v", items)
+		}
+
+		var ids []string
+		for _, name := range []string{"Central", "North"} {
+			branch, err := repo.CreateBranch(ctx, Branch{Name: name, Address: name + " Street", Timezone: "UTC"})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ids = append(ids, branch.ID)
+		}
+		for _, item := range []InventoryItem{
+			{BranchID: ids[0], Genre: "fiction", Book: Book{Title: "Emma", Author: []string{"Jane Austen"}}, Copies: 1},
+			{BranchID: ids[0], Genre: "poetry", Book: Book{Title: "Ariel"}, Copies: 2},
+			{BranchID: ids[1], Genre: "fiction", Book: Book{Title: "Dracula"}, Copies: 3},
+			{BranchID: ids[0], Genre: "fiction", Book: Book{Title: "Dracula"}, Copies: 4},
+		} {
+			if _, err := repo.SetCopies(ctx, item); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		items, err = repo.ListInventory(ctx, ids[0], "fiction")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var titles []string
+		for _, item := range items {
+			titles = append(titles, item.Book.Title)
+		}
+		if !reflect.DeepEqual(titles, []string{"Dracula", "Emma"}) || !reflect.DeepEqual(items[1].Book.Author, []string{"Jane Austen"}) {
+			t.Errorf("Expected the fiction of the first branch ordered by title, got %+v", items)
+		}
+
+		items, err = repo.ListInventory(ctx, "", "fiction")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(items) != 3 {
+			t.Errorf("Expected the fiction of both branches, got %+v", items)
+		}
+	})
+}
+
+// sameBranch compares branches, ignoring the monotonic clock of timestamps
+func sameBranch(got, want Branch) bool {
+	return got.ID == want.ID && got.Name == want.Name && got.Address == want.Address && got.Timezone == want.Timezone &&
+		reflect.DeepEqual(got.OpeningHours, want.OpeningHours) && reflect.DeepEqual(got.LoanPeriods, want.LoanPeriods) &&
+		got.CreatedAt.Equal(want.CreatedAt) && got.UpdatedAt.Equal(want.UpdatedAt)
+}
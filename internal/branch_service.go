@@ -0,0 +1,369 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type BranchService interface {
+	ListBranchesService(ctx context.Context) (BranchListResponse, error)
+	GetBranchService(ctx context.Context, id string) (BranchResponse, error)
+	CreateBranchService(ctx context.Context, branch Branch) (BranchResponse, error)
+	SetOpeningHoursService(ctx context.Context, id string, hours []OpeningHours) (BranchResponse, error)
+	SetLoanPeriodsService(ctx context.Context, id string, periods []LoanPeriod) (BranchResponse, error)
+	ListInventoryService(ctx context.Context, id string) (InventoryListResponse, error)
+	SetCopiesService(ctx context.Context, id string, item InventoryItem) (InventoryResponse, error)
+}
+
+type BranchResponse struct {
+	Status    string       `json:"status"`
+	IsSuccess bool         `json:"is_success"`
+	Message   string       `json:"message"`
+	TotalData int          `json:"total_data"`
+	Data      Branch       `json:"data"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+type BranchListResponse struct {
+	Status    string   `json:"status"`
+	IsSuccess bool     `json:"is_success"`
+	Message   string   `json:"message"`
+	TotalData int      `json:"total_data"`
+	Data      []Branch `json:"data"`
+}
+
+type InventoryResponse struct {
+	Status    string        `json:"status"`
+	IsSuccess bool          `json:"is_success"`
+	Message   string        `json:"message"`
+	TotalData int           `json:"total_data"`
+	Data      InventoryItem `json:"data"`
+	Errors    []FieldError  `json:"errors,omitempty"`
+}
+
+type InventoryListResponse struct {
+	Status    string          `json:"status"`
+	IsSuccess bool            `json:"is_success"`
+	Message   string          `json:"message"`
+	TotalData int             `json:"total_data"`
+	Data      []InventoryItem `json:"data"`
+}
+
+type branchService struct {
+	repository BranchRepository
+}
+
+func NewBranchService(repository BranchRepository) BranchService {
+	return &branchService{
+		repository: repository,
+	}
+}
+
+func (s *branchService) ListBranchesService(ctx context.Context) (BranchListResponse, error) {
+	branches, err := s.repository.ListBranches(ctx)
+	if err != nil {
+		return BranchListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch branches: %v", err),
+			Data:      []Branch{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := BranchListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch branches successfully!",
+		TotalData: len(branches),
+		Data:      []Branch{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	response.Data = append(response.Data, branches...)
+
+	return response, nil
+}
+
+func (s *branchService) GetBranchService(ctx context.Context, id string) (BranchResponse, error) {
+	branch, err := s.repository.GetBranch(ctx, id)
+	if err != nil {
+		return failedBranchResponse("failed to fetch branch", err), err
+	}
+
+	response := BranchResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch branch successfully!",
+		TotalData: 1,
+		Data:      branch,
+	}
+
+	return response, nil
+}
+
+func (s *branchService) CreateBranchService(ctx context.Context, branch Branch) (BranchResponse, error) {
+	branch.Name = strings.TrimSpace(branch.Name)
+	branch.Address = strings.TrimSpace(branch.Address)
+	branch.Timezone = strings.TrimSpace(branch.Timezone)
+
+	var fields []FieldError
+	if branch.Name == "" {
+		fields = append(fields, FieldError{Field: "name", Message: "is required"})
+	}
+	if branch.Address == "" {
+		fields = append(fields, FieldError{Field: "address", Message: "is required"})
+	}
+	if _, err := loadLocation(branch.Timezone); err != nil {
+		fields = append(fields, FieldError{Field: "timezone", Message: "must be an IANA time zone, like Asia/Jakarta"})
+	}
+	var hourFields []FieldError
+	branch.OpeningHours, hourFields = normalizeOpeningHours(branch.OpeningHours)
+	fields = append(fields, hourFields...)
+	var periodFields []FieldError
+	branch.LoanPeriods, periodFields = normalizeLoanPeriods(branch.LoanPeriods)
+	fields = append(fields, periodFields...)
+
+	if len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return failedBranchResponse("invalid branch", err), err
+	}
+
+	branch, err := s.repository.CreateBranch(ctx, branch)
+	if err != nil {
+		return failedBranchResponse("failed to save branch", err), err
+	}
+
+	response := BranchResponse{
+		Status:    "201 CREATED",
+		IsSuccess: true,
+		Message:   "save branch successfully!",
+		TotalData: 1,
+		Data:      branch,
+	}
+
+	return response, nil
+}
+
+func (s *branchService) SetOpeningHoursService(ctx context.Context, id string, hours []OpeningHours) (BranchResponse, error) {
+	hours, fields := normalizeOpeningHours(hours)
+	if len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return failedBranchResponse("invalid opening hours", err), err
+	}
+
+	branch, err := s.repository.SetOpeningHours(ctx, id, hours)
+	if err != nil {
+		return failedBranchResponse("failed to update opening hours", err), err
+	}
+
+	response := BranchResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "update opening hours successfully!",
+		TotalData: 1,
+		Data:      branch,
+	}
+
+	return response, nil
+}
+
+func (s *branchService) SetLoanPeriodsService(ctx context.Context, id string, periods []LoanPeriod) (BranchResponse, error) {
+	periods, fields := normalizeLoanPeriods(periods)
+	if len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return failedBranchResponse("invalid loan periods", err), err
+	}
+
+	branch, err := s.repository.SetLoanPeriods(ctx, id, periods)
+	if err != nil {
+		return failedBranchResponse("failed to update loan periods", err), err
+	}
+
+	response := BranchResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "update loan periods successfully!",
+		TotalData: 1,
+		Data:      branch,
+	}
+
+	return response, nil
+}
+
+func (s *branchService) ListInventoryService(ctx context.Context, id string) (InventoryListResponse, error) {
+	items, err := s.inventoryOf(ctx, id)
+	if err != nil {
+		return InventoryListResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch inventory: %v", err),
+			Data:      []InventoryItem{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := InventoryListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch inventory successfully!",
+		TotalData: len(items),
+		Data:      []InventoryItem{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	response.Data = append(response.Data, items...)
+
+	return response, nil
+}
+
+// inventoryOf returns the inventory of a branch that has to exist
+func (s *branchService) inventoryOf(ctx context.Context, id string) ([]InventoryItem, error) {
+	if _, err := s.repository.GetBranch(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repository.ListInventory(ctx, id, "")
+}
+
+func (s *branchService) SetCopiesService(ctx context.Context, id string, item InventoryItem) (InventoryResponse, error) {
+	item.BranchID = id
+	item.Genre = strings.TrimSpace(item.Genre)
+	item.Book.AvailableCopies = nil
+
+	var fields []FieldError
+	if item.Genre == "" {
+		fields = append(fields, FieldError{Field: "genre", Message: "is required"})
+	}
+	if strings.TrimSpace(item.Book.Title) == "" {
+		fields = append(fields, FieldError{Field: "book.title", Message: "is required"})
+	}
+	if item.Copies < 0 {
+		fields = append(fields, FieldError{Field: "copies", Message: "must not be negative"})
+	}
+	if len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return failedInventoryResponse("invalid inventory", err), err
+	}
+
+	item, err := s.repository.SetCopies(ctx, item)
+	if err != nil {
+		return failedInventoryResponse("failed to update inventory", err), err
+	}
+
+	response := InventoryResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "update inventory successfully!",
+		TotalData: 1,
+		Data:      item,
+	}
+
+	return response, nil
+}
+
+// normalizeOpeningHours lowercases the weekdays and checks that every window
+// is a valid time range that does not overlap another one of the same day
+func normalizeOpeningHours(hours []OpeningHours) ([]OpeningHours, []FieldError) {
+	type span struct {
+		index, opens, closes int
+	}
+
+	normalized := make([]OpeningHours, 0, len(hours))
+	spans := make(map[time.Weekday][]span)
+	var fields []FieldError
+
+	for i, window := range hours {
+		field := fmt.Sprintf("opening_hours[%d]", i)
+		window.Weekday = strings.ToLower(strings.TrimSpace(window.Weekday))
+		normalized = append(normalized, window)
+
+		weekday, weekdayOK := parseWeekday(window.Weekday)
+		if !weekdayOK {
+			fields = append(fields, FieldError{Field: field + ".weekday", Message: "must be one of " + strings.Join(Weekdays, ", ")})
+		}
+		opens, opensErr := parseClock(window.Opens)
+		if opensErr != nil {
+			fields = append(fields, FieldError{Field: field + ".opens", Message: "must be a time of day like 09:00"})
+		}
+		closes, closesErr := parseClock(window.Closes)
+		if closesErr != nil {
+			fields = append(fields, FieldError{Field: field + ".closes", Message: "must be a time of day like 17:00, or 24:00"})
+		}
+		if !weekdayOK || opensErr != nil || closesErr != nil {
+			continue
+		}
+
+		if closes <= opens {
+			fields = append(fields, FieldError{Field: field + ".closes", Message: "must be after opens"})
+			continue
+		}
+		for _, other := range spans[weekday] {
+			if opens < other.closes && other.opens < closes {
+				fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf("overlaps opening_hours[%d]", other.index)})
+			}
+		}
+		spans[weekday] = append(spans[weekday], span{index: i, opens: opens, closes: closes})
+	}
+
+	return normalized, fields
+}
+
+// normalizeLoanPeriods trims the genres and checks that every period is at
+// least a day and that no genre has two of them
+func normalizeLoanPeriods(periods []LoanPeriod) ([]LoanPeriod, []FieldError) {
+	normalized := make([]LoanPeriod, 0, len(periods))
+	seen := make(map[string]int)
+	var fields []FieldError
+
+	for i, period := range periods {
+		field := fmt.Sprintf("loan_periods[%d]", i)
+		period.Genre = strings.TrimSpace(period.Genre)
+		normalized = append(normalized, period)
+
+		if period.Days < 1 {
+			fields = append(fields, FieldError{Field: field + ".days", Message: "must be at least 1"})
+		}
+		if other, exists := seen[period.Genre]; exists {
+			fields = append(fields, FieldError{Field: field + ".genre", Message: fmt.Sprintf("repeats loan_periods[%d]", other)})
+			continue
+		}
+		seen[period.Genre] = i
+	}
+
+	return normalized, fields
+}
+
+// failedBranchResponse describes an error of a single branch request
+func failedBranchResponse(message string, err error) BranchResponse {
+	response := BranchResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   fmt.Sprintf("%s: %v", message, err),
+		Data:      Branch{},
+		TotalData: 0,
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Errors = validationErr.Fields
+	}
+
+	return response
+}
+
+// failedInventoryResponse describes an error of a single inventory request
+func failedInventoryResponse(message string, err error) InventoryResponse {
+	response := InventoryResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   fmt.Sprintf("%s: %v", message, err),
+		Data:      InventoryItem{},
+		TotalData: 0,
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Errors = validationErr.Fields
+	}
+
+	return response
+}
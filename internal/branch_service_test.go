@@ -0,0 +1,419 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type mockBranchRepository struct {
+	branches             map[string]Branch
+	listBranchesError    error
+	getBranchError       error
+	createBranchError    error
+	createBranchInput    Branch
+	setOpeningHoursError error
+	setOpeningHoursInput []OpeningHours
+	setLoanPeriodsError  error
+	setLoanPeriodsInput  []LoanPeriod
+	inventory            []InventoryItem
+	listInventoryError   error
+	setCopiesError       error
+	setCopiesInput       *InventoryItem
+	countCopiesError     error
+}
+
+func (m *mockBranchRepository) ListBranches(ctx context.Context) ([]Branch, error) {
+	var branches []Branch
+	for _, branch := range m.branches {
+		branches = append(branches, branch)
+	}
+	return branches, m.listBranchesError
+}
+
+func (m *mockBranchRepository) GetBranch(ctx context.Context, id string) (Branch, error) {
+	if m.getBranchError != nil {
+		return Branch{}, m.getBranchError
+	}
+	branch, exists := m.branches[id]
+	if !exists {
+		return Branch{}, ErrBranchNotFound
+	}
+	return branch, nil
+}
+
+func (m *mockBranchRepository) CreateBranch(ctx context.Context, branch Branch) (Branch, error) {
+	m.createBranchInput = branch
+	branch.ID = "new"
+	return branch, m.createBranchError
+}
+
+func (m *mockBranchRepository) SetOpeningHours(ctx context.Context, id string, hours []OpeningHours) (Branch, error) {
+	m.setOpeningHoursInput = hours
+	if m.setOpeningHoursError != nil {
+		return Branch{}, m.setOpeningHoursError
+	}
+	branch, exists := m.branches[id]
+	if !exists {
+		return Branch{}, ErrBranchNotFound
+	}
+	branch.OpeningHours = hours
+	return branch, nil
+}
+
+func (m *mockBranchRepository) SetLoanPeriods(ctx context.Context, id string, periods []LoanPeriod) (Branch, error) {
+	m.setLoanPeriodsInput = periods
+	if m.setLoanPeriodsError != nil {
+		return Branch{}, m.setLoanPeriodsError
+	}
+	branch, exists := m.branches[id]
+	if !exists {
+		return Branch{}, ErrBranchNotFound
+	}
+	branch.LoanPeriods = periods
+	return branch, nil
+}
+
+func (m *mockBranchRepository) SetCopies(ctx context.Context, item InventoryItem) (InventoryItem, error) {
+	m.setCopiesInput = &item
+	if m.setCopiesError != nil {
+		return InventoryItem{}, m.setCopiesError
+	}
+	if _, exists := m.branches[item.BranchID]; !exists {
+		return InventoryItem{}, ErrBranchNotFound
+	}
+	return item, nil
+}
+
+func (m *mockBranchRepository) ListInventory(ctx context.Context, branchID, genre string) ([]InventoryItem, error) {
+	items := []InventoryItem{}
+	for _, item := range m.inventory {
+		if (branchID == "" || item.BranchID == branchID) && (genre == "" || item.Genre == genre) {
+			items = append(items, item)
+		}
+	}
+	return items, m.listInventoryError
+}
+
+func (m *mockBranchRepository) CountCopies(ctx context.Context, branchID, genre string, book Book) (int, error) {
+	for _, item := range m.inventory {
+		if item.BranchID == branchID && bookKey(item.Genre, item.Book) == bookKey(genre, book) {
+			return item.Copies, m.countCopiesError
+		}
+	}
+	return 0, m.countCopiesError
+}
+
+// stock gives every branch of the repository the copies of the books
+func (m *mockBranchRepository) stock(copies int, genre string, books ...Book) *mockBranchRepository {
+	for id := range m.branches {
+		for _, book := range books {
+			m.inventory = append(m.inventory, InventoryItem{BranchID: id, Genre: genre, Book: book, Copies: copies})
+		}
+	}
+	return m
+}
+
+// testBranch is open around the clock in UTC, so only the branch independent
+// rules decide whether a pick-up can be booked there
+var testBranch = Branch{ID: "central", Name: "Central", Address: "Main Street 1", Timezone: "UTC", OpeningHours: everyDay("00:00", "24:00")}
+
+// everyDay returns one opening window for each day of the week
+func everyDay(opens, closes string) []OpeningHours {
+	var hours []OpeningHours
+	for _, weekday := range Weekdays {
+		hours = append(hours, OpeningHours{Weekday: weekday, Opens: opens, Closes: closes})
+	}
+	return hours
+}
+
+// newMockBranches returns a repository holding the branches
+func newMockBranches(branches ...Branch) *mockBranchRepository {
+	m := &mockBranchRepository{branches: make(map[string]Branch)}
+	for _, branch := range branches {
+		m.branches[branch.ID] = branch
+	}
+	return m
+}
+
+func TestBranchService_ListBranchesService(t *testing.T) {
+	mockRepo := newMockBranches()
+	service := NewBranchService(mockRepo)
+
+	t.Run("PositiveCase_Empty", func(t *testing.T) {
+		response, err := service.ListBranchesService(context.Background())
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data == nil || response.TotalData != 0 {
+			t.Errorf("Expected empty non-nil list, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_RepositoryFailure", func(t *testing.T) {
+		mockRepo.listBranchesError = fmt.Errorf("database is down")
+
+		response, err := service.ListBranchesService(context.Background())
+		if err == nil {
+			t.Error("Expected error, but got nil")
+		}
+
+		if response.Status != "500 Internal Server Error" {
+			t.Errorf("Expected 500 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestBranchService_GetBranchService(t *testing.T) {
+	service := NewBranchService(newMockBranches(testBranch))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.GetBranchService(context.Background(), "central")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data.Name != "Central" {
+			t.Errorf("Expected the central branch, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		response, err := service.GetBranchService(context.Background(), "missing")
+		if !errors.Is(err, ErrBranchNotFound) {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+
+		if response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestBranchService_CreateBranchService(t *testing.T) {
+	mockRepo := newMockBranches()
+	service := NewBranchService(mockRepo)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.CreateBranchService(context.Background(), Branch{
+			Name:         " North ",
+			Address:      "North Street 2",
+			Timezone:     "Asia/Jakarta",
+			OpeningHours: []OpeningHours{{Weekday: " Monday", Opens: "09:00", Closes: "12:00"}, {Weekday: "monday", Opens: "13:00", Closes: "17:00"}},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if response.Status != "201 CREATED" || response.Data.ID != "new" {
+			t.Errorf("Expected created branch, got %+v", response)
+		}
+
+		input := mockRepo.createBranchInput
+		if input.Name != "North" || input.OpeningHours[0].Weekday != "monday" {
+			t.Errorf("Expected trimmed name and lowercase weekday, got %+v", input)
+		}
+	})
+
+	t.Run("NegativeCase_Invalid", func(t *testing.T) {
+		response, err := service.CreateBranchService(context.Background(), Branch{
+			Timezone: "Mars/Olympus",
+			OpeningHours: []OpeningHours{
+				{Weekday: "moonday", Opens: "9", Closes: "25:00"},
+				{Weekday: "friday", Opens: "17:00", Closes: "09:00"},
+				{Weekday: "saturday", Opens: "09:00", Closes: "13:00"},
+				{Weekday: "saturday", Opens: "12:00", Closes: "15:00"},
+			},
+			LoanPeriods: []LoanPeriod{{Days: 0}, {Genre: "love", Days: 7}, {Genre: " love ", Days: 14}},
+		})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ValidationError, got %v", err)
+		}
+		if response.Status != "400 Bad Request" {
+			t.Errorf("Expected 400 failure, got %q", response.Status)
+		}
+
+		want := []string{
+			"name", "address", "timezone",
+			"opening_hours[0].weekday", "opening_hours[0].opens", "opening_hours[0].closes",
+			"opening_hours[1].closes", "opening_hours[3]",
+			"loan_periods[0].days", "loan_periods[2].genre",
+		}
+		if len(response.Errors) != len(want) {
+			t.Fatalf("Expected errors for %v, got %v", want, response.Errors)
+		}
+		for i, field := range want {
+			if response.Errors[i].Field != field {
+				t.Errorf("Expected %s error, got %v", field, response.Errors[i])
+			}
+		}
+	})
+}
+
+func TestBranchService_SetOpeningHoursService(t *testing.T) {
+	mockRepo := newMockBranches(testBranch)
+	service := NewBranchService(mockRepo)
+
+	t.Run("PositiveCase_Closed", func(t *testing.T) {
+		response, err := service.SetOpeningHoursService(context.Background(), "central", nil)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || mockRepo.setOpeningHoursInput == nil || len(mockRepo.setOpeningHoursInput) != 0 {
+			t.Errorf("Expected the branch to be closed, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_Invalid", func(t *testing.T) {
+		mockRepo.setOpeningHoursInput = nil
+
+		response, err := service.SetOpeningHoursService(context.Background(), "central", []OpeningHours{{Weekday: "sunday", Opens: "10:00", Closes: "10:00"}})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || len(response.Errors) != 1 || response.Errors[0].Field != "opening_hours[0].closes" {
+			t.Errorf("Expected ValidationError for closes, got %v", err)
+		}
+		if mockRepo.setOpeningHoursInput != nil {
+			t.Error("Expected invalid hours not to reach the repository")
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		response, err := service.SetOpeningHoursService(context.Background(), "missing", everyDay("09:00", "17:00"))
+		if !errors.Is(err, ErrBranchNotFound) {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+
+		if response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 failure, got %q", response.Status)
+		}
+	})
+}
+
+func TestBranchService_SetLoanPeriodsService(t *testing.T) {
+	mockRepo := newMockBranches(testBranch)
+	service := NewBranchService(mockRepo)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.SetLoanPeriodsService(context.Background(), "central", []LoanPeriod{{Genre: " fiction ", Days: 21}, {Days: 14}})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		want := []LoanPeriod{{Genre: "fiction", Days: 21}, {Days: 14}}
+		if response.IsSuccess != true || !reflect.DeepEqual(response.Data.LoanPeriods, want) {
+			t.Errorf("Expected trimmed loan periods %v, got %+v", want, response)
+		}
+	})
+
+	t.Run("NegativeCase_Invalid", func(t *testing.T) {
+		mockRepo.setLoanPeriodsInput = nil
+
+		response, err := service.SetLoanPeriodsService(context.Background(), "central", []LoanPeriod{{Days: 14}, {Days: -1}})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || len(response.Errors) != 2 || response.Errors[0].Field != "loan_periods[1].days" || response.Errors[1].Field != "loan_periods[1].genre" {
+			t.Errorf("Expected ValidationError for days and genre, got %v", err)
+		}
+		if mockRepo.setLoanPeriodsInput != nil {
+			t.Error("Expected invalid loan periods not to reach the repository")
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		response, err := service.SetLoanPeriodsService(context.Background(), "missing", nil)
+		if !errors.Is(err, ErrBranchNotFound) || response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 failure, got %v %+v", err, response)
+		}
+	})
+}
+
+func TestBranchService_ListInventoryService(t *testing.T) {
+	mockRepo := newMockBranches(testBranch).stock(2, "fiction", Book{Title: "TestBook"})
+	service := NewBranchService(mockRepo)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.ListInventoryService(context.Background(), "central")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.TotalData != 1 || response.Data[0].Copies != 2 {
+			t.Errorf("Expected the stocked book, got %+v", response)
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		response, err := service.ListInventoryService(context.Background(), "missing")
+		if !errors.Is(err, ErrBranchNotFound) {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+
+		if response.Status != "404 Not Found" || response.Data == nil {
+			t.Errorf("Expected 404 failure with an empty list, got %+v", response)
+		}
+	})
+}
+
+func TestBranchService_SetCopiesService(t *testing.T) {
+	mockRepo := newMockBranches(testBranch)
+	service := NewBranchService(mockRepo)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		available := 7
+		response, err := service.SetCopiesService(context.Background(), "central", InventoryItem{
+			BranchID: "other",
+			Genre:    " fiction ",
+			Book:     Book{Title: "TestBook", AvailableCopies: &available},
+			Copies:   3,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if response.IsSuccess != true || response.Data.Copies != 3 {
+			t.Errorf("Expected the updated item, got %+v", response)
+		}
+
+		input := mockRepo.setCopiesInput
+		if input.BranchID != "central" || input.Genre != "fiction" || input.Book.AvailableCopies != nil {
+			t.Errorf("Expected the item of the branch with a trimmed genre, got %+v", input)
+		}
+	})
+
+	t.Run("NegativeCase_Invalid", func(t *testing.T) {
+		mockRepo.setCopiesInput = nil
+
+		response, err := service.SetCopiesService(context.Background(), "central", InventoryItem{Copies: -1})
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || response.Status != "400 Bad Request" {
+			t.Fatalf("Expected 400 ValidationError, got %v", err)
+		}
+		for i, field := range []string{"genre", "book.title", "copies"} {
+			if response.Errors[i].Field != field {
+				t.Errorf("Expected %s error, got %v", field, response.Errors)
+			}
+		}
+		if mockRepo.setCopiesInput != nil {
+			t.Error("Expected an invalid item not to reach the repository")
+		}
+	})
+
+	t.Run("NegativeCase_NotFound", func(t *testing.T) {
+		response, err := service.SetCopiesService(context.Background(), "missing", InventoryItem{Genre: "fiction", Book: Book{Title: "TestBook"}})
+		if !errors.Is(err, ErrBranchNotFound) {
+			t.Errorf("Expected ErrBranchNotFound, got %v", err)
+		}
+
+		if response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 failure, got %q", response.Status)
+		}
+	})
+}
@@ -0,0 +1,392 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLBranchRepository is a BranchRepository on top of database/sql, sharing
+// the schema and migrations of SQLRepository
+type SQLBranchRepository struct {
+	db *sql.DB
+}
+
+// NewSQLBranchRepository applies every pending migration to db before returning
+func NewSQLBranchRepository(ctx context.Context, db *sql.DB) (*SQLBranchRepository, error) {
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return &SQLBranchRepository{db: db}, nil
+}
+
+func (r *SQLBranchRepository) ListBranches(ctx context.Context) ([]Branch, error) {
+	return r.queryBranches(ctx, r.db, `1 = 1`)
+}
+
+func (r *SQLBranchRepository) GetBranch(ctx context.Context, id string) (Branch, error) {
+	return r.branchByID(ctx, r.db, id)
+}
+
+func (r *SQLBranchRepository) CreateBranch(ctx context.Context, branch Branch) (Branch, error) {
+	now := time.Now().UTC()
+	branch.ID = newBranchID()
+	branch.CreatedAt = now
+	branch.UpdatedAt = now
+
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO branches (public_id, name, address, timezone, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			branch.ID, branch.Name, branch.Address, branch.Timezone,
+			formatTime(branch.CreatedAt), formatTime(branch.UpdatedAt))
+		if err != nil {
+			return fmt.Errorf("failed to insert branch: %v", err)
+		}
+
+		if err := r.replaceOpeningHours(ctx, tx, branch.ID, branch.OpeningHours); err != nil {
+			return err
+		}
+		return r.replaceLoanPeriods(ctx, tx, branch.ID, branch.LoanPeriods)
+	})
+	if err != nil {
+		return Branch{}, err
+	}
+
+	return cloneBranch(branch), nil
+}
+
+func (r *SQLBranchRepository) SetOpeningHours(ctx context.Context, id string, hours []OpeningHours) (Branch, error) {
+	var branch Branch
+
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		if branch, err = r.branchByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		branch.OpeningHours = append([]OpeningHours{}, hours...)
+		branch.UpdatedAt = time.Now().UTC()
+
+		_, err = tx.ExecContext(ctx, `UPDATE branches SET updated_at = ? WHERE public_id = ?`,
+			formatTime(branch.UpdatedAt), id)
+		if err != nil {
+			return fmt.Errorf("failed to update branch: %v", err)
+		}
+
+		return r.replaceOpeningHours(ctx, tx, id, hours)
+	})
+	if err != nil {
+		return Branch{}, err
+	}
+
+	return branch, nil
+}
+
+func (r *SQLBranchRepository) SetLoanPeriods(ctx context.Context, id string, periods []LoanPeriod) (Branch, error) {
+	var branch Branch
+
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		if branch, err = r.branchByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		branch.LoanPeriods = append([]LoanPeriod{}, periods...)
+		branch.UpdatedAt = time.Now().UTC()
+
+		_, err = tx.ExecContext(ctx, `UPDATE branches SET updated_at = ? WHERE public_id = ?`,
+			formatTime(branch.UpdatedAt), id)
+		if err != nil {
+			return fmt.Errorf("failed to update branch: %v", err)
+		}
+
+		return r.replaceLoanPeriods(ctx, tx, id, periods)
+	})
+	if err != nil {
+		return Branch{}, err
+	}
+
+	return branch, nil
+}
+
+func (r *SQLBranchRepository) SetCopies(ctx context.Context, item InventoryItem) (InventoryItem, error) {
+	item = cloneInventoryItem(item)
+	item.UpdatedAt = time.Now().UTC()
+
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := r.branchByID(ctx, tx, item.BranchID); err != nil {
+			return err
+		}
+
+		bookID, err := upsertBook(ctx, tx, item.Genre, item.Book)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO branch_inventory (branch_id, book_id, copies, updated_at)
+			SELECT id, ?, ?, ? FROM branches WHERE public_id = ?
+			ON CONFLICT (branch_id, book_id) DO UPDATE SET copies = excluded.copies, updated_at = excluded.updated_at`,
+			bookID, item.Copies, formatTime(item.UpdatedAt), item.BranchID)
+		if err != nil {
+			return fmt.Errorf("failed to save inventory: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return InventoryItem{}, err
+	}
+
+	return item, nil
+}
+
+func (r *SQLBranchRepository) ListInventory(ctx context.Context, branchID, genre string) ([]InventoryItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT br.public_id, g.name, b.id, b.title, b.edition_number, i.copies, i.updated_at
+		FROM branch_inventory i
+		JOIN branches br ON br.id = i.branch_id
+		JOIN books b ON b.id = i.book_id
+		JOIN genres g ON g.id = b.genre_id
+		WHERE (? = '' OR br.public_id = ?) AND (? = '' OR g.name = ?)
+		ORDER BY br.public_id, g.name, b.title, b.edition_number, b.authors_key`,
+		branchID, branchID, genre, genre)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load inventory: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	items := []InventoryItem{}
+	var bookIDs []int64
+	for rows.Next() {
+		var bookID int64
+		var updatedAt string
+		var item InventoryItem
+		err := rows.Scan(&item.BranchID, &item.Genre, &bookID, &item.Book.Title, &item.Book.EditionNumber, &item.Copies, &updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read inventory: %v", err)
+		}
+		if item.UpdatedAt, err = parseTime(updatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		bookIDs = append(bookIDs, bookID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %v", err)
+	}
+
+	authors, err := authorsOf(ctx, r.db, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Book.Author = authors[bookIDs[i]]
+	}
+
+	return items, nil
+}
+
+func (r *SQLBranchRepository) CountCopies(ctx context.Context, branchID, genre string, book Book) (int, error) {
+	var copies int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT i.copies
+		FROM branch_inventory i
+		JOIN branches br ON br.id = i.branch_id
+		JOIN books b ON b.id = i.book_id
+		JOIN genres g ON g.id = b.genre_id
+		WHERE br.public_id = ? AND g.name = ? AND b.title = ? AND b.edition_number = ? AND b.authors_key = ?`,
+		branchID, genre, book.Title, book.EditionNumber, strings.Join(book.Author, "\x1f")).Scan(&copies)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to count copies: %v", err)
+	}
+
+	return copies, nil
+}
+
+func (r *SQLBranchRepository) replaceOpeningHours(ctx context.Context, tx *sql.Tx, id string, hours []OpeningHours) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM branch_opening_hours
+		WHERE branch_id = (SELECT id FROM branches WHERE public_id = ?)`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete opening hours: %v", err)
+	}
+
+	for position, window := range hours {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO branch_opening_hours (branch_id, position, weekday, opens, closes)
+			SELECT id, ?, ?, ?, ? FROM branches WHERE public_id = ?`,
+			position, window.Weekday, window.Opens, window.Closes, id)
+		if err != nil {
+			return fmt.Errorf("failed to save opening hours: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *SQLBranchRepository) replaceLoanPeriods(ctx context.Context, tx *sql.Tx, id string, periods []LoanPeriod) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM branch_loan_periods
+		WHERE branch_id = (SELECT id FROM branches WHERE public_id = ?)`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete loan periods: %v", err)
+	}
+
+	for position, period := range periods {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO branch_loan_periods (branch_id, position, genre, days)
+			SELECT id, ?, ?, ? FROM branches WHERE public_id = ?`,
+			position, period.Genre, period.Days, id)
+		if err != nil {
+			return fmt.Errorf("failed to save loan periods: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *SQLBranchRepository) branchByID(ctx context.Context, q querier, id string) (Branch, error) {
+	branches, err := r.queryBranches(ctx, q, `public_id = ?`, id)
+	if err != nil {
+		return Branch{}, err
+	}
+	if len(branches) == 0 {
+		return Branch{}, ErrBranchNotFound
+	}
+
+	return branches[0], nil
+}
+
+// queryBranches loads the branches matching the where clause ordered by name,
+// with their opening hours and loan periods
+func (r *SQLBranchRepository) queryBranches(ctx context.Context, q querier, where string, args ...interface{}) ([]Branch, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, public_id, name, address, timezone, created_at, updated_at
+		FROM branches
+		WHERE `+where+`
+		ORDER BY name, public_id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load branches: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	branches := []Branch{}
+	var rowIDs []interface{}
+	for rows.Next() {
+		var rowID int64
+		var createdAt, updatedAt string
+		var branch Branch
+		err := rows.Scan(&rowID, &branch.ID, &branch.Name, &branch.Address, &branch.Timezone, &createdAt, &updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read branch: %v", err)
+		}
+		if branch.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		if branch.UpdatedAt, err = parseTime(updatedAt); err != nil {
+			return nil, err
+		}
+		branch.OpeningHours = []OpeningHours{}
+		branch.LoanPeriods = []LoanPeriod{}
+		branches = append(branches, branch)
+		rowIDs = append(rowIDs, rowID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read branches: %v", err)
+	}
+	if len(branches) == 0 {
+		return branches, nil
+	}
+
+	hours, err := r.openingHoursOf(ctx, q, rowIDs)
+	if err != nil {
+		return nil, err
+	}
+	periods, err := r.loanPeriodsOf(ctx, q, rowIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range branches {
+		branches[i].OpeningHours = append(branches[i].OpeningHours, hours[rowIDs[i].(int64)]...)
+		branches[i].LoanPeriods = append(branches[i].LoanPeriods, periods[rowIDs[i].(int64)]...)
+	}
+
+	return branches, nil
+}
+
+// openingHoursOf loads the opening hours of the branches by row id
+func (r *SQLBranchRepository) openingHoursOf(ctx context.Context, q querier, rowIDs []interface{}) (map[int64][]OpeningHours, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(rowIDs)), ", ")
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT branch_id, weekday, opens, closes
+		FROM branch_opening_hours
+		WHERE branch_id IN (`+placeholders+`)
+		ORDER BY branch_id, position`, rowIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load opening hours: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	hours := make(map[int64][]OpeningHours)
+	for rows.Next() {
+		var rowID int64
+		var window OpeningHours
+		if err := rows.Scan(&rowID, &window.Weekday, &window.Opens, &window.Closes); err != nil {
+			return nil, fmt.Errorf("failed to read opening hours: %v", err)
+		}
+		hours[rowID] = append(hours[rowID], window)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read opening hours: %v", err)
+	}
+
+	return hours, nil
+}
+
+// loanPeriodsOf loads the loan periods of the branches by row id
+func (r *SQLBranchRepository) loanPeriodsOf(ctx context.Context, q querier, rowIDs []interface{}) (map[int64][]LoanPeriod, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(rowIDs)), ", ")
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT branch_id, genre, days
+		FROM branch_loan_periods
+		WHERE branch_id IN (`+placeholders+`)
+		ORDER BY branch_id, position`, rowIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load loan periods: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	periods := make(map[int64][]LoanPeriod)
+	for rows.Next() {
+		var rowID int64
+		var period LoanPeriod
+		if err := rows.Scan(&rowID, &period.Genre, &period.Days); err != nil {
+			return nil, fmt.Errorf("failed to read loan periods: %v", err)
+		}
+		periods[rowID] = append(periods[rowID], period)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read loan periods: %v", err)
+	}
+
+	return periods, nil
+}
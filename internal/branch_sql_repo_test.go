@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSQLBranchRepository_Suite(t *testing.T) {
+	testBranchRepository(t, sqlStoreOf(NewSQLBranchRepository))
+}
+
+func TestSQLBranchRepository_Schedules(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	branches, err := NewSQLBranchRepository(ctx, db)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repo, err := NewSQLRepository(ctx, db)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	central, err := branches.CreateBranch(ctx, Branch{Name: "Central", Address: "Main Street 1", Timezone: "UTC"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	north, err := branches.CreateBranch(ctx, Branch{Name: "North", Address: "North Street 2", Timezone: "UTC"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, branchID := range []string{central.ID, north.ID, central.ID} {
+		if _, err := repo.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BranchID: branchID, BookInfo: Book{Title: "Shared"}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	t.Run("PositiveCase_FilterByBranch", func(t *testing.T) {
+		page, err := repo.ListPickUpSchedules(ctx, ScheduleQuery{BranchID: central.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(page.Schedules) != 2 {
+			t.Fatalf("Expected 2 schedules at the central branch, got %d", len(page.Schedules))
+		}
+		for _, schedule := range page.Schedules {
+			if schedule.BranchID != central.ID {
+				t.Errorf("Expected branch %s, got %q", central.ID, schedule.BranchID)
+			}
+		}
+	})
+}
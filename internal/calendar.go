@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// calendarProductID names this service as the producer of calendars
+	calendarProductID = "-//Cosmart//Book Pick-Ups//EN"
+	// calendarUIDDomain makes the UIDs of pick-up events globally unique
+	calendarUIDDomain = "pick-ups.cosmart"
+
+	calendarTimeLayout = "20060102T150405"
+	calendarDayLayout  = "20060102"
+	// calendarLineLength is the most octets a content line may have before it
+	// is folded
+	calendarLineLength = 75
+)
+
+// Event statuses of RFC 5545
+const (
+	CalendarEventTentative = "TENTATIVE"
+	CalendarEventConfirmed = "CONFIRMED"
+	CalendarEventCancelled = "CANCELLED"
+)
+
+// Calendar is an RFC 5545 iCalendar feed of pick-ups
+type Calendar struct {
+	// Name is what calendar apps show for a subscribed feed
+	Name   string
+	Events []CalendarEvent
+}
+
+// CalendarEvent is one pick-up written as a VEVENT. A calendar app replaces
+// the event with the same UID and a higher Sequence when it is moved or
+// cancelled.
+type CalendarEvent struct {
+	UID         string
+	Sequence    int
+	Summary     string
+	Description string
+	Location    string
+	Status      string
+	Start       time.Time
+	End         time.Time
+	// AllDay events only take the day of Start, in UTC
+	AllDay bool
+	// Timezone is the IANA time zone Start and End are written in, they are
+	// written in UTC when it is empty or unknown
+	Timezone     string
+	Created      time.Time
+	LastModified time.Time
+}
+
+// ICS renders the calendar as an iCalendar object stamped with now
+func (c Calendar) ICS(now time.Time) []byte {
+	var w calendarWriter
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", calendarProductID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME", escapeCalendarText(c.Name))
+	}
+
+	// Every time zone an event refers to is described once, covering the
+	// years of its events
+	zones := map[string][2]time.Time{}
+	for _, event := range c.Events {
+		if _, err := loadLocation(event.Timezone); err != nil || event.AllDay {
+			continue
+		}
+		span, seen := zones[event.Timezone]
+		if !seen || event.Start.Before(span[0]) {
+			span[0] = event.Start
+		}
+		if !seen || event.End.After(span[1]) {
+			span[1] = event.End
+		}
+		zones[event.Timezone] = span
+	}
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		loc, _ := loadLocation(name)
+		w.timezone(name, loc, zones[name][0], zones[name][1])
+	}
+
+	for _, event := range c.Events {
+		w.event(event, now)
+	}
+
+	w.line("END", "VCALENDAR")
+	return []byte(w.String())
+}
+
+// calendarWriter builds an iCalendar object line by line
+type calendarWriter struct {
+	strings.Builder
+}
+
+// line writes a content line, folded to calendarLineLength octets and ended
+// with CRLF. name can carry parameters, like DTSTART;TZID=Asia/Jakarta.
+func (w *calendarWriter) line(name, value string) {
+	line := name + ":" + value
+	for limit := calendarLineLength; len(line) > limit; limit = calendarLineLength - 1 {
+		// Lines are only folded between characters
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	w.WriteString(line + "\r\n")
+}
+
+// event writes a VEVENT
+func (w *calendarWriter) event(event CalendarEvent, now time.Time) {
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", event.UID)
+	w.line("DTSTAMP", now.UTC().Format(calendarTimeLayout)+"Z")
+	switch loc, err := loadLocation(event.Timezone); {
+	case event.AllDay:
+		w.line("DTSTART;VALUE=DATE", event.Start.UTC().Format(calendarDayLayout))
+		w.line("DTEND;VALUE=DATE", event.Start.UTC().AddDate(0, 0, 1).Format(calendarDayLayout))
+	case err == nil:
+		w.line("DTSTART;TZID="+event.Timezone, event.Start.In(loc).Format(calendarTimeLayout))
+		w.line("DTEND;TZID="+event.Timezone, event.End.In(loc).Format(calendarTimeLayout))
+	default:
+		w.line("DTSTART", event.Start.UTC().Format(calendarTimeLayout)+"Z")
+		w.line("DTEND", event.End.UTC().Format(calendarTimeLayout)+"Z")
+	}
+	w.line("SEQUENCE", fmt.Sprint(event.Sequence))
+	w.line("STATUS", event.Status)
+	w.line("SUMMARY", escapeCalendarText(event.Summary))
+	if event.Description != "" {
+		w.line("DESCRIPTION", escapeCalendarText(event.Description))
+	}
+	if event.Location != "" {
+		w.line("LOCATION", escapeCalendarText(event.Location))
+	}
+	if !event.Created.IsZero() {
+		w.line("CREATED", event.Created.UTC().Format(calendarTimeLayout)+"Z")
+	}
+	if !event.LastModified.IsZero() {
+		w.line("LAST-MODIFIED", event.LastModified.UTC().Format(calendarTimeLayout)+"Z")
+	}
+	w.line("END", "VEVENT")
+}
+
+// timezone writes a VTIMEZONE for loc with one observance for the offset in
+// effect at the start of the year of from and one for every change of offset
+// until to
+func (w *calendarWriter) timezone(name string, loc *time.Location, from, to time.Time) {
+	start := time.Date(from.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", name)
+	first, offset := start.Zone()
+	w.observance(start, offset, offset, first, start.IsDST())
+	for _, change := range offsetChanges(loc, start, to) {
+		abbreviation, next := change.Zone()
+		w.observance(change, offset, next, abbreviation, change.IsDST())
+		offset = next
+	}
+	w.line("END", "VTIMEZONE")
+}
+
+// observance writes a STANDARD or DAYLIGHT observance of a VTIMEZONE that
+// starts at start, moving the offset from offsetFrom to offsetTo
+func (w *calendarWriter) observance(start time.Time, offsetFrom, offsetTo int, abbreviation string, dst bool) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+
+	w.line("BEGIN", kind)
+	// The start is written in the local time before the change
+	w.line("DTSTART", start.In(time.FixedZone("", offsetFrom)).Format(calendarTimeLayout))
+	w.line("TZOFFSETFROM", formatUTCOffset(offsetFrom))
+	w.line("TZOFFSETTO", formatUTCOffset(offsetTo))
+	w.line("TZNAME", escapeCalendarText(abbreviation))
+	w.line("END", kind)
+}
+
+// offsetChanges returns every moment after from and up to to at which loc
+// changes its offset from UTC, in loc
+func offsetChanges(loc *time.Location, from, to time.Time) []time.Time {
+	offsetAt := func(t time.Time) int {
+		_, offset := t.In(loc).Zone()
+		return offset
+	}
+
+	var changes []time.Time
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if offsetAt(day) == offsetAt(next) {
+			continue
+		}
+		// Offsets change at most once a day, find the second it happens
+		before, after := day, next
+		for after.Sub(before) > time.Second {
+			middle := before.Add(after.Sub(before) / 2)
+			if offsetAt(middle) == offsetAt(before) {
+				before = middle
+			} else {
+				after = middle
+			}
+		}
+		changes = append(changes, after.In(loc))
+	}
+
+	return changes
+}
+
+// formatUTCOffset writes an offset in seconds as +0700, or as +004430 when it
+// is not whole minutes
+func formatUTCOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	formatted := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		formatted += fmt.Sprintf("%02d", offset%60)
+	}
+	return formatted
+}
+
+// escapeCalendarText escapes a TEXT value of RFC 5545
+func escapeCalendarText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
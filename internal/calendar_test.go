@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestCalendar_ICS(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("PositiveCase_Empty", func(t *testing.T) {
+		got := string(Calendar{Name: "Pick-ups"}.ICS(now))
+		want := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:" + calendarProductID + "\r\nCALSCALE:GREGORIAN\r\nMETHOD:PUBLISH\r\nX-WR-CALNAME:Pick-ups\r\nEND:VCALENDAR\r\n"
+		if got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("PositiveCase_TimeZone", func(t *testing.T) {
+		start := time.Date(2024, time.March, 12, 14, 0, 0, 0, time.UTC)
+		got := string(Calendar{Events: []CalendarEvent{{
+			UID:      "abc@" + calendarUIDDomain,
+			Sequence: 2,
+			Summary:  "Pick up TestBook",
+			Status:   CalendarEventTentative,
+			Start:    start,
+			End:      start.Add(30 * time.Minute),
+			Timezone: "America/New_York",
+		}}}.ICS(now))
+
+		for _, want := range []string{
+			"BEGIN:VTIMEZONE\r\nTZID:America/New_York\r\n",
+			// Standard time at the start of the year, then the change to
+			// daylight saving time on 2024-03-10 at 02:00
+			"BEGIN:STANDARD\r\nDTSTART:20240101T000000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0500\r\nTZNAME:EST\r\nEND:STANDARD\r\n",
+			"BEGIN:DAYLIGHT\r\nDTSTART:20240310T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\nEND:DAYLIGHT\r\n",
+			"UID:abc@" + calendarUIDDomain + "\r\n",
+			"DTSTAMP:20240102T030405Z\r\n",
+			"DTSTART;TZID=America/New_York:20240312T100000\r\n",
+			"DTEND;TZID=America/New_York:20240312T103000\r\n",
+			"SEQUENCE:2\r\n",
+			"STATUS:TENTATIVE\r\n",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("Expected %q in %q", want, got)
+			}
+		}
+		if strings.Count(got, "BEGIN:DAYLIGHT") != 1 {
+			t.Errorf("Expected only the changes until the event, got %q", got)
+		}
+	})
+
+	t.Run("PositiveCase_UTCAndAllDay", func(t *testing.T) {
+		start := time.Date(2024, time.March, 12, 14, 0, 0, 0, time.UTC)
+		got := string(Calendar{Events: []CalendarEvent{
+			{UID: "unknown", Start: start, End: start.Add(time.Hour), Timezone: "Mars/Olympus"},
+			{UID: "all-day", Start: start, AllDay: true, Timezone: "Asia/Jakarta"},
+		}}.ICS(now))
+
+		for _, want := range []string{
+			"DTSTART:20240312T140000Z\r\nDTEND:20240312T150000Z\r\n",
+			"DTSTART;VALUE=DATE:20240312\r\nDTEND;VALUE=DATE:20240313\r\n",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("Expected %q in %q", want, got)
+			}
+		}
+		if strings.Contains(got, "VTIMEZONE") {
+			t.Errorf("Expected no time zone for UTC and all-day events, got %q", got)
+		}
+	})
+
+	t.Run("PositiveCase_EscapedAndFolded", func(t *testing.T) {
+		got := string(Calendar{Events: []CalendarEvent{{
+			Summary:     "Pick up Dune; Messiah, " + strings.Repeat("é", 40),
+			Location:    `Central\North`,
+			Description: "Genre: fiction\nStatus: active",
+		}}}.ICS(now))
+
+		for _, want := range []string{
+			`LOCATION:Central\\North` + "\r\n",
+			`DESCRIPTION:Genre: fiction\nStatus: active` + "\r\n",
+			`SUMMARY:Pick up Dune\; Messiah\, `,
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("Expected %q in %q", want, got)
+			}
+		}
+
+		for _, line := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+			if len(line) > calendarLineLength {
+				t.Errorf("Expected lines of at most %d octets, got %d in %q", calendarLineLength, len(line), line)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("Expected lines to be folded between characters, got %q", line)
+			}
+		}
+		unfolded := strings.ReplaceAll(got, "\r\n ", "")
+		if !strings.Contains(unfolded, strings.Repeat("é", 40)+"\r\n") {
+			t.Errorf("Expected the summary back after unfolding, got %q", unfolded)
+		}
+	})
+}
+
+func TestFormatUTCOffset(t *testing.T) {
+	for offset, want := range map[int]string{
+		0:                "+0000",
+		7 * 3600:         "+0700",
+		-(4*3600 + 1800): "-0430",
+		-(44*60 + 30):    "-004430",
+	} {
+		if got := formatUTCOffset(offset); got != want {
+			t.Errorf("Expected %d to be %s, got %s", offset, want, got)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const fileClosuresName = "closures.json"
+
+// FileClosureRepository is a ClosureRepository that keeps every closure in
+// memory and rewrites all of them to a single file on every change, like
+// FileBranchRepository
+type FileClosureRepository struct {
+	*InMemoryClosureRepository
+
+	dir string
+}
+
+// closuresRecord is the content of the closures file
+type closuresRecord struct {
+	Closures []Closure `json:"closures"`
+}
+
+func NewFileClosureRepository(dir string) (*FileClosureRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	r := &FileClosureRepository{
+		InMemoryClosureRepository: NewInMemoryClosureRepository(),
+		dir:                       dir,
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, fileClosuresName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read closures: %v", err)
+	default:
+		var record closuresRecord
+		if err := decodeRecord(data, &record); err != nil {
+			return nil, fmt.Errorf("corrupt closures file: %v", err)
+		}
+		r.restore(record.Closures)
+	}
+	r.persist = r.writeClosures
+
+	return r, nil
+}
+
+// writeClosures is the persist hook of the embedded InMemoryClosureRepository
+func (r *FileClosureRepository) writeClosures(closures []Closure) error {
+	data, err := encodeRecord(closuresRecord{Closures: closures})
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(r.dir, fileClosuresName, "closures", data)
+}
@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFileClosureRepository_Suite(t *testing.T) {
+	newRepo := fileStoreOf(NewFileClosureRepository)
+	testClosureRepository(t, func(t *testing.T) (ClosureRepository, string) {
+		return newRepo(t), "central"
+	})
+}
+
+func TestFileClosureRepository_Restart(t *testing.T) {
+	ctx := context.Background()
+
+	testFileRestart(t, NewFileClosureRepository, fileClosuresName, func(t *testing.T, repo *FileClosureRepository) func(t *testing.T, reopened *FileClosureRepository) {
+		created, err := repo.CreateClosure(ctx, Closure{BranchID: "central", StartDate: "2023-12-25", EndDate: "2023-12-25", Recurrence: ClosureYearly, Reason: "Christmas"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return func(t *testing.T, reopened *FileClosureRepository) {
+			closures, err := reopened.ListClosures(ctx, "central")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(closures) != 1 || !sameClosure(closures[0], created) {
+				t.Errorf("Expected [%+v] after restart, got %+v", created, closures)
+			}
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ClosureHandler interface {
+	ListClosuresHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CreateClosureHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	DeleteClosureHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+}
+
+type closureHandler struct {
+	service ClosureService
+}
+
+func NewClosureHandler(service ClosureService) ClosureHandler {
+	return &closureHandler{
+		service: service,
+	}
+}
+
+// ListClosuresHandler serves GET /branches/:id/closures
+func (h *closureHandler) ListClosuresHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	closures, err := h.service.ListClosuresService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), closures)
+		return
+	}
+	writeJSON(w, http.StatusOK, closures)
+}
+
+// CreateClosureHandler serves POST /branches/:id/closures with a Closure body
+func (h *closureHandler) CreateClosureHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var closure Closure
+	if err := json.NewDecoder(r.Body).Decode(&closure); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	created, err := h.service.CreateClosureService(r.Context(), params.ByName("id"), closure)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), created)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// DeleteClosureHandler serves DELETE /branches/:id/closures/:closure_id,
+// opening the branch again on the days of the closure
+func (h *closureHandler) DeleteClosureHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	deleted, err := h.service.DeleteClosureService(r.Context(), params.ByName("id"), params.ByName("closure_id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), deleted)
+		return
+	}
+	writeJSON(w, http.StatusOK, deleted)
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type mockClosureService struct {
+	listClosuresResponse  ClosureListResponse
+	listClosuresError     error
+	listClosuresBranchID  string
+	createClosureResponse ClosureResponse
+	createClosureError    error
+	createClosureBranchID string
+	createClosureInput    Closure
+	deleteClosureResponse ClosureResponse
+	deleteClosureError    error
+	deleteClosureBranchID string
+	deleteClosureID       string
+}
+
+func (m *mockClosureService) ListClosuresService(ctx context.Context, branchID string) (ClosureListResponse, error) {
+	m.listClosuresBranchID = branchID
+	return m.listClosuresResponse, m.listClosuresError
+}
+
+func (m *mockClosureService) CreateClosureService(ctx context.Context, branchID string, closure Closure) (ClosureResponse, error) {
+	m.createClosureBranchID = branchID
+	m.createClosureInput = closure
+	return m.createClosureResponse, m.createClosureError
+}
+
+func (m *mockClosureService) DeleteClosureService(ctx context.Context, branchID, id string) (ClosureResponse, error) {
+	m.deleteClosureBranchID = branchID
+	m.deleteClosureID = id
+	return m.deleteClosureResponse, m.deleteClosureError
+}
+
+func TestClosureHandler_ListClosuresHandler(t *testing.T) {
+	mockService := &mockClosureService{
+		listClosuresResponse: ClosureListResponse{Status: "404 Not Found"},
+		listClosuresError:    ErrBranchNotFound,
+	}
+	handler := NewClosureHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/branches/:id/closures", handler.ListClosuresHandler)
+
+	req := httptest.NewRequest("GET", "/branches/missing/closures", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound || mockService.listClosuresBranchID != "missing" {
+		t.Errorf("Expected status code 404 for missing, got %d for %q", rec.Code, mockService.listClosuresBranchID)
+	}
+}
+
+func TestClosureHandler_CreateClosureHandler(t *testing.T) {
+	mockService := &mockClosureService{
+		createClosureResponse: ClosureResponse{Status: "201 CREATED", IsSuccess: true, Data: Closure{ID: "new"}},
+	}
+	handler := NewClosureHandler(mockService)
+
+	router := httprouter.New()
+	router.POST("/branches/:id/closures", handler.CreateClosureHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"start_date": "2023-12-25", "end_date": "2023-12-26", "recurrence": "yearly", "reason": "Christmas"}`
+		req := httptest.NewRequest("POST", "/branches/central/closures", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("Expected status code 201, got %d", rec.Code)
+		}
+		input := mockService.createClosureInput
+		if mockService.createClosureBranchID != "central" || input.EndDate != "2023-12-26" || input.Recurrence != ClosureYearly {
+			t.Errorf("Expected closure of central from body, got %q %+v", mockService.createClosureBranchID, input)
+		}
+	})
+
+	t.Run("NegativeCase_BadRequest", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/branches/central/closures", bytes.NewReader([]byte("invalid request body")))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestClosureHandler_DeleteClosureHandler(t *testing.T) {
+	mockService := &mockClosureService{
+		deleteClosureResponse: ClosureResponse{Status: "404 Not Found"},
+		deleteClosureError:    ErrClosureNotFound,
+	}
+	handler := NewClosureHandler(mockService)
+
+	router := httprouter.New()
+	router.DELETE("/branches/:id/closures/:closure_id", handler.DeleteClosureHandler)
+
+	req := httptest.NewRequest("DELETE", "/branches/central/closures/abc", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404, got %d", rec.Code)
+	}
+	if mockService.deleteClosureBranchID != "central" || mockService.deleteClosureID != "abc" {
+		t.Errorf("Expected closure abc of central, got %q %q", mockService.deleteClosureBranchID, mockService.deleteClosureID)
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"time"
+)
+
+// ClosureRecurrence is how often a closure repeats
+type ClosureRecurrence string
+
+const (
+	// ClosureOnce closes the branch on its dates only, like a stock-take day
+	ClosureOnce ClosureRecurrence = "once"
+	// ClosureYearly closes the branch on the same days every year, like a
+	// public holiday. The year of its dates is ignored.
+	ClosureYearly ClosureRecurrence = "yearly"
+)
+
+// Closure is a stretch of days a branch takes no pick-ups on, from StartDate
+// up to and including EndDate as 2006-01-02 in the local time of the branch
+type Closure struct {
+	ID         string            `json:"id"`
+	BranchID   string            `json:"branch_id"`
+	StartDate  string            `json:"start_date"`
+	EndDate    string            `json:"end_date"`
+	Recurrence ClosureRecurrence `json:"recurrence"`
+	Reason     string            `json:"reason,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// closes reports whether the closure covers the day, given in any location
+// as its year, month and day
+func (c Closure) closes(day time.Time) bool {
+	start, err := time.Parse(pickUpDayLayout, c.StartDate)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(pickUpDayLayout, c.EndDate)
+	if err != nil {
+		return false
+	}
+
+	if c.Recurrence != ClosureYearly {
+		date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+		return !date.Before(start) && !date.After(end)
+	}
+
+	// Compare month and day only, a closure over new year wraps around
+	from, to, at := monthDay(start), monthDay(end), monthDay(day)
+	if from <= to {
+		return from <= at && at <= to
+	}
+	return at >= from || at <= to
+}
+
+// monthDay orders the days of a year regardless of the year
+func monthDay(t time.Time) int {
+	return int(t.Month())*100 + t.Day()
+}
+
+// closureOn returns the first of the closures that covers the day, or nil
+func closureOn(closures []Closure, day time.Time) *Closure {
+	for i := range closures {
+		if closures[i].closes(day) {
+			return &closures[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClosure_Closes(t *testing.T) {
+	for _, tc := range []struct {
+		closure Closure
+		day     string
+		want    bool
+	}{
+		{Closure{StartDate: "2023-12-25", EndDate: "2023-12-26", Recurrence: ClosureOnce}, "2023-12-25", true},
+		{Closure{StartDate: "2023-12-25", EndDate: "2023-12-26", Recurrence: ClosureOnce}, "2023-12-26", true},
+		{Closure{StartDate: "2023-12-25", EndDate: "2023-12-26", Recurrence: ClosureOnce}, "2023-12-27", false},
+		{Closure{StartDate: "2023-12-25", EndDate: "2023-12-26", Recurrence: ClosureOnce}, "2024-12-25", false},
+		{Closure{StartDate: "2023-12-25", EndDate: "2023-12-25", Recurrence: ClosureYearly}, "2030-12-25", true},
+		{Closure{StartDate: "2023-12-25", EndDate: "2023-12-25", Recurrence: ClosureYearly}, "2030-12-24", false},
+		// A yearly closure over new year wraps around
+		{Closure{StartDate: "2023-12-31", EndDate: "2024-01-01", Recurrence: ClosureYearly}, "2025-12-31", true},
+		{Closure{StartDate: "2023-12-31", EndDate: "2024-01-01", Recurrence: ClosureYearly}, "2026-01-01", true},
+		{Closure{StartDate: "2023-12-31", EndDate: "2024-01-01", Recurrence: ClosureYearly}, "2026-01-02", false},
+		{Closure{StartDate: "invalid", EndDate: "2024-01-01", Recurrence: ClosureOnce}, "2024-01-01", false},
+	} {
+		day, err := time.Parse(pickUpDayLayout, tc.day)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := tc.closure.closes(day); got != tc.want {
+			t.Errorf("Expected %+v to close %s: %v, got %v", tc.closure, tc.day, tc.want, got)
+		}
+	}
+
+	// The day is taken as it is in its own location
+	jakarta, err := loadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	closure := Closure{StartDate: "2023-12-25", EndDate: "2023-12-25", Recurrence: ClosureOnce}
+	if !closure.closes(time.Date(2023, 12, 25, 1, 0, 0, 0, jakarta)) {
+		t.Error("Expected the local day in Jakarta to be closed")
+	}
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrClosureNotFound is returned when a branch has no closure with the
+// requested ID
+var ErrClosureNotFound = errors.New("closure not found")
+
+// ClosureRepository stores the calendar of days branches are closed on
+type ClosureRepository interface {
+	// ListClosures returns the closures of the branch ordered by start date
+	ListClosures(ctx context.Context, branchID string) ([]Closure, error)
+	// CreateClosure assigns the closure a new ID and timestamp and stores it
+	CreateClosure(ctx context.Context, closure Closure) (Closure, error)
+	// DeleteClosure removes a closure of the branch
+	DeleteClosure(ctx context.Context, branchID, id string) error
+}
+
+type InMemoryClosureRepository struct {
+	mu       sync.RWMutex
+	closures map[string]Closure
+
+	// persist, when set, is called with every closure as it is after a
+	// change while mu is held. The change is only stored if it returns no
+	// error.
+	persist func(closures []Closure) error
+}
+
+func NewInMemoryClosureRepository() *InMemoryClosureRepository {
+	return &InMemoryClosureRepository{
+		closures: make(map[string]Closure),
+	}
+}
+
+func (r *InMemoryClosureRepository) ListClosures(_ context.Context, branchID string) ([]Closure, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	closures := []Closure{}
+	for _, closure := range r.sorted(nil, "") {
+		if closure.BranchID == branchID {
+			closures = append(closures, closure)
+		}
+	}
+
+	return closures, nil
+}
+
+func (r *InMemoryClosureRepository) CreateClosure(_ context.Context, closure Closure) (Closure, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	closure.ID = newClosureID()
+	closure.CreatedAt = time.Now().UTC()
+
+	if r.persist != nil {
+		if err := r.persist(r.sorted(&closure, "")); err != nil {
+			return Closure{}, err
+		}
+	}
+
+	r.closures[closure.ID] = closure
+
+	return closure, nil
+}
+
+func (r *InMemoryClosureRepository) DeleteClosure(_ context.Context, branchID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	closure, exists := r.closures[id]
+	if !exists || closure.BranchID != branchID {
+		return ErrClosureNotFound
+	}
+
+	if r.persist != nil {
+		if err := r.persist(r.sorted(nil, id)); err != nil {
+			return err
+		}
+	}
+
+	delete(r.closures, id)
+
+	return nil
+}
+
+// restore puts already persisted closures back without calling persist
+func (r *InMemoryClosureRepository) restore(closures []Closure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, closure := range closures {
+		r.closures[closure.ID] = closure
+	}
+}
+
+// sorted returns every closure ordered by branch and start date, with added
+// among them when it is not nil and without the one with the ID deleted. mu
+// must be held.
+func (r *InMemoryClosureRepository) sorted(added *Closure, deleted string) []Closure {
+	closures := make([]Closure, 0, len(r.closures)+1)
+	for _, closure := range r.closures {
+		if closure.ID != deleted {
+			closures = append(closures, closure)
+		}
+	}
+	if added != nil {
+		closures = append(closures, *added)
+	}
+
+	sort.Slice(closures, func(i, j int) bool {
+		a, b := closures[i], closures[j]
+		if a.BranchID != b.BranchID {
+			return a.BranchID < b.BranchID
+		}
+		if a.StartDate != b.StartDate {
+			return a.StartDate < b.StartDate
+		}
+		return a.ID < b.ID
+	})
+
+	return closures
+}
+
+// newClosureID returns a random identifier of the same form as schedule IDs
+func newClosureID() string {
+	return newScheduleID()
+}
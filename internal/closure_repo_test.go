@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInMemoryClosureRepository_Suite(t *testing.T) {
+	testClosureRepository(t, func(t *testing.T) (ClosureRepository, string) {
+		return NewInMemoryClosureRepository(), "central"
+	})
+}
+
+// testClosureRepository checks the behaviour every ClosureRepository shares.
+// newRepo must return an empty repository and the ID of a branch it can
+// store closures of.
+func testClosureRepository(t *testing.T, newRepo func(t *testing.T) (ClosureRepository, string)) {
+	ctx := context.Background()
+
+	t.Run("CreateClosure_AssignsIDAndTimestamp", func(t *testing.T) {
+		repo, branchID := newRepo(t)
+
+		created, err := repo.CreateClosure(ctx, Closure{BranchID: branchID, StartDate: "2023-12-25", EndDate: "2023-12-26", Recurrence: ClosureYearly, Reason: "Christmas"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if created.ID == "" || created.CreatedAt.IsZero() {
+			t.Errorf("Expected ID and timestamp, got %+v", created)
+		}
+
+		closures, err := repo.ListClosures(ctx, branchID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(closures) != 1 || !sameClosure(closures[0], created) {
+			t.Errorf("Expected [%+v], got %+v", created, closures)
+		}
+	})
+
+	t.Run("ListClosures_OrderedByStartDate", func(t *testing.T) {
+		repo, branchID := newRepo(t)
+
+		closures, err := repo.ListClosures(ctx, branchID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if closures == nil || len(closures) != 0 {
+			t.Errorf("Expected an empty non-nil list, got %v", closures)
+		}
+
+		for _, date := range []string{"2023-12-25", "2023-08-17", "2024-01-01"} {
+			if _, err := repo.CreateClosure(ctx, Closure{BranchID: branchID, StartDate: date, EndDate: date, Recurrence: ClosureOnce}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		closures, err = repo.ListClosures(ctx, branchID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var dates []string
+		for _, closure := range closures {
+			dates = append(dates, closure.StartDate)
+		}
+		if !reflect.DeepEqual(dates, []string{"2023-08-17", "2023-12-25", "2024-01-01"}) {
+			t.Errorf("Expected closures ordered by start date, got %v", dates)
+		}
+	})
+
+	t.Run("DeleteClosure", func(t *testing.T) {
+		repo, branchID := newRepo(t)
+
+		created, err := repo.CreateClosure(ctx, Closure{BranchID: branchID, StartDate: "2023-12-25", EndDate: "2023-12-25", Recurrence: ClosureOnce})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := repo.DeleteClosure(ctx, "other", created.ID); !errors.Is(err, ErrClosureNotFound) {
+			t.Errorf("Expected ErrClosureNotFound for another branch, got %v", err)
+		}
+		if err := repo.DeleteClosure(ctx, branchID, created.ID); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.DeleteClosure(ctx, branchID, created.ID); !errors.Is(err, ErrClosureNotFound) {
+			t.Errorf("Expected ErrClosureNotFound when deleting twice, got %v", err)
+		}
+
+		closures, err := repo.ListClosures(ctx, branchID)
+		if err != nil || len(closures) != 0 {
+			t.Errorf("Expected no closures left, got %v, %v", closures, err)
+		}
+	})
+}
+
+func sameClosure(got, want Closure) bool {
+	return got.ID == want.ID && got.BranchID == want.BranchID && got.StartDate == want.StartDate && got.EndDate == want.EndDate &&
+		got.Recurrence == want.Recurrence && got.Reason == want.Reason && got.CreatedAt.Equal(want.CreatedAt)
+}
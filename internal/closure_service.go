@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ClosureService interface {
+	ListClosuresService(ctx context.Context, branchID string) (ClosureListResponse, error)
+	CreateClosureService(ctx context.Context, branchID string, closure Closure) (ClosureResponse, error)
+	DeleteClosureService(ctx context.Context, branchID, id string) (ClosureResponse, error)
+}
+
+type ClosureResponse struct {
+	Status    string       `json:"status"`
+	IsSuccess bool         `json:"is_success"`
+	Message   string       `json:"message"`
+	TotalData int          `json:"total_data"`
+	Data      Closure      `json:"data"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+type ClosureListResponse struct {
+	Status    string    `json:"status"`
+	IsSuccess bool      `json:"is_success"`
+	Message   string    `json:"message"`
+	TotalData int       `json:"total_data"`
+	Data      []Closure `json:"data"`
+}
+
+type closureService struct {
+	repository ClosureRepository
+	branches   BranchRepository
+}
+
+// NewClosureService returns a ClosureService keeping the closures of the
+// branches in repository
+func NewClosureService(repository ClosureRepository, branches BranchRepository) ClosureService {
+	return &closureService{
+		repository: repository,
+		branches:   branches,
+	}
+}
+
+func (s *closureService) ListClosuresService(ctx context.Context, branchID string) (ClosureListResponse, error) {
+	closures, err := s.closuresOf(ctx, branchID)
+	if err != nil {
+		return ClosureListResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch closures: %v", err),
+			Data:      []Closure{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := ClosureListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch closures successfully!",
+		TotalData: len(closures),
+		Data:      []Closure{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	response.Data = append(response.Data, closures...)
+
+	return response, nil
+}
+
+// closuresOf returns the closures of a branch that has to exist
+func (s *closureService) closuresOf(ctx context.Context, branchID string) ([]Closure, error) {
+	if _, err := s.branches.GetBranch(ctx, branchID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListClosures(ctx, branchID)
+}
+
+func (s *closureService) CreateClosureService(ctx context.Context, branchID string, closure Closure) (ClosureResponse, error) {
+	closure.BranchID = branchID
+	closure.StartDate = strings.TrimSpace(closure.StartDate)
+	closure.EndDate = strings.TrimSpace(closure.EndDate)
+	closure.Reason = strings.TrimSpace(closure.Reason)
+	closure.Recurrence = ClosureRecurrence(strings.ToLower(strings.TrimSpace(string(closure.Recurrence))))
+	if closure.EndDate == "" {
+		closure.EndDate = closure.StartDate
+	}
+	if closure.Recurrence == "" {
+		closure.Recurrence = ClosureOnce
+	}
+
+	if fields := validateClosure(closure); len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return failedClosureResponse("invalid closure", err), err
+	}
+
+	if _, err := s.branches.GetBranch(ctx, branchID); err != nil {
+		return failedClosureResponse("failed to save closure", err), err
+	}
+	closure, err := s.repository.CreateClosure(ctx, closure)
+	if err != nil {
+		return failedClosureResponse("failed to save closure", err), err
+	}
+
+	response := ClosureResponse{
+		Status:    "201 CREATED",
+		IsSuccess: true,
+		Message:   "save closure successfully!",
+		TotalData: 1,
+		Data:      closure,
+	}
+
+	return response, nil
+}
+
+func (s *closureService) DeleteClosureService(ctx context.Context, branchID, id string) (ClosureResponse, error) {
+	closures, err := s.closuresOf(ctx, branchID)
+	if err != nil {
+		return failedClosureResponse("failed to delete closure", err), err
+	}
+
+	// The deleted closure is returned so the client can tell what reopened
+	var deleted *Closure
+	for i := range closures {
+		if closures[i].ID == id {
+			deleted = &closures[i]
+		}
+	}
+	if deleted == nil {
+		return failedClosureResponse("failed to delete closure", ErrClosureNotFound), ErrClosureNotFound
+	}
+
+	if err := s.repository.DeleteClosure(ctx, branchID, id); err != nil {
+		return failedClosureResponse("failed to delete closure", err), err
+	}
+
+	response := ClosureResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "delete closure successfully!",
+		TotalData: 1,
+		Data:      *deleted,
+	}
+
+	return response, nil
+}
+
+// validateClosure checks that the dates of a normalized closure are a valid
+// range and that a yearly one spans less than a year
+func validateClosure(closure Closure) []FieldError {
+	var fields []FieldError
+
+	start, err := time.Parse(pickUpDayLayout, closure.StartDate)
+	if closure.StartDate == "" {
+		fields = append(fields, FieldError{Field: "start_date", Message: "is required"})
+	} else if err != nil {
+		fields = append(fields, FieldError{Field: "start_date", Message: "must be a date like 2023-12-25"})
+	}
+	end, endErr := time.Parse(pickUpDayLayout, closure.EndDate)
+	switch {
+	case closure.StartDate == "" || err != nil:
+	case endErr != nil:
+		fields = append(fields, FieldError{Field: "end_date", Message: "must be a date like 2023-12-26"})
+	case end.Before(start):
+		fields = append(fields, FieldError{Field: "end_date", Message: "must not be before start_date"})
+	case closure.Recurrence == ClosureYearly && !end.Before(start.AddDate(1, 0, 0)):
+		fields = append(fields, FieldError{Field: "end_date", Message: "must be less than a year after start_date for a yearly closure"})
+	}
+
+	if closure.Recurrence != ClosureOnce && closure.Recurrence != ClosureYearly {
+		fields = append(fields, FieldError{Field: "recurrence", Message: fmt.Sprintf("must be %s or %s", ClosureOnce, ClosureYearly)})
+	}
+
+	return fields
+}
+
+// failedClosureResponse describes an error of a single closure request
+func failedClosureResponse(message string, err error) ClosureResponse {
+	response := ClosureResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   fmt.Sprintf("%s: %v", message, err),
+		Data:      Closure{},
+		TotalData: 0,
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Errors = validationErr.Fields
+	}
+
+	return response
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestClosureService_CreateClosureService(t *testing.T) {
+	ctx := context.Background()
+	service := NewClosureService(NewInMemoryClosureRepository(), newMockBranches(testBranch))
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		response, err := service.CreateClosureService(ctx, "central", Closure{StartDate: " 2023-12-25 ", Reason: " Christmas ", Recurrence: "Yearly"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		closure := response.Data
+		if response.Status != "201 CREATED" || closure.ID == "" || closure.BranchID != "central" {
+			t.Errorf("Expected created closure of central, got %+v", response)
+		}
+		if closure.StartDate != "2023-12-25" || closure.EndDate != "2023-12-25" || closure.Recurrence != ClosureYearly || closure.Reason != "Christmas" {
+			t.Errorf("Expected a normalized one-day yearly closure, got %+v", closure)
+		}
+	})
+
+	t.Run("PositiveCase_DefaultsToOnce", func(t *testing.T) {
+		response, err := service.CreateClosureService(ctx, "central", Closure{StartDate: "2023-11-23", EndDate: "2023-11-24", Reason: "stock-take"})
+		if err != nil || response.Data.Recurrence != ClosureOnce {
+			t.Errorf("Expected a one-off closure, got %+v, %v", response.Data, err)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidClosure", func(t *testing.T) {
+		for _, tc := range []struct {
+			closure Closure
+			field   string
+		}{
+			{Closure{}, "start_date"},
+			{Closure{StartDate: "25-12-2023"}, "start_date"},
+			{Closure{StartDate: "2023-12-25", EndDate: "tomorrow"}, "end_date"},
+			{Closure{StartDate: "2023-12-25", EndDate: "2023-12-24"}, "end_date"},
+			{Closure{StartDate: "2023-12-25", EndDate: "2024-12-25", Recurrence: ClosureYearly}, "end_date"},
+			{Closure{StartDate: "2023-12-25", Recurrence: "weekly"}, "recurrence"},
+		} {
+			response, err := service.CreateClosureService(ctx, "central", tc.closure)
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("Expected ValidationError for %+v, got %v", tc.closure, err)
+			}
+			if response.Status != "400 Bad Request" || len(response.Errors) != 1 || response.Errors[0].Field != tc.field {
+				t.Errorf("Expected 400 failure for %s, got %+v", tc.field, response)
+			}
+		}
+	})
+
+	t.Run("NegativeCase_UnknownBranch", func(t *testing.T) {
+		response, err := service.CreateClosureService(ctx, "missing", Closure{StartDate: "2023-12-25"})
+		if !errors.Is(err, ErrBranchNotFound) || response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 ErrBranchNotFound, got %q, %v", response.Status, err)
+		}
+	})
+}
+
+func TestClosureService_ListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	service := NewClosureService(NewInMemoryClosureRepository(), newMockBranches(testBranch))
+
+	created, err := service.CreateClosureService(ctx, "central", Closure{StartDate: "2023-12-25"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("PositiveCase_List", func(t *testing.T) {
+		response, err := service.ListClosuresService(ctx, "central")
+		if err != nil || response.TotalData != 1 || response.Data[0].ID != created.Data.ID {
+			t.Errorf("Expected the closure listed, got %+v, %v", response, err)
+		}
+	})
+
+	t.Run("NegativeCase_ListUnknownBranch", func(t *testing.T) {
+		response, err := service.ListClosuresService(ctx, "missing")
+		if !errors.Is(err, ErrBranchNotFound) || response.Status != "404 Not Found" || response.Data == nil {
+			t.Errorf("Expected 404 with an empty list, got %+v, %v", response, err)
+		}
+	})
+
+	t.Run("PositiveCase_Delete", func(t *testing.T) {
+		response, err := service.DeleteClosureService(ctx, "central", created.Data.ID)
+		if err != nil || response.Data.ID != created.Data.ID {
+			t.Fatalf("Expected the deleted closure, got %+v, %v", response, err)
+		}
+
+		response, err = service.DeleteClosureService(ctx, "central", created.Data.ID)
+		if !errors.Is(err, ErrClosureNotFound) || response.Status != "404 Not Found" {
+			t.Errorf("Expected 404 ErrClosureNotFound, got %q, %v", response.Status, err)
+		}
+	})
+}
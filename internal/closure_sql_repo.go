@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLClosureRepository is a ClosureRepository on top of database/sql, sharing
+// the schema and migrations of SQLRepository
+type SQLClosureRepository struct {
+	db *sql.DB
+}
+
+// NewSQLClosureRepository applies every pending migration to db before
+// returning
+func NewSQLClosureRepository(ctx context.Context, db *sql.DB) (*SQLClosureRepository, error) {
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return &SQLClosureRepository{db: db}, nil
+}
+
+func (r *SQLClosureRepository) ListClosures(ctx context.Context, branchID string) ([]Closure, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.public_id, b.public_id, c.start_date, c.end_date, c.recurrence, c.reason, c.created_at
+		FROM closures c
+		JOIN branches b ON b.id = c.branch_id
+		WHERE b.public_id = ?
+		ORDER BY c.start_date, c.public_id`, branchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load closures: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	closures := []Closure{}
+	for rows.Next() {
+		var createdAt string
+		var closure Closure
+		if err := rows.Scan(&closure.ID, &closure.BranchID, &closure.StartDate, &closure.EndDate, &closure.Recurrence, &closure.Reason, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to read closure: %v", err)
+		}
+		if closure.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		closures = append(closures, closure)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read closures: %v", err)
+	}
+
+	return closures, nil
+}
+
+func (r *SQLClosureRepository) CreateClosure(ctx context.Context, closure Closure) (Closure, error) {
+	closure.ID = newClosureID()
+	closure.CreatedAt = time.Now().UTC()
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO closures (public_id, branch_id, start_date, end_date, recurrence, reason, created_at)
+		SELECT ?, id, ?, ?, ?, ?, ? FROM branches WHERE public_id = ?`,
+		closure.ID, closure.StartDate, closure.EndDate, closure.Recurrence, closure.Reason, formatTime(closure.CreatedAt), closure.BranchID)
+	if err != nil {
+		return Closure{}, fmt.Errorf("failed to insert closure: %v", err)
+	}
+	if inserted, err := result.RowsAffected(); err == nil && inserted == 0 {
+		return Closure{}, ErrBranchNotFound
+	}
+
+	return closure, nil
+}
+
+func (r *SQLClosureRepository) DeleteClosure(ctx context.Context, branchID, id string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM closures
+		WHERE public_id = ? AND branch_id = (SELECT id FROM branches WHERE public_id = ?)`, id, branchID)
+	if err != nil {
+		return fmt.Errorf("failed to delete closure: %v", err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return ErrClosureNotFound
+	}
+
+	return nil
+}
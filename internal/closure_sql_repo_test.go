@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSQLClosureRepository_Suite(t *testing.T) {
+	testClosureRepository(t, func(t *testing.T) (ClosureRepository, string) {
+		ctx := context.Background()
+		db := openTestDB(t)
+
+		branches, err := NewSQLBranchRepository(ctx, db)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		branch, err := branches.CreateBranch(ctx, Branch{Name: "Central", Address: "Main Street 1", Timezone: "UTC"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		repo, err := NewSQLClosureRepository(ctx, db)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return repo, branch.ID
+	})
+}
+
+func TestSQLClosureRepository_UnknownBranch(t *testing.T) {
+	repo, err := NewSQLClosureRepository(context.Background(), openTestDB(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err = repo.CreateClosure(context.Background(), Closure{BranchID: "missing", StartDate: "2023-12-25", EndDate: "2023-12-25", Recurrence: ClosureOnce})
+	if !errors.Is(err, ErrBranchNotFound) {
+		t.Errorf("Expected ErrBranchNotFound, got %v", err)
+	}
+}
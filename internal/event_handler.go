@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// eventKeepAlive is how often an idle stream sends a comment, so proxies
+	// do not close it
+	eventKeepAlive = 15 * time.Second
+	// eventRetry is how long clients wait before reconnecting, in
+	// milliseconds
+	eventRetry = 3000
+)
+
+type EventHandler interface {
+	StreamScheduleEventsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+}
+
+type eventHandler struct {
+	stream    *ScheduleEventStream
+	keepAlive time.Duration
+}
+
+func NewEventHandler(stream *ScheduleEventStream) EventHandler {
+	return &eventHandler{
+		stream:    stream,
+		keepAlive: eventKeepAlive,
+	}
+}
+
+// StreamScheduleEventsHandler serves GET /schedules/events as Server-Sent
+// Events, optionally filtered by branch_id and genre. A client reconnecting
+// with the Last-Event-ID header, or last_event_id where it cannot set headers,
+// first gets the events it missed. When those are no longer kept it gets a
+// reset event instead and has to reload the schedules it shows.
+func (h *eventHandler) StreamScheduleEventsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	values := r.URL.Query()
+	filter := ScheduleEventFilter{
+		BranchID: values.Get("branch_id"),
+		Genre:    values.Get("genre"),
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = values.Get("last_event_id")
+	}
+
+	missed, events, resumed, cancel := h.stream.Subscribe(filter, lastEventID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Keeps reverse proxies like nginx from holding events back
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", eventRetry); err != nil {
+		return
+	}
+	if !resumed {
+		if err := writeEvent(w, "", "reset", map[string]string{"last_event_id": lastEventID}); err != nil {
+			return
+		}
+	}
+	for _, event := range missed {
+		if err := writeEvent(w, event.ID, string(event.Type), event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(h.keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// Fell behind or the server stops, the client reconnects
+				// and resumes
+				return
+			}
+			if err := writeEvent(w, event.ID, string(event.Type), event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes v as the JSON data of a Server-Sent Event with the ID,
+// left out when empty, and the name
+func writeEvent(w io.Writer, id, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// readEvent reads the next Server-Sent Event, skipping comments and the
+// retry field
+func readEvent(t *testing.T, reader *bufio.Reader) (id, name, data string) {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return id, name, data
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestEventHandler_StreamScheduleEventsHandler(t *testing.T) {
+	ctx := context.Background()
+	stream := NewScheduleEventStream(10)
+	handler := &eventHandler{stream: stream, keepAlive: 10 * time.Millisecond}
+
+	router := httprouter.New()
+	router.GET("/schedules/events", handler.StreamScheduleEventsHandler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	connect := func(t *testing.T, query, lastEventID string) *bufio.Reader {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/schedules/events"+query, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return bufio.NewReader(resp.Body)
+	}
+
+	var lastEventID string
+	t.Run("PositiveCase_LiveEvents", func(t *testing.T) {
+		reader := connect(t, "?branch_id=central", "")
+
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCreated, PickUpSchedule{ID: "def", BranchID: "harbour"})
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCreated, PickUpSchedule{ID: "abc", BranchID: "central"})
+
+		id, name, data := readEvent(t, reader)
+		var event ScheduleEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if name != "schedule.created" || id == "" || event.ID != id || event.Data.ID != "abc" {
+			t.Errorf("Expected the event of central, got %s %s %s", id, name, data)
+		}
+		lastEventID = id
+	})
+
+	t.Run("PositiveCase_Resume", func(t *testing.T) {
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCancelled, PickUpSchedule{ID: "abc", BranchID: "central"})
+
+		reader := connect(t, "?branch_id=central", lastEventID)
+
+		_, name, data := readEvent(t, reader)
+		if name != "schedule.cancelled" || !strings.Contains(data, `"id":"abc"`) {
+			t.Errorf("Expected the missed cancellation, got %s %s", name, data)
+		}
+	})
+
+	t.Run("NegativeCase_Reset", func(t *testing.T) {
+		reader := connect(t, "?last_event_id=unknown-1", "")
+
+		id, name, _ := readEvent(t, reader)
+		if name != "reset" || id != "" {
+			t.Errorf("Expected a reset event without ID, got %s %s", id, name)
+		}
+	})
+
+	t.Run("PositiveCase_KeepAlive", func(t *testing.T) {
+		reader := connect(t, "", "")
+
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if line == ": keep-alive\n" {
+				break
+			}
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultEventBufferSize is how many of the latest schedule events a
+	// ScheduleEventStream keeps for resuming clients unless told otherwise
+	DefaultEventBufferSize = 1000
+	// eventSubscriberBuffer is how many events a subscriber can fall behind
+	// before it is dropped
+	eventSubscriberBuffer = 64
+)
+
+// ScheduleEvent is a change of a pick-up schedule sent to stream subscribers.
+// Its Type is one of the types webhooks are sent.
+type ScheduleEvent struct {
+	ID         string           `json:"id"`
+	Type       WebhookEventType `json:"type"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	Data       PickUpSchedule   `json:"data"`
+}
+
+// ScheduleEventFilter selects the events of a subscriber. Empty filters match
+// everything.
+type ScheduleEventFilter struct {
+	BranchID string
+	Genre    string
+}
+
+// matches reports whether the event passes every filter
+func (f ScheduleEventFilter) matches(event ScheduleEvent) bool {
+	switch {
+	case f.BranchID != "" && event.Data.BranchID != f.BranchID:
+		return false
+	case f.Genre != "" && event.Data.Genre != f.Genre:
+		return false
+	default:
+		return true
+	}
+}
+
+// ScheduleEventStream fans schedule events out to subscribers as they happen
+// and keeps the latest of them in memory, so a subscriber that reconnects can
+// pick up after the last event it saw. Event IDs look like "<run>-<sequence>",
+// where the run changes on every start, so IDs of a previous run are never
+// mistaken for current ones.
+type ScheduleEventStream struct {
+	mu  sync.Mutex
+	run string
+	// buffer holds the latest events in order, the one with sequence next-1
+	// last
+	buffer      []ScheduleEvent
+	size        int
+	next        uint64
+	subscribers map[*eventSubscription]bool
+	// closed is set once the stream is closed, subscribers then get no events
+	closed bool
+	now    func() time.Time
+}
+
+// eventSubscription is a subscriber waiting for events on its channel
+type eventSubscription struct {
+	filter ScheduleEventFilter
+	events chan ScheduleEvent
+}
+
+// NewScheduleEventStream returns a stream keeping the latest size events
+func NewScheduleEventStream(size int) *ScheduleEventStream {
+	return &ScheduleEventStream{
+		run:         newScheduleID()[:8],
+		size:        size,
+		next:        1,
+		subscribers: make(map[*eventSubscription]bool),
+		now:         time.Now,
+	}
+}
+
+// PublishScheduleEvent sends the event to every subscriber whose filter it
+// matches. It never blocks: a subscriber too far behind is dropped and has to
+// reconnect.
+func (s *ScheduleEventStream) PublishScheduleEvent(_ context.Context, eventType WebhookEventType, schedule PickUpSchedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event := ScheduleEvent{
+		ID:         s.run + "-" + strconv.FormatUint(s.next, 10),
+		Type:       eventType,
+		OccurredAt: s.now().UTC(),
+		Data:       schedule,
+	}
+	s.next++
+	s.buffer = append(s.buffer, event)
+	if len(s.buffer) > s.size {
+		s.buffer = append([]ScheduleEvent(nil), s.buffer[len(s.buffer)-s.size:]...)
+	}
+
+	for subscription := range s.subscribers {
+		if !subscription.filter.matches(event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			delete(s.subscribers, subscription)
+			close(subscription.events)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the events matching filter after lastEventID that are
+// still kept, and a channel of the events to come, which is closed when the
+// subscriber falls behind or the stream is closed. Without a lastEventID only
+// events to come are sent. resumed is false when lastEventID is given but the
+// events after it are no longer kept or it is unknown, the subscriber then has
+// to reload what it shows. cancel must be called once the subscriber is done.
+func (s *ScheduleEventStream) Subscribe(filter ScheduleEventFilter, lastEventID string) (missed []ScheduleEvent, events <-chan ScheduleEvent, resumed bool, cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resumed = true
+	if lastEventID != "" {
+		missed, resumed = s.since(lastEventID)
+	}
+	matching := []ScheduleEvent{}
+	for _, event := range missed {
+		if filter.matches(event) {
+			matching = append(matching, event)
+		}
+	}
+
+	subscription := &eventSubscription{filter: filter, events: make(chan ScheduleEvent, eventSubscriberBuffer)}
+	if s.closed {
+		close(subscription.events)
+		return matching, subscription.events, resumed, func() {}
+	}
+	s.subscribers[subscription] = true
+	cancel = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.subscribers[subscription] {
+			delete(s.subscribers, subscription)
+			close(subscription.events)
+		}
+	}
+	return matching, subscription.events, resumed, cancel
+}
+
+// Close ends every subscription, so streams held open by clients finish, and
+// ends the ones made after it right away. It is meant for server shutdown.
+func (s *ScheduleEventStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for subscription := range s.subscribers {
+		delete(s.subscribers, subscription)
+		close(subscription.events)
+	}
+}
+
+// since returns the kept events after the one with the ID and whether all of
+// them are still kept. mu must be held.
+func (s *ScheduleEventStream) since(id string) ([]ScheduleEvent, bool) {
+	run, sequence, found := strings.Cut(id, "-")
+	seen, err := strconv.ParseUint(sequence, 10, 64)
+	if !found || run != s.run || err != nil || seen >= s.next {
+		return nil, false
+	}
+
+	// The first kept event has the sequence oldest, every one after it is kept
+	oldest := s.next - uint64(len(s.buffer))
+	if seen+1 < oldest {
+		return nil, false
+	}
+	return append([]ScheduleEvent(nil), s.buffer[seen+1-oldest:]...), true
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScheduleEventStream(t *testing.T) {
+	ctx := context.Background()
+	central := PickUpSchedule{ID: "abc", BranchID: "central", Genre: "fiction"}
+	harbour := PickUpSchedule{ID: "def", BranchID: "harbour", Genre: "love"}
+	ids := func(events []ScheduleEvent) []string {
+		var ids []string
+		for _, event := range events {
+			ids = append(ids, event.Data.ID+" "+string(event.Type))
+		}
+		return ids
+	}
+	equal := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("PositiveCase_Filtered", func(t *testing.T) {
+		stream := NewScheduleEventStream(10)
+		_, events, resumed, cancel := stream.Subscribe(ScheduleEventFilter{BranchID: "central"}, "")
+		defer cancel()
+		if !resumed {
+			t.Error("Expected a new subscriber to be resumed")
+		}
+
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCreated, harbour)
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCreated, central)
+
+		event := <-events
+		if event.Data.ID != "abc" || event.Type != WebhookScheduleCreated || event.OccurredAt.IsZero() {
+			t.Errorf("Expected the event of central, got %+v", event)
+		}
+		select {
+		case other := <-events:
+			t.Errorf("Expected no other event, got %+v", other)
+		default:
+		}
+	})
+
+	t.Run("PositiveCase_Resume", func(t *testing.T) {
+		stream := NewScheduleEventStream(10)
+		_, events, _, cancel := stream.Subscribe(ScheduleEventFilter{}, "")
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCreated, central)
+		last := <-events
+		cancel()
+
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleUpdated, central)
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCreated, harbour)
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCancelled, central)
+
+		missed, _, resumed, cancel := stream.Subscribe(ScheduleEventFilter{Genre: "fiction"}, last.ID)
+		defer cancel()
+		want := []string{"abc schedule.updated", "abc schedule.cancelled"}
+		if !resumed || !equal(ids(missed), want) {
+			t.Errorf("Expected to resume with %v, got %v (%v)", want, ids(missed), resumed)
+		}
+
+		// Nothing missed after the latest event
+		missed, _, resumed, cancel = stream.Subscribe(ScheduleEventFilter{}, stream.buffer[len(stream.buffer)-1].ID)
+		defer cancel()
+		if !resumed || len(missed) != 0 {
+			t.Errorf("Expected nothing missed, got %v (%v)", ids(missed), resumed)
+		}
+	})
+
+	t.Run("NegativeCase_ResumeTooLate", func(t *testing.T) {
+		stream := NewScheduleEventStream(2)
+		_, events, _, cancel := stream.Subscribe(ScheduleEventFilter{}, "")
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCreated, central)
+		last := <-events
+		cancel()
+		for i := 0; i < 3; i++ {
+			_ = stream.PublishScheduleEvent(ctx, WebhookScheduleUpdated, central)
+		}
+
+		missed, _, resumed, cancel := stream.Subscribe(ScheduleEventFilter{}, last.ID)
+		defer cancel()
+		if resumed || len(missed) != 0 {
+			t.Errorf("Expected the events to be lost, got %v (%v)", ids(missed), resumed)
+		}
+	})
+
+	t.Run("NegativeCase_UnknownEventID", func(t *testing.T) {
+		stream := NewScheduleEventStream(10)
+		_ = stream.PublishScheduleEvent(ctx, WebhookScheduleCreated, central)
+
+		for _, id := range []string{"garbage", "deadbeef-1", stream.run + "-2", stream.run + "-x"} {
+			missed, _, resumed, cancel := stream.Subscribe(ScheduleEventFilter{}, id)
+			cancel()
+			if resumed || len(missed) != 0 {
+				t.Errorf("Expected %s not to resume, got %v", id, ids(missed))
+			}
+		}
+	})
+
+	t.Run("NegativeCase_SlowSubscriberDropped", func(t *testing.T) {
+		stream := NewScheduleEventStream(10)
+		_, events, _, cancel := stream.Subscribe(ScheduleEventFilter{}, "")
+		defer cancel()
+
+		for i := 0; i <= eventSubscriberBuffer; i++ {
+			_ = stream.PublishScheduleEvent(ctx, WebhookScheduleUpdated, central)
+		}
+
+		received := 0
+		for range events {
+			received++
+		}
+		if received != eventSubscriberBuffer {
+			t.Errorf("Expected %d events before the channel closed, got %d", eventSubscriberBuffer, received)
+		}
+	})
+
+	t.Run("PositiveCase_CloseEndsSubscribers", func(t *testing.T) {
+		stream := NewScheduleEventStream(10)
+		_, events, _, cancel := stream.Subscribe(ScheduleEventFilter{}, "")
+		defer cancel()
+
+		stream.Close()
+		if _, open := <-events; open {
+			t.Error("Expected the subscription to end on close")
+		}
+
+		_, later, _, cancelLater := stream.Subscribe(ScheduleEventFilter{}, "")
+		defer cancelLater()
+		if _, open := <-later; open {
+			t.Error("Expected a subscription after close to end right away")
+		}
+		if err := stream.PublishScheduleEvent(ctx, WebhookScheduleCreated, central); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileLogCompactAfter is how many records a fileLog appends before it folds
+// them into a new snapshot
+const fileLogCompactAfter = 1000
+
+// fileLog makes a small store durable the way FileRepository does schedules:
+// every change is appended to a log and fsynced, and after
+// fileLogCompactAfter changes the log is folded into a snapshot. A change has
+// to hold the complete new state of what it changed, so replaying changes the
+// snapshot already contains, after a crash between writing the snapshot and
+// emptying the log, is harmless.
+type fileLog struct {
+	dir          string
+	snapshotName string
+	logName      string
+	// what describes the store in error messages
+	what string
+	// compactAfter is how many records are appended before the log is folded
+	compactAfter int
+
+	// mu guards the log file and pending
+	mu      sync.Mutex
+	file    *os.File
+	pending int
+}
+
+func newFileLog(dir, snapshotName, logName, what string) *fileLog {
+	return &fileLog{
+		dir:          dir,
+		snapshotName: snapshotName,
+		logName:      logName,
+		what:         what,
+		compactAfter: fileLogCompactAfter,
+	}
+}
+
+// readSnapshot decodes the snapshot into v, leaving v as it is when there is
+// no snapshot yet
+func (l *fileLog) readSnapshot(v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(l.dir, l.snapshotName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %v", l.what, err)
+	}
+
+	if err := decodeRecord(data, v); err != nil {
+		return fmt.Errorf("corrupt %s file: %v", l.what, err)
+	}
+	return nil
+}
+
+// open replays every record of the log with replay and opens it for
+// appending. A record torn by a crash at the end of the log is dropped.
+func (l *fileLog) open(replay func(line []byte) error) error {
+	file, err := os.OpenFile(filepath.Join(l.dir, l.logName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s log: %v", l.what, err)
+	}
+
+	valid, err := l.replay(file, replay)
+	if err == nil {
+		err = file.Truncate(valid)
+	}
+	if err == nil {
+		_, err = file.Seek(valid, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.file = file
+	return nil
+}
+
+// replay passes every complete record of the log to fn and returns the offset
+// right behind the last one
+func (l *fileLog) replay(file *os.File, fn func(line []byte) error) (int64, error) {
+	reader := bufio.NewReader(file)
+
+	var valid int64
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) == 0 && readErr == io.EOF {
+			return valid, nil
+		}
+		if readErr != nil && readErr != io.EOF {
+			return 0, fmt.Errorf("failed to read %s log: %v", l.what, readErr)
+		}
+
+		if err := fn(line); err != nil {
+			// Only the last record can be torn, anything else is corruption
+			if rest, _ := io.ReadAll(reader); errors.Is(err, errTornRecord) && bytes.IndexByte(rest, '\n') < 0 {
+				return valid, nil
+			}
+			return 0, fmt.Errorf("corrupt %s log record at offset %d: %v", l.what, valid, err)
+		}
+		valid += int64(len(line))
+		l.pending++
+	}
+}
+
+// append writes the record to the log. When the log is due to be folded,
+// snapshot is called first for everything stored before the record, which
+// replaces the snapshot, and the log starts over.
+func (l *fileLog) append(record interface{}, snapshot func() interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("%s file is closed", l.what)
+	}
+	if l.pending >= l.compactAfter {
+		if err := l.compact(snapshot()); err != nil {
+			return err
+		}
+	}
+
+	line, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to append to %s log: %v", l.what, err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s log: %v", l.what, err)
+	}
+
+	l.pending++
+	return nil
+}
+
+// compact replaces the snapshot with v and empties the log. mu must be held.
+func (l *fileLog) compact(v interface{}) error {
+	data, err := encodeRecord(v)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(l.dir, l.snapshotName, l.what, data); err != nil {
+		return err
+	}
+
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate %s log: %v", l.what, err)
+	}
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to truncate %s log: %v", l.what, err)
+	}
+	l.pending = 0
+
+	return l.file.Sync()
+}
+
+// close folds the log into a final snapshot of v and closes it
+func (l *fileLog) close(v interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.compact(v)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+
+	return err
+}
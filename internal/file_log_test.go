@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testLogRecord is a change of the test store of TestFileLog
+type testLogRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func TestFileLog(t *testing.T) {
+	// open returns the log in dir with everything it stores by key
+	open := func(t *testing.T, dir string) (*fileLog, map[string]string, error) {
+		l := newFileLog(dir, "test.json", "test.log", "test")
+		l.compactAfter = 2
+
+		stored := map[string]string{}
+		if err := l.readSnapshot(&stored); err != nil {
+			return nil, nil, err
+		}
+		err := l.open(func(line []byte) error {
+			var record testLogRecord
+			if err := decodeRecord(line, &record); err != nil {
+				return err
+			}
+			stored[record.Key] = record.Value
+			return nil
+		})
+		return l, stored, err
+	}
+	// write appends the records to a new log in dir and closes it without a
+	// final snapshot
+	write := func(t *testing.T, dir string, records ...testLogRecord) {
+		l, stored, err := open(t, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, record := range records {
+			snapshot := func() interface{} {
+				return stored
+			}
+			if err := l.append(record, snapshot); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			stored[record.Key] = record.Value
+		}
+		if err := l.file.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	t.Run("PositiveCase_CompactsAndReplays", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, testLogRecord{"a", "1"}, testLogRecord{"b", "2"}, testLogRecord{"a", "3"})
+
+		logged, err := os.ReadFile(filepath.Join(dir, "test.log"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if lines := strings.Count(string(logged), "\n"); lines != 1 {
+			t.Errorf("Expected the log folded after 2 records, got %d records", lines)
+		}
+
+		_, stored, err := open(t, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(stored, map[string]string{"a": "3", "b": "2"}) {
+			t.Errorf("Expected the snapshot and the log replayed, got %v", stored)
+		}
+	})
+
+	t.Run("PositiveCase_TornRecordDropped", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, testLogRecord{"a", "1"})
+		file, err := os.OpenFile(filepath.Join(dir, "test.log"), os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := file.WriteString(`0000 {"key": "b"`); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		_ = file.Close()
+
+		_, stored, err := open(t, dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(stored, map[string]string{"a": "1"}) {
+			t.Errorf("Expected only the complete record, got %v", stored)
+		}
+	})
+
+	t.Run("NegativeCase_CorruptRecord", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "test.log"), []byte("garbage\ngarbage\n"), 0o644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, _, err := open(t, dir); err == nil {
+			t.Error("Expected error, but got nil")
+		}
+	})
+
+	t.Run("NegativeCase_Closed", func(t *testing.T) {
+		l, stored, err := open(t, t.TempDir())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := l.close(stored); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := l.append(testLogRecord{"a", "1"}, func() interface{} { return stored }); err == nil {
+			t.Error("Expected error, but got nil")
+		}
+	})
+}
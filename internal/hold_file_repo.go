@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const fileHoldsName = "holds.json"
+
+// FileHoldRepository is a HoldRepository that keeps every hold in memory and
+// rewrites all of them to a single file on every change, like
+// FileBranchRepository
+type FileHoldRepository struct {
+	*InMemoryHoldRepository
+
+	dir string
+}
+
+// holdsRecord is the content of the holds file
+type holdsRecord struct {
+	Holds []Hold `json:"holds"`
+}
+
+func NewFileHoldRepository(dir string) (*FileHoldRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	r := &FileHoldRepository{
+		InMemoryHoldRepository: NewInMemoryHoldRepository(),
+		dir:                    dir,
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, fileHoldsName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read holds: %v", err)
+	default:
+		var record holdsRecord
+		if err := decodeRecord(data, &record); err != nil {
+			return nil, fmt.Errorf("corrupt holds file: %v", err)
+		}
+		r.restore(record.Holds)
+	}
+	r.persist = r.writeHolds
+
+	return r, nil
+}
+
+// writeHolds is the persist hook of the embedded InMemoryHoldRepository
+func (r *FileHoldRepository) writeHolds(holds []Hold) error {
+	data, err := encodeRecord(holdsRecord{Holds: holds})
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(r.dir, fileHoldsName, "holds", data)
+}
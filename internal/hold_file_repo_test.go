@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFileHoldRepository_Suite(t *testing.T) {
+	testHoldRepository(t, func(t *testing.T) holdStores {
+		dir := t.TempDir()
+		return holdStores{
+			holds:     openFileStore(t, NewFileHoldRepository, dir),
+			branches:  openFileStore(t, NewFileBranchRepository, dir),
+			schedules: NewInMemoryRepository(context.Background()),
+		}
+	})
+}
+
+func TestFileHoldRepository_Restart(t *testing.T) {
+	ctx := context.Background()
+
+	testFileRestart(t, NewFileHoldRepository, fileHoldsName, func(t *testing.T, repo *FileHoldRepository) func(t *testing.T, reopened *FileHoldRepository) {
+		var holds []Hold
+		for i := 0; i < 3; i++ {
+			hold, err := repo.CreateHold(ctx, Hold{Genre: "fiction", BookInfo: Book{Title: "Emma"}, BranchID: "central"})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			holds = append(holds, hold)
+		}
+		if _, err := repo.UpdateHold(ctx, holds[0].ID, func(hold *Hold) error {
+			hold.Status = HoldStatusCancelled
+			return nil
+		}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// The queue survives with the cancelled hold out of it
+		return func(t *testing.T, reopened *FileHoldRepository) {
+			for i, want := range []struct {
+				status   HoldStatus
+				position int
+			}{{HoldStatusCancelled, 0}, {HoldStatusWaiting, 1}, {HoldStatusWaiting, 2}} {
+				fetched, err := reopened.GetHold(ctx, holds[i].ID)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if fetched.Status != want.status || fetched.Position != want.position {
+					t.Errorf("Expected hold %d to be %s at %d after restart, got %s at %d", i, want.status, want.position, fetched.Status, fetched.Position)
+				}
+			}
+		}
+	})
+}
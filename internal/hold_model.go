@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// HoldStatus is where a hold is in the queue of its book. A hold waits until
+// a copy of the book is free at its branch, is then offered a pick-up
+// schedule for it, and either confirmed by the borrower in time or expired.
+// A hold can be cancelled until it is confirmed.
+type HoldStatus string
+
+const (
+	HoldStatusWaiting   HoldStatus = "waiting"
+	HoldStatusOffered   HoldStatus = "offered"
+	HoldStatusConfirmed HoldStatus = "confirmed"
+	HoldStatusExpired   HoldStatus = "expired"
+	HoldStatusCancelled HoldStatus = "cancelled"
+)
+
+// Hold is a place in the queue for a book at a branch that had no free copy.
+// Holds of the same book at the same branch are served first come, first
+// served.
+type Hold struct {
+	ID       string `json:"id"`
+	Genre    string `json:"genre"`
+	BookInfo Book   `json:"book_info"`
+	BranchID string `json:"branch_id"`
+	// BorrowerID is who the hold queues for and who is offered the copy
+	BorrowerID string     `json:"borrower_id"`
+	Status     HoldStatus `json:"status"`
+	// Position is the place of a waiting hold in its queue, 1 being next
+	Position int `json:"position,omitempty"`
+	// ScheduleID is the pick-up schedule the hold was offered
+	ScheduleID string `json:"schedule_id,omitempty"`
+	// ExpiresAt is when an offer lapses unless the borrower confirms it
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// queueKey identifies the queue of the hold, the book at the branch
+func (h Hold) queueKey() string {
+	return h.BranchID + "/" + bookKey(h.Genre, h.BookInfo)
+}
+
+// HoldQuery filters holds. Empty filters match everything.
+type HoldQuery struct {
+	BranchID string
+	Genre    string
+	// Book is only matched together with Genre
+	Book   *Book
+	Status HoldStatus
+}
+
+// matches reports whether the hold passes every filter of the query
+func (q HoldQuery) matches(hold Hold) bool {
+	switch {
+	case q.BranchID != "" && hold.BranchID != q.BranchID:
+		return false
+	case q.Genre != "" && hold.Genre != q.Genre:
+		return false
+	case q.Book != nil && bookKey(hold.Genre, hold.BookInfo) != bookKey(q.Genre, *q.Book):
+		return false
+	case q.Status != "" && hold.Status != q.Status:
+		return false
+	default:
+		return true
+	}
+}
+
+// HoldConfig sets how long a borrower has to confirm the pick-up schedule
+// offered to their hold
+type HoldConfig struct {
+	ConfirmWithin time.Duration
+}
+
+// DefaultHoldConfig is used unless WithHolds says otherwise
+var DefaultHoldConfig = HoldConfig{ConfirmWithin: 24 * time.Hour}
+
+// DefaultHoldCheckInterval is how often RunHoldCheck expires offers and
+// serves the queues unless told otherwise
+const DefaultHoldCheckInterval = 5 * time.Minute
+
+// Validate reports a confirmation window shorter than a minute
+func (c HoldConfig) Validate() error {
+	if c.ConfirmWithin < time.Minute {
+		return fmt.Errorf("hold confirmation window of %v must be at least a minute", c.ConfirmWithin)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrHoldNotFound is returned when no hold has the requested ID
+	ErrHoldNotFound = errors.New("hold not found")
+	// ErrHoldNotOffered is returned when confirming a hold that has no offer
+	ErrHoldNotOffered = errors.New("hold has no offer to confirm")
+	// ErrHoldExpired is returned when confirming an offer after it lapsed
+	ErrHoldExpired = errors.New("hold offer expired")
+	// ErrHoldClosed is returned when cancelling a hold that was already
+	// confirmed, expired or cancelled
+	ErrHoldClosed = errors.New("hold is no longer open")
+)
+
+// HoldRepository stores the holds queueing for books. Holds of a queue keep
+// the order they were created in.
+type HoldRepository interface {
+	// CreateHold assigns the hold a new ID and timestamps and stores it as
+	// waiting at the back of its queue
+	CreateHold(ctx context.Context, hold Hold) (Hold, error)
+	// GetHold returns the hold with its Position when it is waiting
+	GetHold(ctx context.Context, id string) (Hold, error)
+	// ListHolds returns the holds matching the query in the order they were
+	// created, the waiting ones with their Position
+	ListHolds(ctx context.Context, query HoldQuery) ([]Hold, error)
+	// UpdateHold applies fn to the stored hold and stores the result, stamped
+	// with the current time, unless fn fails. Only the status, schedule and
+	// expiry of a hold can change.
+	UpdateHold(ctx context.Context, id string, fn func(hold *Hold) error) (Hold, error)
+}
+
+type InMemoryHoldRepository struct {
+	mu    sync.RWMutex
+	holds map[string]Hold
+	// order holds the IDs of every hold in the order they were created
+	order []string
+
+	// persist, when set, is called with every hold in order including the new
+	// or changed one while mu is held. The change is only stored if it returns
+	// no error.
+	persist func(holds []Hold) error
+}
+
+func NewInMemoryHoldRepository() *InMemoryHoldRepository {
+	return &InMemoryHoldRepository{
+		holds: make(map[string]Hold),
+	}
+}
+
+func (r *InMemoryHoldRepository) CreateHold(_ context.Context, hold Hold) (Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	hold = cloneHold(hold)
+	hold.ID = newHoldID()
+	hold.Status = HoldStatusWaiting
+	hold.ScheduleID = ""
+	hold.ExpiresAt = nil
+	hold.CreatedAt = now
+	hold.UpdatedAt = now
+
+	if r.persist != nil {
+		if err := r.persist(r.sorted(&hold)); err != nil {
+			return Hold{}, err
+		}
+	}
+
+	r.holds[hold.ID] = hold
+	r.order = append(r.order, hold.ID)
+
+	return r.positioned(hold), nil
+}
+
+func (r *InMemoryHoldRepository) GetHold(_ context.Context, id string) (Hold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hold, exists := r.holds[id]
+	if !exists {
+		return Hold{}, ErrHoldNotFound
+	}
+
+	return r.positioned(hold), nil
+}
+
+func (r *InMemoryHoldRepository) ListHolds(_ context.Context, query HoldQuery) ([]Hold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	holds := []Hold{}
+	for _, hold := range r.sorted(nil) {
+		if query.matches(hold) {
+			holds = append(holds, hold)
+		}
+	}
+
+	return holds, nil
+}
+
+func (r *InMemoryHoldRepository) UpdateHold(_ context.Context, id string, fn func(hold *Hold) error) (Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.holds[id]
+	if !exists {
+		return Hold{}, ErrHoldNotFound
+	}
+
+	hold := cloneHold(stored)
+	if err := fn(&hold); err != nil {
+		return Hold{}, err
+	}
+	// A hold stays in the queue it was created in, for the same borrower
+	hold.Genre, hold.BookInfo, hold.BranchID = stored.Genre, cloneHold(stored).BookInfo, stored.BranchID
+	hold.BorrowerID = stored.BorrowerID
+	hold.Position = 0
+	hold.UpdatedAt = time.Now().UTC()
+
+	if r.persist != nil {
+		if err := r.persist(r.sorted(&hold)); err != nil {
+			return Hold{}, err
+		}
+	}
+
+	r.holds[id] = hold
+
+	return r.positioned(hold), nil
+}
+
+// restore puts already persisted holds back in order without calling persist
+func (r *InMemoryHoldRepository) restore(holds []Hold) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, hold := range holds {
+		if _, exists := r.holds[hold.ID]; !exists {
+			r.order = append(r.order, hold.ID)
+		}
+		hold.Position = 0
+		r.holds[hold.ID] = cloneHold(hold)
+	}
+}
+
+// sorted returns a copy of every hold in the order they were created with
+// their positions, with changed in place of the stored hold with the same ID
+// or last when it is new. mu must be held.
+func (r *InMemoryHoldRepository) sorted(changed *Hold) []Hold {
+	holds := make([]Hold, 0, len(r.order)+1)
+	for _, id := range r.order {
+		if changed != nil && id == changed.ID {
+			holds = append(holds, cloneHold(*changed))
+			changed = nil
+			continue
+		}
+		holds = append(holds, cloneHold(r.holds[id]))
+	}
+	if changed != nil {
+		holds = append(holds, cloneHold(*changed))
+	}
+
+	return positionHolds(holds)
+}
+
+// positioned returns a copy of the stored hold with its position, mu must be
+// held
+func (r *InMemoryHoldRepository) positioned(hold Hold) Hold {
+	hold = cloneHold(hold)
+	if hold.Status != HoldStatusWaiting {
+		return hold
+	}
+
+	key := hold.queueKey()
+	for _, id := range r.order {
+		if other := r.holds[id]; other.Status == HoldStatusWaiting && other.queueKey() == key {
+			hold.Position++
+		}
+		if id == hold.ID {
+			break
+		}
+	}
+
+	return hold
+}
+
+// positionHolds numbers the waiting holds of each queue in the order given,
+// clearing the position of every other hold
+func positionHolds(holds []Hold) []Hold {
+	waiting := make(map[string]int)
+	for i := range holds {
+		holds[i].Position = 0
+		if holds[i].Status == HoldStatusWaiting {
+			key := holds[i].queueKey()
+			waiting[key]++
+			holds[i].Position = waiting[key]
+		}
+	}
+	return holds
+}
+
+// cloneHold copies the hold so callers cannot change stored authors or the
+// expiry
+func cloneHold(hold Hold) Hold {
+	hold.BookInfo.Author = append([]string(nil), hold.BookInfo.Author...)
+	hold.BookInfo.AvailableCopies = nil
+	if hold.ExpiresAt != nil {
+		expiresAt := *hold.ExpiresAt
+		hold.ExpiresAt = &expiresAt
+	}
+	return hold
+}
+
+// newHoldID returns a random identifier of the same form as schedule IDs
+func newHoldID() string {
+	return newScheduleID()
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInMemoryHoldRepository_Suite(t *testing.T) {
+	testHoldRepository(t, func(t *testing.T) holdStores {
+		return holdStores{
+			holds:     NewInMemoryHoldRepository(),
+			branches:  NewInMemoryBranchRepository(),
+			schedules: NewInMemoryRepository(context.Background()),
+		}
+	})
+}
+
+// holdStores are the repositories a hold refers to, sharing one storage
+type holdStores struct {
+	holds     HoldRepository
+	branches  BranchRepository
+	schedules BookRepository
+}
+
+// testHoldRepository checks the behaviour every HoldRepository shares.
+// newStores must return empty repositories.
+func testHoldRepository(t *testing.T, newStores func(t *testing.T) holdStores) {
+	ctx := context.Background()
+	emma := Book{Title: "Emma", Author: []string{"Jane Austen"}}
+	persuasion := Book{Title: "Persuasion", Author: []string{"Jane Austen"}}
+
+	// setUp returns fresh stores with a branch and holds placed on emma three
+	// times and on persuasion once, in that order
+	setUp := func(t *testing.T) (holdStores, []Hold) {
+		stores := newStores(t)
+		branch, err := stores.branches.CreateBranch(ctx, Branch{Name: "Central", Address: "Main Street 1", Timezone: "UTC"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var holds []Hold
+		for _, book := range []Book{emma, emma, persuasion, emma} {
+			hold, err := stores.holds.CreateHold(ctx, Hold{Genre: "fiction", BookInfo: book, BranchID: branch.ID})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			holds = append(holds, hold)
+		}
+		return stores, holds
+	}
+
+	positions := func(t *testing.T, repo HoldRepository, holds []Hold) []int {
+		var positions []int
+		for _, hold := range holds {
+			fetched, err := repo.GetHold(ctx, hold.ID)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			positions = append(positions, fetched.Position)
+		}
+		return positions
+	}
+
+	t.Run("CreateHold_QueuesPerBook", func(t *testing.T) {
+		stores, holds := setUp(t)
+
+		for _, hold := range holds {
+			if hold.ID == "" || hold.Status != HoldStatusWaiting || hold.CreatedAt.IsZero() {
+				t.Errorf("Expected a waiting hold with ID and timestamps, got %+v", hold)
+			}
+		}
+		if got := positions(t, stores.holds, holds); !reflect.DeepEqual(got, []int{1, 2, 1, 3}) {
+			t.Errorf("Expected positions [1 2 1 3], got %v", got)
+		}
+
+		fetched, err := stores.holds.GetHold(ctx, holds[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(fetched.BookInfo, emma) || fetched.Genre != "fiction" || fetched.BranchID != holds[0].BranchID {
+			t.Errorf("Expected the hold as placed, got %+v", fetched)
+		}
+	})
+
+	t.Run("GetHold_NotFound", func(t *testing.T) {
+		stores := newStores(t)
+
+		if _, err := stores.holds.GetHold(ctx, "missing"); !errors.Is(err, ErrHoldNotFound) {
+			t.Errorf("Expected ErrHoldNotFound, got %v", err)
+		}
+	})
+
+	t.Run("UpdateHold_MovesTheQueue", func(t *testing.T) {
+		stores, holds := setUp(t)
+
+		saved, err := stores.schedules.SavePickUpSchedule(ctx, PickUpSchedule{Genre: "fiction", BookInfo: emma, BranchID: holds[0].BranchID})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		scheduleID := saved[len(saved)-1].ID
+		expiresAt := time.Date(2023, 11, 21, 8, 0, 0, 0, time.UTC)
+
+		offered, err := stores.holds.UpdateHold(ctx, holds[0].ID, func(hold *Hold) error {
+			hold.Status = HoldStatusOffered
+			hold.ScheduleID = scheduleID
+			hold.ExpiresAt = &expiresAt
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if offered.Status != HoldStatusOffered || offered.ScheduleID != scheduleID || offered.ExpiresAt == nil || !offered.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("Expected the offer to be stored, got %+v", offered)
+		}
+		if got := positions(t, stores.holds, holds); !reflect.DeepEqual(got, []int{0, 1, 1, 2}) {
+			t.Errorf("Expected positions [0 1 1 2], got %v", got)
+		}
+
+		waiting, err := stores.holds.ListHolds(ctx, HoldQuery{BranchID: holds[0].BranchID, Genre: "fiction", Book: &emma, Status: HoldStatusWaiting})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(waiting) != 2 || waiting[0].ID != holds[1].ID || waiting[1].ID != holds[3].ID {
+			t.Errorf("Expected the waiting holds on emma in order, got %+v", waiting)
+		}
+	})
+
+	t.Run("UpdateHold_FailureStoresNothing", func(t *testing.T) {
+		stores, holds := setUp(t)
+
+		failure := errors.New("not now")
+		_, err := stores.holds.UpdateHold(ctx, holds[0].ID, func(hold *Hold) error {
+			hold.Status = HoldStatusCancelled
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Errorf("Expected the error of fn, got %v", err)
+		}
+
+		fetched, err := stores.holds.GetHold(ctx, holds[0].ID)
+		if err != nil || fetched.Status != HoldStatusWaiting {
+			t.Errorf("Expected the hold to keep waiting, got %+v, %v", fetched, err)
+		}
+
+		if _, err := stores.holds.UpdateHold(ctx, "missing", func(hold *Hold) error { return nil }); !errors.Is(err, ErrHoldNotFound) {
+			t.Errorf("Expected ErrHoldNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ListHolds_Filters", func(t *testing.T) {
+		stores, holds := setUp(t)
+
+		if _, err := stores.holds.UpdateHold(ctx, holds[2].ID, func(hold *Hold) error {
+			hold.Status = HoldStatusCancelled
+			return nil
+		}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, tc := range []struct {
+			query HoldQuery
+			want  []string
+		}{
+			{HoldQuery{}, []string{holds[0].ID, holds[1].ID, holds[2].ID, holds[3].ID}},
+			{HoldQuery{Status: HoldStatusCancelled}, []string{holds[2].ID}},
+			{HoldQuery{Genre: "fiction", Book: &persuasion}, []string{holds[2].ID}},
+			{HoldQuery{BranchID: "elsewhere"}, nil},
+		} {
+			listed, err := stores.holds.ListHolds(ctx, tc.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if listed == nil {
+				t.Errorf("Expected a non-nil list for %+v", tc.query)
+			}
+			var ids []string
+			for _, hold := range listed {
+				ids = append(ids, hold.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.want) {
+				t.Errorf("Expected %v for %+v, got %v", tc.want, tc.query, ids)
+			}
+		}
+	})
+}
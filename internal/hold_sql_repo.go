@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLHoldRepository is a HoldRepository on top of database/sql, sharing the
+// schema and migrations of SQLRepository
+type SQLHoldRepository struct {
+	db *sql.DB
+}
+
+// NewSQLHoldRepository applies every pending migration to db before returning
+func NewSQLHoldRepository(ctx context.Context, db *sql.DB) (*SQLHoldRepository, error) {
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return &SQLHoldRepository{db: db}, nil
+}
+
+func (r *SQLHoldRepository) CreateHold(ctx context.Context, hold Hold) (Hold, error) {
+	now := time.Now().UTC()
+	hold = cloneHold(hold)
+	hold.ID = newHoldID()
+	hold.Status = HoldStatusWaiting
+	hold.ScheduleID = ""
+	hold.ExpiresAt = nil
+	hold.CreatedAt = now
+	hold.UpdatedAt = now
+
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		bookID, err := upsertBook(ctx, tx, hold.Genre, hold.BookInfo)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO holds (public_id, branch_id, borrower_id, book_id, status, created_at, updated_at)
+			SELECT ?, id, (SELECT id FROM borrowers WHERE public_id = ?), ?, ?, ?, ? FROM branches WHERE public_id = ?`,
+			hold.ID, hold.BorrowerID, bookID, hold.Status, formatTime(hold.CreatedAt), formatTime(hold.UpdatedAt), hold.BranchID)
+		if err != nil {
+			return fmt.Errorf("failed to insert hold: %v", err)
+		}
+		if inserted, err := result.RowsAffected(); err == nil && inserted == 0 {
+			return ErrBranchNotFound
+		}
+
+		hold, err = r.holdByID(ctx, tx, hold.ID)
+		return err
+	})
+	if err != nil {
+		return Hold{}, err
+	}
+
+	return hold, nil
+}
+
+func (r *SQLHoldRepository) GetHold(ctx context.Context, id string) (Hold, error) {
+	return r.holdByID(ctx, r.db, id)
+}
+
+func (r *SQLHoldRepository) ListHolds(ctx context.Context, query HoldQuery) ([]Hold, error) {
+	var conditions []string
+	var args []interface{}
+	if query.BranchID != "" {
+		conditions = append(conditions, `br.public_id = ?`)
+		args = append(args, query.BranchID)
+	}
+	if query.Genre != "" {
+		conditions = append(conditions, `g.name = ?`)
+		args = append(args, query.Genre)
+	}
+	if query.Genre != "" && query.Book != nil {
+		conditions = append(conditions, `b.title = ? AND b.edition_number = ? AND b.authors_key = ?`)
+		args = append(args, query.Book.Title, query.Book.EditionNumber, strings.Join(query.Book.Author, "\x1f"))
+	}
+	if query.Status != "" {
+		conditions = append(conditions, `h.status = ?`)
+		args = append(args, query.Status)
+	}
+
+	where := `1 = 1`
+	if len(conditions) > 0 {
+		where = strings.Join(conditions, ` AND `)
+	}
+
+	return r.queryHolds(ctx, r.db, where, args...)
+}
+
+func (r *SQLHoldRepository) UpdateHold(ctx context.Context, id string, fn func(hold *Hold) error) (Hold, error) {
+	var hold Hold
+
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		if hold, err = r.holdByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if err := fn(&hold); err != nil {
+			return err
+		}
+		hold.UpdatedAt = time.Now().UTC()
+
+		var expiresAt sql.NullString
+		if hold.ExpiresAt != nil {
+			expiresAt = sql.NullString{String: formatTime(*hold.ExpiresAt), Valid: true}
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE holds
+			SET status = ?, schedule_id = (SELECT id FROM pick_up_schedules WHERE public_id = ?), expires_at = ?, updated_at = ?
+			WHERE public_id = ?`,
+			hold.Status, hold.ScheduleID, expiresAt, formatTime(hold.UpdatedAt), id)
+		if err != nil {
+			return fmt.Errorf("failed to update hold: %v", err)
+		}
+
+		hold, err = r.holdByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return Hold{}, err
+	}
+
+	return hold, nil
+}
+
+func (r *SQLHoldRepository) holdByID(ctx context.Context, q querier, id string) (Hold, error) {
+	holds, err := r.queryHolds(ctx, q, `h.public_id = ?`, id)
+	if err != nil {
+		return Hold{}, err
+	}
+	if len(holds) == 0 {
+		return Hold{}, ErrHoldNotFound
+	}
+
+	return holds[0], nil
+}
+
+// queryHolds loads the holds matching the where clause in queue order with
+// their positions. The clause can refer to the hold, book, genre, branch and
+// borrower as h, b, g, br and bo.
+func (r *SQLHoldRepository) queryHolds(ctx context.Context, q querier, where string, args ...interface{}) ([]Hold, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT h.public_id, b.id, b.title, b.edition_number, g.name, br.public_id, COALESCE(bo.public_id, ''), h.status,
+			CASE WHEN h.status = ? THEN (
+				SELECT COUNT(*) FROM holds w
+				WHERE w.branch_id = h.branch_id AND w.book_id = h.book_id AND w.status = h.status AND w.id <= h.id
+			) ELSE 0 END,
+			COALESCE(s.public_id, ''), h.expires_at, h.created_at, h.updated_at
+		FROM holds h
+		JOIN books b ON b.id = h.book_id
+		JOIN genres g ON g.id = b.genre_id
+		JOIN branches br ON br.id = h.branch_id
+		LEFT JOIN borrowers bo ON bo.id = h.borrower_id
+		LEFT JOIN pick_up_schedules s ON s.id = h.schedule_id
+		WHERE `+where+`
+		ORDER BY h.id`, append([]interface{}{HoldStatusWaiting}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holds: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	holds := []Hold{}
+	var bookIDs []int64
+	for rows.Next() {
+		var bookID int64
+		var createdAt, updatedAt string
+		var expiresAt sql.NullString
+		var hold Hold
+		err := rows.Scan(&hold.ID, &bookID, &hold.BookInfo.Title, &hold.BookInfo.EditionNumber, &hold.Genre, &hold.BranchID,
+			&hold.BorrowerID, &hold.Status, &hold.Position, &hold.ScheduleID, &expiresAt, &createdAt, &updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read hold: %v", err)
+		}
+		if expiresAt.Valid {
+			expires, err := parseTime(expiresAt.String)
+			if err != nil {
+				return nil, err
+			}
+			hold.ExpiresAt = &expires
+		}
+		if hold.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		if hold.UpdatedAt, err = parseTime(updatedAt); err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+		bookIDs = append(bookIDs, bookID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read holds: %v", err)
+	}
+
+	authors, err := authorsOf(ctx, q, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range holds {
+		holds[i].BookInfo.Author = authors[bookIDs[i]]
+	}
+
+	return holds, nil
+}
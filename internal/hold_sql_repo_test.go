@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSQLHoldRepository_Suite(t *testing.T) {
+	testHoldRepository(t, func(t *testing.T) holdStores {
+		ctx := context.Background()
+		db := openTestDB(t)
+
+		holds, err := NewSQLHoldRepository(ctx, db)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		branches, err := NewSQLBranchRepository(ctx, db)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		schedules, err := NewSQLRepository(ctx, db)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return holdStores{holds: holds, branches: branches, schedules: schedules}
+	})
+}
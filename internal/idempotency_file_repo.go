@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	fileIdempotencyKeysName    = "idempotency_keys.json"
+	fileIdempotencyKeysLogName = "idempotency_keys.log"
+)
+
+// FileIdempotencyRepository is an IdempotencyRepository that keeps every
+// record in memory and makes it durable with a fileLog, appending every
+// reserved, answered or released key to a log next to the snapshot of all of
+// them
+type FileIdempotencyRepository struct {
+	*InMemoryIdempotencyRepository
+
+	log *fileLog
+}
+
+// idempotencyKeysRecord is the content of the idempotency keys snapshot
+type idempotencyKeysRecord struct {
+	Keys []IdempotencyRecord `json:"keys"`
+}
+
+func NewFileIdempotencyRepository(dir string) (*FileIdempotencyRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	r := &FileIdempotencyRepository{
+		InMemoryIdempotencyRepository: NewInMemoryIdempotencyRepository(),
+		log:                           newFileLog(dir, fileIdempotencyKeysName, fileIdempotencyKeysLogName, "idempotency keys"),
+	}
+
+	var snapshot idempotencyKeysRecord
+	if err := r.log.readSnapshot(&snapshot); err != nil {
+		return nil, err
+	}
+	records := make(map[string]IdempotencyRecord, len(snapshot.Keys))
+	for _, record := range snapshot.Keys {
+		records[record.Key] = record
+	}
+	err := r.log.open(func(line []byte) error {
+		var change idempotencyChange
+		if err := decodeRecord(line, &change); err != nil {
+			return err
+		}
+		if change.Record == nil {
+			delete(records, change.Key)
+		} else {
+			records[change.Key] = *change.Record
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.restore(sortedIdempotencyRecords(records))
+	r.persist = r.appendChange
+
+	return r, nil
+}
+
+// Close writes a final snapshot and closes the log
+func (r *FileIdempotencyRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.log.close(r.snapshot())
+}
+
+// appendChange is the persist hook of the embedded
+// InMemoryIdempotencyRepository
+func (r *FileIdempotencyRepository) appendChange(change idempotencyChange) error {
+	return r.log.append(change, r.snapshot)
+}
+
+// snapshot returns every stored record. mu must be held.
+func (r *FileIdempotencyRepository) snapshot() interface{} {
+	return idempotencyKeysRecord{Keys: sortedIdempotencyRecords(r.records)}
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFileIdempotencyRepository_Suite(t *testing.T) {
+	testIdempotencyRepository(t, fileStoreOf(NewFileIdempotencyRepository))
+}
+
+func TestFileIdempotencyRepository_Restart(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)
+	record := IdempotencyRecord{Key: "retry-1", Fingerprint: "body", CreatedAt: createdAt}
+
+	testFileRestart(t, NewFileIdempotencyRepository, fileIdempotencyKeysName, func(t *testing.T, repo *FileIdempotencyRepository) func(t *testing.T, reopened *FileIdempotencyRepository) {
+		if _, _, err := repo.ReserveIdempotencyKey(ctx, record, createdAt.Add(-time.Hour)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.CompleteIdempotencyKey(ctx, record.Key, 200, []byte(`{"status":"201 CREATED"}`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return func(t *testing.T, reopened *FileIdempotencyRepository) {
+			stored, ok, err := reopened.ReserveIdempotencyKey(ctx, record, createdAt.Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ok || stored.StatusCode != 200 || string(stored.Response) != `{"status":"201 CREATED"}` {
+				t.Errorf("Expected the stored answer after restart, got %+v (%v)", stored, ok)
+			}
+		}
+	})
+}
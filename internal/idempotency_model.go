@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header a client sends the same value
+// in when it retries a request, so the request only takes effect once
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the longest idempotency key accepted
+const maxIdempotencyKeyLength = 255
+
+var (
+	// ErrIdempotencyKeyReused is returned when an idempotency key comes with
+	// another request than the one it was first used with
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used with a different request")
+	// ErrIdempotencyKeyInFlight is returned when a request with the same
+	// idempotency key has not been answered yet
+	ErrIdempotencyKeyInFlight = errors.New("request with the same idempotency key is still in progress")
+	// ErrInvalidIdempotencyKey is returned for an idempotency key that is too
+	// long
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+)
+
+// IdempotencyRecord is the first request made with an idempotency key and,
+// once it was answered, the answer repeated for every retry
+type IdempotencyRecord struct {
+	Key string `json:"key"`
+	// Fingerprint identifies the body of the request
+	Fingerprint string `json:"fingerprint"`
+	// StatusCode and Response are the HTTP status and body of the answer,
+	// they are zero while the request is in progress
+	StatusCode int             `json:"status_code,omitempty"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// completed reports whether the request of the record was answered
+func (r IdempotencyRecord) completed() bool {
+	return r.StatusCode != 0
+}
+
+// IdempotencyConfig sets how long an idempotency key is remembered after its
+// first use
+type IdempotencyConfig struct {
+	Window time.Duration
+}
+
+// DefaultIdempotencyConfig is used unless told otherwise
+var DefaultIdempotencyConfig = IdempotencyConfig{Window: 24 * time.Hour}
+
+// Validate reports a window shorter than a minute
+func (c IdempotencyConfig) Validate() error {
+	if c.Window < time.Minute {
+		return fmt.Errorf("idempotency window of %v must be at least a minute", c.Window)
+	}
+	return nil
+}
+
+// requestFingerprint identifies a request by its method, path and JSON body,
+// regardless of the whitespace of the body
+func requestFingerprint(method, path string, body []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil {
+		// Not JSON, compare it as it is
+		compact.Reset()
+		compact.Write(body)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(compact.Bytes())
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// IdempotencyRepository stores the requests made with idempotency keys and
+// their answers
+type IdempotencyRepository interface {
+	// ReserveIdempotencyKey stores record as the request in progress of its
+	// key and returns it with true. When the key is already stored the stored
+	// record is returned with false instead. Records created before
+	// expiredBefore are dropped first.
+	ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord, expiredBefore time.Time) (IdempotencyRecord, bool, error)
+	// CompleteIdempotencyKey stores the answer to the request of a reserved
+	// key
+	CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, response []byte) error
+	// ReleaseIdempotencyKey drops a key so its request can be made again
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+type InMemoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+	// order holds the reserved keys by creation time, oldest first, so
+	// expired keys are dropped from the front without a look at the rest.
+	// Keys released or reserved again since are skipped.
+	order []idempotencyEntry
+
+	// persist, when set, is called with every change while mu is held. The
+	// change is only stored if it returns no error.
+	persist func(change idempotencyChange) error
+}
+
+// idempotencyEntry is a key in the order it was reserved in
+type idempotencyEntry struct {
+	key       string
+	createdAt time.Time
+}
+
+// idempotencyChange is a record stored under Key, or with a nil Record the
+// key being dropped
+type idempotencyChange struct {
+	Key    string             `json:"key"`
+	Record *IdempotencyRecord `json:"record,omitempty"`
+}
+
+func NewInMemoryIdempotencyRepository() *InMemoryIdempotencyRepository {
+	return &InMemoryIdempotencyRepository{
+		records: make(map[string]IdempotencyRecord),
+	}
+}
+
+func (r *InMemoryIdempotencyRepository) ReserveIdempotencyKey(_ context.Context, record IdempotencyRecord, expiredBefore time.Time) (IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dropExpired(expiredBefore)
+	// Keys reserved out of order may have expired behind the front
+	if stored, exists := r.records[record.Key]; exists && !stored.CreatedAt.Before(expiredBefore) {
+		return stored, false, nil
+	}
+
+	if err := r.store(record); err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+	r.order = append(r.order, idempotencyEntry{key: record.Key, createdAt: record.CreatedAt})
+	return record, true, nil
+}
+
+func (r *InMemoryIdempotencyRepository) CompleteIdempotencyKey(_ context.Context, key string, statusCode int, response []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, exists := r.records[key]
+	if !exists {
+		// Expired while the request was in progress, nothing to repeat
+		return nil
+	}
+	record.StatusCode = statusCode
+	record.Response = append([]byte(nil), response...)
+
+	return r.store(record)
+}
+
+func (r *InMemoryIdempotencyRepository) ReleaseIdempotencyKey(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.records[key]; !exists {
+		return nil
+	}
+	if r.persist != nil {
+		if err := r.persist(idempotencyChange{Key: key}); err != nil {
+			return err
+		}
+	}
+
+	delete(r.records, key)
+	return nil
+}
+
+// store saves the record once it is persisted. mu must be held.
+func (r *InMemoryIdempotencyRepository) store(record IdempotencyRecord) error {
+	if r.persist != nil {
+		if err := r.persist(idempotencyChange{Key: record.Key, Record: &record}); err != nil {
+			return err
+		}
+	}
+
+	r.records[record.Key] = record
+	return nil
+}
+
+// dropExpired drops the keys created before expiredBefore from the front of
+// the order. They are not persisted as dropped, a restored key expires again.
+// mu must be held.
+func (r *InMemoryIdempotencyRepository) dropExpired(expiredBefore time.Time) {
+	dropped := 0
+	for _, entry := range r.order {
+		if !entry.createdAt.Before(expiredBefore) {
+			break
+		}
+		if stored, exists := r.records[entry.key]; exists && stored.CreatedAt.Equal(entry.createdAt) {
+			delete(r.records, entry.key)
+		}
+		dropped++
+	}
+	r.order = r.order[dropped:]
+}
+
+// restore puts already persisted records back without calling persist
+func (r *InMemoryIdempotencyRepository) restore(records []IdempotencyRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, record := range records {
+		r.records[record.Key] = record
+	}
+	r.order = r.order[:0]
+	for _, record := range sortedIdempotencyRecords(r.records) {
+		r.order = append(r.order, idempotencyEntry{key: record.Key, createdAt: record.CreatedAt})
+	}
+}
+
+// sortedIdempotencyRecords returns the records ordered by creation time
+func sortedIdempotencyRecords(records map[string]IdempotencyRecord) []IdempotencyRecord {
+	sorted := make([]IdempotencyRecord, 0, len(records))
+	for _, record := range records {
+		sorted = append(sorted, record)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.Key < b.Key
+	})
+
+	return sorted
+}
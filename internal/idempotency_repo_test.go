@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInMemoryIdempotencyRepository_Suite(t *testing.T) {
+	testIdempotencyRepository(t, func(t *testing.T) IdempotencyRepository {
+		return NewInMemoryIdempotencyRepository()
+	})
+}
+
+// testIdempotencyRepository checks the behaviour every IdempotencyRepository
+// shares. newRepo must return an empty repository.
+func testIdempotencyRepository[R IdempotencyRepository](t *testing.T, newRepo func(t *testing.T) R) {
+	ctx := context.Background()
+	createdAt := time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)
+	record := IdempotencyRecord{Key: "retry-1", Fingerprint: "body", CreatedAt: createdAt}
+	expiredBefore := createdAt.Add(-time.Hour)
+
+	t.Run("ReserveIdempotencyKey_New", func(t *testing.T) {
+		repo := newRepo(t)
+
+		reserved, ok, err := repo.ReserveIdempotencyKey(ctx, record, expiredBefore)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !ok || reserved.Key != record.Key || reserved.completed() {
+			t.Errorf("Expected %+v to be reserved, got %+v (%v)", record, reserved, ok)
+		}
+	})
+
+	t.Run("ReserveIdempotencyKey_InProgress", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, _, err := repo.ReserveIdempotencyKey(ctx, record, expiredBefore); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		retry := record
+		retry.Fingerprint = "other body"
+		stored, ok, err := repo.ReserveIdempotencyKey(ctx, retry, expiredBefore)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ok || stored.Fingerprint != record.Fingerprint || stored.completed() {
+			t.Errorf("Expected the first request still in progress, got %+v (%v)", stored, ok)
+		}
+	})
+
+	t.Run("CompleteIdempotencyKey_Replayed", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, _, err := repo.ReserveIdempotencyKey(ctx, record, expiredBefore); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.CompleteIdempotencyKey(ctx, record.Key, 409, []byte(`{"status":"409 Conflict"}`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		stored, ok, err := repo.ReserveIdempotencyKey(ctx, record, expiredBefore)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ok || stored.StatusCode != 409 || string(stored.Response) != `{"status":"409 Conflict"}` || !stored.CreatedAt.Equal(createdAt) {
+			t.Errorf("Expected the stored answer, got %+v (%v)", stored, ok)
+		}
+	})
+
+	t.Run("ReleaseIdempotencyKey_CanBeReservedAgain", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, _, err := repo.ReserveIdempotencyKey(ctx, record, expiredBefore); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.ReleaseIdempotencyKey(ctx, record.Key); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, ok, err := repo.ReserveIdempotencyKey(ctx, record, expiredBefore); err != nil || !ok {
+			t.Errorf("Expected the released key to be reserved again, got %v (%v)", ok, err)
+		}
+	})
+
+	t.Run("ReserveIdempotencyKey_ReservedAgainOutlivesRelease", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, _, err := repo.ReserveIdempotencyKey(ctx, record, expiredBefore); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.ReleaseIdempotencyKey(ctx, record.Key); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		again := record
+		again.CreatedAt = createdAt.Add(2 * time.Hour)
+		if _, ok, err := repo.ReserveIdempotencyKey(ctx, again, expiredBefore); err != nil || !ok {
+			t.Fatalf("Expected the released key to be reserved again, got %v (%v)", ok, err)
+		}
+
+		// Only the first reservation expired
+		other := IdempotencyRecord{Key: "retry-2", Fingerprint: "body", CreatedAt: again.CreatedAt}
+		if _, _, err := repo.ReserveIdempotencyKey(ctx, other, createdAt.Add(time.Hour)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		stored, ok, err := repo.ReserveIdempotencyKey(ctx, again, createdAt.Add(time.Hour))
+		if err != nil || ok || !stored.CreatedAt.Equal(again.CreatedAt) {
+			t.Errorf("Expected the key reserved again to be kept, got %+v (%v), %v", stored, ok, err)
+		}
+	})
+
+	t.Run("ReserveIdempotencyKey_Expired", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, _, err := repo.ReserveIdempotencyKey(ctx, record, expiredBefore); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.CompleteIdempotencyKey(ctx, record.Key, 200, []byte(`{}`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		later := record
+		later.Fingerprint = "other body"
+		later.CreatedAt = createdAt.Add(2 * time.Hour)
+		stored, ok, err := repo.ReserveIdempotencyKey(ctx, later, createdAt.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !ok || stored.Fingerprint != later.Fingerprint || stored.completed() {
+			t.Errorf("Expected the expired key to be reserved anew, got %+v (%v)", stored, ok)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLIdempotencyRepository is an IdempotencyRepository on top of
+// database/sql, sharing the schema and migrations of SQLRepository
+type SQLIdempotencyRepository struct {
+	db *sql.DB
+}
+
+// NewSQLIdempotencyRepository applies every pending migration to db before
+// returning
+func NewSQLIdempotencyRepository(ctx context.Context, db *sql.DB) (*SQLIdempotencyRepository, error) {
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return &SQLIdempotencyRepository{db: db}, nil
+}
+
+func (r *SQLIdempotencyRepository) ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord, expiredBefore time.Time) (IdempotencyRecord, bool, error) {
+	reserved := false
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < ?`, formatTime(expiredBefore)); err != nil {
+			return fmt.Errorf("failed to drop expired idempotency keys: %v", err)
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO idempotency_keys (key, fingerprint, created_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT (key) DO NOTHING`,
+			record.Key, record.Fingerprint, formatTime(record.CreatedAt))
+		if err != nil {
+			return fmt.Errorf("failed to insert idempotency key: %v", err)
+		}
+		if inserted, err := result.RowsAffected(); err == nil && inserted == 1 {
+			reserved = true
+			return nil
+		}
+
+		record, err = r.record(ctx, tx, record.Key)
+		return err
+	})
+	if err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+
+	return record, reserved, nil
+}
+
+func (r *SQLIdempotencyRepository) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, response []byte) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = ?, response = ?
+		WHERE key = ?`, statusCode, string(response), key)
+	if err != nil {
+		return fmt.Errorf("failed to update idempotency key: %v", err)
+	}
+
+	return nil
+}
+
+func (r *SQLIdempotencyRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %v", err)
+	}
+
+	return nil
+}
+
+// record loads the record stored under key
+func (r *SQLIdempotencyRepository) record(ctx context.Context, q querier, key string) (IdempotencyRecord, error) {
+	var statusCode sql.NullInt64
+	var response sql.NullString
+	var createdAt string
+	record := IdempotencyRecord{Key: key}
+	err := q.QueryRowContext(ctx, `
+		SELECT fingerprint, status_code, response, created_at
+		FROM idempotency_keys
+		WHERE key = ?`, key).Scan(&record.Fingerprint, &statusCode, &response, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return IdempotencyRecord{}, fmt.Errorf("idempotency key %q disappeared", key)
+	}
+	if err != nil {
+		return IdempotencyRecord{}, fmt.Errorf("failed to read idempotency key: %v", err)
+	}
+
+	record.StatusCode = int(statusCode.Int64)
+	if response.Valid {
+		record.Response = []byte(response.String)
+	}
+	if record.CreatedAt, err = parseTime(createdAt); err != nil {
+		return IdempotencyRecord{}, err
+	}
+
+	return record, nil
+}
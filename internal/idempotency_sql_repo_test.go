@@ -0,0 +1,7 @@
+package internal
+
+import "testing"
+
+func TestSQLIdempotencyRepository_Suite(t *testing.T) {
+	testIdempotencyRepository(t, sqlStoreOf(NewSQLIdempotencyRepository))
+}
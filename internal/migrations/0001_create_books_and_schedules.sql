@@ -0,0 +1,34 @@
+CREATE TABLE genres
+(
+    id   INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE
+);
+
+-- A book is identified by its genre, title, edition and the ordered list of
+-- its authors, authors_key holds that list joined for the unique constraint.
+CREATE TABLE books
+(
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    genre_id       INTEGER NOT NULL REFERENCES genres (id),
+    title          TEXT    NOT NULL,
+    edition_number INTEGER NOT NULL,
+    authors_key    TEXT    NOT NULL,
+    UNIQUE (genre_id, title, edition_number, authors_key)
+);
+
+CREATE TABLE book_authors
+(
+    book_id  INTEGER NOT NULL REFERENCES books (id),
+    position INTEGER NOT NULL,
+    name     TEXT    NOT NULL,
+    PRIMARY KEY (book_id, position)
+);
+
+CREATE TABLE pick_up_schedules
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id      INTEGER NOT NULL REFERENCES books (id),
+    pick_up_date TEXT    NOT NULL
+);
+
+CREATE INDEX pick_up_schedules_book_id ON pick_up_schedules (book_id);
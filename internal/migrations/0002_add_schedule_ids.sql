@@ -0,0 +1,12 @@
+ALTER TABLE pick_up_schedules ADD COLUMN public_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE pick_up_schedules ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+ALTER TABLE pick_up_schedules ADD COLUMN updated_at TEXT NOT NULL DEFAULT '';
+
+-- Schedules saved before this migration get a random id and the time of the
+-- migration as their timestamps
+UPDATE pick_up_schedules
+SET public_id  = lower(hex(randomblob(16))),
+    created_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now'),
+    updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now');
+
+CREATE UNIQUE INDEX pick_up_schedules_public_id ON pick_up_schedules (public_id);
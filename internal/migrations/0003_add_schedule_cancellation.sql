@@ -0,0 +1,6 @@
+ALTER TABLE pick_up_schedules ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE pick_up_schedules ADD COLUMN cancelled_by TEXT;
+ALTER TABLE pick_up_schedules ADD COLUMN cancel_reason TEXT;
+ALTER TABLE pick_up_schedules ADD COLUMN cancelled_at TEXT;
+
+CREATE INDEX pick_up_schedules_status ON pick_up_schedules (status);
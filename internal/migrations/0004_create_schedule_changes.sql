@@ -0,0 +1,14 @@
+CREATE TABLE schedule_changes
+(
+    id                INTEGER PRIMARY KEY AUTOINCREMENT,
+    schedule_id       INTEGER NOT NULL REFERENCES pick_up_schedules (id),
+    changed_by        TEXT    NOT NULL,
+    changed_at        TEXT    NOT NULL,
+    reason            TEXT    NOT NULL DEFAULT '',
+    from_pick_up_date TEXT    NOT NULL,
+    to_pick_up_date   TEXT    NOT NULL,
+    from_book_id      INTEGER REFERENCES books (id),
+    to_book_id        INTEGER REFERENCES books (id)
+);
+
+CREATE INDEX schedule_changes_schedule_id ON schedule_changes (schedule_id);
@@ -0,0 +1,8 @@
+CREATE TABLE schedule_reservations
+(
+    schedule_id     INTEGER NOT NULL REFERENCES pick_up_schedules (id),
+    reservation_key TEXT    NOT NULL,
+    PRIMARY KEY (schedule_id, reservation_key)
+);
+
+CREATE INDEX schedule_reservations_reservation_key ON schedule_reservations (reservation_key);
@@ -0,0 +1,25 @@
+CREATE TABLE branches
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    public_id  TEXT NOT NULL UNIQUE,
+    name       TEXT NOT NULL,
+    address    TEXT NOT NULL,
+    timezone   TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE branch_opening_hours
+(
+    branch_id INTEGER NOT NULL REFERENCES branches (id),
+    position  INTEGER NOT NULL,
+    weekday   TEXT    NOT NULL,
+    opens     TEXT    NOT NULL,
+    closes    TEXT    NOT NULL,
+    PRIMARY KEY (branch_id, position)
+);
+
+-- Schedules saved before branches existed do not reference one
+ALTER TABLE pick_up_schedules ADD COLUMN branch_id INTEGER REFERENCES branches (id);
+
+CREATE INDEX pick_up_schedules_branch_id ON pick_up_schedules (branch_id);
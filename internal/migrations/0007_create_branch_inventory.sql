@@ -0,0 +1,8 @@
+CREATE TABLE branch_inventory
+(
+    branch_id  INTEGER NOT NULL REFERENCES branches (id),
+    book_id    INTEGER NOT NULL REFERENCES books (id),
+    copies     INTEGER NOT NULL,
+    updated_at TEXT    NOT NULL,
+    PRIMARY KEY (branch_id, book_id)
+);
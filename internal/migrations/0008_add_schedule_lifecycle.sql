@@ -0,0 +1,6 @@
+-- Schedules stored before the lifecycle existed were only ever requested
+UPDATE pick_up_schedules SET status = 'requested' WHERE status = 'active';
+
+-- Only set for changes that moved the status
+ALTER TABLE schedule_changes ADD COLUMN from_status TEXT;
+ALTER TABLE schedule_changes ADD COLUMN to_status TEXT;
@@ -0,0 +1,13 @@
+-- Only set once the book is picked up
+ALTER TABLE pick_up_schedules ADD COLUMN due_at TEXT;
+
+CREATE INDEX pick_up_schedules_status_due_at ON pick_up_schedules (status, due_at);
+
+CREATE TABLE branch_loan_periods
+(
+    branch_id INTEGER NOT NULL REFERENCES branches (id),
+    position  INTEGER NOT NULL,
+    genre     TEXT    NOT NULL,
+    days      INTEGER NOT NULL,
+    PRIMARY KEY (branch_id, position)
+);
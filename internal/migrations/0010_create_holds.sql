@@ -0,0 +1,16 @@
+-- Holds queue in insertion order, so id is their place in the queue
+CREATE TABLE holds
+(
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    public_id   TEXT    NOT NULL UNIQUE,
+    branch_id   INTEGER NOT NULL REFERENCES branches (id),
+    book_id     INTEGER NOT NULL REFERENCES books (id),
+    status      TEXT    NOT NULL,
+    -- Only set once the hold is offered a pick-up schedule
+    schedule_id INTEGER REFERENCES pick_up_schedules (id),
+    expires_at  TEXT,
+    created_at  TEXT    NOT NULL,
+    updated_at  TEXT    NOT NULL
+);
+
+CREATE INDEX holds_queue ON holds (branch_id, book_id, status);
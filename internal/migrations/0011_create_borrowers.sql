@@ -0,0 +1,15 @@
+CREATE TABLE borrowers
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    public_id  TEXT NOT NULL UNIQUE,
+    name       TEXT NOT NULL,
+    email      TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+-- Schedules and holds saved before borrowers existed do not reference one
+ALTER TABLE pick_up_schedules ADD COLUMN borrower_id INTEGER REFERENCES borrowers (id);
+ALTER TABLE holds ADD COLUMN borrower_id INTEGER REFERENCES borrowers (id);
+
+CREATE INDEX pick_up_schedules_borrower_id ON pick_up_schedules (borrower_id);
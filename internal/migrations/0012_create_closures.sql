@@ -0,0 +1,13 @@
+CREATE TABLE closures
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    public_id  TEXT    NOT NULL UNIQUE,
+    branch_id  INTEGER NOT NULL REFERENCES branches (id),
+    start_date TEXT    NOT NULL,
+    end_date   TEXT    NOT NULL,
+    recurrence TEXT    NOT NULL,
+    reason     TEXT    NOT NULL,
+    created_at TEXT    NOT NULL
+);
+
+CREATE INDEX closures_branch_id ON closures (branch_id, start_date);
@@ -0,0 +1,5 @@
+-- Schedules saved before time zones were attached get the one of their branch
+ALTER TABLE pick_up_schedules ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+
+UPDATE pick_up_schedules
+SET timezone = COALESCE((SELECT timezone FROM branches WHERE branches.id = pick_up_schedules.branch_id), '');
@@ -0,0 +1,11 @@
+CREATE TABLE idempotency_keys
+(
+    key         TEXT PRIMARY KEY,
+    fingerprint TEXT    NOT NULL,
+    -- Only set once the request was answered
+    status_code INTEGER,
+    response    TEXT,
+    created_at  TEXT    NOT NULL
+);
+
+CREATE INDEX idempotency_keys_created_at ON idempotency_keys (created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE notifications
+(
+    -- The public id of the pick-up schedule the borrower was told about
+    schedule_id TEXT NOT NULL,
+    kind        TEXT NOT NULL,
+    sent_at     TEXT NOT NULL,
+    PRIMARY KEY (schedule_id, kind)
+);
@@ -0,0 +1,32 @@
+CREATE TABLE webhooks
+(
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    public_id   TEXT    NOT NULL UNIQUE,
+    url         TEXT    NOT NULL,
+    -- Comma separated, empty for every event type
+    event_types TEXT    NOT NULL,
+    secret      TEXT    NOT NULL,
+    created_at  TEXT    NOT NULL
+);
+
+-- Deliveries outlive their webhook, so webhook_id is the public ID without a
+-- reference
+CREATE TABLE webhook_deliveries
+(
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    public_id        TEXT    NOT NULL UNIQUE,
+    webhook_id       TEXT    NOT NULL,
+    -- The JSON payload sent to the webhook
+    event            TEXT    NOT NULL,
+    status           TEXT    NOT NULL,
+    attempts         INTEGER NOT NULL,
+    next_attempt_at  TEXT,
+    last_attempt_at  TEXT,
+    last_status_code INTEGER NOT NULL,
+    last_error       TEXT    NOT NULL,
+    created_at       TEXT    NOT NULL,
+    updated_at       TEXT    NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
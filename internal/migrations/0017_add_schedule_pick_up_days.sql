@@ -0,0 +1,6 @@
+-- The day of the pick-up in the local time of the branch, which the day
+-- filters of schedule queries compare. It is filled in by the repository,
+-- SQLite cannot convert between time zones.
+ALTER TABLE pick_up_schedules ADD COLUMN pick_up_day TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX pick_up_schedules_pick_up_day ON pick_up_schedules (pick_up_day);
@@ -0,0 +1,3 @@
+-- Sent notifications are dropped once they are older than the retention of
+-- NotificationConfig
+CREATE INDEX notifications_sent_at ON notifications (sent_at);
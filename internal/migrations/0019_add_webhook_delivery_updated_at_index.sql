@@ -0,0 +1,3 @@
+-- Delivered deliveries are dropped once their last update is older than the
+-- retention of WebhookConfig
+CREATE INDEX webhook_deliveries_status_updated_at ON webhook_deliveries (status, updated_at);
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	fileNotificationsName    = "notifications.json"
+	fileNotificationsLogName = "notifications.log"
+)
+
+// FileNotificationRepository is a NotificationRepository that keeps every
+// record in memory and makes it durable with a fileLog, appending every
+// claimed or released notification to a log next to the snapshot of all of
+// them
+type FileNotificationRepository struct {
+	*InMemoryNotificationRepository
+
+	log *fileLog
+}
+
+// notificationsRecord is the content of the notifications snapshot
+type notificationsRecord struct {
+	Notifications []NotificationRecord `json:"notifications"`
+}
+
+func NewFileNotificationRepository(dir string) (*FileNotificationRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	r := &FileNotificationRepository{
+		InMemoryNotificationRepository: NewInMemoryNotificationRepository(),
+		log:                            newFileLog(dir, fileNotificationsName, fileNotificationsLogName, "notifications"),
+	}
+
+	var snapshot notificationsRecord
+	if err := r.log.readSnapshot(&snapshot); err != nil {
+		return nil, err
+	}
+	records := make(map[string]NotificationRecord, len(snapshot.Notifications))
+	for _, record := range snapshot.Notifications {
+		records[record.key()] = record
+	}
+	err := r.log.open(func(line []byte) error {
+		var change notificationChange
+		if err := decodeRecord(line, &change); err != nil {
+			return err
+		}
+		if change.Released {
+			delete(records, change.Record.key())
+		} else {
+			records[change.Record.key()] = change.Record
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.restore(sortedNotificationRecords(records))
+	r.persist = r.appendChange
+
+	return r, nil
+}
+
+// Close writes a final snapshot and closes the log
+func (r *FileNotificationRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.log.close(r.snapshot())
+}
+
+// appendChange is the persist hook of the embedded
+// InMemoryNotificationRepository
+func (r *FileNotificationRepository) appendChange(change notificationChange) error {
+	return r.log.append(change, r.snapshot)
+}
+
+// snapshot returns every stored record. mu must be held.
+func (r *FileNotificationRepository) snapshot() interface{} {
+	return notificationsRecord{Notifications: sortedNotificationRecords(r.records)}
+}
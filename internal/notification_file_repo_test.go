@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFileNotificationRepository_Suite(t *testing.T) {
+	testNotificationRepository(t, fileStoreOf(NewFileNotificationRepository))
+}
+
+func TestFileNotificationRepository_Restart(t *testing.T) {
+	ctx := context.Background()
+	record := NotificationRecord{ScheduleID: "abc", Kind: NotificationReminder, SentAt: time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)}
+
+	testFileRestart(t, NewFileNotificationRepository, fileNotificationsName, func(t *testing.T, repo *FileNotificationRepository) func(t *testing.T, reopened *FileNotificationRepository) {
+		if _, err := repo.ClaimNotification(ctx, record, record.SentAt); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return func(t *testing.T, reopened *FileNotificationRepository) {
+			if claimed, err := reopened.ClaimNotification(ctx, record, record.SentAt); err != nil || claimed {
+				t.Errorf("Expected the notification to stay sent after restart, got %v (%v)", claimed, err)
+			}
+		}
+	})
+}
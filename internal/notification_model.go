@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// NotificationKind is why a borrower is told about a pick-up schedule
+type NotificationKind string
+
+const (
+	// NotificationConfirmation is sent once a pick-up schedule is saved
+	NotificationConfirmation NotificationKind = "confirmation"
+	// NotificationReminder is sent NotificationConfig.RemindBefore ahead of
+	// the pick-up
+	NotificationReminder NotificationKind = "reminder"
+)
+
+// Notification is one message about a pick-up schedule to its borrower
+type Notification struct {
+	Kind     NotificationKind
+	Schedule PickUpSchedule
+	Borrower Borrower
+	// Branch is empty for a schedule without one
+	Branch Branch
+}
+
+// NotificationChannel delivers notifications to borrowers, like SMTPChannel
+// does by email
+type NotificationChannel interface {
+	Send(ctx context.Context, notification Notification) error
+}
+
+// NotificationRecord remembers that a notification of a schedule was sent,
+// so it is sent only once
+type NotificationRecord struct {
+	ScheduleID string           `json:"schedule_id"`
+	Kind       NotificationKind `json:"kind"`
+	SentAt     time.Time        `json:"sent_at"`
+}
+
+// key identifies the notification the record is about
+func (r NotificationRecord) key() string {
+	return r.ScheduleID + "/" + string(r.Kind)
+}
+
+// NotificationConfig sets when borrowers are reminded of their pick-ups
+type NotificationConfig struct {
+	// RemindBefore is how long before the pick-up the reminder is sent
+	RemindBefore time.Duration
+	// Retention is how long a sent notification is remembered. Once it is
+	// forgotten it could be sent again, so it has to outlast RemindBefore.
+	Retention time.Duration
+}
+
+// DefaultNotificationConfig is used unless WithNotifications says otherwise
+var DefaultNotificationConfig = NotificationConfig{RemindBefore: 24 * time.Hour, Retention: 30 * 24 * time.Hour}
+
+// DefaultReminderCheckInterval is how often RunReminderCheck looks for
+// pick-ups to remind of unless told otherwise
+const DefaultReminderCheckInterval = 5 * time.Minute
+
+// Validate reports a reminder that is not at least a minute ahead, or sent
+// notifications forgotten before the pick-up they remind of
+func (c NotificationConfig) Validate() error {
+	switch {
+	case c.RemindBefore < time.Minute:
+		return fmt.Errorf("reminder %v before the pick-up must be at least a minute", c.RemindBefore)
+	case c.Retention <= c.RemindBefore:
+		return fmt.Errorf("retention %v of sent notifications must be longer than the reminder %v before the pick-up", c.Retention, c.RemindBefore)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NotificationRepository remembers which notifications were sent
+type NotificationRepository interface {
+	// ClaimNotification stores record and returns true, or returns false
+	// when the notification it is about was already claimed. Records sent
+	// before expiredBefore are dropped first.
+	ClaimNotification(ctx context.Context, record NotificationRecord, expiredBefore time.Time) (bool, error)
+	// ReleaseNotification drops the claim of a notification that could not
+	// be sent, so it is tried again
+	ReleaseNotification(ctx context.Context, scheduleID string, kind NotificationKind) error
+}
+
+type InMemoryNotificationRepository struct {
+	mu      sync.Mutex
+	records map[string]NotificationRecord
+	// order holds the claimed notifications by when they were sent, oldest
+	// first, so expired records are dropped from the front without a look at
+	// the rest. Notifications released or claimed again since are skipped.
+	order []NotificationRecord
+
+	// persist, when set, is called with every change while mu is held. The
+	// change is only stored if it returns no error.
+	persist func(change notificationChange) error
+}
+
+// notificationChange is a record being claimed, or with Released set the
+// claim of its notification being dropped
+type notificationChange struct {
+	Record   NotificationRecord `json:"record"`
+	Released bool               `json:"released,omitempty"`
+}
+
+func NewInMemoryNotificationRepository() *InMemoryNotificationRepository {
+	return &InMemoryNotificationRepository{
+		records: make(map[string]NotificationRecord),
+	}
+}
+
+func (r *InMemoryNotificationRepository) ClaimNotification(_ context.Context, record NotificationRecord, expiredBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dropExpired(expiredBefore)
+	// Records claimed out of order may have expired behind the front
+	if stored, exists := r.records[record.key()]; exists && !stored.SentAt.Before(expiredBefore) {
+		return false, nil
+	}
+
+	if r.persist != nil {
+		if err := r.persist(notificationChange{Record: record}); err != nil {
+			return false, err
+		}
+	}
+	r.records[record.key()] = record
+	r.order = append(r.order, record)
+	return true, nil
+}
+
+func (r *InMemoryNotificationRepository) ReleaseNotification(_ context.Context, scheduleID string, kind NotificationKind) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	released := NotificationRecord{ScheduleID: scheduleID, Kind: kind}
+	if _, exists := r.records[released.key()]; !exists {
+		return nil
+	}
+	if r.persist != nil {
+		if err := r.persist(notificationChange{Record: released, Released: true}); err != nil {
+			return err
+		}
+	}
+
+	delete(r.records, released.key())
+	return nil
+}
+
+// dropExpired drops the records sent before expiredBefore from the front of
+// the order. They are not persisted as dropped, a restored record expires
+// again. mu must be held.
+func (r *InMemoryNotificationRepository) dropExpired(expiredBefore time.Time) {
+	dropped := 0
+	for _, entry := range r.order {
+		if !entry.SentAt.Before(expiredBefore) {
+			break
+		}
+		if stored, exists := r.records[entry.key()]; exists && stored.SentAt.Equal(entry.SentAt) {
+			delete(r.records, entry.key())
+		}
+		dropped++
+	}
+	r.order = r.order[dropped:]
+}
+
+// restore puts already persisted records back without calling persist
+func (r *InMemoryNotificationRepository) restore(records []NotificationRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, record := range records {
+		r.records[record.key()] = record
+	}
+	r.order = sortedNotificationRecords(r.records)
+}
+
+// sortedNotificationRecords returns the records ordered by when they were sent
+func sortedNotificationRecords(records map[string]NotificationRecord) []NotificationRecord {
+	sorted := make([]NotificationRecord, 0, len(records))
+	for _, record := range records {
+		sorted = append(sorted, record)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if !a.SentAt.Equal(b.SentAt) {
+			return a.SentAt.Before(b.SentAt)
+		}
+		return a.key() < b.key()
+	})
+
+	return sorted
+}
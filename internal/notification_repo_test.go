@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInMemoryNotificationRepository_Suite(t *testing.T) {
+	testNotificationRepository(t, func(t *testing.T) NotificationRepository {
+		return NewInMemoryNotificationRepository()
+	})
+}
+
+// testNotificationRepository checks the behaviour every NotificationRepository
+// shares. newRepo must return an empty repository.
+func testNotificationRepository[R NotificationRepository](t *testing.T, newRepo func(t *testing.T) R) {
+	ctx := context.Background()
+	sentAt := time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)
+	record := NotificationRecord{ScheduleID: "abc", Kind: NotificationReminder, SentAt: sentAt}
+	expiredBefore := sentAt.Add(-30 * 24 * time.Hour)
+
+	t.Run("ClaimNotification_Once", func(t *testing.T) {
+		repo := newRepo(t)
+
+		claimed, err := repo.ClaimNotification(ctx, record, expiredBefore)
+		if err != nil || !claimed {
+			t.Fatalf("Expected the notification to be claimed, got %v (%v)", claimed, err)
+		}
+		claimed, err = repo.ClaimNotification(ctx, record, expiredBefore)
+		if err != nil || claimed {
+			t.Errorf("Expected the notification to be claimed only once, got %v (%v)", claimed, err)
+		}
+	})
+
+	t.Run("ClaimNotification_OtherKind", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.ClaimNotification(ctx, record, expiredBefore); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		confirmation := record
+		confirmation.Kind = NotificationConfirmation
+		if claimed, err := repo.ClaimNotification(ctx, confirmation, expiredBefore); err != nil || !claimed {
+			t.Errorf("Expected another kind of the same schedule to be claimed, got %v (%v)", claimed, err)
+		}
+	})
+
+	t.Run("ReleaseNotification_CanBeClaimedAgain", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.ClaimNotification(ctx, record, expiredBefore); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.ReleaseNotification(ctx, record.ScheduleID, record.Kind); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if claimed, err := repo.ClaimNotification(ctx, record, expiredBefore); err != nil || !claimed {
+			t.Errorf("Expected the released notification to be claimed again, got %v (%v)", claimed, err)
+		}
+	})
+
+	t.Run("ReleaseNotification_Unknown", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if err := repo.ReleaseNotification(ctx, "missing", NotificationReminder); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+	t.Run("ClaimNotification_Expired", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.ClaimNotification(ctx, record, expiredBefore); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		later := record
+		later.SentAt = sentAt.Add(31 * 24 * time.Hour)
+		if claimed, err := repo.ClaimNotification(ctx, later, sentAt.Add(time.Hour)); err != nil || !claimed {
+			t.Errorf("Expected the expired notification to be claimed again, got %v (%v)", claimed, err)
+		}
+	})
+
+	t.Run("ClaimNotification_ClaimedAgainOutlivesRelease", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.ClaimNotification(ctx, record, expiredBefore); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := repo.ReleaseNotification(ctx, record.ScheduleID, record.Kind); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		again := record
+		again.SentAt = sentAt.Add(2 * time.Hour)
+		if claimed, err := repo.ClaimNotification(ctx, again, expiredBefore); err != nil || !claimed {
+			t.Fatalf("Expected the released notification to be claimed again, got %v (%v)", claimed, err)
+		}
+
+		// Only the first claim expired
+		if claimed, err := repo.ClaimNotification(ctx, again, sentAt.Add(time.Hour)); err != nil || claimed {
+			t.Errorf("Expected the notification claimed again to be kept, got %v (%v)", claimed, err)
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// smtpTimeout bounds the delivery of one email when the context has no
+// deadline of its own
+const smtpTimeout = 30 * time.Second
+
+// SMTPConfig says how SMTPChannel reaches the mail server
+type SMTPConfig struct {
+	// Addr is the host:port of the mail server
+	Addr string
+	// From is the address emails are sent from, like
+	// "Cosmart Library <library@example.com>"
+	From string
+	// Username and Password log in with PLAIN auth when Username is set,
+	// which is only done over TLS or to localhost
+	Username string
+	Password string
+}
+
+// Validate reports a missing server or sender
+func (c SMTPConfig) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("SMTP server %q must be a host:port: %v", c.Addr, err)
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("SMTP sender %q must be an email address: %v", c.From, err)
+	}
+	return nil
+}
+
+// emailTemplates are the HTML bodies of the emails, one template per
+// NotificationKind
+var emailTemplates = template.Must(template.New("email").Parse(`
+{{- define "details" -}}
+<table>
+<tr><th align="left">Book</th><td>{{.Title}}</td></tr>
+<tr><th align="left">When</th><td>{{.When}}</td></tr>
+{{- if .Branch}}
+<tr><th align="left">Where</th><td>{{.Branch}}{{if .Address}}<br>{{.Address}}{{end}}</td></tr>
+{{- end}}
+<tr><th align="left">Reference</th><td>{{.ScheduleID}}</td></tr>
+</table>
+{{- end -}}
+
+{{- define "confirmation" -}}
+<!DOCTYPE html>
+<html>
+<body>
+<p>Hello {{.Name}},</p>
+<p>your pick-up is booked. Please bring your library card.</p>
+{{template "details" .}}
+<p>If you cannot make it, please cancel the pick-up so someone else can borrow the book.</p>
+</body>
+</html>
+{{- end -}}
+
+{{- define "reminder" -}}
+<!DOCTYPE html>
+<html>
+<body>
+<p>Hello {{.Name}},</p>
+<p>this is a reminder of your upcoming pick-up.</p>
+{{template "details" .}}
+<p>If you cannot make it, please cancel the pick-up so someone else can borrow the book.</p>
+</body>
+</html>
+{{- end -}}
+`))
+
+// emailData is what the email templates are rendered with
+type emailData struct {
+	Name       string
+	Title      string
+	When       string
+	Branch     string
+	Address    string
+	ScheduleID string
+}
+
+// SMTPChannel is a NotificationChannel sending HTML emails through a mail
+// server. Borrowers without an email address are not sent anything.
+type SMTPChannel struct {
+	config SMTPConfig
+	from   *mail.Address
+	now    func() time.Time
+}
+
+// NewSMTPChannel returns an SMTPChannel for a config that passes
+// SMTPConfig.Validate
+func NewSMTPChannel(config SMTPConfig) (*SMTPChannel, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	from, _ := mail.ParseAddress(config.From)
+
+	return &SMTPChannel{config: config, from: from, now: time.Now}, nil
+}
+
+func (c *SMTPChannel) Send(ctx context.Context, notification Notification) error {
+	if notification.Borrower.Email == "" {
+		return nil
+	}
+	to, err := mail.ParseAddress(notification.Borrower.Email)
+	if err != nil {
+		return fmt.Errorf("invalid email address of borrower %s: %v", notification.Borrower.ID, err)
+	}
+	to.Name = notification.Borrower.Name
+
+	message, err := c.message(notification, to)
+	if err != nil {
+		return err
+	}
+
+	return c.deliver(ctx, to.Address, message)
+}
+
+// message renders the notification as an email to to
+func (c *SMTPChannel) message(notification Notification, to *mail.Address) ([]byte, error) {
+	schedule := notification.Schedule
+	data := emailData{
+		Name:       notification.Borrower.Name,
+		Title:      schedule.BookInfo.Title,
+		When:       pickUpTimeText(schedule, notification.Branch),
+		Branch:     notification.Branch.Name,
+		Address:    notification.Branch.Address,
+		ScheduleID: schedule.ID,
+	}
+
+	var subject string
+	switch notification.Kind {
+	case NotificationConfirmation:
+		subject = fmt.Sprintf("Your pick-up of %s is booked", data.Title)
+	case NotificationReminder:
+		subject = fmt.Sprintf("Reminder: pick up %s on %s", data.Title, data.When)
+	default:
+		return nil, fmt.Errorf("unknown notification %q", notification.Kind)
+	}
+
+	var body bytes.Buffer
+	if err := emailTemplates.ExecuteTemplate(&body, string(notification.Kind), data); err != nil {
+		return nil, fmt.Errorf("failed to render %s email: %v", notification.Kind, err)
+	}
+
+	var message bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", c.from.String()},
+		{"To", to.String()},
+		// Q-encoding leaves no line break in the subject
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", c.now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/html; charset=UTF-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	} {
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+
+	writer := quotedprintable.NewWriter(&message)
+	if _, err := writer.Write(body.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return message.Bytes(), nil
+}
+
+// deliver hands the message for to over to the mail server, upgrading to TLS
+// when the server offers it
+func (c *SMTPChannel) deliver(ctx context.Context, to string, message []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to reach SMTP server: %v", err)
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to reach SMTP server: %v", err)
+	}
+
+	host, _, _ := net.SplitHostPort(c.config.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("failed to greet SMTP server: %v", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("failed to start TLS with SMTP server: %v", err)
+		}
+	}
+	if c.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.config.Username, c.config.Password, host)); err != nil {
+			return fmt.Errorf("failed to log in to SMTP server: %v", err)
+		}
+	}
+
+	if err := client.Mail(c.from.Address); err != nil {
+		return fmt.Errorf("SMTP server refused sender: %v", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("SMTP server refused recipient %s: %v", to, err)
+	}
+	data, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP server refused email: %v", err)
+	}
+	if _, err := data.Write(message); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("SMTP server refused email: %v", err)
+	}
+
+	// The email is accepted, failing to say goodbye must not send it again
+	_ = client.Quit()
+	return nil
+}
+
+// pickUpTimeText writes when a pick-up is in the time zone of its branch, like
+// "Monday, 4 December 2023 at 10:00 WIB"
+func pickUpTimeText(schedule PickUpSchedule, branch Branch) string {
+	at := schedule.PickUpDate.Time()
+	if !schedule.PickUpDate.HasTime() {
+		return at.UTC().Format("Monday, 2 January 2006")
+	}
+
+	timezone := schedule.Timezone
+	if timezone == "" {
+		timezone = branch.Timezone
+	}
+	if loc, err := loadLocation(timezone); err == nil {
+		at = at.In(loc)
+	} else {
+		at = at.UTC()
+	}
+	return at.Format("Monday, 2 January 2006 at 15:04 MST")
+}
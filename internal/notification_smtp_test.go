@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpStandIn is a mail server just good enough for SMTPChannel, keeping
+// every email it accepts
+type smtpStandIn struct {
+	addr string
+	// refuse is a recipient the server has no mailbox for
+	refuse string
+
+	mu     sync.Mutex
+	emails []smtpEmail
+}
+
+type smtpEmail struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// newSMTPStandIn starts a stand-in on a free local port until the test ends
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	s := &smtpStandIn{addr: listener.Addr().String()}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	text := textproto.NewConn(conn)
+	defer text.Close()
+
+	_ = text.PrintfLine("220 stand-in ESMTP")
+	var email smtpEmail
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, argument, _ := strings.Cut(line, " ")
+		address := argument[strings.Index(argument, "<")+1:]
+		address, _, _ = strings.Cut(address, ">")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			_ = text.PrintfLine("250 stand-in")
+		case "MAIL":
+			email = smtpEmail{From: address}
+			_ = text.PrintfLine("250 OK")
+		case "RCPT":
+			if address == s.refuse {
+				_ = text.PrintfLine("550 no such mailbox")
+				continue
+			}
+			email.To = append(email.To, address)
+			_ = text.PrintfLine("250 OK")
+		case "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			email.Data = data
+			s.mu.Lock()
+			s.emails = append(s.emails, email)
+			s.mu.Unlock()
+			_ = text.PrintfLine("250 OK")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		default:
+			_ = text.PrintfLine("250 OK")
+		}
+	}
+}
+
+// received returns the emails accepted so far
+func (s *smtpStandIn) received() []smtpEmail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpEmail(nil), s.emails...)
+}
+
+func TestSMTPChannel_Send(t *testing.T) {
+	ctx := context.Background()
+	server := newSMTPStandIn(t)
+	channel, err := NewSMTPChannel(SMTPConfig{Addr: server.addr, From: "Cosmart Library <library@example.com>"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	channel.now = serviceClock
+
+	branch := Branch{ID: "central", Name: "Central", Address: "Jl. Sudirman 1", Timezone: "Asia/Jakarta"}
+	borrower := Borrower{ID: "reader", Name: "Réader", Email: "reader@example.com"}
+	notification := Notification{
+		Kind:     NotificationConfirmation,
+		Schedule: PickUpSchedule{ID: "abc", BookInfo: Book{Title: "Dune <Part 1> & more"}, BranchID: "central", Timezone: "Asia/Jakarta", PickUpDate: mustParsePickUpDate("2023-12-04T03:00:00Z")},
+		Borrower: borrower,
+		Branch:   branch,
+	}
+	read := func(t *testing.T, email smtpEmail) (*mail.Message, string, string) {
+		t.Helper()
+		message, err := mail.ReadMessage(strings.NewReader(string(email.Data)))
+		if err != nil {
+			t.Fatalf("Failed to read email: %v", err)
+		}
+		subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+		if err != nil {
+			t.Fatalf("Failed to decode subject: %v", err)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(message.Body))
+		if err != nil {
+			t.Fatalf("Failed to decode body: %v", err)
+		}
+		return message, subject, string(body)
+	}
+
+	t.Run("PositiveCase_Confirmation", func(t *testing.T) {
+		if err := channel.Send(ctx, notification); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		emails := server.received()
+		if len(emails) != 1 || emails[0].From != "library@example.com" || len(emails[0].To) != 1 || emails[0].To[0] != "reader@example.com" {
+			t.Fatalf("Expected one email to the borrower, got %+v", emails)
+		}
+		message, subject, body := read(t, emails[0])
+		if subject != "Your pick-up of Dune <Part 1> & more is booked" {
+			t.Errorf("Unexpected subject %q", subject)
+		}
+		if to, err := message.Header.AddressList("To"); err != nil || to[0].Name != "Réader" {
+			t.Errorf("Expected the borrower by name, got %v (%v)", to, err)
+		}
+		if got := message.Header.Get("Content-Type"); got != "text/html; charset=UTF-8" {
+			t.Errorf("Expected an HTML email, got %q", got)
+		}
+		for _, want := range []string{
+			"Hello Réader,",
+			"your pick-up is booked",
+			// Values are escaped in the HTML
+			"Dune &lt;Part 1&gt; &amp; more",
+			"Monday, 4 December 2023 at 10:00 WIB",
+			"Central<br>Jl. Sudirman 1",
+			"abc",
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Expected %q in %q", want, body)
+			}
+		}
+	})
+
+	t.Run("PositiveCase_Reminder", func(t *testing.T) {
+		reminder := notification
+		reminder.Kind = NotificationReminder
+
+		if err := channel.Send(ctx, reminder); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		emails := server.received()
+		_, subject, body := read(t, emails[len(emails)-1])
+		if subject != "Reminder: pick up Dune <Part 1> & more on Monday, 4 December 2023 at 10:00 WIB" {
+			t.Errorf("Unexpected subject %q", subject)
+		}
+		if !strings.Contains(body, "reminder of your upcoming pick-up") {
+			t.Errorf("Expected the reminder text in %q", body)
+		}
+	})
+
+	t.Run("PositiveCase_NoEmailAddress", func(t *testing.T) {
+		before := len(server.received())
+		anonymous := notification
+		anonymous.Borrower.Email = ""
+
+		if err := channel.Send(ctx, anonymous); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(server.received()) != before {
+			t.Error("Expected no email to a borrower without an address")
+		}
+	})
+
+	t.Run("NegativeCase_RecipientRefused", func(t *testing.T) {
+		server.refuse = "gone@example.com"
+		refused := notification
+		refused.Borrower.Email = "gone@example.com"
+
+		if err := channel.Send(ctx, refused); err == nil {
+			t.Error("Expected error, but got nil")
+		}
+	})
+
+	t.Run("NegativeCase_ServerDown", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		addr := listener.Addr().String()
+		_ = listener.Close()
+		down, err := NewSMTPChannel(SMTPConfig{Addr: addr, From: "library@example.com"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if err := down.Send(ctx, notification); err == nil {
+			t.Error("Expected error, but got nil")
+		}
+	})
+}
+
+func TestSMTPConfig_Validate(t *testing.T) {
+	for _, config := range []SMTPConfig{
+		{Addr: "localhost", From: "library@example.com"},
+		{Addr: "localhost:25", From: "library"},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", config)
+		}
+	}
+	if err := (SMTPConfig{Addr: "localhost:25", From: "Library <library@example.com>"}).Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
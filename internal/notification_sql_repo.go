@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLNotificationRepository is a NotificationRepository on top of
+// database/sql, sharing the schema and migrations of SQLRepository
+type SQLNotificationRepository struct {
+	db *sql.DB
+}
+
+// NewSQLNotificationRepository applies every pending migration to db before
+// returning
+func NewSQLNotificationRepository(ctx context.Context, db *sql.DB) (*SQLNotificationRepository, error) {
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return &SQLNotificationRepository{db: db}, nil
+}
+
+func (r *SQLNotificationRepository) ClaimNotification(ctx context.Context, record NotificationRecord, expiredBefore time.Time) (bool, error) {
+	claimed := false
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM notifications WHERE sent_at < ?`, formatTime(expiredBefore)); err != nil {
+			return fmt.Errorf("failed to drop expired notifications: %v", err)
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO notifications (schedule_id, kind, sent_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT (schedule_id, kind) DO NOTHING`,
+			record.ScheduleID, record.Kind, formatTime(record.SentAt))
+		if err != nil {
+			return fmt.Errorf("failed to insert notification: %v", err)
+		}
+
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to insert notification: %v", err)
+		}
+		claimed = inserted == 1
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return claimed, nil
+}
+
+func (r *SQLNotificationRepository) ReleaseNotification(ctx context.Context, scheduleID string, kind NotificationKind) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM notifications WHERE schedule_id = ? AND kind = ?`, scheduleID, kind); err != nil {
+		return fmt.Errorf("failed to delete notification: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,7 @@
+package internal
+
+import "testing"
+
+func TestSQLNotificationRepository_Suite(t *testing.T) {
+	testNotificationRepository(t, sqlStoreOf(NewSQLNotificationRepository))
+}
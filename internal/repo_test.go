@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fileStoreOf returns a constructor of stores kept in a new directory, for the
+// repository suites. Stores that can be closed are closed after the test.
+func fileStoreOf[R any](open func(dir string) (R, error)) func(t *testing.T) R {
+	return func(t *testing.T) R {
+		return openFileStore(t, open, t.TempDir())
+	}
+}
+
+// sqlStoreOf returns a constructor of stores kept in a new database, for the
+// repository suites
+func sqlStoreOf[R any](open func(ctx context.Context, db *sql.DB) (R, error)) func(t *testing.T) R {
+	return func(t *testing.T) R {
+		t.Helper()
+
+		repo, err := open(context.Background(), openTestDB(t))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return repo
+	}
+}
+
+// testFileRestart fills a store in a new directory and checks that a store
+// opened on the directory again passes the check returned by fill, and that
+// none opens once the file called name is corrupt
+func testFileRestart[R any](t *testing.T, open func(dir string) (R, error), name string, fill func(t *testing.T, repo R) func(t *testing.T, reopened R)) {
+	dir := t.TempDir()
+	check := fill(t, openFileStore(t, open, dir))
+
+	t.Run("PositiveCase_Survives", func(t *testing.T) {
+		check(t, openFileStore(t, open, dir))
+	})
+
+	t.Run("NegativeCase_CorruptFile", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("garbage\n"), 0o644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, err := open(dir); err == nil {
+			t.Error("Expected error, but got nil")
+		}
+	})
+}
+
+// openFileStore opens the store kept in dir, closed after the test when it
+// can be
+func openFileStore[R any](t *testing.T, open func(dir string) (R, error), dir string) R {
+	t.Helper()
+
+	repo, err := open(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if closer, ok := any(repo).(io.Closer); ok {
+		t.Cleanup(func() {
+			_ = closer.Close()
+		})
+	}
+	return repo
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	fileWebhooksName    = "webhooks.json"
+	fileWebhooksLogName = "webhooks.log"
+)
+
+// FileWebhookRepository is a WebhookRepository that keeps every subscription
+// and delivery in memory and makes them durable with a fileLog, appending
+// every change of a subscription or a delivery to a log next to the snapshot
+// of all of them
+type FileWebhookRepository struct {
+	*InMemoryWebhookRepository
+
+	log *fileLog
+}
+
+// webhooksRecord is the content of the webhooks snapshot
+type webhooksRecord struct {
+	Webhooks   []WebhookSubscription `json:"webhooks"`
+	Deliveries []WebhookDelivery     `json:"deliveries"`
+}
+
+func NewFileWebhookRepository(dir string) (*FileWebhookRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	r := &FileWebhookRepository{
+		InMemoryWebhookRepository: NewInMemoryWebhookRepository(),
+		log:                       newFileLog(dir, fileWebhooksName, fileWebhooksLogName, "webhooks"),
+	}
+
+	var snapshot webhooksRecord
+	if err := r.log.readSnapshot(&snapshot); err != nil {
+		return nil, err
+	}
+	r.restore(snapshot.Webhooks, snapshot.Deliveries)
+	err := r.log.open(func(line []byte) error {
+		var change webhookChange
+		if err := decodeRecord(line, &change); err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.apply(change)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.persist = r.appendChange
+
+	return r, nil
+}
+
+// Close writes a final snapshot and closes the log
+func (r *FileWebhookRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.log.close(r.snapshot())
+}
+
+// appendChange is the persist hook of the embedded InMemoryWebhookRepository
+func (r *FileWebhookRepository) appendChange(change webhookChange) error {
+	return r.log.append(change, r.snapshot)
+}
+
+// snapshot returns every stored subscription and delivery. mu must be held.
+func (r *FileWebhookRepository) snapshot() interface{} {
+	return webhooksRecord{Webhooks: r.sortedWebhooks(), Deliveries: r.sortedDeliveries()}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFileWebhookRepository_Suite(t *testing.T) {
+	testWebhookRepository(t, fileStoreOf(NewFileWebhookRepository))
+}
+
+func TestFileWebhookRepository_Restart(t *testing.T) {
+	ctx := context.Background()
+
+	testFileRestart(t, NewFileWebhookRepository, fileWebhooksName, func(t *testing.T, repo *FileWebhookRepository) func(t *testing.T, reopened *FileWebhookRepository) {
+		subscription, err := repo.CreateWebhook(ctx, WebhookSubscription{URL: "https://desk.example.com/hooks", Secret: "desk-secret-0123456789"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		deliveries, err := repo.CreateWebhookDeliveries(ctx, []WebhookDelivery{{SubscriptionID: subscription.ID, Event: WebhookEvent{ID: "event", Type: WebhookScheduleCreated}, Status: WebhookDeliveryPending}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.UpdateWebhookDelivery(ctx, deliveries[0].ID, func(delivery *WebhookDelivery) error {
+			delivery.Status = WebhookDeliveryDead
+			return nil
+		}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return func(t *testing.T, reopened *FileWebhookRepository) {
+			fetched, err := reopened.GetWebhook(ctx, subscription.ID)
+			if err != nil || fetched.Secret != "desk-secret-0123456789" {
+				t.Errorf("Expected the webhook after restart, got %+v (%v)", fetched, err)
+			}
+			dead, err := reopened.ListWebhookDeliveries(ctx, WebhookDeliveryQuery{Status: WebhookDeliveryDead})
+			if err != nil || len(dead) != 1 || dead[0].Event.ID != "event" {
+				t.Errorf("Expected the dead delivery after restart, got %+v (%v)", dead, err)
+			}
+		}
+	})
+}
+
+func TestFileWebhookRepository_Prune(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	t.Run("PositiveCase_PrunedStayDropped", func(t *testing.T) {
+		repo := openFileStore(t, NewFileWebhookRepository, dir)
+		deliveries, err := repo.CreateWebhookDeliveries(ctx, []WebhookDelivery{
+			{SubscriptionID: "desk", Event: WebhookEvent{ID: "old"}, Status: WebhookDeliveryDead},
+			{SubscriptionID: "desk", Event: WebhookEvent{ID: "new"}, Status: WebhookDeliveryDead},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.PruneWebhookDeliveries(ctx, time.Now(), 1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		reopened := openFileStore(t, NewFileWebhookRepository, dir)
+		listed, err := reopened.ListWebhookDeliveries(ctx, WebhookDeliveryQuery{})
+		if err != nil || len(listed) != 1 || listed[0].ID != deliveries[1].ID {
+			t.Errorf("Expected only the kept dead delivery after restart, got %+v (%v)", listed, err)
+		}
+	})
+}
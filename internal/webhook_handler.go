@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type WebhookHandler interface {
+	ListWebhooksHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	CreateWebhookHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetWebhookHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	DeleteWebhookHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	ReplayWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+}
+
+type webhookHandler struct {
+	service WebhookService
+}
+
+func NewWebhookHandler(service WebhookService) WebhookHandler {
+	return &webhookHandler{
+		service: service,
+	}
+}
+
+// ListWebhooksHandler serves GET /webhooks
+func (h *webhookHandler) ListWebhooksHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	webhooks, err := h.service.ListWebhooksService(r.Context())
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), webhooks)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhooks)
+}
+
+// CreateWebhookHandler serves POST /webhooks with a WebhookSubscription body
+func (h *webhookHandler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var subscription WebhookSubscription
+	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			panic(err.Error())
+		}
+	}(r.Body)
+
+	created, err := h.service.CreateWebhookService(r.Context(), subscription)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), created)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// GetWebhookHandler serves GET /webhooks/:id
+func (h *webhookHandler) GetWebhookHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	webhook, err := h.service.GetWebhookService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), webhook)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhook)
+}
+
+// DeleteWebhookHandler serves DELETE /webhooks/:id
+func (h *webhookHandler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	deleted, err := h.service.DeleteWebhookService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), deleted)
+		return
+	}
+	writeJSON(w, http.StatusOK, deleted)
+}
+
+// ListWebhookDeliveriesHandler serves GET /webhooks/:id/deliveries and
+// GET /webhook-deliveries, where webhook_id filters by webhook, and both take
+// a status filter: ?status=dead lists the dead letters
+func (h *webhookHandler) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	values := r.URL.Query()
+	query := WebhookDeliveryQuery{
+		SubscriptionID: params.ByName("id"),
+		Status:         WebhookDeliveryStatus(values.Get("status")),
+	}
+	if query.SubscriptionID == "" {
+		query.SubscriptionID = values.Get("webhook_id")
+	}
+
+	deliveries, err := h.service.ListWebhookDeliveriesService(r.Context(), query)
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), deliveries)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// GetWebhookDeliveryHandler serves GET /webhook-deliveries/:id
+func (h *webhookHandler) GetWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	delivery, err := h.service.GetWebhookDeliveryService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), delivery)
+		return
+	}
+	writeJSON(w, http.StatusOK, delivery)
+}
+
+// ReplayWebhookDeliveryHandler serves POST /webhook-deliveries/:id/replay
+func (h *webhookHandler) ReplayWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	delivery, err := h.service.ReplayWebhookDeliveryService(r.Context(), params.ByName("id"))
+	if err != nil {
+		writeJSON(w, statusCodeOf(err), delivery)
+		return
+	}
+	writeJSON(w, http.StatusOK, delivery)
+}
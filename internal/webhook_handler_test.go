@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type mockWebhookService struct {
+	createWebhookResponse WebhookResponse
+	createWebhookError    error
+	createWebhookInput    WebhookSubscription
+	listWebhooksResponse  WebhookListResponse
+	getWebhookResponse    WebhookResponse
+	getWebhookError       error
+	deleteWebhookResponse WebhookResponse
+	deleteWebhookError    error
+	deleteWebhookID       string
+	listDeliveriesError   error
+	listDeliveriesQuery   WebhookDeliveryQuery
+	replayResponse        WebhookDeliveryResponse
+	replayError           error
+	replayID              string
+}
+
+func (m *mockWebhookService) PublishScheduleEvent(ctx context.Context, eventType WebhookEventType, schedule PickUpSchedule) error {
+	return nil
+}
+
+func (m *mockWebhookService) CreateWebhookService(ctx context.Context, subscription WebhookSubscription) (WebhookResponse, error) {
+	m.createWebhookInput = subscription
+	return m.createWebhookResponse, m.createWebhookError
+}
+
+func (m *mockWebhookService) ListWebhooksService(ctx context.Context) (WebhookListResponse, error) {
+	return m.listWebhooksResponse, nil
+}
+
+func (m *mockWebhookService) GetWebhookService(ctx context.Context, id string) (WebhookResponse, error) {
+	return m.getWebhookResponse, m.getWebhookError
+}
+
+func (m *mockWebhookService) DeleteWebhookService(ctx context.Context, id string) (WebhookResponse, error) {
+	m.deleteWebhookID = id
+	return m.deleteWebhookResponse, m.deleteWebhookError
+}
+
+func (m *mockWebhookService) ListWebhookDeliveriesService(ctx context.Context, query WebhookDeliveryQuery) (WebhookDeliveryListResponse, error) {
+	m.listDeliveriesQuery = query
+	return WebhookDeliveryListResponse{Status: "200 OK", IsSuccess: true, Data: []WebhookDelivery{}}, m.listDeliveriesError
+}
+
+func (m *mockWebhookService) GetWebhookDeliveryService(ctx context.Context, id string) (WebhookDeliveryResponse, error) {
+	return WebhookDeliveryResponse{}, nil
+}
+
+func (m *mockWebhookService) ReplayWebhookDeliveryService(ctx context.Context, id string) (WebhookDeliveryResponse, error) {
+	m.replayID = id
+	return m.replayResponse, m.replayError
+}
+
+func (m *mockWebhookService) DeliverWebhooksService(ctx context.Context) (WebhookDeliveryListResponse, error) {
+	return WebhookDeliveryListResponse{}, nil
+}
+
+func TestWebhookHandler_CreateWebhookHandler(t *testing.T) {
+	mockService := &mockWebhookService{
+		createWebhookResponse: WebhookResponse{Status: "201 CREATED", IsSuccess: true, Data: WebhookSubscription{ID: "new"}},
+	}
+	handler := NewWebhookHandler(mockService)
+
+	router := httprouter.New()
+	router.POST("/webhooks", handler.CreateWebhookHandler)
+
+	t.Run("PositiveCase", func(t *testing.T) {
+		body := `{"url": "https://desk.example.com/hooks", "event_types": ["schedule.cancelled"]}`
+		req := httptest.NewRequest("POST", "/webhooks", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("Expected status code 201, got %d", rec.Code)
+		}
+		input := mockService.createWebhookInput
+		if input.URL != "https://desk.example.com/hooks" || len(input.EventTypes) != 1 || input.EventTypes[0] != WebhookScheduleCancelled {
+			t.Errorf("Expected webhook from body, got %+v", input)
+		}
+	})
+
+	t.Run("NegativeCase_BadRequest", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/webhooks", bytes.NewReader([]byte("invalid request body")))
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestWebhookHandler_DeleteWebhookHandler(t *testing.T) {
+	mockService := &mockWebhookService{
+		deleteWebhookResponse: WebhookResponse{Status: "404 Not Found"},
+		deleteWebhookError:    ErrWebhookNotFound,
+	}
+	handler := NewWebhookHandler(mockService)
+
+	router := httprouter.New()
+	router.DELETE("/webhooks/:id", handler.DeleteWebhookHandler)
+
+	req := httptest.NewRequest("DELETE", "/webhooks/abc", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound || mockService.deleteWebhookID != "abc" {
+		t.Errorf("Expected status code 404 for abc, got %d for %q", rec.Code, mockService.deleteWebhookID)
+	}
+}
+
+func TestWebhookHandler_ListWebhookDeliveriesHandler(t *testing.T) {
+	mockService := &mockWebhookService{}
+	handler := NewWebhookHandler(mockService)
+
+	router := httprouter.New()
+	router.GET("/webhooks/:id/deliveries", handler.ListWebhookDeliveriesHandler)
+	router.GET("/webhook-deliveries", handler.ListWebhookDeliveriesHandler)
+
+	t.Run("PositiveCase_OfWebhook", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/webhooks/abc/deliveries?status=dead", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+		if query := mockService.listDeliveriesQuery; query.SubscriptionID != "abc" || query.Status != WebhookDeliveryDead {
+			t.Errorf("Expected dead deliveries of abc, got %+v", query)
+		}
+	})
+
+	t.Run("PositiveCase_DeadLetters", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/webhook-deliveries?status=dead&webhook_id=def", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status code 200, got %d", rec.Code)
+		}
+		if query := mockService.listDeliveriesQuery; query.SubscriptionID != "def" || query.Status != WebhookDeliveryDead {
+			t.Errorf("Expected dead deliveries of def, got %+v", query)
+		}
+	})
+
+	t.Run("NegativeCase_InvalidStatus", func(t *testing.T) {
+		mockService.listDeliveriesError = &ValidationError{Fields: []FieldError{{Field: "status", Message: "must be one of [pending delivered dead]"}}}
+		req := httptest.NewRequest("GET", "/webhook-deliveries?status=lost", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestWebhookHandler_ReplayWebhookDeliveryHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"PositiveCase", nil, http.StatusOK},
+		{"NegativeCase_Pending", ErrWebhookDeliveryPending, http.StatusConflict},
+		{"NegativeCase_NotAccepted", fmt.Errorf("%w: webhook answered 500 Internal Server Error", ErrWebhookDeliveryFailed), http.StatusBadGateway},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &mockWebhookService{replayError: tc.err}
+			handler := NewWebhookHandler(mockService)
+
+			router := httprouter.New()
+			router.POST("/webhook-deliveries/:id/replay", handler.ReplayWebhookDeliveryHandler)
+
+			req := httptest.NewRequest("POST", "/webhook-deliveries/abc/replay", nil)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.code || mockService.replayID != "abc" {
+				t.Errorf("Expected status code %d for abc, got %d for %q", tc.code, rec.Code, mockService.replayID)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WebhookEventType is what happened to a pick-up schedule
+type WebhookEventType string
+
+const (
+	// WebhookScheduleCreated is sent for a submitted schedule and for one
+	// offered to a hold
+	WebhookScheduleCreated WebhookEventType = "schedule.created"
+	// WebhookScheduleUpdated is sent when a schedule is rescheduled or changes
+	// status other than by being cancelled
+	WebhookScheduleUpdated WebhookEventType = "schedule.updated"
+	// WebhookScheduleCancelled is sent when a schedule is cancelled, also by
+	// the hold queue
+	WebhookScheduleCancelled WebhookEventType = "schedule.cancelled"
+)
+
+// WebhookEventTypes are the events a webhook can subscribe to
+var WebhookEventTypes = []WebhookEventType{WebhookScheduleCreated, WebhookScheduleUpdated, WebhookScheduleCancelled}
+
+// WebhookSubscription is an endpoint that is sent the schedule events of
+// EventTypes, or every event when EventTypes is empty
+type WebhookSubscription struct {
+	ID         string             `json:"id"`
+	URL        string             `json:"url"`
+	EventTypes []WebhookEventType `json:"event_types"`
+	// Secret signs the payloads sent to the endpoint. It is only returned
+	// when the subscription is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// wants reports whether the subscription is sent events of the type
+func (w WebhookSubscription) wants(eventType WebhookEventType) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, subscribed := range w.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is the JSON payload sent to a webhook. Its ID stays the same
+// across retries and replays, so endpoints can skip events they already got.
+type WebhookEvent struct {
+	ID         string           `json:"id"`
+	Type       WebhookEventType `json:"type"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	Data       PickUpSchedule   `json:"data"`
+}
+
+// WebhookDeliveryStatus is where the delivery of an event to one webhook is.
+// A pending delivery is retried with backoff until it is delivered or runs out
+// of attempts and is dead, which only a replay sends again.
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryDead      WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDeliveryStatuses are the statuses deliveries can be listed by
+var WebhookDeliveryStatuses = []WebhookDeliveryStatus{WebhookDeliveryPending, WebhookDeliveryDelivered, WebhookDeliveryDead}
+
+// WebhookDelivery is an event to be sent to one webhook subscription
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	SubscriptionID string                `json:"webhook_id"`
+	Event          WebhookEvent          `json:"event"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	// Attempts counts the tries since the delivery was created or replayed
+	Attempts int `json:"attempts"`
+	// NextAttemptAt is when a pending delivery is tried next
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	// LastStatusCode and LastError tell how the last try went
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// WebhookDeliveryQuery filters deliveries. Empty filters match everything.
+type WebhookDeliveryQuery struct {
+	SubscriptionID string
+	Status         WebhookDeliveryStatus
+	// DueBy only matches deliveries to be tried no later than it
+	DueBy time.Time
+}
+
+// matches reports whether the delivery passes every filter of the query
+func (q WebhookDeliveryQuery) matches(delivery WebhookDelivery) bool {
+	switch {
+	case q.SubscriptionID != "" && delivery.SubscriptionID != q.SubscriptionID:
+		return false
+	case q.Status != "" && delivery.Status != q.Status:
+		return false
+	case !q.DueBy.IsZero() && (delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(q.DueBy)):
+		return false
+	default:
+		return true
+	}
+}
+
+// WebhookPublisher is told about every change of a pick-up schedule
+type WebhookPublisher interface {
+	// PublishScheduleEvent queues the event for every webhook subscribed to
+	// it
+	PublishScheduleEvent(ctx context.Context, eventType WebhookEventType, schedule PickUpSchedule) error
+}
+
+// WebhookConfig sets how deliveries are sent and retried. The n-th retry
+// waits FirstRetry doubled n-1 times, but never longer than MaxRetry.
+type WebhookConfig struct {
+	MaxAttempts int
+	FirstRetry  time.Duration
+	MaxRetry    time.Duration
+	// Timeout bounds a single try
+	Timeout time.Duration
+	// Retention is how long a delivered delivery is kept after it was
+	// delivered
+	Retention time.Duration
+	// MaxDead is how many of the latest dead deliveries are kept for replays
+	MaxDead int
+}
+
+// DefaultWebhookConfig is used unless NewWebhookService is told otherwise
+var DefaultWebhookConfig = WebhookConfig{
+	MaxAttempts: 8,
+	FirstRetry:  30 * time.Second,
+	MaxRetry:    time.Hour,
+	Timeout:     10 * time.Second,
+	Retention:   7 * 24 * time.Hour,
+	MaxDead:     1000,
+}
+
+// DefaultWebhookCheckInterval is how often RunWebhookDelivery sends the due
+// deliveries unless told otherwise
+const DefaultWebhookCheckInterval = 5 * time.Second
+
+// Validate reports settings that would never deliver, never wait or keep
+// nothing to look at
+func (c WebhookConfig) Validate() error {
+	switch {
+	case c.MaxAttempts < 1:
+		return fmt.Errorf("webhook attempts of %d must be at least 1", c.MaxAttempts)
+	case c.FirstRetry < time.Second:
+		return fmt.Errorf("first webhook retry after %v must wait at least a second", c.FirstRetry)
+	case c.MaxRetry < c.FirstRetry:
+		return fmt.Errorf("longest webhook retry wait of %v must not be shorter than the first of %v", c.MaxRetry, c.FirstRetry)
+	case c.Timeout < time.Second:
+		return fmt.Errorf("webhook timeout of %v must be at least a second", c.Timeout)
+	case c.Retention < time.Hour:
+		return fmt.Errorf("webhook delivery retention of %v must be at least an hour", c.Retention)
+	case c.MaxDead < 0:
+		return fmt.Errorf("dead webhook deliveries kept of %d must not be negative", c.MaxDead)
+	}
+	return nil
+}
+
+// retryAfter returns how long to wait after the attempts-th failed try
+func (c WebhookConfig) retryAfter(attempts int) time.Duration {
+	wait := c.FirstRetry
+	for i := 1; i < attempts && wait < c.MaxRetry; i++ {
+		wait *= 2
+	}
+	if wait > c.MaxRetry {
+		return c.MaxRetry
+	}
+	return wait
+}
@@ -0,0 +1,347 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWebhookNotFound is returned when no webhook subscription has the
+	// requested ID
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrWebhookDeliveryNotFound is returned when no delivery has the
+	// requested ID
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrWebhookDeliveryPending is returned when replaying a delivery that is
+	// still being tried
+	ErrWebhookDeliveryPending = errors.New("webhook delivery is still pending")
+)
+
+// WebhookRepository stores webhook subscriptions and the deliveries of events
+// to them, both in the order they were created
+type WebhookRepository interface {
+	// CreateWebhook assigns the subscription a new ID and creation time and
+	// stores it
+	CreateWebhook(ctx context.Context, subscription WebhookSubscription) (WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id string) (WebhookSubscription, error)
+	ListWebhooks(ctx context.Context) ([]WebhookSubscription, error)
+	// DeleteWebhook drops the subscription, its deliveries are kept
+	DeleteWebhook(ctx context.Context, id string) error
+	// CreateWebhookDeliveries assigns each delivery a new ID and timestamps
+	// and stores all of them or none
+	CreateWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) ([]WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id string) (WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, query WebhookDeliveryQuery) ([]WebhookDelivery, error)
+	// UpdateWebhookDelivery applies fn to the stored delivery and stores the
+	// result, stamped with the current time, unless fn fails. Only the status
+	// and the attempts of a delivery can change.
+	UpdateWebhookDelivery(ctx context.Context, id string, fn func(delivery *WebhookDelivery) error) (WebhookDelivery, error)
+	// PruneWebhookDeliveries drops the deliveries delivered, as of their last
+	// update, before deliveredBefore and every dead delivery but the keepDead
+	// created last, and returns how many it dropped
+	PruneWebhookDeliveries(ctx context.Context, deliveredBefore time.Time, keepDead int) (int, error)
+}
+
+type InMemoryWebhookRepository struct {
+	mu            sync.RWMutex
+	subscriptions map[string]WebhookSubscription
+	deliveries    map[string]WebhookDelivery
+	// subscriptionOrder and deliveryOrder hold the IDs in the order they were
+	// created
+	subscriptionOrder []string
+	deliveryOrder     []string
+
+	// persist, when set, is called with every change while mu is held. The
+	// change is only stored if it returns no error.
+	persist func(change webhookChange) error
+}
+
+// webhookChange is a subscription stored or, by its ID, deleted, and the
+// deliveries stored or, by their IDs, dropped with it. Every stored
+// subscription and delivery is complete, so applying a change twice is
+// harmless.
+type webhookChange struct {
+	Webhook           *WebhookSubscription `json:"webhook,omitempty"`
+	DeletedWebhook    string               `json:"deleted_webhook,omitempty"`
+	Deliveries        []WebhookDelivery    `json:"deliveries,omitempty"`
+	DroppedDeliveries []string             `json:"dropped_deliveries,omitempty"`
+}
+
+func NewInMemoryWebhookRepository() *InMemoryWebhookRepository {
+	return &InMemoryWebhookRepository{
+		subscriptions: make(map[string]WebhookSubscription),
+		deliveries:    make(map[string]WebhookDelivery),
+	}
+}
+
+func (r *InMemoryWebhookRepository) CreateWebhook(_ context.Context, subscription WebhookSubscription) (WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscription = cloneWebhook(subscription)
+	subscription.ID = newWebhookID()
+	subscription.CreatedAt = time.Now().UTC()
+
+	if err := r.store(webhookChange{Webhook: &subscription}); err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	return cloneWebhook(subscription), nil
+}
+
+func (r *InMemoryWebhookRepository) GetWebhook(_ context.Context, id string) (WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscription, exists := r.subscriptions[id]
+	if !exists {
+		return WebhookSubscription{}, ErrWebhookNotFound
+	}
+
+	return cloneWebhook(subscription), nil
+}
+
+func (r *InMemoryWebhookRepository) ListWebhooks(_ context.Context) ([]WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sortedWebhooks(), nil
+}
+
+func (r *InMemoryWebhookRepository) DeleteWebhook(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.subscriptions[id]; !exists {
+		return ErrWebhookNotFound
+	}
+
+	return r.store(webhookChange{DeletedWebhook: id})
+}
+
+func (r *InMemoryWebhookRepository) CreateWebhookDeliveries(_ context.Context, deliveries []WebhookDelivery) ([]WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	created := make([]WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		delivery = cloneWebhookDelivery(delivery)
+		delivery.ID = newWebhookID()
+		delivery.CreatedAt = now
+		delivery.UpdatedAt = now
+		created = append(created, delivery)
+	}
+
+	if err := r.store(webhookChange{Deliveries: created}); err != nil {
+		return nil, err
+	}
+
+	result := make([]WebhookDelivery, 0, len(created))
+	for _, delivery := range created {
+		result = append(result, cloneWebhookDelivery(delivery))
+	}
+	return result, nil
+}
+
+func (r *InMemoryWebhookRepository) GetWebhookDelivery(_ context.Context, id string) (WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, exists := r.deliveries[id]
+	if !exists {
+		return WebhookDelivery{}, ErrWebhookDeliveryNotFound
+	}
+
+	return cloneWebhookDelivery(delivery), nil
+}
+
+func (r *InMemoryWebhookRepository) ListWebhookDeliveries(_ context.Context, query WebhookDeliveryQuery) ([]WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []WebhookDelivery{}
+	for _, id := range r.deliveryOrder {
+		if delivery := r.deliveries[id]; query.matches(delivery) {
+			deliveries = append(deliveries, cloneWebhookDelivery(delivery))
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (r *InMemoryWebhookRepository) UpdateWebhookDelivery(_ context.Context, id string, fn func(delivery *WebhookDelivery) error) (WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.deliveries[id]
+	if !exists {
+		return WebhookDelivery{}, ErrWebhookDeliveryNotFound
+	}
+
+	delivery := cloneWebhookDelivery(stored)
+	if err := fn(&delivery); err != nil {
+		return WebhookDelivery{}, err
+	}
+	// A delivery keeps the event and the subscription it was created for
+	delivery.SubscriptionID, delivery.Event, delivery.CreatedAt = stored.SubscriptionID, cloneWebhookDelivery(stored).Event, stored.CreatedAt
+	delivery.UpdatedAt = time.Now().UTC()
+
+	if err := r.store(webhookChange{Deliveries: []WebhookDelivery{delivery}}); err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	return cloneWebhookDelivery(delivery), nil
+}
+
+func (r *InMemoryWebhookRepository) PruneWebhookDeliveries(_ context.Context, deliveredBefore time.Time, keepDead int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var dropped []string
+	dead := 0
+	// Newest first, so the dead deliveries kept are the latest
+	for i := len(r.deliveryOrder) - 1; i >= 0; i-- {
+		delivery := r.deliveries[r.deliveryOrder[i]]
+		switch delivery.Status {
+		case WebhookDeliveryDelivered:
+			if delivery.UpdatedAt.Before(deliveredBefore) {
+				dropped = append(dropped, delivery.ID)
+			}
+		case WebhookDeliveryDead:
+			if dead++; dead > keepDead {
+				dropped = append(dropped, delivery.ID)
+			}
+		}
+	}
+	if len(dropped) == 0 {
+		return 0, nil
+	}
+
+	if err := r.store(webhookChange{DroppedDeliveries: dropped}); err != nil {
+		return 0, err
+	}
+	return len(dropped), nil
+}
+
+// store applies the change once it is persisted. mu must be held.
+func (r *InMemoryWebhookRepository) store(change webhookChange) error {
+	if r.persist != nil {
+		if err := r.persist(change); err != nil {
+			return err
+		}
+	}
+
+	r.apply(change)
+	return nil
+}
+
+// apply changes the stored subscriptions and deliveries without calling
+// persist. mu must be held.
+func (r *InMemoryWebhookRepository) apply(change webhookChange) {
+	if subscription := change.Webhook; subscription != nil {
+		if _, exists := r.subscriptions[subscription.ID]; !exists {
+			r.subscriptionOrder = append(r.subscriptionOrder, subscription.ID)
+		}
+		r.subscriptions[subscription.ID] = cloneWebhook(*subscription)
+	}
+	if id := change.DeletedWebhook; id != "" {
+		delete(r.subscriptions, id)
+		r.subscriptionOrder = withoutIDs(r.subscriptionOrder, map[string]bool{id: true})
+	}
+	for _, delivery := range change.Deliveries {
+		if _, exists := r.deliveries[delivery.ID]; !exists {
+			r.deliveryOrder = append(r.deliveryOrder, delivery.ID)
+		}
+		r.deliveries[delivery.ID] = cloneWebhookDelivery(delivery)
+	}
+	if len(change.DroppedDeliveries) > 0 {
+		dropped := make(map[string]bool, len(change.DroppedDeliveries))
+		for _, id := range change.DroppedDeliveries {
+			dropped[id] = true
+			delete(r.deliveries, id)
+		}
+		r.deliveryOrder = withoutIDs(r.deliveryOrder, dropped)
+	}
+}
+
+// restore puts already persisted subscriptions and deliveries back in order
+// without calling persist
+func (r *InMemoryWebhookRepository) restore(subscriptions []WebhookSubscription, deliveries []WebhookDelivery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range subscriptions {
+		r.apply(webhookChange{Webhook: &subscriptions[i]})
+	}
+	r.apply(webhookChange{Deliveries: deliveries})
+}
+
+// sortedWebhooks returns a copy of every subscription in the order they were
+// created. mu must be held.
+func (r *InMemoryWebhookRepository) sortedWebhooks() []WebhookSubscription {
+	subscriptions := make([]WebhookSubscription, 0, len(r.subscriptionOrder))
+	for _, id := range r.subscriptionOrder {
+		subscriptions = append(subscriptions, cloneWebhook(r.subscriptions[id]))
+	}
+	return subscriptions
+}
+
+// sortedDeliveries returns a copy of every delivery in the order they were
+// created. mu must be held.
+func (r *InMemoryWebhookRepository) sortedDeliveries() []WebhookDelivery {
+	deliveries := make([]WebhookDelivery, 0, len(r.deliveryOrder))
+	for _, id := range r.deliveryOrder {
+		deliveries = append(deliveries, cloneWebhookDelivery(r.deliveries[id]))
+	}
+	return deliveries
+}
+
+// withoutIDs returns the IDs of order that are not dropped, in order
+func withoutIDs(order []string, dropped map[string]bool) []string {
+	kept := make([]string, 0, len(order))
+	for _, id := range order {
+		if !dropped[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// cloneWebhook copies the subscription so callers cannot change its stored
+// event types
+func cloneWebhook(subscription WebhookSubscription) WebhookSubscription {
+	subscription.EventTypes = append([]WebhookEventType{}, subscription.EventTypes...)
+	return subscription
+}
+
+// cloneWebhookDelivery copies the delivery so callers cannot change its
+// stored times or event
+func cloneWebhookDelivery(delivery WebhookDelivery) WebhookDelivery {
+	schedule := &delivery.Event.Data
+	schedule.BookInfo.Author = append([]string(nil), schedule.BookInfo.Author...)
+	if schedule.Cancellation != nil {
+		cancellation := *schedule.Cancellation
+		schedule.Cancellation = &cancellation
+	}
+	if schedule.DueAt != nil {
+		due := *schedule.DueAt
+		schedule.DueAt = &due
+	}
+	if delivery.NextAttemptAt != nil {
+		next := *delivery.NextAttemptAt
+		delivery.NextAttemptAt = &next
+	}
+	if delivery.LastAttemptAt != nil {
+		last := *delivery.LastAttemptAt
+		delivery.LastAttemptAt = &last
+	}
+	return delivery
+}
+
+// newWebhookID returns a random identifier of the same form as schedule IDs
+func newWebhookID() string {
+	return newScheduleID()
+}
@@ -0,0 +1,252 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInMemoryWebhookRepository_Suite(t *testing.T) {
+	testWebhookRepository(t, func(t *testing.T) WebhookRepository {
+		return NewInMemoryWebhookRepository()
+	})
+}
+
+// testWebhookRepository checks the behaviour every WebhookRepository shares.
+// newRepo must return an empty repository.
+func testWebhookRepository[R WebhookRepository](t *testing.T, newRepo func(t *testing.T) R) {
+	ctx := context.Background()
+	due := time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)
+	event := WebhookEvent{
+		ID:         "event",
+		Type:       WebhookScheduleCreated,
+		OccurredAt: due,
+		Data:       PickUpSchedule{ID: "abc", BookInfo: Book{Title: "Emma", Author: []string{"Jane Austen"}}, PickUpDate: mustParsePickUpDate("2023-11-21T10:00:00Z"), Status: ScheduleStatusRequested},
+	}
+
+	// setUp returns a repository with two webhooks and a pending delivery of
+	// event to each of them
+	setUp := func(t *testing.T) (WebhookRepository, []WebhookSubscription, []WebhookDelivery) {
+		repo := newRepo(t)
+		var subscriptions []WebhookSubscription
+		for _, subscription := range []WebhookSubscription{
+			{URL: "https://desk.example.com/hooks", Secret: "desk-secret-0123456789"},
+			{URL: "https://warehouse.example.com/hooks", EventTypes: []WebhookEventType{WebhookScheduleCreated, WebhookScheduleCancelled}, Secret: "warehouse-secret-0123"},
+		} {
+			created, err := repo.CreateWebhook(ctx, subscription)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			subscriptions = append(subscriptions, created)
+		}
+
+		deliveries, err := repo.CreateWebhookDeliveries(ctx, []WebhookDelivery{
+			{SubscriptionID: subscriptions[0].ID, Event: event, Status: WebhookDeliveryPending, NextAttemptAt: &due},
+			{SubscriptionID: subscriptions[1].ID, Event: event, Status: WebhookDeliveryPending, NextAttemptAt: &due},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return repo, subscriptions, deliveries
+	}
+
+	t.Run("CreateWebhook_PositiveCase", func(t *testing.T) {
+		repo, subscriptions, _ := setUp(t)
+
+		if subscriptions[0].ID == "" || subscriptions[0].ID == subscriptions[1].ID || subscriptions[0].CreatedAt.IsZero() {
+			t.Errorf("Expected new IDs and creation times, got %+v", subscriptions)
+		}
+		fetched, err := repo.GetWebhook(ctx, subscriptions[1].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fetched.URL != "https://warehouse.example.com/hooks" || fetched.Secret != "warehouse-secret-0123" || len(fetched.EventTypes) != 2 || fetched.EventTypes[1] != WebhookScheduleCancelled {
+			t.Errorf("Expected the stored webhook, got %+v", fetched)
+		}
+		if !fetched.CreatedAt.Equal(subscriptions[1].CreatedAt) {
+			t.Errorf("Expected created_at %v, got %v", subscriptions[1].CreatedAt, fetched.CreatedAt)
+		}
+	})
+
+	t.Run("ListWebhooks_InOrder", func(t *testing.T) {
+		repo, subscriptions, _ := setUp(t)
+
+		listed, err := repo.ListWebhooks(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(listed) != 2 || listed[0].ID != subscriptions[0].ID || listed[1].ID != subscriptions[1].ID {
+			t.Errorf("Expected the webhooks in the order they were created, got %+v", listed)
+		}
+		if listed[0].EventTypes == nil || len(listed[0].EventTypes) != 0 {
+			t.Errorf("Expected no event types to be an empty list, got %#v", listed[0].EventTypes)
+		}
+	})
+
+	t.Run("DeleteWebhook_KeepsDeliveries", func(t *testing.T) {
+		repo, subscriptions, deliveries := setUp(t)
+
+		if err := repo.DeleteWebhook(ctx, subscriptions[0].ID); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := repo.GetWebhook(ctx, subscriptions[0].ID); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+		}
+		if err := repo.DeleteWebhook(ctx, subscriptions[0].ID); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("Expected ErrWebhookNotFound deleting twice, got %v", err)
+		}
+		if _, err := repo.GetWebhookDelivery(ctx, deliveries[0].ID); err != nil {
+			t.Errorf("Expected the delivery to be kept, got %v", err)
+		}
+	})
+
+	t.Run("GetWebhook_NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetWebhook(ctx, "missing"); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+		}
+	})
+
+	t.Run("CreateWebhookDeliveries_PositiveCase", func(t *testing.T) {
+		repo, subscriptions, deliveries := setUp(t)
+
+		fetched, err := repo.GetWebhookDelivery(ctx, deliveries[1].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fetched.SubscriptionID != subscriptions[1].ID || fetched.Status != WebhookDeliveryPending || fetched.NextAttemptAt == nil || !fetched.NextAttemptAt.Equal(due) {
+			t.Errorf("Expected the pending delivery, got %+v", fetched)
+		}
+		if fetched.Event.ID != "event" || fetched.Event.Data.ID != "abc" || fetched.Event.Data.BookInfo.Author[0] != "Jane Austen" || !fetched.Event.Data.PickUpDate.Time().Equal(event.Data.PickUpDate.Time()) {
+			t.Errorf("Expected the stored event, got %+v", fetched.Event)
+		}
+	})
+
+	t.Run("ListWebhookDeliveries_Filters", func(t *testing.T) {
+		repo, subscriptions, deliveries := setUp(t)
+		later := due.Add(time.Minute)
+		if _, err := repo.UpdateWebhookDelivery(ctx, deliveries[1].ID, func(delivery *WebhookDelivery) error {
+			delivery.NextAttemptAt = &later
+			return nil
+		}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for name, want := range map[string]struct {
+			query WebhookDeliveryQuery
+			ids   []string
+		}{
+			"all":     {WebhookDeliveryQuery{}, []string{deliveries[0].ID, deliveries[1].ID}},
+			"webhook": {WebhookDeliveryQuery{SubscriptionID: subscriptions[1].ID}, []string{deliveries[1].ID}},
+			"due":     {WebhookDeliveryQuery{Status: WebhookDeliveryPending, DueBy: due}, []string{deliveries[0].ID}},
+			"dead":    {WebhookDeliveryQuery{Status: WebhookDeliveryDead}, nil},
+		} {
+			listed, err := repo.ListWebhookDeliveries(ctx, want.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(listed) != len(want.ids) {
+				t.Errorf("Expected %d %s deliveries, got %+v", len(want.ids), name, listed)
+				continue
+			}
+			for i := range listed {
+				if listed[i].ID != want.ids[i] {
+					t.Errorf("Expected %s deliveries %v, got %+v", name, want.ids, listed)
+				}
+			}
+		}
+	})
+
+	t.Run("UpdateWebhookDelivery_PositiveCase", func(t *testing.T) {
+		repo, subscriptions, deliveries := setUp(t)
+
+		updated, err := repo.UpdateWebhookDelivery(ctx, deliveries[0].ID, func(delivery *WebhookDelivery) error {
+			delivery.Status = WebhookDeliveryDead
+			delivery.Attempts = 8
+			delivery.NextAttemptAt = nil
+			delivery.LastAttemptAt = &due
+			delivery.LastStatusCode = 500
+			delivery.LastError = "webhook answered 500 Internal Server Error"
+			// Not changeable
+			delivery.SubscriptionID = subscriptions[1].ID
+			delivery.Event.Type = WebhookScheduleCancelled
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		fetched, err := repo.GetWebhookDelivery(ctx, deliveries[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, delivery := range []WebhookDelivery{updated, fetched} {
+			if delivery.Status != WebhookDeliveryDead || delivery.Attempts != 8 || delivery.NextAttemptAt != nil || delivery.LastStatusCode != 500 ||
+				delivery.LastAttemptAt == nil || !delivery.LastAttemptAt.Equal(due) || delivery.LastError == "" {
+				t.Errorf("Expected the dead delivery, got %+v", delivery)
+			}
+			if delivery.SubscriptionID != subscriptions[0].ID || delivery.Event.Type != WebhookScheduleCreated {
+				t.Errorf("Expected the webhook and event to be kept, got %+v", delivery)
+			}
+		}
+	})
+
+	t.Run("UpdateWebhookDelivery_FnFails", func(t *testing.T) {
+		repo, _, deliveries := setUp(t)
+		failure := errors.New("failure")
+
+		_, err := repo.UpdateWebhookDelivery(ctx, deliveries[0].ID, func(delivery *WebhookDelivery) error {
+			delivery.Status = WebhookDeliveryDelivered
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Errorf("Expected the error of fn, got %v", err)
+		}
+		if fetched, _ := repo.GetWebhookDelivery(ctx, deliveries[0].ID); fetched.Status != WebhookDeliveryPending {
+			t.Errorf("Expected the delivery to stay pending, got %s", fetched.Status)
+		}
+	})
+
+	t.Run("UpdateWebhookDelivery_NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.UpdateWebhookDelivery(ctx, "missing", func(*WebhookDelivery) error { return nil })
+		if !errors.Is(err, ErrWebhookDeliveryNotFound) {
+			t.Errorf("Expected ErrWebhookDeliveryNotFound, got %v", err)
+		}
+	})
+	t.Run("PruneWebhookDeliveries_DeliveredAndDead", func(t *testing.T) {
+		repo, subscriptions, deliveries := setUp(t)
+		more, err := repo.CreateWebhookDeliveries(ctx, []WebhookDelivery{{SubscriptionID: subscriptions[0].ID, Event: event, Status: WebhookDeliveryPending, NextAttemptAt: &due}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for id, status := range map[string]WebhookDeliveryStatus{deliveries[0].ID: WebhookDeliveryDelivered, deliveries[1].ID: WebhookDeliveryDead, more[0].ID: WebhookDeliveryDead} {
+			if _, err := repo.UpdateWebhookDelivery(ctx, id, func(delivery *WebhookDelivery) error {
+				delivery.Status = status
+				return nil
+			}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		// Delivered just now and within the dead letters kept
+		if dropped, err := repo.PruneWebhookDeliveries(ctx, time.Now().Add(-time.Hour), 2); err != nil || dropped != 0 {
+			t.Fatalf("Expected nothing to be dropped, got %d (%v)", dropped, err)
+		}
+
+		dropped, err := repo.PruneWebhookDeliveries(ctx, time.Now().Add(time.Hour), 1)
+		if err != nil || dropped != 2 {
+			t.Fatalf("Expected 2 deliveries to be dropped, got %d (%v)", dropped, err)
+		}
+		listed, err := repo.ListWebhookDeliveries(ctx, WebhookDeliveryQuery{})
+		if err != nil || len(listed) != 1 || listed[0].ID != more[0].ID {
+			t.Errorf("Expected only the latest dead delivery to be kept, got %+v (%v)", listed, err)
+		}
+		if _, err := repo.GetWebhookDelivery(ctx, deliveries[0].ID); !errors.Is(err, ErrWebhookDeliveryNotFound) {
+			t.Errorf("Expected ErrWebhookDeliveryNotFound, got %v", err)
+		}
+	})
+}
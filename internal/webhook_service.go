@@ -0,0 +1,588 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrWebhookDeliveryFailed is returned when a replayed delivery was not
+// accepted by its webhook
+var ErrWebhookDeliveryFailed = errors.New("webhook did not accept the delivery")
+
+// errWebhookDeliveryNotDue is returned when claiming a delivery that another
+// check is already trying or that was already delivered
+var errWebhookDeliveryNotDue = errors.New("webhook delivery is not due")
+
+const (
+	// minWebhookSecretLength is the shortest secret a webhook can be given
+	minWebhookSecretLength = 16
+	// maxWebhookResponseSize is how much of the answer of a webhook is read
+	// before the connection is given up
+	maxWebhookResponseSize = 64 << 10
+)
+
+type WebhookService interface {
+	WebhookPublisher
+	CreateWebhookService(ctx context.Context, subscription WebhookSubscription) (WebhookResponse, error)
+	ListWebhooksService(ctx context.Context) (WebhookListResponse, error)
+	GetWebhookService(ctx context.Context, id string) (WebhookResponse, error)
+	DeleteWebhookService(ctx context.Context, id string) (WebhookResponse, error)
+	ListWebhookDeliveriesService(ctx context.Context, query WebhookDeliveryQuery) (WebhookDeliveryListResponse, error)
+	GetWebhookDeliveryService(ctx context.Context, id string) (WebhookDeliveryResponse, error)
+	ReplayWebhookDeliveryService(ctx context.Context, id string) (WebhookDeliveryResponse, error)
+	DeliverWebhooksService(ctx context.Context) (WebhookDeliveryListResponse, error)
+}
+
+type WebhookResponse struct {
+	Status    string              `json:"status"`
+	IsSuccess bool                `json:"is_success"`
+	Message   string              `json:"message"`
+	TotalData int                 `json:"total_data"`
+	Data      WebhookSubscription `json:"data"`
+	Errors    []FieldError        `json:"errors,omitempty"`
+}
+
+type WebhookListResponse struct {
+	Status    string                `json:"status"`
+	IsSuccess bool                  `json:"is_success"`
+	Message   string                `json:"message"`
+	TotalData int                   `json:"total_data"`
+	Data      []WebhookSubscription `json:"data"`
+}
+
+type WebhookDeliveryResponse struct {
+	Status    string          `json:"status"`
+	IsSuccess bool            `json:"is_success"`
+	Message   string          `json:"message"`
+	TotalData int             `json:"total_data"`
+	Data      WebhookDelivery `json:"data"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Status    string            `json:"status"`
+	IsSuccess bool              `json:"is_success"`
+	Message   string            `json:"message"`
+	TotalData int               `json:"total_data"`
+	Data      []WebhookDelivery `json:"data"`
+}
+
+type webhookService struct {
+	repository WebhookRepository
+	config     WebhookConfig
+	client     *http.Client
+	now        func() time.Time
+}
+
+// NewWebhookService returns a WebhookService keeping subscriptions and
+// deliveries in repository and sending them as config says. The config must
+// pass WebhookConfig.Validate.
+func NewWebhookService(repository WebhookRepository, config WebhookConfig) WebhookService {
+	return &webhookService{
+		repository: repository,
+		config:     config,
+		client: &http.Client{
+			// A redirect counts as a failed try, the payload is only signed
+			// for the registered URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// RunWebhookDelivery sends the deliveries that are due right away and then
+// every interval, until ctx is done
+func RunWebhookDelivery(ctx context.Context, service WebhookService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		response, err := service.DeliverWebhooksService(ctx)
+		switch {
+		case err != nil:
+			log.Printf("webhook delivery failed: %v", err)
+		case response.TotalData > 0:
+			log.Printf("webhook delivery tried %d deliveries", response.TotalData)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishScheduleEvent stores a pending delivery of the event for every
+// webhook subscribed to it, to be sent by DeliverWebhooksService
+func (s *webhookService) PublishScheduleEvent(ctx context.Context, eventType WebhookEventType, schedule PickUpSchedule) error {
+	subscriptions, err := s.repository.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch webhooks: %v", err)
+	}
+
+	now := s.now().UTC()
+	event := WebhookEvent{ID: newWebhookID(), Type: eventType, OccurredAt: now, Data: schedule}
+	var deliveries []WebhookDelivery
+	for _, subscription := range subscriptions {
+		if subscription.wants(eventType) {
+			deliveries = append(deliveries, WebhookDelivery{SubscriptionID: subscription.ID, Event: event, Status: WebhookDeliveryPending, NextAttemptAt: &now})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if _, err := s.repository.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %v", err)
+	}
+	return nil
+}
+
+func (s *webhookService) CreateWebhookService(ctx context.Context, subscription WebhookSubscription) (WebhookResponse, error) {
+	subscription.URL = strings.TrimSpace(subscription.URL)
+	subscription.Secret = strings.TrimSpace(subscription.Secret)
+
+	// Listing a type twice would not send it twice
+	seen := make(map[WebhookEventType]bool)
+	eventTypes := []WebhookEventType{}
+	for _, eventType := range subscription.EventTypes {
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	subscription.EventTypes = eventTypes
+
+	if fields := validateWebhook(subscription); len(fields) > 0 {
+		err := &ValidationError{Fields: fields}
+		return failedWebhookResponse("invalid webhook", err), err
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = newWebhookSecret()
+	}
+
+	created, err := s.repository.CreateWebhook(ctx, subscription)
+	if err != nil {
+		return failedWebhookResponse("failed to save webhook", err), err
+	}
+
+	// The only time the secret is shown
+	response := WebhookResponse{
+		Status:    "201 CREATED",
+		IsSuccess: true,
+		Message:   "save webhook successfully!",
+		TotalData: 1,
+		Data:      created,
+	}
+
+	return response, nil
+}
+
+func (s *webhookService) ListWebhooksService(ctx context.Context) (WebhookListResponse, error) {
+	subscriptions, err := s.repository.ListWebhooks(ctx)
+	if err != nil {
+		return WebhookListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch webhooks: %v", err),
+			Data:      []WebhookSubscription{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := WebhookListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch webhooks successfully!",
+		TotalData: len(subscriptions),
+		Data:      []WebhookSubscription{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	for _, subscription := range subscriptions {
+		subscription.Secret = ""
+		response.Data = append(response.Data, subscription)
+	}
+
+	return response, nil
+}
+
+func (s *webhookService) GetWebhookService(ctx context.Context, id string) (WebhookResponse, error) {
+	subscription, err := s.repository.GetWebhook(ctx, id)
+	if err != nil {
+		return failedWebhookResponse("failed to fetch webhook", err), err
+	}
+	subscription.Secret = ""
+
+	response := WebhookResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch webhook successfully!",
+		TotalData: 1,
+		Data:      subscription,
+	}
+
+	return response, nil
+}
+
+// DeleteWebhookService stops sending events to a webhook. Its pending
+// deliveries are dropped on their next try, the others stay listed.
+func (s *webhookService) DeleteWebhookService(ctx context.Context, id string) (WebhookResponse, error) {
+	subscription, err := s.repository.GetWebhook(ctx, id)
+	if err == nil {
+		err = s.repository.DeleteWebhook(ctx, id)
+	}
+	if err != nil {
+		return failedWebhookResponse("failed to delete webhook", err), err
+	}
+	subscription.Secret = ""
+
+	response := WebhookResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "delete webhook successfully!",
+		TotalData: 1,
+		Data:      subscription,
+	}
+
+	return response, nil
+}
+
+func (s *webhookService) ListWebhookDeliveriesService(ctx context.Context, query WebhookDeliveryQuery) (WebhookDeliveryListResponse, error) {
+	if query.Status != "" && !isWebhookDeliveryStatus(query.Status) {
+		err := &ValidationError{Fields: []FieldError{{Field: "status", Message: fmt.Sprintf("must be one of %v", WebhookDeliveryStatuses)}}}
+		return WebhookDeliveryListResponse{
+			Status:    statusOf(err),
+			IsSuccess: false,
+			Message:   err.Error(),
+			Data:      []WebhookDelivery{},
+			TotalData: 0,
+		}, err
+	}
+
+	deliveries, err := s.repository.ListWebhookDeliveries(ctx, query)
+	if err != nil {
+		return WebhookDeliveryListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch webhook deliveries: %v", err),
+			Data:      []WebhookDelivery{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := WebhookDeliveryListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch webhook deliveries successfully!",
+		TotalData: len(deliveries),
+		Data:      []WebhookDelivery{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	response.Data = append(response.Data, deliveries...)
+
+	return response, nil
+}
+
+func (s *webhookService) GetWebhookDeliveryService(ctx context.Context, id string) (WebhookDeliveryResponse, error) {
+	delivery, err := s.repository.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		return failedWebhookDeliveryResponse("failed to fetch webhook delivery", delivery, err), err
+	}
+
+	response := WebhookDeliveryResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "fetch webhook delivery successfully!",
+		TotalData: 1,
+		Data:      delivery,
+	}
+
+	return response, nil
+}
+
+// ReplayWebhookDeliveryService sends a delivered or dead delivery again right
+// away with the same event. When the webhook does not accept it, the delivery
+// is retried like a new one and ErrWebhookDeliveryFailed is returned.
+func (s *webhookService) ReplayWebhookDeliveryService(ctx context.Context, id string) (WebhookDeliveryResponse, error) {
+	delivery, err := s.attempt(ctx, id, true)
+	if err != nil {
+		return failedWebhookDeliveryResponse("failed to replay webhook delivery", WebhookDelivery{}, err), err
+	}
+	if delivery.Status != WebhookDeliveryDelivered {
+		err := fmt.Errorf("%w: %s", ErrWebhookDeliveryFailed, delivery.LastError)
+		return failedWebhookDeliveryResponse("failed to replay webhook delivery", delivery, err), err
+	}
+
+	response := WebhookDeliveryResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "replay webhook delivery successfully!",
+		TotalData: 1,
+		Data:      delivery,
+	}
+
+	return response, nil
+}
+
+// DeliverWebhooksService tries every pending delivery that is due once and
+// returns them as they are after the try. Deliveries delivered longer than
+// WebhookConfig.Retention ago and dead deliveries beyond WebhookConfig.MaxDead
+// are dropped first.
+func (s *webhookService) DeliverWebhooksService(ctx context.Context) (WebhookDeliveryListResponse, error) {
+	now := s.now().UTC()
+	if _, err := s.repository.PruneWebhookDeliveries(ctx, now.Add(-s.config.Retention), s.config.MaxDead); err != nil {
+		return WebhookDeliveryListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to drop old webhook deliveries: %v", err),
+			Data:      []WebhookDelivery{},
+			TotalData: 0,
+		}, err
+	}
+
+	due, err := s.repository.ListWebhookDeliveries(ctx, WebhookDeliveryQuery{Status: WebhookDeliveryPending, DueBy: now})
+	if err != nil {
+		return WebhookDeliveryListResponse{
+			Status:    "500 Internal Server Error",
+			IsSuccess: false,
+			Message:   fmt.Sprintf("failed to fetch due webhook deliveries: %v", err),
+			Data:      []WebhookDelivery{},
+			TotalData: 0,
+		}, err
+	}
+
+	response := WebhookDeliveryListResponse{
+		Status:    "200 OK",
+		IsSuccess: true,
+		Message:   "deliver webhooks successfully!",
+		Data:      []WebhookDelivery{}, // Initialize with empty slice to avoid null in JSON response
+	}
+	var failures []error
+	for _, delivery := range due {
+		tried, err := s.attempt(ctx, delivery.ID, false)
+		if errors.Is(err, errWebhookDeliveryNotDue) {
+			continue
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("webhook delivery %s: %v", delivery.ID, err))
+			continue
+		}
+		response.Data = append(response.Data, tried)
+	}
+	response.TotalData = len(response.Data)
+
+	if len(failures) > 0 {
+		err := fmt.Errorf("failed to deliver %d webhooks: %v", len(failures), errors.Join(failures...))
+		response.Status = "500 Internal Server Error"
+		response.IsSuccess = false
+		response.Message = err.Error()
+		return response, err
+	}
+	return response, nil
+}
+
+// attempt claims the delivery, sends it once and records how it went. A
+// replay starts the attempts of a delivered or dead delivery over, otherwise
+// only a pending delivery that is due is claimed. Failing to reach the
+// webhook is recorded on the delivery, only storage errors are returned.
+func (s *webhookService) attempt(ctx context.Context, id string, replay bool) (WebhookDelivery, error) {
+	now := s.now().UTC()
+	// Until the try is recorded the delivery is not due, so no other check
+	// sends it too. Should the try never be recorded, it counts as failed.
+	claimedUntil := now.Add(s.config.Timeout + s.config.FirstRetry)
+	delivery, err := s.repository.UpdateWebhookDelivery(ctx, id, func(delivery *WebhookDelivery) error {
+		switch {
+		case replay && delivery.Status == WebhookDeliveryPending:
+			return ErrWebhookDeliveryPending
+		case replay:
+			delivery.Status = WebhookDeliveryPending
+			delivery.Attempts = 0
+		case delivery.Status != WebhookDeliveryPending || delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(now):
+			return errWebhookDeliveryNotDue
+		}
+		delivery.NextAttemptAt = &claimedUntil
+		return nil
+	})
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	var statusCode int
+	subscription, err := s.repository.GetWebhook(ctx, delivery.SubscriptionID)
+	switch {
+	case errors.Is(err, ErrWebhookNotFound):
+		err = errors.New("webhook was deleted")
+	case err != nil:
+		return WebhookDelivery{}, err
+	default:
+		statusCode, err = s.send(ctx, subscription, delivery)
+	}
+
+	return s.repository.UpdateWebhookDelivery(context.WithoutCancel(ctx), id, func(delivery *WebhookDelivery) error {
+		delivery.Attempts++
+		delivery.LastAttemptAt = &now
+		delivery.LastStatusCode = statusCode
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+
+		switch {
+		case err == nil:
+			delivery.Status = WebhookDeliveryDelivered
+		case subscription.ID == "" || delivery.Attempts >= s.config.MaxAttempts:
+			delivery.Status = WebhookDeliveryDead
+			delivery.LastError = err.Error()
+		default:
+			next := now.Add(s.config.retryAfter(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+			delivery.LastError = err.Error()
+		}
+		return nil
+	})
+}
+
+// send posts the event of the delivery to the webhook, signed with its
+// secret, and returns the status code it answered with. Only an answer with
+// 2xx counts as accepted.
+func (s *webhookService) send(ctx context.Context, subscription WebhookSubscription, delivery WebhookDelivery) (int, error) {
+	payload, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode webhook event: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build webhook request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Webhook-ID", delivery.ID)
+	request.Header.Set("X-Webhook-Event", string(delivery.Event.Type))
+	request.Header.Set("X-Webhook-Signature", webhookSignature(subscription.Secret, s.now(), payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach webhook: %v", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(response.Body)
+	// Reading the answer lets the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxWebhookResponseSize))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook answered %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// webhookSignature signs the payload sent at the time with the secret, like
+// "t=1700467200,v1=<hex HMAC-SHA256 of "1700467200.<payload>">". The time is
+// signed too, so endpoints can refuse old payloads sent again.
+func webhookSignature(secret string, at time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateWebhook checks the URL, event types and secret of a normalized
+// subscription
+func validateWebhook(subscription WebhookSubscription) []FieldError {
+	var fields []FieldError
+
+	target, err := url.Parse(subscription.URL)
+	switch {
+	case subscription.URL == "":
+		fields = append(fields, FieldError{Field: "url", Message: "is required"})
+	case err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "":
+		fields = append(fields, FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+	}
+
+	for _, eventType := range subscription.EventTypes {
+		known := false
+		for _, other := range WebhookEventTypes {
+			known = known || eventType == other
+		}
+		if !known {
+			fields = append(fields, FieldError{Field: "event_types", Message: fmt.Sprintf("must each be one of %v", WebhookEventTypes)})
+			break
+		}
+	}
+
+	if subscription.Secret != "" && len(subscription.Secret) < minWebhookSecretLength {
+		fields = append(fields, FieldError{Field: "secret", Message: fmt.Sprintf("must be at least %d characters", minWebhookSecretLength)})
+	}
+
+	return fields
+}
+
+func isWebhookDeliveryStatus(status WebhookDeliveryStatus) bool {
+	for _, known := range WebhookDeliveryStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+// newWebhookSecret returns a random secret for a webhook registered without
+// one
+func newWebhookSecret() string {
+	var secret [32]byte
+	if _, err := rand.Read(secret[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate webhook secret: %v", err))
+	}
+	return "whsec_" + hex.EncodeToString(secret[:])
+}
+
+// failedWebhookResponse describes an error of a single webhook request
+func failedWebhookResponse(message string, err error) WebhookResponse {
+	response := WebhookResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   fmt.Sprintf("%s: %v", message, err),
+		Data:      WebhookSubscription{EventTypes: []WebhookEventType{}},
+		TotalData: 0,
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Errors = validationErr.Fields
+	}
+
+	return response
+}
+
+// failedWebhookDeliveryResponse describes an error of a single delivery
+// request, with the delivery as it is when there is one
+func failedWebhookDeliveryResponse(message string, delivery WebhookDelivery, err error) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		Status:    statusOf(err),
+		IsSuccess: false,
+		Message:   fmt.Sprintf("%s: %v", message, err),
+		Data:      delivery,
+		TotalData: 0,
+	}
+	if delivery.ID != "" {
+		response.TotalData = 1
+	}
+
+	return response
+}
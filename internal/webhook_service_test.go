@@ -0,0 +1,289 @@
+package internal
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver is an endpoint recording the requests it is sent and
+// answering them with the status codes in answers, then with 200
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	answers  []int
+}
+
+func newWebhookReceiver(t *testing.T, answers ...int) *webhookReceiver {
+	receiver := &webhookReceiver{answers: answers}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		code := http.StatusOK
+		if len(receiver.answers) > 0 {
+			code, receiver.answers = receiver.answers[0], receiver.answers[1:]
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func TestWebhookService(t *testing.T) {
+	ctx := context.Background()
+	schedule := PickUpSchedule{ID: "abc", BookInfo: Book{Title: "Emma"}, PickUpDate: mustParsePickUpDate("2023-11-21T10:00:00Z"), Status: ScheduleStatusRequested}
+	config := WebhookConfig{MaxAttempts: 3, FirstRetry: 30 * time.Second, MaxRetry: time.Hour, Timeout: 5 * time.Second, Retention: 24 * time.Hour, MaxDead: 10}
+	newService := func(now *time.Time) WebhookService {
+		service := NewWebhookService(NewInMemoryWebhookRepository(), config)
+		service.(*webhookService).now = func() time.Time { return *now }
+		return service
+	}
+	register := func(t *testing.T, service WebhookService, subscription WebhookSubscription) WebhookSubscription {
+		t.Helper()
+		response, err := service.CreateWebhookService(ctx, subscription)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return response.Data
+	}
+	deliver := func(t *testing.T, service WebhookService) []WebhookDelivery {
+		t.Helper()
+		response, err := service.DeliverWebhooksService(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return response.Data
+	}
+
+	t.Run("PositiveCase_SignedDelivery", func(t *testing.T) {
+		receiver := newWebhookReceiver(t)
+		now := serviceNow
+		service := newService(&now)
+		subscription := register(t, service, WebhookSubscription{URL: receiver.URL, Secret: "desk-secret-0123456789"})
+
+		if err := service.PublishScheduleEvent(ctx, WebhookScheduleCreated, schedule); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		delivered := deliver(t, service)
+
+		if len(delivered) != 1 || delivered[0].Status != WebhookDeliveryDelivered || delivered[0].Attempts != 1 || delivered[0].LastStatusCode != 200 {
+			t.Fatalf("Expected one delivered delivery, got %+v", delivered)
+		}
+		if receiver.received() != 1 {
+			t.Fatalf("Expected one request, got %d", receiver.received())
+		}
+		request, body := receiver.requests[0], receiver.bodies[0]
+		if request.Header.Get("X-Webhook-Event") != "schedule.created" || request.Header.Get("X-Webhook-ID") != delivered[0].ID || request.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected headers %v", request.Header)
+		}
+		want := webhookSignature(subscription.Secret, now, body)
+		if got := request.Header.Get("X-Webhook-Signature"); !hmac.Equal([]byte(got), []byte(want)) || !strings.HasPrefix(got, "t=1700467200,v1=") {
+			t.Errorf("Expected signature %s, got %s", want, got)
+		}
+		var event WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if event.ID != delivered[0].Event.ID || event.Type != WebhookScheduleCreated || event.Data.ID != "abc" {
+			t.Errorf("Expected the event in the body, got %+v", event)
+		}
+
+		// Nothing is due any more
+		if again := deliver(t, service); len(again) != 0 {
+			t.Errorf("Expected nothing to be sent again, got %+v", again)
+		}
+	})
+
+	t.Run("PositiveCase_EventTypeFilter", func(t *testing.T) {
+		now := serviceNow
+		service := newService(&now)
+		everything := register(t, service, WebhookSubscription{URL: "https://desk.example.com/hooks"})
+		register(t, service, WebhookSubscription{URL: "https://warehouse.example.com/hooks", EventTypes: []WebhookEventType{WebhookScheduleCancelled}})
+
+		if err := service.PublishScheduleEvent(ctx, WebhookScheduleUpdated, schedule); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		response, err := service.ListWebhookDeliveriesService(ctx, WebhookDeliveryQuery{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.TotalData != 1 || response.Data[0].SubscriptionID != everything.ID || response.Data[0].Status != WebhookDeliveryPending {
+			t.Errorf("Expected one pending delivery to the webhook of every event, got %+v", response.Data)
+		}
+	})
+
+	t.Run("NegativeCase_RetriedWithBackoffThenDead", func(t *testing.T) {
+		receiver := newWebhookReceiver(t, 500, 503, 500, 500)
+		now := serviceNow
+		service := newService(&now)
+		register(t, service, WebhookSubscription{URL: receiver.URL})
+		if err := service.PublishScheduleEvent(ctx, WebhookScheduleCancelled, schedule); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		first := deliver(t, service)
+		if len(first) != 1 || first[0].Status != WebhookDeliveryPending || first[0].LastStatusCode != 500 || !first[0].NextAttemptAt.Equal(now.Add(30*time.Second)) {
+			t.Fatalf("Expected a retry in 30s, got %+v", first)
+		}
+
+		// Not due before the wait is over
+		now = now.Add(29 * time.Second)
+		if tried := deliver(t, service); len(tried) != 0 {
+			t.Fatalf("Expected no try before the retry is due, got %+v", tried)
+		}
+
+		now = now.Add(time.Second)
+		second := deliver(t, service)
+		if len(second) != 1 || second[0].Attempts != 2 || second[0].LastStatusCode != 503 || !second[0].NextAttemptAt.Equal(now.Add(time.Minute)) {
+			t.Fatalf("Expected a retry in a minute, got %+v", second)
+		}
+
+		now = now.Add(time.Minute)
+		third := deliver(t, service)
+		if len(third) != 1 || third[0].Status != WebhookDeliveryDead || third[0].Attempts != 3 || third[0].NextAttemptAt != nil || third[0].LastError == "" {
+			t.Fatalf("Expected the delivery to be dead, got %+v", third)
+		}
+
+		dead, err := service.ListWebhookDeliveriesService(ctx, WebhookDeliveryQuery{Status: WebhookDeliveryDead})
+		if err != nil || dead.TotalData != 1 {
+			t.Errorf("Expected the delivery in the dead letters, got %+v (%v)", dead, err)
+		}
+
+		// A replay the webhook does not accept either is retried again
+		replayed, err := service.ReplayWebhookDeliveryService(ctx, third[0].ID)
+		if !errors.Is(err, ErrWebhookDeliveryFailed) {
+			t.Fatalf("Expected ErrWebhookDeliveryFailed, got %v", err)
+		}
+		if replayed.Status != "502 Bad Gateway" || replayed.Data.Status != WebhookDeliveryPending || replayed.Data.Attempts != 1 {
+			t.Errorf("Expected a pending delivery after the failed replay, got %+v", replayed)
+		}
+		if receiver.received() != 4 {
+			t.Errorf("Expected 4 requests, got %d", receiver.received())
+		}
+	})
+
+	t.Run("PositiveCase_Replay", func(t *testing.T) {
+		receiver := newWebhookReceiver(t, 500)
+		now := serviceNow
+		service := newService(&now)
+		register(t, service, WebhookSubscription{URL: receiver.URL})
+		if err := service.PublishScheduleEvent(ctx, WebhookScheduleCreated, schedule); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		pending := deliver(t, service)[0]
+
+		if _, err := service.ReplayWebhookDeliveryService(ctx, pending.ID); !errors.Is(err, ErrWebhookDeliveryPending) {
+			t.Fatalf("Expected ErrWebhookDeliveryPending, got %v", err)
+		}
+
+		now = now.Add(30 * time.Second)
+		delivered := deliver(t, service)[0]
+		replayed, err := service.ReplayWebhookDeliveryService(ctx, delivered.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if replayed.Data.Status != WebhookDeliveryDelivered || replayed.Data.Attempts != 1 || replayed.Data.Event.ID != pending.Event.ID {
+			t.Errorf("Expected the same event to be delivered again, got %+v", replayed.Data)
+		}
+		if receiver.received() != 3 {
+			t.Errorf("Expected 3 requests, got %d", receiver.received())
+		}
+	})
+
+	t.Run("NegativeCase_DeletedWebhook", func(t *testing.T) {
+		receiver := newWebhookReceiver(t)
+		now := serviceNow
+		service := newService(&now)
+		subscription := register(t, service, WebhookSubscription{URL: receiver.URL})
+		if err := service.PublishScheduleEvent(ctx, WebhookScheduleCreated, schedule); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := service.DeleteWebhookService(ctx, subscription.ID); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		tried := deliver(t, service)
+		if len(tried) != 1 || tried[0].Status != WebhookDeliveryDead || tried[0].LastError != "webhook was deleted" {
+			t.Errorf("Expected the delivery to be dead, got %+v", tried)
+		}
+		if receiver.received() != 0 {
+			t.Errorf("Expected no request, got %d", receiver.received())
+		}
+		if _, err := service.GetWebhookService(ctx, subscription.ID); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+		}
+	})
+
+	t.Run("PositiveCase_SecretOnlyOnCreate", func(t *testing.T) {
+		now := serviceNow
+		service := newService(&now)
+		created := register(t, service, WebhookSubscription{URL: "https://desk.example.com/hooks", EventTypes: []WebhookEventType{WebhookScheduleCreated, WebhookScheduleCreated}})
+
+		if !strings.HasPrefix(created.Secret, "whsec_") || len(created.EventTypes) != 1 {
+			t.Errorf("Expected a generated secret and one event type, got %+v", created)
+		}
+		fetched, err := service.GetWebhookService(ctx, created.ID)
+		if err != nil || fetched.Data.Secret != "" {
+			t.Errorf("Expected the secret to be hidden, got %+v (%v)", fetched, err)
+		}
+		listed, err := service.ListWebhooksService(ctx)
+		if err != nil || listed.TotalData != 1 || listed.Data[0].Secret != "" {
+			t.Errorf("Expected the secret to be hidden, got %+v (%v)", listed, err)
+		}
+	})
+
+	t.Run("NegativeCase_Invalid", func(t *testing.T) {
+		now := serviceNow
+		service := newService(&now)
+
+		response, err := service.CreateWebhookService(ctx, WebhookSubscription{URL: "desk.example.com", EventTypes: []WebhookEventType{"schedule.deleted"}, Secret: "short"})
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected a ValidationError, got %v", err)
+		}
+		if response.Status != "400 Bad Request" || len(response.Errors) != 3 {
+			t.Errorf("Expected errors of url, event_types and secret, got %+v", response)
+		}
+
+		listed, err := service.ListWebhookDeliveriesService(ctx, WebhookDeliveryQuery{Status: "lost"})
+		if !errors.As(err, &validationErr) || listed.Status != "400 Bad Request" {
+			t.Errorf("Expected an invalid status filter, got %+v (%v)", listed, err)
+		}
+	})
+}
+
+func TestWebhookConfig_RetryAfter(t *testing.T) {
+	config := WebhookConfig{MaxAttempts: 10, FirstRetry: 30 * time.Second, MaxRetry: 5 * time.Minute, Timeout: time.Second, Retention: time.Hour}
+
+	for attempts, want := range map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 4: 4 * time.Minute, 5: 5 * time.Minute, 9: 5 * time.Minute} {
+		if got := config.retryAfter(attempts); got != want {
+			t.Errorf("Expected a wait of %v after %d attempts, got %v", want, attempts, got)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := (WebhookConfig{MaxAttempts: 1, FirstRetry: time.Minute, MaxRetry: time.Second, Timeout: time.Second}).Validate(); err == nil {
+		t.Error("Expected error, but got nil")
+	}
+}
@@ -0,0 +1,332 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLWebhookRepository is a WebhookRepository on top of database/sql,
+// sharing the schema and migrations of SQLRepository
+type SQLWebhookRepository struct {
+	db *sql.DB
+}
+
+// NewSQLWebhookRepository applies every pending migration to db before
+// returning
+func NewSQLWebhookRepository(ctx context.Context, db *sql.DB) (*SQLWebhookRepository, error) {
+	if err := migrate(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return &SQLWebhookRepository{db: db}, nil
+}
+
+func (r *SQLWebhookRepository) CreateWebhook(ctx context.Context, subscription WebhookSubscription) (WebhookSubscription, error) {
+	subscription = cloneWebhook(subscription)
+	subscription.ID = newWebhookID()
+	subscription.CreatedAt = time.Now().UTC()
+
+	eventTypes := make([]string, 0, len(subscription.EventTypes))
+	for _, eventType := range subscription.EventTypes {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO webhooks (public_id, url, event_types, secret, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		subscription.ID, subscription.URL, strings.Join(eventTypes, ","), subscription.Secret, formatTime(subscription.CreatedAt))
+	if err != nil {
+		return WebhookSubscription{}, fmt.Errorf("failed to insert webhook: %v", err)
+	}
+
+	return subscription, nil
+}
+
+func (r *SQLWebhookRepository) GetWebhook(ctx context.Context, id string) (WebhookSubscription, error) {
+	subscriptions, err := r.queryWebhooks(ctx, `public_id = ?`, id)
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+	if len(subscriptions) == 0 {
+		return WebhookSubscription{}, ErrWebhookNotFound
+	}
+
+	return subscriptions[0], nil
+}
+
+func (r *SQLWebhookRepository) ListWebhooks(ctx context.Context) ([]WebhookSubscription, error) {
+	return r.queryWebhooks(ctx, `1 = 1`)
+}
+
+func (r *SQLWebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE public_id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %v", err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLWebhookRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) ([]WebhookDelivery, error) {
+	now := time.Now().UTC()
+	created := make([]WebhookDelivery, 0, len(deliveries))
+
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		for _, delivery := range deliveries {
+			delivery = cloneWebhookDelivery(delivery)
+			delivery.ID = newWebhookID()
+			delivery.CreatedAt = now
+			delivery.UpdatedAt = now
+
+			event, err := json.Marshal(delivery.Event)
+			if err != nil {
+				return fmt.Errorf("failed to encode webhook event: %v", err)
+			}
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO webhook_deliveries (public_id, webhook_id, event, status, attempts, next_attempt_at, last_attempt_at,
+					last_status_code, last_error, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				delivery.ID, delivery.SubscriptionID, string(event), delivery.Status, delivery.Attempts,
+				nullTime(delivery.NextAttemptAt), nullTime(delivery.LastAttemptAt), delivery.LastStatusCode, delivery.LastError,
+				formatTime(delivery.CreatedAt), formatTime(delivery.UpdatedAt))
+			if err != nil {
+				return fmt.Errorf("failed to insert webhook delivery: %v", err)
+			}
+			created = append(created, delivery)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *SQLWebhookRepository) GetWebhookDelivery(ctx context.Context, id string) (WebhookDelivery, error) {
+	return r.deliveryByID(ctx, r.db, id)
+}
+
+func (r *SQLWebhookRepository) ListWebhookDeliveries(ctx context.Context, query WebhookDeliveryQuery) ([]WebhookDelivery, error) {
+	var conditions []string
+	var args []interface{}
+	if query.SubscriptionID != "" {
+		conditions = append(conditions, `webhook_id = ?`)
+		args = append(args, query.SubscriptionID)
+	}
+	if query.Status != "" {
+		conditions = append(conditions, `status = ?`)
+		args = append(args, query.Status)
+	}
+	if !query.DueBy.IsZero() {
+		conditions = append(conditions, `next_attempt_at <= ?`)
+		args = append(args, formatTime(query.DueBy))
+	}
+
+	where := `1 = 1`
+	if len(conditions) > 0 {
+		where = strings.Join(conditions, ` AND `)
+	}
+
+	return r.queryDeliveries(ctx, r.db, where, args...)
+}
+
+func (r *SQLWebhookRepository) UpdateWebhookDelivery(ctx context.Context, id string, fn func(delivery *WebhookDelivery) error) (WebhookDelivery, error) {
+	var delivery WebhookDelivery
+
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		if delivery, err = r.deliveryByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if err := fn(&delivery); err != nil {
+			return err
+		}
+		delivery.UpdatedAt = time.Now().UTC()
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = ?, attempts = ?, next_attempt_at = ?, last_attempt_at = ?, last_status_code = ?, last_error = ?, updated_at = ?
+			WHERE public_id = ?`,
+			delivery.Status, delivery.Attempts, nullTime(delivery.NextAttemptAt), nullTime(delivery.LastAttemptAt),
+			delivery.LastStatusCode, delivery.LastError, formatTime(delivery.UpdatedAt), id)
+		if err != nil {
+			return fmt.Errorf("failed to update webhook delivery: %v", err)
+		}
+
+		delivery, err = r.deliveryByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+func (r *SQLWebhookRepository) PruneWebhookDeliveries(ctx context.Context, deliveredBefore time.Time, keepDead int) (int, error) {
+	var dropped int64
+	err := runInTx(ctx, r.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM webhook_deliveries
+			WHERE status = ? AND updated_at < ?`,
+			WebhookDeliveryDelivered, formatTime(deliveredBefore))
+		if err != nil {
+			return fmt.Errorf("failed to drop delivered webhook deliveries: %v", err)
+		}
+		delivered, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to drop delivered webhook deliveries: %v", err)
+		}
+
+		result, err = tx.ExecContext(ctx, `
+			DELETE FROM webhook_deliveries
+			WHERE status = ? AND id NOT IN (
+				SELECT id FROM webhook_deliveries WHERE status = ? ORDER BY id DESC LIMIT ?
+			)`,
+			WebhookDeliveryDead, WebhookDeliveryDead, keepDead)
+		if err != nil {
+			return fmt.Errorf("failed to drop dead webhook deliveries: %v", err)
+		}
+		dead, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to drop dead webhook deliveries: %v", err)
+		}
+
+		dropped = delivered + dead
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(dropped), nil
+}
+
+// queryWebhooks loads the subscriptions matching the where clause in the
+// order they were created
+func (r *SQLWebhookRepository) queryWebhooks(ctx context.Context, where string, args ...interface{}) ([]WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT public_id, url, event_types, secret, created_at
+		FROM webhooks
+		WHERE `+where+`
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhooks: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	subscriptions := []WebhookSubscription{}
+	for rows.Next() {
+		var eventTypes, createdAt string
+		subscription := WebhookSubscription{EventTypes: []WebhookEventType{}}
+		if err := rows.Scan(&subscription.ID, &subscription.URL, &eventTypes, &subscription.Secret, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to read webhook: %v", err)
+		}
+		if eventTypes != "" {
+			for _, eventType := range strings.Split(eventTypes, ",") {
+				subscription.EventTypes = append(subscription.EventTypes, WebhookEventType(eventType))
+			}
+		}
+		if subscription.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read webhooks: %v", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *SQLWebhookRepository) deliveryByID(ctx context.Context, q querier, id string) (WebhookDelivery, error) {
+	deliveries, err := r.queryDeliveries(ctx, q, `public_id = ?`, id)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return WebhookDelivery{}, ErrWebhookDeliveryNotFound
+	}
+
+	return deliveries[0], nil
+}
+
+// queryDeliveries loads the deliveries matching the where clause in the order
+// they were created
+func (r *SQLWebhookRepository) queryDeliveries(ctx context.Context, q querier, where string, args ...interface{}) ([]WebhookDelivery, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT public_id, webhook_id, event, status, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error,
+			created_at, updated_at
+		FROM webhook_deliveries
+		WHERE `+where+`
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook deliveries: %v", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var event, createdAt, updatedAt string
+		var nextAttemptAt, lastAttemptAt sql.NullString
+		var delivery WebhookDelivery
+		err := rows.Scan(&delivery.ID, &delivery.SubscriptionID, &event, &delivery.Status, &delivery.Attempts, &nextAttemptAt,
+			&lastAttemptAt, &delivery.LastStatusCode, &delivery.LastError, &createdAt, &updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook delivery: %v", err)
+		}
+		if err := json.Unmarshal([]byte(event), &delivery.Event); err != nil {
+			return nil, fmt.Errorf("failed to decode webhook event: %v", err)
+		}
+		if delivery.NextAttemptAt, err = parseNullTime(nextAttemptAt); err != nil {
+			return nil, err
+		}
+		if delivery.LastAttemptAt, err = parseNullTime(lastAttemptAt); err != nil {
+			return nil, err
+		}
+		if delivery.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		if delivery.UpdatedAt, err = parseTime(updatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read webhook deliveries: %v", err)
+	}
+
+	return deliveries, nil
+}
+
+// nullTime stores an optional time, NULL when it is not set
+func nullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
+
+// parseNullTime reads an optional time stored by nullTime
+func parseNullTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	t, err := parseTime(value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
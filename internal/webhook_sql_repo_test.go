@@ -0,0 +1,7 @@
+package internal
+
+import "testing"
+
+func TestSQLWebhookRepository_Suite(t *testing.T) {
+	testWebhookRepository(t, sqlStoreOf(NewSQLWebhookRepository))
+}
@@ -3,29 +3,630 @@ package main
 import (
 	"context"
 	"costmart-backend-test/internal"
+	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/julienschmidt/httprouter"
+	"io"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "modernc.org/sqlite"
 )
 
 func main() {
-	// Initialize context
-	ctx := context.Background()
+	// Initialize context, cancelled once the server is asked to stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Initialize http router
 	router := httprouter.New()
 
-	// Initialize book module with in-memory storage
-	bookRepo := internal.NewInMemoryRepository(ctx)
-	bookService := internal.NewService(bookRepo)
-	bookHandler := internal.NewHandler(bookService)
+	// Initialize the storage of every module, which outlives the context so
+	// the requests in flight can still use it while the server stops
+	repos, err := newRepositories(context.WithoutCancel(ctx))
+	if err != nil {
+		log.Fatalf("failed to initialize book storage: %v", err)
+	}
+	bookingHorizon, err := bookingHorizon()
+	if err != nil {
+		log.Fatalf("failed to read booking horizon: %v", err)
+	}
+	slots, err := slotConfig()
+	if err != nil {
+		log.Fatalf("failed to read slot configuration: %v", err)
+	}
+	loans, err := loanConfig()
+	if err != nil {
+		log.Fatalf("failed to read loan configuration: %v", err)
+	}
+	overdueCheckInterval, err := overdueCheckInterval()
+	if err != nil {
+		log.Fatalf("failed to read overdue check interval: %v", err)
+	}
+	holds, err := holdConfig()
+	if err != nil {
+		log.Fatalf("failed to read hold configuration: %v", err)
+	}
+	holdCheckInterval, err := holdCheckInterval()
+	if err != nil {
+		log.Fatalf("failed to read hold check interval: %v", err)
+	}
+	limits, err := borrowerLimits()
+	if err != nil {
+		log.Fatalf("failed to read borrower limits: %v", err)
+	}
+	idempotency, err := idempotencyConfig()
+	if err != nil {
+		log.Fatalf("failed to read idempotency configuration: %v", err)
+	}
+	channel, err := notificationChannel()
+	if err != nil {
+		log.Fatalf("failed to read SMTP configuration: %v", err)
+	}
+	notifications, err := notificationConfig()
+	if err != nil {
+		log.Fatalf("failed to read notification configuration: %v", err)
+	}
+	reminderCheckInterval, err := reminderCheckInterval()
+	if err != nil {
+		log.Fatalf("failed to read reminder check interval: %v", err)
+	}
+	webhooks, err := webhookConfig()
+	if err != nil {
+		log.Fatalf("failed to read webhook configuration: %v", err)
+	}
+	webhookCheckInterval, err := webhookCheckInterval()
+	if err != nil {
+		log.Fatalf("failed to read webhook check interval: %v", err)
+	}
+	eventBuffer, err := eventBufferSize()
+	if err != nil {
+		log.Fatalf("failed to read event buffer size: %v", err)
+	}
+	webhookService := internal.NewWebhookService(repos.webhooks, webhooks)
+	eventStream := internal.NewScheduleEventStream(eventBuffer)
+	options := []internal.ServiceOption{
+		internal.WithBookingHorizon(bookingHorizon),
+		internal.WithSlots(slots),
+		internal.WithLoans(loans),
+		internal.WithHolds(repos.holds, holds),
+		internal.WithBorrowerLimits(limits),
+		internal.WithClosures(repos.closures),
+		internal.WithWebhooks(webhookService),
+		internal.WithEventStream(eventStream),
+	}
+	if channel != nil {
+		options = append(options, internal.WithNotifications(channel, repos.notifications, notifications))
+	}
+	bookService := internal.NewService(repos.books, repos.branches, repos.borrowers, options...)
+	bookHandler := internal.NewHandler(bookService, internal.WithIdempotencyKeys(repos.idempotency, idempotency))
+	branchHandler := internal.NewBranchHandler(internal.NewBranchService(repos.branches))
+	borrowerHandler := internal.NewBorrowerHandler(internal.NewBorrowerService(repos.borrowers))
+	closureHandler := internal.NewClosureHandler(internal.NewClosureService(repos.closures, repos.branches))
+	webhookHandler := internal.NewWebhookHandler(webhookService)
+	eventHandler := internal.NewEventHandler(eventStream)
 
 	// Define API routes
 	router.GET("/books/:genre", bookHandler.GetBooksByGenreHandler)
 	router.POST("/books/schedule", bookHandler.SubmitPickUpScheduleHandler)
+	router.GET("/books/:genre/:id", bookHandler.GetPickUpScheduleHandler) // GET /books/schedule/:id
+	router.DELETE("/books/schedule/:id", bookHandler.CancelPickUpScheduleHandler)
+	router.PATCH("/books/schedule/:id", bookHandler.ReschedulePickUpScheduleHandler)
+	router.POST("/books/schedule/:id/status", bookHandler.TransitionPickUpScheduleHandler)
+	router.GET("/books/:genre/:id/history", bookHandler.GetPickUpScheduleHistoryHandler)       // GET /books/schedule/:id/history
+	router.GET("/books/:genre/:id/calendar.ics", bookHandler.GetPickUpScheduleCalendarHandler) // GET /books/schedule/:id/calendar.ics
+	router.GET("/schedules", bookHandler.ListPickUpSchedulesHandler)
+	router.POST("/schedules/batch", bookHandler.SubmitPickUpSchedulesHandler)
+	router.GET("/schedules/events", eventHandler.StreamScheduleEventsHandler)
+	router.GET("/slots", bookHandler.ListSlotsHandler)
+	router.GET("/loans/overdue", bookHandler.ListOverdueLoansHandler)
+	router.POST("/holds", bookHandler.PlaceHoldHandler)
+	router.GET("/holds/:id", bookHandler.GetHoldHandler)
+	router.POST("/holds/:id/confirm", bookHandler.ConfirmHoldHandler)
+	router.DELETE("/holds/:id", bookHandler.CancelHoldHandler)
+	router.GET("/branches", branchHandler.ListBranchesHandler)
+	router.POST("/branches", branchHandler.CreateBranchHandler)
+	router.GET("/branches/:id", branchHandler.GetBranchHandler)
+	router.PUT("/branches/:id/hours", branchHandler.SetOpeningHoursHandler)
+	router.PUT("/branches/:id/loan-periods", branchHandler.SetLoanPeriodsHandler)
+	router.GET("/branches/:id/inventory", branchHandler.ListInventoryHandler)
+	router.PUT("/branches/:id/inventory", branchHandler.SetCopiesHandler)
+	router.GET("/branches/:id/closures", closureHandler.ListClosuresHandler)
+	router.POST("/branches/:id/closures", closureHandler.CreateClosureHandler)
+	router.DELETE("/branches/:id/closures/:closure_id", closureHandler.DeleteClosureHandler)
+	router.GET("/branches/:id/calendar.ics", bookHandler.GetBranchCalendarHandler)
+	router.GET("/branches/:id/manifest", bookHandler.GetPickUpManifestHandler)
+	router.GET("/borrowers", borrowerHandler.ListBorrowersHandler)
+	router.POST("/borrowers", borrowerHandler.CreateBorrowerHandler)
+	router.GET("/borrowers/:id", borrowerHandler.GetBorrowerHandler)
+	router.GET("/borrowers/:id/calendar.ics", bookHandler.GetBorrowerCalendarHandler)
+	router.GET("/webhooks", webhookHandler.ListWebhooksHandler)
+	router.POST("/webhooks", webhookHandler.CreateWebhookHandler)
+	router.GET("/webhooks/:id", webhookHandler.GetWebhookHandler)
+	router.DELETE("/webhooks/:id", webhookHandler.DeleteWebhookHandler)
+	router.GET("/webhooks/:id/deliveries", webhookHandler.ListWebhookDeliveriesHandler)
+	router.GET("/webhook-deliveries", webhookHandler.ListWebhookDeliveriesHandler) // ?status=dead lists the dead letters
+	router.GET("/webhook-deliveries/:id", webhookHandler.GetWebhookDeliveryHandler)
+	router.POST("/webhook-deliveries/:id/replay", webhookHandler.ReplayWebhookDeliveryHandler)
 
-	// Run the server
-	err := http.ListenAndServe(":8080", router)
-	if err != nil {
-		return
+	// Flag loans past their due date, serve the hold queues, send webhook
+	// deliveries and remind borrowers of their pick-ups in the background
+	go internal.RunOverdueCheck(ctx, bookService, overdueCheckInterval)
+	go internal.RunHoldCheck(ctx, bookService, holdCheckInterval)
+	go internal.RunWebhookDelivery(ctx, webhookService, webhookCheckInterval)
+	if channel != nil {
+		go internal.RunReminderCheck(ctx, bookService, reminderCheckInterval)
+	}
+
+	// Run the server until it is asked to stop, then wait for the requests in
+	// flight to finish before the storage is closed
+	server := &http.Server{Addr: ":8080", Handler: router}
+	// Event streams only end with their client, so they are ended for it
+	server.RegisterOnShutdown(eventStream.Close)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			_ = server.Close()
+		}
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("server stopped: %v", err)
+		stop()
+	}
+	<-stopped
+	if err := repos.close(); err != nil {
+		log.Printf("failed to close storage: %v", err)
+	}
+}
+
+// shutdownTimeout is how long requests in flight get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// repositories are where every module keeps its data
+type repositories struct {
+	books         internal.BookRepository
+	branches      internal.BranchRepository
+	borrowers     internal.BorrowerRepository
+	closures      internal.ClosureRepository
+	holds         internal.HoldRepository
+	idempotency   internal.IdempotencyRepository
+	notifications internal.NotificationRepository
+	webhooks      internal.WebhookRepository
+
+	// closers are closed once the server stopped
+	closers []io.Closer
+}
+
+// close closes the storage, so the file repositories write their snapshots
+// and the database is released
+func (r repositories) close() error {
+	var errs []error
+	for _, closer := range r.closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newRepositories opens the storage of every module selected by BOOK_STORAGE:
+// "memory" (default), "file", which keeps its data in BOOK_DATA_DIR (default
+// ./data), or "sqlite", which opens BOOK_DATABASE_DSN
+func newRepositories(ctx context.Context) (repositories, error) {
+	switch storage := getEnv("BOOK_STORAGE", "memory"); storage {
+	case "memory":
+		return repositories{
+			books:         internal.NewInMemoryRepository(ctx),
+			branches:      internal.NewInMemoryBranchRepository(),
+			borrowers:     internal.NewInMemoryBorrowerRepository(),
+			closures:      internal.NewInMemoryClosureRepository(),
+			holds:         internal.NewInMemoryHoldRepository(),
+			idempotency:   internal.NewInMemoryIdempotencyRepository(),
+			notifications: internal.NewInMemoryNotificationRepository(),
+			webhooks:      internal.NewInMemoryWebhookRepository(),
+		}, nil
+	case "file":
+		dir := getEnv("BOOK_DATA_DIR", "./data")
+		bookRepo, err := internal.NewFileRepository(ctx, dir)
+		if err != nil {
+			return repositories{}, err
+		}
+		branchRepo, err := internal.NewFileBranchRepository(dir)
+		if err != nil {
+			return repositories{}, err
+		}
+		borrowerRepo, err := internal.NewFileBorrowerRepository(dir)
+		if err != nil {
+			return repositories{}, err
+		}
+		closureRepo, err := internal.NewFileClosureRepository(dir)
+		if err != nil {
+			return repositories{}, err
+		}
+		holdRepo, err := internal.NewFileHoldRepository(dir)
+		if err != nil {
+			return repositories{}, err
+		}
+		idempotencyRepo, err := internal.NewFileIdempotencyRepository(dir)
+		if err != nil {
+			return repositories{}, err
+		}
+		notificationRepo, err := internal.NewFileNotificationRepository(dir)
+		if err != nil {
+			return repositories{}, err
+		}
+		webhookRepo, err := internal.NewFileWebhookRepository(dir)
+		if err != nil {
+			return repositories{}, err
+		}
+		return repositories{
+			books: bookRepo, branches: branchRepo, borrowers: borrowerRepo, closures: closureRepo, holds: holdRepo, idempotency: idempotencyRepo, notifications: notificationRepo, webhooks: webhookRepo,
+			closers: []io.Closer{bookRepo, idempotencyRepo, notificationRepo, webhookRepo},
+		}, nil
+	case "sqlite":
+		db, err := sql.Open("sqlite", getEnv("BOOK_DATABASE_DSN", "file:books.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"))
+		if err != nil {
+			return repositories{}, err
+		}
+
+		// SQLite allows a single writer, serialize access through one connection
+		db.SetMaxOpenConns(1)
+
+		bookRepo, err := internal.NewSQLRepository(ctx, db)
+		if err != nil {
+			return repositories{}, err
+		}
+		branchRepo, err := internal.NewSQLBranchRepository(ctx, db)
+		if err != nil {
+			return repositories{}, err
+		}
+		borrowerRepo, err := internal.NewSQLBorrowerRepository(ctx, db)
+		if err != nil {
+			return repositories{}, err
+		}
+		closureRepo, err := internal.NewSQLClosureRepository(ctx, db)
+		if err != nil {
+			return repositories{}, err
+		}
+		holdRepo, err := internal.NewSQLHoldRepository(ctx, db)
+		if err != nil {
+			return repositories{}, err
+		}
+		idempotencyRepo, err := internal.NewSQLIdempotencyRepository(ctx, db)
+		if err != nil {
+			return repositories{}, err
+		}
+		notificationRepo, err := internal.NewSQLNotificationRepository(ctx, db)
+		if err != nil {
+			return repositories{}, err
+		}
+		webhookRepo, err := internal.NewSQLWebhookRepository(ctx, db)
+		if err != nil {
+			return repositories{}, err
+		}
+		return repositories{
+			books: bookRepo, branches: branchRepo, borrowers: borrowerRepo, closures: closureRepo, holds: holdRepo, idempotency: idempotencyRepo, notifications: notificationRepo, webhooks: webhookRepo,
+			closers: []io.Closer{db},
+		}, nil
+	default:
+		return repositories{}, fmt.Errorf("unknown BOOK_STORAGE %q", storage)
+	}
+}
+
+// bookingHorizon reads how many days ahead pick-ups can be booked from
+// BOOK_BOOKING_HORIZON_DAYS
+func bookingHorizon() (time.Duration, error) {
+	value := getEnv("BOOK_BOOKING_HORIZON_DAYS", "")
+	if value == "" {
+		return internal.DefaultBookingHorizon, nil
+	}
+
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("BOOK_BOOKING_HORIZON_DAYS must be a number of days, got %q", value)
+	}
+
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// slotConfig reads the pick-up slot length in minutes from BOOK_SLOT_MINUTES
+// and the pick-ups per slot from BOOK_SLOT_CAPACITY
+func slotConfig() (internal.SlotConfig, error) {
+	config := internal.DefaultSlotConfig
+
+	if value := getEnv("BOOK_SLOT_MINUTES", ""); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_SLOT_MINUTES must be a number of minutes, got %q", value)
+		}
+		config.Length = time.Duration(minutes) * time.Minute
+	}
+	if value := getEnv("BOOK_SLOT_CAPACITY", ""); value != "" {
+		capacity, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_SLOT_CAPACITY must be a number, got %q", value)
+		}
+		config.Capacity = capacity
+	}
+
+	return config, config.Validate()
+}
+
+// loanConfig reads the loan period in days from BOOK_LOAN_DAYS and the
+// periods of single genres from BOOK_GENRE_LOAN_DAYS, like "fiction=21,love=7"
+func loanConfig() (internal.LoanConfig, error) {
+	config := internal.DefaultLoanConfig
+
+	if value := getEnv("BOOK_LOAN_DAYS", ""); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_LOAN_DAYS must be a number of days, got %q", value)
+		}
+		config.Days = days
+	}
+	if value := getEnv("BOOK_GENRE_LOAN_DAYS", ""); value != "" {
+		config.GenreDays = make(map[string]int)
+		for _, entry := range strings.Split(value, ",") {
+			genre, days, found := strings.Cut(strings.TrimSpace(entry), "=")
+			number, err := strconv.Atoi(days)
+			if !found || genre == "" || err != nil {
+				return config, fmt.Errorf("BOOK_GENRE_LOAN_DAYS must look like fiction=21,love=7, got %q", value)
+			}
+			config.GenreDays[genre] = number
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// overdueCheckInterval reads how often loans are checked for being overdue
+// from BOOK_OVERDUE_CHECK_MINUTES
+func overdueCheckInterval() (time.Duration, error) {
+	value := getEnv("BOOK_OVERDUE_CHECK_MINUTES", "")
+	if value == "" {
+		return internal.DefaultOverdueCheckInterval, nil
+	}
+
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 1 {
+		return 0, fmt.Errorf("BOOK_OVERDUE_CHECK_MINUTES must be a number of minutes, got %q", value)
+	}
+
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// holdConfig reads how many hours a borrower has to confirm the pick-up
+// offered to their hold from BOOK_HOLD_CONFIRM_HOURS
+func holdConfig() (internal.HoldConfig, error) {
+	config := internal.DefaultHoldConfig
+
+	if value := getEnv("BOOK_HOLD_CONFIRM_HOURS", ""); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_HOLD_CONFIRM_HOURS must be a number of hours, got %q", value)
+		}
+		config.ConfirmWithin = time.Duration(hours) * time.Hour
+	}
+
+	return config, config.Validate()
+}
+
+// holdCheckInterval reads how often lapsed offers are expired and the hold
+// queues are served from BOOK_HOLD_CHECK_MINUTES
+func holdCheckInterval() (time.Duration, error) {
+	value := getEnv("BOOK_HOLD_CHECK_MINUTES", "")
+	if value == "" {
+		return internal.DefaultHoldCheckInterval, nil
+	}
+
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 1 {
+		return 0, fmt.Errorf("BOOK_HOLD_CHECK_MINUTES must be a number of minutes, got %q", value)
+	}
+
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// borrowerLimits reads how many active loans a borrower can have from
+// BOOK_MAX_ACTIVE_LOANS and how many pick-ups a week from
+// BOOK_MAX_PICK_UPS_PER_WEEK, where 0 lifts the limit
+func borrowerLimits() (internal.BorrowerLimits, error) {
+	limits := internal.DefaultBorrowerLimits
+
+	if value := getEnv("BOOK_MAX_ACTIVE_LOANS", ""); value != "" {
+		loans, err := strconv.Atoi(value)
+		if err != nil {
+			return limits, fmt.Errorf("BOOK_MAX_ACTIVE_LOANS must be a number, got %q", value)
+		}
+		limits.MaxActiveLoans = loans
+	}
+	if value := getEnv("BOOK_MAX_PICK_UPS_PER_WEEK", ""); value != "" {
+		pickUps, err := strconv.Atoi(value)
+		if err != nil {
+			return limits, fmt.Errorf("BOOK_MAX_PICK_UPS_PER_WEEK must be a number, got %q", value)
+		}
+		limits.MaxPickUpsPerWeek = pickUps
+	}
+
+	return limits, limits.Validate()
+}
+
+// idempotencyConfig reads how many hours an Idempotency-Key is remembered
+// from BOOK_IDEMPOTENCY_WINDOW_HOURS
+func idempotencyConfig() (internal.IdempotencyConfig, error) {
+	config := internal.DefaultIdempotencyConfig
+
+	if value := getEnv("BOOK_IDEMPOTENCY_WINDOW_HOURS", ""); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_IDEMPOTENCY_WINDOW_HOURS must be a number of hours, got %q", value)
+		}
+		config.Window = time.Duration(hours) * time.Hour
+	}
+
+	return config, config.Validate()
+}
+
+// notificationChannel emails borrowers through the SMTP server at
+// BOOK_SMTP_ADDR, sending from BOOK_SMTP_FROM and logging in with
+// BOOK_SMTP_USERNAME and BOOK_SMTP_PASSWORD when set. Without a server nothing
+// is sent and it returns nil.
+func notificationChannel() (internal.NotificationChannel, error) {
+	addr := getEnv("BOOK_SMTP_ADDR", "")
+	if addr == "" {
+		return nil, nil
+	}
+
+	return internal.NewSMTPChannel(internal.SMTPConfig{
+		Addr:     addr,
+		From:     getEnv("BOOK_SMTP_FROM", ""),
+		Username: getEnv("BOOK_SMTP_USERNAME", ""),
+		Password: getEnv("BOOK_SMTP_PASSWORD", ""),
+	})
+}
+
+// notificationConfig reads how many hours before a pick-up its borrower is
+// reminded of it from BOOK_REMINDER_HOURS and how many days sent
+// notifications are remembered from BOOK_NOTIFICATION_RETENTION_DAYS
+func notificationConfig() (internal.NotificationConfig, error) {
+	config := internal.DefaultNotificationConfig
+
+	if value := getEnv("BOOK_REMINDER_HOURS", ""); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_REMINDER_HOURS must be a number of hours, got %q", value)
+		}
+		config.RemindBefore = time.Duration(hours) * time.Hour
+	}
+	if value := getEnv("BOOK_NOTIFICATION_RETENTION_DAYS", ""); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_NOTIFICATION_RETENTION_DAYS must be a number of days, got %q", value)
+		}
+		config.Retention = time.Duration(days) * 24 * time.Hour
+	}
+
+	return config, config.Validate()
+}
+
+// reminderCheckInterval reads how often borrowers are reminded of their
+// upcoming pick-ups from BOOK_REMINDER_CHECK_MINUTES
+func reminderCheckInterval() (time.Duration, error) {
+	value := getEnv("BOOK_REMINDER_CHECK_MINUTES", "")
+	if value == "" {
+		return internal.DefaultReminderCheckInterval, nil
+	}
+
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 1 {
+		return 0, fmt.Errorf("BOOK_REMINDER_CHECK_MINUTES must be a number of minutes, got %q", value)
+	}
+
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// webhookConfig reads how many times a webhook delivery is tried from
+// BOOK_WEBHOOK_MAX_ATTEMPTS, the wait before the first retry from
+// BOOK_WEBHOOK_FIRST_RETRY_SECONDS, the longest wait from
+// BOOK_WEBHOOK_MAX_RETRY_MINUTES, how long a try may take from
+// BOOK_WEBHOOK_TIMEOUT_SECONDS, how many days delivered deliveries are kept
+// from BOOK_WEBHOOK_RETENTION_DAYS and how many dead ones from
+// BOOK_WEBHOOK_MAX_DEAD
+func webhookConfig() (internal.WebhookConfig, error) {
+	config := internal.DefaultWebhookConfig
+
+	if value := getEnv("BOOK_WEBHOOK_MAX_ATTEMPTS", ""); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_WEBHOOK_MAX_ATTEMPTS must be a number, got %q", value)
+		}
+		config.MaxAttempts = attempts
+	}
+	if value := getEnv("BOOK_WEBHOOK_FIRST_RETRY_SECONDS", ""); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_WEBHOOK_FIRST_RETRY_SECONDS must be a number of seconds, got %q", value)
+		}
+		config.FirstRetry = time.Duration(seconds) * time.Second
+	}
+	if value := getEnv("BOOK_WEBHOOK_MAX_RETRY_MINUTES", ""); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_WEBHOOK_MAX_RETRY_MINUTES must be a number of minutes, got %q", value)
+		}
+		config.MaxRetry = time.Duration(minutes) * time.Minute
+	}
+	if value := getEnv("BOOK_WEBHOOK_TIMEOUT_SECONDS", ""); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_WEBHOOK_TIMEOUT_SECONDS must be a number of seconds, got %q", value)
+		}
+		config.Timeout = time.Duration(seconds) * time.Second
+	}
+	if value := getEnv("BOOK_WEBHOOK_RETENTION_DAYS", ""); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_WEBHOOK_RETENTION_DAYS must be a number of days, got %q", value)
+		}
+		config.Retention = time.Duration(days) * 24 * time.Hour
+	}
+	if value := getEnv("BOOK_WEBHOOK_MAX_DEAD", ""); value != "" {
+		dead, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("BOOK_WEBHOOK_MAX_DEAD must be a number, got %q", value)
+		}
+		config.MaxDead = dead
+	}
+
+	return config, config.Validate()
+}
+
+// webhookCheckInterval reads how often due webhook deliveries are sent from
+// BOOK_WEBHOOK_CHECK_SECONDS
+func webhookCheckInterval() (time.Duration, error) {
+	value := getEnv("BOOK_WEBHOOK_CHECK_SECONDS", "")
+	if value == "" {
+		return internal.DefaultWebhookCheckInterval, nil
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 1 {
+		return 0, fmt.Errorf("BOOK_WEBHOOK_CHECK_SECONDS must be a number of seconds, got %q", value)
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// eventBufferSize reads how many of the latest schedule events are kept for
+// event stream clients resuming with Last-Event-ID from BOOK_EVENT_BUFFER_SIZE
+func eventBufferSize() (int, error) {
+	value := getEnv("BOOK_EVENT_BUFFER_SIZE", "")
+	if value == "" {
+		return internal.DefaultEventBufferSize, nil
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 {
+		return 0, fmt.Errorf("BOOK_EVENT_BUFFER_SIZE must be a number of events, got %q", value)
+	}
+
+	return size, nil
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
 	}
+	return fallback
 }